@@ -122,12 +122,15 @@ func (client *Ristretto) Get(key string) (any, bool) {
 
 func (client *Ristretto) Set(key string, value any, expire time.Duration) bool {
 	size := util.Tenary(client.limit > 0, int64(unsafe.Sizeof(value)), 0)
-	return client.cache.SetWithTTL(key, value, size, expire)
+	return client.SetWithCost(key, value, size, expire)
 }
 
 func (client *Ristretto) SetWithCost(key string, value any, cost int64, expire time.Duration) bool {
 	size := util.Tenary(client.limit > 0, cost, 0)
-	return client.cache.SetWithTTL(key, value, size, expire)
+	ok := client.cache.SetWithTTL(key, value, size, expire)
+	// ristretto applies sets through a buffer, wait so that the value is visible once returned
+	client.cache.Wait()
+	return ok
 }
 
 func (client *Ristretto) Del(key string) {
@@ -4,7 +4,7 @@ import (
 	"github.com/spf13/viper"
 )
 
-const AppConfigVersion = "1.3.0"
+const AppConfigVersion = "1.4.0"
 
 var (
 	AppConfig *viper.Viper
@@ -50,6 +50,14 @@ func init() {
 	AppConfig.SetDefault("storage.s3.bucket", "BUCKET")
 	AppConfig.SetDefault("storage.s3.region", "REGION")
 
+	AppConfig.SetDefault("mail.driver", "")
+	AppConfig.SetDefault("mail.from", "maintainman@localhost")
+	AppConfig.SetDefault("mail.file.path", "./mails")
+	AppConfig.SetDefault("mail.smtp.host", "localhost")
+	AppConfig.SetDefault("mail.smtp.port", 25)
+	AppConfig.SetDefault("mail.smtp.username", "")
+	AppConfig.SetDefault("mail.smtp.password", "")
+
 	AppConfig.SetDefault("bus_buffer", 1000)
 
 	ReadAndUpdateConfig(AppConfig, "app", AppConfigVersion)
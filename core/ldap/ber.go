@@ -0,0 +1,188 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// identifier octet of BER elements, only the low tag numbers (< 31) are supported.
+const (
+	classUniversal   = 0x00
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagEnumerated  = 0x0a
+	tagSequence    = constructed | 0x10
+	tagSet         = constructed | 0x11
+)
+
+// maxPacketSize limits the size of a message read from the peer.
+const maxPacketSize = 16 << 20
+
+// packet is a BER encoded element, whose children are decoded if it is constructed.
+type packet struct {
+	id       byte
+	value    []byte
+	children []*packet
+}
+
+func newPacket(id byte, children ...*packet) *packet {
+	return &packet{id: id, children: children}
+}
+
+func newString(id byte, s string) *packet {
+	return &packet{id: id, value: []byte(s)}
+}
+
+func newInt(id byte, v int64) *packet {
+	// two's complement in the fewest octets
+	value := []byte{byte(v)}
+	for v >>= 8; !(v == 0 && value[0]&0x80 == 0) && !(v == -1 && value[0]&0x80 != 0); v >>= 8 {
+		value = append([]byte{byte(v)}, value...)
+	}
+	return &packet{id: id, value: value}
+}
+
+func newBool(v bool) *packet {
+	if v {
+		return &packet{id: tagBoolean, value: []byte{0xff}}
+	}
+	return &packet{id: tagBoolean, value: []byte{0x00}}
+}
+
+func (p *packet) isConstructed() bool {
+	return p.id&constructed != 0
+}
+
+func (p *packet) str() string {
+	return string(p.value)
+}
+
+func (p *packet) int() int64 {
+	if len(p.value) == 0 {
+		return 0
+	}
+	v := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v
+}
+
+// child returns the i-th child, or an empty packet if absent, so that malformed
+// messages are rejected by the checks of the identifiers instead of panicking.
+func (p *packet) child(i int) *packet {
+	if i < len(p.children) {
+		return p.children[i]
+	}
+	return &packet{}
+}
+
+func (p *packet) encode() []byte {
+	content := p.value
+	if p.isConstructed() {
+		content = nil
+		for _, c := range p.children {
+			content = append(content, c.encode()...)
+		}
+	}
+	data := []byte{p.id}
+	if n := len(content); n < 0x80 {
+		data = append(data, byte(n))
+	} else {
+		length := []byte{}
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		data = append(data, 0x80|byte(len(length)))
+		data = append(data, length...)
+	}
+	return append(data, content...)
+}
+
+func readPacket(r *bufio.Reader) (*packet, error) {
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return parsePacket(id, content)
+}
+
+func readLength(r io.ByteReader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b < 0x80 {
+		return int(b), nil
+	}
+	n := int(b & 0x7f)
+	if n == 0 || n > 4 {
+		return 0, fmt.Errorf("unsupported ber length of %d octets", n)
+	}
+	length := 0
+	for i := 0; i < n; i++ {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxPacketSize {
+		return 0, fmt.Errorf("ber element too large: %d", length)
+	}
+	return length, nil
+}
+
+func parsePacket(id byte, content []byte) (*packet, error) {
+	if id&0x1f == 0x1f {
+		return nil, fmt.Errorf("unsupported ber high tag number")
+	}
+	p := &packet{id: id}
+	if !p.isConstructed() {
+		p.value = content
+		return p, nil
+	}
+	for len(content) > 0 {
+		child, n, err := decodePacket(content)
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		content = content[n:]
+	}
+	return p, nil
+}
+
+// decodePacket decodes the first element of data, returning the number of octets consumed.
+func decodePacket(data []byte) (*packet, int, error) {
+	r := bytes.NewReader(data)
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if length > r.Len() {
+		return nil, 0, fmt.Errorf("malformed ber element: truncated content")
+	}
+	header := len(data) - r.Len()
+	p, err := parsePacket(id, data[header:header+length])
+	return p, header + length, err
+}
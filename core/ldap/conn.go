@@ -0,0 +1,256 @@
+// Package ldap implements the subset of LDAPv3 (RFC 4511) needed to authenticate
+// users against a directory: simple bind and search. Server is an in-memory
+// stand-in of a directory for development and tests.
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// protocol operations of RFC 4511 4.2 - 4.5
+const (
+	opBindRequest     = classApplication | constructed | 0
+	opBindResponse    = classApplication | constructed | 1
+	opUnbindRequest   = classApplication | 2
+	opSearchRequest   = classApplication | constructed | 3
+	opSearchEntry     = classApplication | constructed | 4
+	opSearchDone      = classApplication | constructed | 5
+	opSearchReference = classApplication | constructed | 19
+)
+
+// search scopes
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+// result codes of RFC 4511 4.1.9
+const (
+	ResultSuccess            = 0
+	ResultOperationsError    = 1
+	ResultProtocolError      = 2
+	ResultSizeLimitExceeded  = 4
+	ResultNoSuchObject       = 32
+	ResultInvalidCredentials = 49
+	ResultUnwillingToPerform = 53
+)
+
+// Error is a non-success result returned by the server.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ldap result code %d: %s", e.Code, e.Message)
+}
+
+// IsErrorCode reports whether err is a result of the code.
+func IsErrorCode(err error, code int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
+
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// GetAll returns the values of the attribute, whose name is case-insensitive.
+func (e *Entry) GetAll(name string) []string {
+	for k, v := range e.Attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Get returns the first value of the attribute, or empty if absent.
+func (e *Entry) Get(name string) string {
+	if values := e.GetAll(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type SearchRequest struct {
+	BaseDN     string
+	Scope      int
+	Filter     string
+	Attributes []string
+	SizeLimit  int
+}
+
+// Conn is a connection to a directory, on which operations are sent one at a time.
+type Conn struct {
+	lock      sync.Mutex
+	conn      net.Conn
+	reader    *bufio.Reader
+	messageID int64
+	timeout   time.Duration
+}
+
+// Dial connects to a ldap:// or ldaps:// url, the timeout applies to every operation as well.
+func Dial(rawURL string, timeout time.Duration, config *tls.Config) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.Dial("tcp", withDefaultPort(u.Host, "389"))
+	case "ldaps":
+		if config == nil {
+			config = &tls.Config{ServerName: u.Hostname()}
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", withDefaultPort(u.Host, "636"), config)
+	default:
+		return nil, fmt.Errorf("unsupported ldap url scheme: %s", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		timeout: timeout,
+	}, nil
+}
+
+func withDefaultPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(host, port)
+	}
+	return host
+}
+
+// Bind authenticates the connection with a simple bind. An empty password is
+// refused, since servers treat it as an unauthenticated bind which always succeeds.
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return &Error{Code: ResultUnwillingToPerform, Message: "empty password"}
+	}
+	op := newPacket(opBindRequest,
+		newInt(tagInteger, 3),
+		newString(tagOctetString, dn),
+		newString(classContext|0, password),
+	)
+	return c.roundTrip(op, func(p *packet) (bool, error) {
+		if p.id != opBindResponse {
+			return true, fmt.Errorf("unexpected ldap response %#x", p.id)
+		}
+		return true, resultError(p)
+	})
+}
+
+// Search returns the entries matching the request.
+func (c *Conn) Search(req *SearchRequest) ([]*Entry, error) {
+	filter, err := compileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attributes := newPacket(tagSequence)
+	for _, attr := range req.Attributes {
+		attributes.children = append(attributes.children, newString(tagOctetString, attr))
+	}
+	op := newPacket(opSearchRequest,
+		newString(tagOctetString, req.BaseDN),
+		newInt(tagEnumerated, int64(req.Scope)),
+		newInt(tagEnumerated, 0), // never dereference aliases
+		newInt(tagInteger, int64(req.SizeLimit)),
+		newInt(tagInteger, int64(c.timeout/time.Second)),
+		newBool(false),
+		filter,
+		attributes,
+	)
+	entries := []*Entry{}
+	err = c.roundTrip(op, func(p *packet) (bool, error) {
+		switch p.id {
+		case opSearchEntry:
+			entries = append(entries, decodeEntry(p))
+			return false, nil
+		case opSearchReference:
+			return false, nil
+		case opSearchDone:
+			return true, resultError(p)
+		default:
+			return true, fmt.Errorf("unexpected ldap response %#x", p.id)
+		}
+	})
+	return entries, err
+}
+
+// Close sends an unbind request and closes the connection.
+func (c *Conn) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.messageID++
+	msg := newPacket(tagSequence, newInt(tagInteger, c.messageID), newPacket(opUnbindRequest))
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.conn.Write(msg.encode())
+	return c.conn.Close()
+}
+
+// roundTrip sends an operation and passes its responses to handle until it is done.
+func (c *Conn) roundTrip(op *packet, handle func(*packet) (bool, error)) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.messageID++
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	msg := newPacket(tagSequence, newInt(tagInteger, c.messageID), op)
+	if _, err := c.conn.Write(msg.encode()); err != nil {
+		return err
+	}
+	for {
+		resp, err := readPacket(c.reader)
+		if err != nil {
+			return err
+		}
+		if resp.id != tagSequence || resp.child(0).int() != c.messageID {
+			// including the notice of disconnection, whose message ID is 0
+			return fmt.Errorf("unexpected ldap message %d", resp.child(0).int())
+		}
+		if done, err := handle(resp.child(1)); done {
+			return err
+		}
+	}
+}
+
+func resultError(p *packet) error {
+	if len(p.children) < 3 {
+		return fmt.Errorf("malformed ldap result")
+	}
+	if code := int(p.child(0).int()); code != ResultSuccess {
+		return &Error{Code: code, Message: p.child(2).str()}
+	}
+	return nil
+}
+
+func decodeEntry(p *packet) *Entry {
+	entry := &Entry{
+		DN:         p.child(0).str(),
+		Attributes: map[string][]string{},
+	}
+	for _, attr := range p.child(1).children {
+		values := []string{}
+		for _, v := range attr.child(1).children {
+			values = append(values, v.str())
+		}
+		entry.Attributes[attr.child(0).str()] = values
+	}
+	return entry
+}
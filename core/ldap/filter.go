@@ -0,0 +1,239 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// filter choices of RFC 4511 4.5.1.7
+const (
+	filterAnd            = classContext | constructed | 0
+	filterOr             = classContext | constructed | 1
+	filterNot            = classContext | constructed | 2
+	filterEqualityMatch  = classContext | constructed | 3
+	filterSubstrings     = classContext | constructed | 4
+	filterGreaterOrEqual = classContext | constructed | 5
+	filterLessOrEqual    = classContext | constructed | 6
+	filterPresent        = classContext | 7
+	filterApproxMatch    = classContext | constructed | 8
+
+	substringInitial = classContext | 0
+	substringAny     = classContext | 1
+	substringFinal   = classContext | 2
+)
+
+// EscapeFilter escapes the special characters of a value to be put into a filter.
+func EscapeFilter(value string) string {
+	builder := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&builder, "\\%02x", c)
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// compileFilter encodes a filter in the string representation of RFC 4515,
+// extensible matches are not supported.
+func compileFilter(filter string) (*packet, error) {
+	p, rest, err := parseFilter(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filter %q: unexpected %q", filter, rest)
+	}
+	return p, nil
+}
+
+func parseFilter(s string) (*packet, string, error) {
+	if !strings.HasPrefix(s, "(") || len(s) < 2 {
+		return nil, "", fmt.Errorf("missing (")
+	}
+	s = s[1:]
+	var p *packet
+	switch s[0] {
+	case '&', '|', '!':
+		p = newPacket(filterAnd)
+		if s[0] == '|' {
+			p.id = filterOr
+		} else if s[0] == '!' {
+			p.id = filterNot
+		}
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			p.children = append(p.children, child)
+			s = rest
+		}
+		if p.id == filterNot && len(p.children) != 1 {
+			return nil, "", fmt.Errorf("not requires exactly one filter")
+		}
+	default:
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return nil, "", fmt.Errorf("missing )")
+		}
+		item, err := parseItem(s[:end])
+		if err != nil {
+			return nil, "", err
+		}
+		p, s = item, s[end:]
+	}
+	if !strings.HasPrefix(s, ")") {
+		return nil, "", fmt.Errorf("missing )")
+	}
+	return p, s[1:], nil
+}
+
+func parseItem(item string) (*packet, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("missing attribute or =")
+	}
+	attr, value := item[:eq], item[eq+1:]
+	id := byte(filterEqualityMatch)
+	switch attr[len(attr)-1] {
+	case '>':
+		id = filterGreaterOrEqual
+	case '<':
+		id = filterLessOrEqual
+	case '~':
+		id = filterApproxMatch
+	}
+	if id != filterEqualityMatch {
+		attr = attr[:len(attr)-1]
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("missing attribute")
+	}
+
+	if id == filterEqualityMatch && value == "*" {
+		return newString(filterPresent, attr), nil
+	}
+	if id == filterEqualityMatch && strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		substrings := newPacket(tagSequence)
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			unescaped, err := unescapeFilter(part)
+			if err != nil {
+				return nil, err
+			}
+			choice := byte(substringAny)
+			if i == 0 {
+				choice = substringInitial
+			} else if i == len(parts)-1 {
+				choice = substringFinal
+			}
+			substrings.children = append(substrings.children, newString(choice, unescaped))
+		}
+		return newPacket(filterSubstrings, newString(tagOctetString, attr), substrings), nil
+	}
+	unescaped, err := unescapeFilter(value)
+	if err != nil {
+		return nil, err
+	}
+	return newPacket(id, newString(tagOctetString, attr), newString(tagOctetString, unescaped)), nil
+}
+
+func unescapeFilter(value string) (string, error) {
+	if !strings.Contains(value, "\\") {
+		return value, nil
+	}
+	builder := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			builder.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("invalid escape in %q", value)
+		}
+		b, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", value)
+		}
+		builder.Write(b)
+		i += 2
+	}
+	return builder.String(), nil
+}
+
+// matchFilter evaluates an encoded filter on the entry, matching the values case-insensitively.
+func matchFilter(entry *Entry, filter *packet) bool {
+	switch filter.id {
+	case filterAnd:
+		for _, c := range filter.children {
+			if !matchFilter(entry, c) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, c := range filter.children {
+			if matchFilter(entry, c) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return !matchFilter(entry, filter.child(0))
+	case filterPresent:
+		return len(entry.GetAll(filter.str())) > 0
+	case filterEqualityMatch, filterApproxMatch, filterGreaterOrEqual, filterLessOrEqual:
+		expected := strings.ToLower(filter.child(1).str())
+		for _, v := range entry.GetAll(filter.child(0).str()) {
+			v = strings.ToLower(v)
+			if (filter.id == filterGreaterOrEqual && v >= expected) ||
+				(filter.id == filterLessOrEqual && v <= expected) ||
+				(v == expected) {
+				return true
+			}
+		}
+		return false
+	case filterSubstrings:
+		for _, v := range entry.GetAll(filter.child(0).str()) {
+			if matchSubstrings(strings.ToLower(v), filter.child(1).children) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func matchSubstrings(value string, substrings []*packet) bool {
+	for _, s := range substrings {
+		sub := strings.ToLower(s.str())
+		switch s.id {
+		case substringInitial:
+			if !strings.HasPrefix(value, sub) {
+				return false
+			}
+			value = value[len(sub):]
+		case substringAny:
+			i := strings.Index(value, sub)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(sub):]
+		case substringFinal:
+			if !strings.HasSuffix(value, sub) {
+				return false
+			}
+			value = ""
+		}
+	}
+	return true
+}
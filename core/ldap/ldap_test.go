@@ -0,0 +1,132 @@
+package ldap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	p, err := compileFilter("(&(uid=a)(objectClass=*))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0xa0, 0x17,
+		0xa3, 0x08, 0x04, 0x03, 'u', 'i', 'd', 0x04, 0x01, 'a',
+		0x87, 0x0b, 'o', 'b', 'j', 'e', 'c', 't', 'C', 'l', 'a', 's', 's',
+	}
+	if !bytes.Equal(p.encode(), expected) {
+		t.Errorf("encoded filter %x, expected %x", p.encode(), expected)
+	}
+
+	entry := &Entry{DN: "uid=alice,dc=example", Attributes: map[string][]string{
+		"uid":  {"alice"},
+		"cn":   {"Alice Liddell"},
+		"mail": {"alice@example.com"},
+	}}
+	cases := map[string]bool{
+		"(uid=ALICE)":                      true,
+		"(cn=alice*)":                      true,
+		"(cn=*lid*ll)":                     true,
+		"(cn=*bob*)":                       false,
+		"(|(uid=bob)(mail=*@example.com))": true,
+		"(!(uid=alice))":                   false,
+		"(&(uid=alice)(phone=*))":          false,
+		"(uid=" + EscapeFilter("a*") + ")": false,
+	}
+	for filter, match := range cases {
+		p, err := compileFilter(filter)
+		if err != nil {
+			t.Errorf("compile %s: %v", filter, err)
+			continue
+		}
+		decoded, _, err := decodePacket(p.encode())
+		if err != nil {
+			t.Errorf("decode %s: %v", filter, err)
+			continue
+		}
+		if matchFilter(entry, decoded) != match {
+			t.Errorf("filter %s on %s should be %v", filter, entry.DN, match)
+		}
+	}
+
+	for _, filter := range []string{"uid=a", "(uid=a", "(=a)", "(!(a=1)(b=2))", "(uid=\\zz)", "(uid=a))"} {
+		if _, err := compileFilter(filter); err == nil {
+			t.Errorf("filter %s should be invalid", filter)
+		}
+	}
+}
+
+func TestConn(t *testing.T) {
+	server := NewServer()
+	server.AddEntry(&Entry{DN: "cn=admin,dc=example", Attributes: map[string][]string{"cn": {"admin"}}}, "admin_password")
+	server.AddEntry(&Entry{DN: "uid=alice,ou=people,dc=example", Attributes: map[string][]string{
+		"uid":      {"alice"},
+		"cn":       {"Alice"},
+		"memberOf": {"cn=staff,ou=groups,dc=example", "cn=maintainers,ou=groups,dc=example"},
+	}}, "alice_password")
+	server.AddEntry(&Entry{DN: "uid=bob,ou=people,dc=example", Attributes: map[string][]string{"uid": {"bob"}}}, "")
+	url, err := server.Start("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	conn, err := Dial(url, 5*time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind("cn=admin,dc=example", "wrong"); !IsErrorCode(err, ResultInvalidCredentials) {
+		t.Errorf("bind with wrong password: %v", err)
+	}
+	if err := conn.Bind("uid=bob,ou=people,dc=example", ""); err == nil {
+		t.Errorf("bind with empty password should fail")
+	}
+	if err := conn.Bind("cn=admin, dc=example", "admin_password"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := conn.Search(&SearchRequest{
+		BaseDN:     "ou=people,dc=example",
+		Scope:      ScopeWholeSubtree,
+		Filter:     "(&(uid=" + EscapeFilter("alice") + ")(objectClass=*))",
+		Attributes: []string{"cn", "memberOf"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("entries without objectClass should not match, got %d", len(entries))
+	}
+	entries, err = conn.Search(&SearchRequest{
+		BaseDN:     "ou=people,dc=example",
+		Scope:      ScopeWholeSubtree,
+		Filter:     "(uid=alice)",
+		Attributes: []string{"cn", "memberOf"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].DN != "uid=alice,ou=people,dc=example" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if entries[0].Get("CN") != "Alice" || len(entries[0].GetAll("memberof")) != 2 || entries[0].Get("uid") != "" {
+		t.Errorf("unexpected attributes %+v", entries[0].Attributes)
+	}
+
+	entries, err = conn.Search(&SearchRequest{BaseDN: "dc=example", Scope: ScopeSingleLevel, Filter: "(cn=*)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("single level search should return 1 entry, got %d", len(entries))
+	}
+	if _, err := conn.Search(&SearchRequest{BaseDN: "dc=example", Scope: ScopeWholeSubtree, Filter: "(uid=*)", SizeLimit: 1}); !IsErrorCode(err, ResultSizeLimitExceeded) {
+		t.Errorf("search exceeding size limit: %v", err)
+	}
+	if err := conn.Bind("uid=alice,ou=people,dc=example", "alice_password"); err != nil {
+		t.Error(err)
+	}
+}
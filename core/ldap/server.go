@@ -0,0 +1,181 @@
+package ldap
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Server is a minimal in-memory directory serving simple bind and search on the
+// added entries, as a stand-in of a real directory for development and tests.
+type Server struct {
+	lock      sync.RWMutex
+	entries   []*Entry
+	passwords map[string]string
+	listener  net.Listener
+}
+
+func NewServer() *Server {
+	return &Server{
+		passwords: map[string]string{},
+	}
+}
+
+// AddEntry adds an entry, which can be bound with the password if not empty.
+func (s *Server) AddEntry(entry *Entry, password string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.entries = append(s.entries, entry)
+	if password != "" {
+		s.passwords[normalizeDN(entry.DN)] = password
+	}
+}
+
+// Start listens on the address (e.g. 127.0.0.1:0) and returns the url of the server.
+func (s *Server) Start(addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	s.listener = listener
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return fmt.Sprintf("ldap://%s", listener.Addr()), nil
+}
+
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		msg, err := readPacket(reader)
+		if err != nil || msg.id != tagSequence {
+			return
+		}
+		id, op := msg.child(0).int(), msg.child(1)
+		var responses []*packet
+		switch op.id {
+		case opBindRequest:
+			responses = []*packet{s.bind(op)}
+		case opSearchRequest:
+			responses = s.search(op)
+		default:
+			// unbind, or operations not supported
+			return
+		}
+		for _, resp := range responses {
+			if _, err := conn.Write(newPacket(tagSequence, newInt(tagInteger, id), resp).encode()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) bind(op *packet) *packet {
+	dn, password := op.child(1).str(), op.child(2)
+	if password.id != classContext|0 {
+		return newResult(opBindResponse, ResultUnwillingToPerform, "simple bind only")
+	}
+	s.lock.RLock()
+	expected, ok := s.passwords[normalizeDN(dn)]
+	s.lock.RUnlock()
+	if password.str() == "" {
+		return newResult(opBindResponse, ResultUnwillingToPerform, "unauthenticated bind not allowed")
+	}
+	if !ok || expected != password.str() {
+		return newResult(opBindResponse, ResultInvalidCredentials, "invalid credentials")
+	}
+	return newResult(opBindResponse, ResultSuccess, "")
+}
+
+func (s *Server) search(op *packet) []*packet {
+	base, scope, sizeLimit := normalizeDN(op.child(0).str()), int(op.child(1).int()), int(op.child(3).int())
+	filter := op.child(6)
+	attributes := []string{}
+	for _, attr := range op.child(7).children {
+		attributes = append(attributes, attr.str())
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	responses := []*packet{}
+	for _, entry := range s.entries {
+		if !inScope(normalizeDN(entry.DN), base, scope) || !matchFilter(entry, filter) {
+			continue
+		}
+		if sizeLimit > 0 && len(responses) >= sizeLimit {
+			return append(responses, newResult(opSearchDone, ResultSizeLimitExceeded, ""))
+		}
+		responses = append(responses, encodeEntry(entry, attributes))
+	}
+	return append(responses, newResult(opSearchDone, ResultSuccess, ""))
+}
+
+func inScope(dn, base string, scope int) bool {
+	switch scope {
+	case ScopeBaseObject:
+		return dn == base
+	case ScopeSingleLevel:
+		parent := ""
+		if i := strings.IndexByte(dn, ','); i >= 0 {
+			parent = dn[i+1:]
+		}
+		return parent == base
+	default:
+		return dn == base || base == "" || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+func encodeEntry(entry *Entry, attributes []string) *packet {
+	all := len(attributes) == 0
+	for _, attr := range attributes {
+		all = all || attr == "*"
+	}
+	attrs := newPacket(tagSequence)
+	for name, values := range entry.Attributes {
+		selected := all
+		for _, attr := range attributes {
+			selected = selected || strings.EqualFold(attr, name)
+		}
+		if !selected {
+			continue
+		}
+		set := newPacket(tagSet)
+		for _, v := range values {
+			set.children = append(set.children, newString(tagOctetString, v))
+		}
+		attrs.children = append(attrs.children, newPacket(tagSequence, newString(tagOctetString, name), set))
+	}
+	return newPacket(opSearchEntry, newString(tagOctetString, entry.DN), attrs)
+}
+
+func newResult(op byte, code int, message string) *packet {
+	return newPacket(op,
+		newInt(tagEnumerated, int64(code)),
+		newString(tagOctetString, ""),
+		newString(tagOctetString, message),
+	)
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(part))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,126 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xaxys/maintainman/core/config"
+	"github.com/xaxys/maintainman/core/logger"
+
+	"github.com/spf13/viper"
+)
+
+var Mail IMail
+
+type IMail interface {
+	Send(msg *Message) error
+}
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+func init() {
+	Mail = InitMail(config.AppConfig)
+}
+
+func InitMail(config *viper.Viper) IMail {
+	if config == nil {
+		return nil
+	}
+	mailType := config.GetString("mail.driver")
+	switch mailType {
+	case "":
+		return nil
+	case "log":
+		return &LogMail{}
+	case "file":
+		return NewFileMail(config.GetString("mail.file.path"))
+	case "smtp":
+		return &SMTPMail{
+			host:     config.GetString("mail.smtp.host"),
+			port:     config.GetInt("mail.smtp.port"),
+			username: config.GetString("mail.smtp.username"),
+			password: config.GetString("mail.smtp.password"),
+			from:     config.GetString("mail.from"),
+		}
+	default:
+		panic(fmt.Errorf("support log, file and smtp only"))
+	}
+}
+
+// Build renders the message in RFC 5322 format.
+func (m *Message) Build(from string) []byte {
+	buffer := bytes.NewBuffer(nil)
+	fmt.Fprintf(buffer, "From: %s\r\n", from)
+	fmt.Fprintf(buffer, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(buffer, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", m.Subject))
+	fmt.Fprintf(buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buffer.WriteString("\r\n")
+	buffer.WriteString(m.Body)
+	return buffer.Bytes()
+}
+
+// LogMail only prints the messages, for development. The body may carry
+// secrets such as password reset tokens, so it is printed at debug level.
+type LogMail struct{}
+
+func (l *LogMail) Send(msg *Message) error {
+	logger.Logger.Infof("mail to %s: %s", strings.Join(msg.To, ", "), msg.Subject)
+	logger.Logger.Debugf("mail body to %s:\n%s", strings.Join(msg.To, ", "), msg.Body)
+	return nil
+}
+
+// FileMail writes every message into a separate .eml file under the path.
+type FileMail struct {
+	path string
+}
+
+func NewFileMail(path string) *FileMail {
+	path = filepath.Clean(path)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		os.MkdirAll(path, 0755)
+	}
+	return &FileMail{
+		path: path,
+	}
+}
+
+func (f *FileMail) Path() string {
+	return f.path
+}
+
+func (f *FileMail) Send(msg *Message) error {
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(f.path, name), msg.Build("maintainman"), 0644)
+}
+
+type SMTPMail struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+func (s *SMTPMail) Send(msg *Message) error {
+	addr := fmt.Sprintf("%s:%d", s.host, s.port)
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+	if err := smtp.SendMail(addr, auth, s.from, msg.To, msg.Build(s.from)); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %v", strings.Join(msg.To, ", "), err)
+	}
+	return nil
+}
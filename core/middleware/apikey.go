@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/model"
+
+	"github.com/kataras/iris/v12"
+)
+
+// APIKeyHeader is the header carrying an API key, which is used instead of a token.
+const APIKeyHeader = "X-API-Key"
+
+var (
+	APIKeyValidator iris.Handler
+
+	// apiKeyAuthenticator resolves an API key to the auth info of its owner,
+	// registered by the module storing the keys.
+	apiKeyAuthenticator func(key, ip string) (*model.AuthInfo, error)
+)
+
+func init() {
+	APIKeyValidator = func(ctx iris.Context) {
+		key := ctx.GetHeader(APIKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		var err error
+		var auth *model.AuthInfo
+		if ctx.Values().Get("auth") != nil {
+			err = fmt.Errorf("不能同时使用Token和API Key")
+		} else if apiKeyAuthenticator == nil {
+			err = fmt.Errorf("不支持API Key")
+		} else {
+			auth, err = apiKeyAuthenticator(key, ctx.Request().RemoteAddr)
+		}
+		if err != nil {
+			response := model.ErrorUnauthorized(err)
+			ctx.StatusCode(response.Code)
+			ctx.JSON(response)
+			ctx.StopExecution()
+			return
+		}
+		ctx.Values().Set("auth", auth)
+		ctx.Next()
+	}
+}
+
+// RegisterAPIKeyAuthenticator sets the function resolving the API keys. The auth info
+// returned should be the same as the one of a token, with the permissions of the key
+// put in `Other["scopes"]` as a []string.
+func RegisterAPIKeyAuthenticator(authenticator func(key, ip string) (*model.AuthInfo, error)) {
+	apiKeyAuthenticator = authenticator
+}
@@ -38,6 +38,14 @@ func init() {
 		jwtToken, ok := ctx.Values().Get("jwt").(*jwt.Token)
 		if ok {
 			jwtInfo := jwtToken.Claims.(jwt.MapClaims)
+			if isTokenRevoked(jwtInfo) {
+				response := model.ErrorUnauthorized(fmt.Errorf("凭证已失效, 请重新登录"))
+				ctx.StatusCode(response.Code)
+				ctx.JSON(response)
+				ctx.StopExecution()
+				return
+			}
+			trackSession(jwtInfo, ctx.Request().RemoteAddr)
 			uid := uint(jwtInfo["user_id"].(float64))
 			name := jwtInfo["user_name"].(string)
 			role := jwtInfo["user_role"].(string)
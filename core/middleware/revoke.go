@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xaxys/maintainman/core/cache"
+	"github.com/xaxys/maintainman/core/config"
+	"github.com/xaxys/maintainman/core/logger"
+
+	"github.com/iris-contrib/middleware/jwt"
+	"github.com/spf13/cast"
+)
+
+// revoked holds the revocations of this instance until they expire. Unlike the
+// cache it never drops a write, so a revoked token can not slip through.
+// revokeCache shares the revocations with the other instances, use redis as
+// cache driver if there are multiple instances, or the revocations of the
+// others will not be seen and all of them will be lost on restart.
+var (
+	revoked      sync.Map // key -> *revocation
+	revokeCache  cache.ICache
+	revokeExpire time.Duration
+
+	// sessionTracker records the activity of a session on every request with its
+	// access tokens, registered by the module storing the sessions.
+	sessionTracker func(sid uint, ip string)
+)
+
+type revocation struct {
+	value  any
+	expire time.Time
+}
+
+func init() {
+	revokeCache = cache.InitCache("revoke", config.AppConfig, nil)
+	revokeExpire = config.AppConfig.GetDuration("token.expire")
+}
+
+// RevokeToken revokes the access token with the id until it expires.
+func RevokeToken(id string, expire time.Time) {
+	if id == "" || !expire.After(time.Now()) {
+		return
+	}
+	revoke("token:"+id, 1, time.Until(expire))
+}
+
+// RevokeSession revokes all access tokens issued with the refresh token (session) of the id.
+func RevokeSession(id uint) {
+	if id == 0 {
+		return
+	}
+	revoke("session:"+strconv.FormatUint(uint64(id), 10), 1, revokeExpire)
+}
+
+// RevokeUser revokes the access tokens of the user issued before now without
+// a session, which are the ones issued before refresh tokens are introduced.
+func RevokeUser(id uint) {
+	if id == 0 {
+		return
+	}
+	revoke("user:"+strconv.FormatUint(uint64(id), 10), time.Now().Unix(), revokeExpire)
+}
+
+func revoke(key string, value any, expire time.Duration) {
+	now := time.Now()
+	revoked.Range(func(k, v any) bool {
+		if !v.(*revocation).expire.After(now) {
+			revoked.Delete(k)
+		}
+		return true
+	})
+	revoked.Store(key, &revocation{value: value, expire: now.Add(expire)})
+	if revokeCache != nil && !revokeCache.Set(key, value, expire) {
+		logger.Logger.Warnf("failed to share revocation %s through cache, it only takes effect on this instance", key)
+	}
+}
+
+func getRevoked(key string) (any, bool) {
+	if v, ok := revoked.Load(key); ok && v.(*revocation).expire.After(time.Now()) {
+		return v.(*revocation).value, true
+	}
+	if revokeCache == nil {
+		return nil, false
+	}
+	return revokeCache.Get(key)
+}
+
+// RegisterSessionTracker sets the function recording the activity of the sessions,
+// which is called on the request path and should return quickly.
+func RegisterSessionTracker(tracker func(sid uint, ip string)) {
+	sessionTracker = tracker
+}
+
+func trackSession(claims jwt.MapClaims, ip string) {
+	if sid := cast.ToUint(claims["sid"]); sid != 0 && sessionTracker != nil {
+		sessionTracker(sid, ip)
+	}
+}
+
+func isTokenRevoked(claims jwt.MapClaims) bool {
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		if _, ok := getRevoked("token:" + jti); ok {
+			return true
+		}
+	}
+	if sid := cast.ToUint64(claims["sid"]); sid != 0 {
+		_, ok := getRevoked("session:" + strconv.FormatUint(sid, 10))
+		return ok
+	}
+	uid := cast.ToUint64(claims["user_id"])
+	if revokedAt, ok := getRevoked("user:" + strconv.FormatUint(uid, 10)); ok {
+		return cast.ToInt64(claims["iat"]) <= cast.ToInt64(revokedAt)
+	}
+	return false
+}
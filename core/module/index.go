@@ -15,6 +15,23 @@ type ModuleContext struct {
 	Cache   cache.ICache
 }
 
+// PrivacyExporter is exported as "privacy.export" by the modules holding personal data.
+// It returns the data of the user to be put into the user's data archive, keyed by file name.
+type PrivacyExporter = func(userID uint) (map[string]any, error)
+
+// PrivacyEraser is exported as "privacy.erase" by the modules holding personal data.
+// It anonymises the data of the user when the account is deleted, and may be called again
+// if the deletion fails.
+type PrivacyEraser = func(userID uint) error
+
+// Analyzer is exported as "analyzer" by the word module, so that the modules share one
+// segmenter instead of each loading its own copy of the dictionaries.
+type Analyzer interface {
+	// SetWords replaces the words kept unsplit on behalf of the owner.
+	SetWords(owner string, words []string)
+	CutForSearch(text string) []string
+}
+
 type IModule interface {
 	Name() string
 	Version() string
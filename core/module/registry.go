@@ -1,6 +1,8 @@
 package module
 
 import (
+	"sort"
+
 	"github.com/xaxys/maintainman/core/cache"
 	"github.com/xaxys/maintainman/core/config"
 	"github.com/xaxys/maintainman/core/database"
@@ -54,3 +56,17 @@ func (r *Registry) Register(module ...*Module) {
 func (r *Registry) Get(moduleName string) IModule {
 	return r.modules[moduleName]
 }
+
+// GetAll returns all registered modules ordered by name.
+func (r *Registry) GetAll() []IModule {
+	names := make([]string, 0, len(r.modules))
+	for name := range r.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	modules := make([]IModule, 0, len(names))
+	for _, name := range names {
+		modules = append(modules, r.modules[name])
+	}
+	return modules
+}
@@ -1,6 +1,8 @@
 package module
 
 import (
+	"github.com/xaxys/maintainman/core/mail"
+
 	"github.com/go-co-op/gocron"
 	"github.com/go-playground/validator"
 	"github.com/kataras/golog"
@@ -14,5 +16,6 @@ type Server struct {
 	Scheduler *gocron.Scheduler
 	Database  *gorm.DB
 	EventBus  *emitter.Emitter
+	Mail      mail.IMail
 	Registry  *Registry
 }
@@ -10,6 +10,7 @@ type RoleInfo struct {
 	DisplayName string   `mapstructure:"display_name" yaml:"display_name"`
 	Default     bool     `mapstructure:"default"      yaml:"default,omitempty"`
 	Guest       bool     `mapstructure:"guest"        yaml:"guest,omitempty"`
+	TwoFactor   bool     `mapstructure:"two_factor"   yaml:"two_factor,omitempty"`
 	Permissions []string `mapstructure:"permissions"  yaml:"permissions"`
 	Inheritance []string `mapstructure:"inheritance"  yaml:"inheritance"`
 }
@@ -30,6 +31,7 @@ type CreateRoleRequest struct {
 	Name        string   `json:"name"         validate:"required,gte=2,lte=50"`
 	DisplayName string   `json:"display_name" validate:"required,lte=191"`
 	Position    uint     `json:"position"`
+	TwoFactor   bool     `json:"two_factor"`
 	Permissions []string `json:"permissions"`
 	Inheritance []string `json:"inheritance"`
 }
@@ -37,6 +39,7 @@ type CreateRoleRequest struct {
 type UpdateRoleRequest struct {
 	DisplayName    string   `json:"display_name" validate:"required,lte=191"`
 	Position       uint     `json:"position"`
+	TwoFactor      *bool    `json:"two_factor"`
 	AddPermissions []string `json:"add_permissions"`
 	DelPermissions []string `json:"del_permissions"`
 	AddInheritance []string `json:"add_inheritance"`
@@ -48,6 +51,7 @@ type RoleJson struct {
 	DisplayName string            `json:"display_name"`
 	Default     bool              `json:"default"`
 	Guest       bool              `json:"guest"`
+	TwoFactor   bool              `json:"two_factor"`
 	Permissions []*PermissionJson `json:"permissions,omitempty"`
 	Inheritance []string          `json:"inheritance,omitempty"`
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/xaxys/maintainman/core/model"
 	"github.com/xaxys/maintainman/core/util"
 
 	"github.com/spf13/viper"
@@ -169,6 +170,25 @@ func CheckPermission(role, perm string) error {
 	return nil
 }
 
+// CheckAuthPermission checks the permission of the role of the auth, which is further
+// limited to the scopes of the auth if any (e.g. authenticated by an API key).
+func CheckAuthPermission(auth *model.AuthInfo, perm string) error {
+	role := util.NilOrBaseValue(auth, func(v *model.AuthInfo) string { return v.Role }, "")
+	if err := CheckPermission(role, perm); err != nil {
+		return err
+	}
+	if scopes, ok := util.NilOrBaseValue(auth, func(v *model.AuthInfo) any { return v.Other["scopes"] }, nil).([]string); ok {
+		if !newPermSet().Add(scopes...).Has(perm) {
+			return fmt.Errorf("权限不足：%s", GetPermissionName(perm))
+		}
+	}
+	return nil
+}
+
+func AuthHasPermission(auth *model.AuthInfo, perm string) bool {
+	return CheckAuthPermission(auth, perm) == nil
+}
+
 func AddInheritance(role string, inherit ...string) error {
 	return RolePO.AddInheritance(role, inherit...)
 }
@@ -327,6 +347,7 @@ func (s *RolePersistence) CreateRole(aul *CreateRoleRequest) (err error) {
 		Name:        aul.Name,
 		DisplayName: aul.DisplayName,
 		Default:     false,
+		TwoFactor:   aul.TwoFactor,
 	}
 
 	role := &Role{
@@ -363,6 +384,11 @@ func (s *RolePersistence) UpdateRole(name string, aul *UpdateRoleRequest) error
 		r.DisplayName = aul.DisplayName
 		r.Unlock()
 	}
+	if aul.TwoFactor != nil {
+		r.Lock()
+		r.TwoFactor = *aul.TwoFactor
+		r.Unlock()
+	}
 	if len(aul.AddPermissions) != 0 {
 		addPermission(r, aul.AddPermissions...)
 	}
@@ -414,6 +440,21 @@ func (s *RolePersistence) DeleteRole(name string) error {
 	return nil
 }
 
+// RequireTwoFactor reports whether the users of the role must login with two-factor authentication.
+func RequireTwoFactor(role string) bool {
+	return RolePO.RequireTwoFactor(role)
+}
+
+func (s *RolePersistence) RequireTwoFactor(role string) bool {
+	r, err := s.getRole(role)
+	if err != nil {
+		return false
+	}
+	r.RLock()
+	defer r.RUnlock()
+	return r.TwoFactor
+}
+
 func GetRole(name string) *RoleJson {
 	return RolePO.GetRole(name)
 }
@@ -502,6 +543,7 @@ func roleToJson(role *Role) *RoleJson {
 		DisplayName: role.DisplayName,
 		Default:     role.Default,
 		Guest:       role.Guest,
+		TwoFactor:   role.TwoFactor,
 		Inheritance: role.Inheritance,
 		Permissions: util.TransSlice(role.Permissions, GetPermission),
 	}
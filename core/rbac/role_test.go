@@ -78,6 +78,19 @@ func TestRole(t *testing.T) {
 		t.Error("test role 1 does not has test role 2 permission")
 	}
 
+	// test two-factor requirement
+	twoFactor := true
+	err = UpdateRole("test role 1", &UpdateRoleRequest{TwoFactor: &twoFactor})
+	if err != nil {
+		t.Error(err)
+	}
+	if !RequireTwoFactor("test role 1") || !GetRole("test role 1").TwoFactor {
+		t.Error("test role 1 does not require two-factor")
+	}
+	if RequireTwoFactor("test role 2") {
+		t.Error("test role 2 requires two-factor")
+	}
+
 	aul4 := UpdateRoleRequest{
 		DelInheritance: []string{"test role 1"},
 	}
@@ -24,7 +24,7 @@ func Register(app *iris.Application) {
 	})
 
 	v1 := app.Party("/v1")
-	v1.Use(middleware.HeaderExtractor, middleware.TokenValidator)
+	v1.Use(middleware.HeaderExtractor, middleware.TokenValidator, middleware.APIKeyValidator)
 	v1.Done(middleware.ResponseHandler)
 	v1.SetExecutionRules(iris.ExecutionRules{Done: iris.ExecutionOptions{Force: true}})
 	APIRoute = v1
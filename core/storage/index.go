@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/xaxys/maintainman/core/config"
 	"github.com/xaxys/maintainman/core/logger"
@@ -29,6 +30,7 @@ type IStorage interface {
 	LoadBytes(id string) ([]byte, error)
 	SaveBytes(id, format string, data []byte) error
 	Delete(id string) error
+	List() ([]string, error) // IDs of the files directly under the storage, excluding the sub storages
 	Sub(path string, clean bool) IStorage
 }
 
@@ -160,6 +162,20 @@ func (s *LocalStorage) Delete(id string) error {
 	return os.Remove(fullPath)
 }
 
+func (s *LocalStorage) List() ([]string, error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
 func (s *LocalStorage) Sub(path string, clean bool) IStorage {
 	subPath := filepath.Join(s.path, path)
 	return newLocalStorage(subPath, clean)
@@ -248,6 +264,25 @@ func (s *S3Storage) Delete(id string) error {
 	return s.bucket.Del(fullPath)
 }
 
+func (s *S3Storage) List() ([]string, error) {
+	prefix := s.path + "/"
+	ids := []string{}
+	marker := ""
+	for {
+		resp, err := s.bucket.List(prefix, "/", marker, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, element := range resp.Contents {
+			ids = append(ids, strings.TrimPrefix(element.Key, prefix))
+		}
+		if !resp.IsTruncated || len(resp.Contents) == 0 {
+			return ids, nil
+		}
+		marker = util.NotEmpty(resp.NextMarker, util.LastElem(resp.Contents).Key)
+	}
+}
+
 func (s *S3Storage) Sub(path string, clean bool) IStorage {
 	subPath := s.path + "/" + path
 	return newS3Storage(s.bucket.S3, s.bucket.Name, subPath, clean)
@@ -22,6 +22,11 @@ func init() {
 	key = []byte(config.AppConfig.GetString("token.key"))
 }
 
+// GetJwtExpire returns the lifetime of the issued tokens.
+func GetJwtExpire() time.Duration {
+	return expire
+}
+
 func GetJwtString(id uint, name, role string) (string, error) {
 	return GetJwtStringWithClaims(id, name, role, nil)
 }
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// SignString returns the payload with its HMAC-SHA256 signature appended,
+// both base64 (url) encoded and joined by a dot.
+func SignString(payload string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(signPayload(payload))
+}
+
+// VerifySignedString checks a string built by SignString and returns its payload.
+func VerifySignedString(signed string) (string, error) {
+	parts := strings.Split(signed, ".")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed signed string")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("malformed payload: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed signature: %v", err)
+	}
+	if !hmac.Equal(signature, signPayload(string(payload))) {
+		return "", fmt.Errorf("invalid signature")
+	}
+	return string(payload), nil
+}
+
+func signPayload(payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
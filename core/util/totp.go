@@ -0,0 +1,79 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters of RFC 6238, which are the defaults of the authenticator apps.
+const (
+	TOTPPeriod = 30
+	TOTPDigits = 6
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random base32 encoded secret of 160 bits.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// GetTOTPStep returns the time step counter of the time.
+func GetTOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// GetTOTPCode returns the code of the secret at the time step counter.
+func GetTOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %v", err)
+	}
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TOTPDigits, value%1000000), nil
+}
+
+// ValidateTOTP checks the code allowing one step of clock skew,
+// and returns the matched time step counter, or -1 if mismatched.
+func ValidateTOTP(secret, code string, t time.Time) int64 {
+	step := GetTOTPStep(t)
+	for _, s := range []int64{step, step - 1, step + 1} {
+		expected, err := GetTOTPCode(secret, s)
+		if err != nil {
+			return -1
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return s
+		}
+	}
+	return -1
+}
+
+// GetTOTPURI returns the provisioning URI to be rendered as a QR code for the authenticator apps.
+func GetTOTPURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(TOTPPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
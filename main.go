@@ -8,6 +8,7 @@ import (
 	"github.com/xaxys/maintainman/core/config"
 	"github.com/xaxys/maintainman/core/database"
 	"github.com/xaxys/maintainman/core/logger"
+	"github.com/xaxys/maintainman/core/mail"
 	"github.com/xaxys/maintainman/core/module"
 	"github.com/xaxys/maintainman/core/router"
 	"github.com/xaxys/maintainman/core/service"
@@ -18,6 +19,7 @@ import (
 	"github.com/xaxys/maintainman/modules/role"
 	"github.com/xaxys/maintainman/modules/sysinfo"
 	"github.com/xaxys/maintainman/modules/user"
+	"github.com/xaxys/maintainman/modules/wordcloud"
 	"github.com/xaxys/maintainman/modules/wxnotify"
 )
 
@@ -67,6 +69,7 @@ func newApp() *iris.Application {
 		Scheduler: service.Scheduler,
 		EventBus:  service.Bus,
 		Database:  database.DB,
+		Mail:      mail.Mail,
 	}
 	registry := module.NewRegistry(&server)
 	registry.Register(
@@ -77,6 +80,7 @@ func newApp() *iris.Application {
 		&order.Module,
 		&wxnotify.Module,
 		&sysinfo.Module,
+		&wordcloud.Module,
 	)
 	service.Scheduler.StartAsync()
 	return app
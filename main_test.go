@@ -1,22 +1,39 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"math/rand"
 	"net"
 	"net/http"
+	nethttptest "net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/xaxys/maintainman/core/ldap"
+	"github.com/xaxys/maintainman/core/mail"
 	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/rbac"
 	"github.com/xaxys/maintainman/core/util"
 	"github.com/xaxys/maintainman/modules/announce"
 	"github.com/xaxys/maintainman/modules/order"
 	"github.com/xaxys/maintainman/modules/user"
 
+	"github.com/iris-contrib/httpexpect/v2"
 	"github.com/kataras/iris/v12/httptest"
 	"github.com/spf13/cast"
 )
@@ -192,6 +209,821 @@ func TestGetUserByIdRouter(t *testing.T) {
 	}
 }
 
+func TestPasswordResetAndEmailVerifyRouter(t *testing.T) {
+	mailer := mail.NewFileMail(t.TempDir())
+	origin := mail.Mail
+	mail.Mail = mailer
+	defer func() { mail.Mail = origin }()
+	user.Module.ModuleConfig.Set("email.reset.body", "{{.Token}}")
+	user.Module.ModuleConfig.Set("email.verify.body", "{{.Token}}")
+
+	app := newApp()
+	e := httptest.New(t, app)
+
+	// latestToken reads the token from the newest mail
+	latestToken := func() string {
+		files, err := filepath.Glob(filepath.Join(mailer.Path(), "*.eml"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no mail sent: %v", err)
+		}
+		sort.Strings(files)
+		content, err := os.ReadFile(files[len(files)-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := strings.SplitN(string(content), "\r\n\r\n", 2)
+		return strings.TrimSpace(parts[1])
+	}
+
+	usr := generateRandomUsers("mailUser", 1)[0]
+	usr.Email = strings.ToLower(usr.Name) + "@maintainman.test"
+	e.POST("/v1/register").WithJSON(usr).Expect().Status(httptest.StatusCreated)
+
+	// unverified email can not be used to login
+	responseBody := e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Email,
+		Password: usr.Password,
+	}).Expect().Status(httptest.StatusNotFound).Body().Raw()
+	t.Log(responseBody)
+
+	token := e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Name,
+		Password: usr.Password,
+	}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+
+	e.POST("/v1/email/verify").WithJSON(user.VerifyEmailRequest{Token: "invalid"}).Expect().Status(httptest.StatusForbidden)
+	e.POST("/v1/user/email/verify").WithHeader("Authorization", "Bearer "+token).Expect().Status(httptest.StatusOK)
+	verifyToken := latestToken()
+	e.POST("/v1/email/verify").WithJSON(user.VerifyEmailRequest{Token: verifyToken}).Expect().Status(httptest.StatusNoContent)
+	e.POST("/v1/email/verify").WithJSON(user.VerifyEmailRequest{Token: verifyToken}).Expect().Status(httptest.StatusForbidden)
+	e.POST("/v1/user/email/verify").WithHeader("Authorization", "Bearer "+token).Expect().Status(httptest.StatusBadRequest)
+
+	e.GET("/v1/user").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("email_verified").Boolean().True()
+	e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Email,
+		Password: usr.Password,
+	}).Expect().Status(httptest.StatusOK)
+
+	// the response never tells whether the account exists
+	e.POST("/v1/password/forgot").WithJSON(user.ForgotPasswordRequest{Account: "nobody" + usr.Name}).Expect().Status(httptest.StatusOK)
+	e.POST("/v1/password/forgot").WithJSON(user.ForgotPasswordRequest{Account: usr.Email}).Expect().Status(httptest.StatusOK)
+	resetToken := latestToken()
+	// a verify token can not be used to reset password
+	e.POST("/v1/password/reset").WithJSON(user.ResetPasswordRequest{Token: verifyToken, Password: "87654321"}).Expect().Status(httptest.StatusForbidden)
+	e.POST("/v1/password/reset").WithJSON(user.ResetPasswordRequest{Token: resetToken, Password: "87654321"}).Expect().Status(httptest.StatusNoContent)
+	e.POST("/v1/password/reset").WithJSON(user.ResetPasswordRequest{Token: resetToken, Password: "12345678"}).Expect().Status(httptest.StatusForbidden)
+
+	e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Name,
+		Password: usr.Password,
+	}).Expect().Status(httptest.StatusForbidden)
+	e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Name,
+		Password: "87654321",
+	}).Expect().Status(httptest.StatusOK)
+}
+
+func TestRefreshTokenRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	usr := generateRandomUsers("refreshUser", 1)[0]
+	e.POST("/v1/register").WithJSON(usr).Expect().Status(httptest.StatusCreated)
+	login := func() (string, string) {
+		data := e.POST("/v1/login").WithJSON(user.LoginRequest{
+			Account:  usr.Name,
+			Password: usr.Password,
+			Refresh:  true,
+		}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+		return data.Value("token").String().Raw(), data.Value("refresh_token").String().Raw()
+	}
+	refresh := func(refreshToken string, status int) (string, string) {
+		response := e.POST("/v1/token/refresh").WithJSON(user.RefreshTokenRequest{RefreshToken: refreshToken}).Expect().Status(status)
+		t.Log(response.Body().Raw())
+		if status != httptest.StatusOK {
+			return "", ""
+		}
+		data := response.JSON().Object().Value("data").Object()
+		return data.Value("token").String().Raw(), data.Value("refresh_token").String().Raw()
+	}
+	view := func(token string, status int) {
+		e.GET("/v1/user").WithHeader("Authorization", "Bearer "+token).Expect().Status(status)
+	}
+
+	e.POST("/v1/logout").Expect().Status(httptest.StatusForbidden)
+	refresh("1.invalid", httptest.StatusForbidden)
+
+	// data stays the bare JWT Token unless the refresh token is asked for
+	view(e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Name,
+		Password: usr.Password,
+	}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw(), httptest.StatusOK)
+
+	// a forged secret is rejected without touching the session
+	token, refreshToken := login()
+	sid := strings.SplitN(refreshToken, ".", 2)[0]
+	refresh(sid+".invalid", httptest.StatusForbidden)
+	view(token, httptest.StatusOK)
+	token, refreshToken = refresh(refreshToken, httptest.StatusOK)
+	refresh(sid+".invalid", httptest.StatusForbidden)
+	view(token, httptest.StatusOK)
+
+	// the refresh token is rotated, reusing the old one revokes the session
+	token, refreshToken = login()
+	view(token, httptest.StatusOK)
+	newToken, newRefreshToken := refresh(refreshToken, httptest.StatusOK)
+	view(token, httptest.StatusOK)
+	view(newToken, httptest.StatusOK)
+	refresh(refreshToken, httptest.StatusForbidden)
+	view(token, httptest.StatusUnauthorized)
+	view(newToken, httptest.StatusUnauthorized)
+	refresh(newRefreshToken, httptest.StatusForbidden)
+
+	// renew stays within the session
+	token, refreshToken = login()
+	renewed := e.GET("/v1/renew").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+	view(renewed, httptest.StatusOK)
+	e.POST("/v1/logout").WithHeader("Authorization", "Bearer "+token).Expect().Status(httptest.StatusNoContent)
+	view(token, httptest.StatusUnauthorized)
+	view(renewed, httptest.StatusUnauthorized)
+	refresh(refreshToken, httptest.StatusForbidden)
+
+	// logout everywhere
+	tokenA, refreshTokenA := login()
+	tokenB, refreshTokenB := login()
+	e.POST("/v1/logout/all").WithHeader("Authorization", "Bearer "+tokenA).Expect().Status(httptest.StatusNoContent)
+	view(tokenA, httptest.StatusUnauthorized)
+	view(tokenB, httptest.StatusUnauthorized)
+	refresh(refreshTokenA, httptest.StatusForbidden)
+	refresh(refreshTokenB, httptest.StatusForbidden)
+
+	token, _ = login()
+	view(token, httptest.StatusOK)
+}
+
+func TestTOTPRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+
+	// codes are relative to a fixed step, so that the test does not depend on when it runs
+	step := util.GetTOTPStep(time.Now())
+	code := func(secret string, offset int64) string {
+		c, err := util.GetTOTPCode(secret, step+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	login := func(usr user.RegisterUserRequest, password string) *httpexpect.Object {
+		return e.POST("/v1/login").WithJSON(user.LoginRequest{
+			Account:  usr.Name,
+			Password: password,
+		}).Expect().Status(httptest.StatusOK).JSON().Object()
+	}
+	loginTOTP := func(challenge, code string, status int) *httpexpect.Object {
+		response := e.POST("/v1/login/totp").WithJSON(user.TOTPLoginRequest{ChallengeToken: challenge, Code: code}).Expect().Status(status)
+		t.Log(response.Body().Raw())
+		return response.JSON().Object()
+	}
+
+	// enroll voluntarily
+	usr := generateRandomUsers("totpUser", 1)[0]
+	id := uint(e.POST("/v1/register").WithJSON(usr).Expect().Status(httptest.StatusCreated).
+		JSON().Object().Value("data").Object().Value("id").Number().Raw())
+	token := login(usr, usr.Password).Value("data").String().Raw()
+	setup := e.POST("/v1/user/totp").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	setup.Value("uri").String().Contains("otpauth://totp/")
+	secret := setup.Value("secret").String().Raw()
+	e.POST("/v1/user/totp/enable").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.TOTPCodeRequest{Code: "abcdef"}).Expect().Status(httptest.StatusForbidden)
+	recoveryCodes := e.POST("/v1/user/totp/enable").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.TOTPCodeRequest{Code: code(secret, 0)}).Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("data").Array()
+	recoveryCodes.Length().Equal(10)
+	e.GET("/v1/user").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("totp_enabled").Boolean().True()
+
+	// login with a challenge, a code can be used only once
+	challenge := login(usr, usr.Password)
+	challenge.Value("status").Boolean().False()
+	challenge.Value("data").Object().Value("enroll").Boolean().False()
+	challengeToken := challenge.Value("data").Object().Value("challenge_token").String().Raw()
+	loginTOTP(challengeToken, code(secret, 0), httptest.StatusForbidden)
+	recoveryCode := recoveryCodes.Element(0).String().Raw()
+	loginTOTP(challengeToken, recoveryCode, httptest.StatusOK).Value("data").Object().Value("token").String().NotEmpty()
+	loginTOTP(challengeToken, code(secret, 1), httptest.StatusForbidden)
+
+	challengeToken = login(usr, usr.Password).Value("data").Object().Value("challenge_token").String().Raw()
+	loginTOTP(challengeToken, recoveryCode, httptest.StatusForbidden)
+	loginTOTP(challengeToken, code(secret, 1), httptest.StatusOK)
+
+	// disabling requires a fresh code
+	e.POST("/v1/user/totp/disable").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.TOTPCodeRequest{Code: code(secret, 1)}).Expect().Status(httptest.StatusForbidden)
+	e.DELETE("/v1/user/"+cast.ToString(id)+"/totp").WithHeader("Authorization", "Bearer "+token).Expect().Status(httptest.StatusForbidden)
+	e.DELETE("/v1/user/"+cast.ToString(id)+"/totp").WithHeader("Authorization", "Bearer "+superAdminToken).Expect().Status(httptest.StatusNoContent)
+	login(usr, usr.Password).Value("status").Boolean().True()
+
+	secret = e.POST("/v1/user/totp").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("secret").String().Raw()
+	e.POST("/v1/user/totp/enable").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.TOTPCodeRequest{Code: code(secret, 0)}).Expect().Status(httptest.StatusOK)
+	e.POST("/v1/user/totp/disable").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.TOTPCodeRequest{Code: code(secret, 0)}).Expect().Status(httptest.StatusForbidden)
+	e.POST("/v1/user/totp/disable").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.TOTPCodeRequest{Code: code(secret, 1)}).Expect().Status(httptest.StatusNoContent)
+	login(usr, usr.Password).Value("status").Boolean().True()
+
+	// the role forces two-factor, enroll on login
+	roleName := "totp_role_" + cast.ToString(rand.Intn(100000))
+	e.POST("/v1/role").WithHeader("Authorization", "Bearer "+superAdminToken).WithJSON(rbac.CreateRoleRequest{
+		Name:        roleName,
+		DisplayName: roleName,
+		Inheritance: []string{"user"},
+	}).Expect().Status(httptest.StatusCreated)
+	twoFactor := true
+	e.PUT("/v1/role/"+roleName).WithHeader("Authorization", "Bearer "+superAdminToken).WithJSON(rbac.UpdateRoleRequest{
+		DisplayName: roleName,
+		TwoFactor:   &twoFactor,
+	}).Expect().Status(httptest.StatusNoContent)
+
+	forced := generateRandomUsers("totpForced", 1)[0]
+	e.POST("/v1/user").WithHeader("Authorization", "Bearer "+superAdminToken).WithJSON(user.CreateUserRequest{
+		RegisterUserRequest: forced,
+		RoleName:            roleName,
+	}).Expect().Status(httptest.StatusCreated)
+	enroll := login(forced, forced.Password).Value("data").Object()
+	enroll.Value("enroll").Boolean().True()
+	secret = enroll.Value("secret").String().Raw()
+	data := loginTOTP(enroll.Value("challenge_token").String().Raw(), code(secret, 0), httptest.StatusOK).Value("data").Object()
+	data.Value("recovery_codes").Array().Length().Equal(10)
+	token = data.Value("token").String().Raw()
+	e.POST("/v1/user/totp/disable").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.TOTPCodeRequest{Code: code(secret, 1)}).Expect().Status(httptest.StatusBadRequest)
+
+	// the challenge is invalidated after too many failed attempts
+	challenge = login(forced, forced.Password)
+	challenge.Value("data").Object().Value("enroll").Boolean().False()
+	challengeToken = challenge.Value("data").Object().Value("challenge_token").String().Raw()
+	for i := 0; i < 5; i++ {
+		loginTOTP(challengeToken, "abcdef", httptest.StatusForbidden)
+	}
+	loginTOTP(challengeToken, code(secret, 1), httptest.StatusForbidden)
+}
+
+func TestLoginLockoutRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+
+	user.Module.ModuleConfig.Set("lockout.attempts", 3)
+	defer user.Module.ModuleConfig.Set("lockout.attempts", 5)
+
+	usr := generateRandomUsers("lockUser", 1)[0]
+	id := uint(e.POST("/v1/register").WithJSON(usr).Expect().Status(httptest.StatusCreated).
+		JSON().Object().Value("data").Object().Value("id").Number().Raw())
+	login := func(password string, status int) *httpexpect.Response {
+		return e.POST("/v1/login").WithHeader("User-Agent", "lockout-test").WithJSON(user.LoginRequest{
+			Account:  usr.Name,
+			Password: password,
+		}).Expect().Status(status)
+	}
+
+	// locked after too many failures, even with the correct password
+	for i := 0; i < 3; i++ {
+		login("wrong_password", httptest.StatusForbidden)
+	}
+	login(usr.Password, httptest.StatusForbidden)
+	e.GET("/v1/user/"+cast.ToString(id)).WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("locked_until").Number().Gt(0)
+
+	e.POST("/v1/user/" + cast.ToString(id) + "/unlock").Expect().Status(httptest.StatusForbidden)
+	e.POST("/v1/user/"+cast.ToString(id)+"/unlock").WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent)
+	token := login(usr.Password, httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+
+	// failures before the unlock are not counted again
+	login("wrong_password", httptest.StatusForbidden)
+	login(usr.Password, httptest.StatusOK)
+
+	records := e.GET("/v1/user/login").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	records.Value("total").Number().Equal(8)
+	entries := records.Value("entries").Array()
+	entries.Element(0).Object().Value("status").String().Equal("success")
+	entries.Element(1).Object().Value("status").String().Equal("failure")
+	entries.Element(3).Object().Value("method").String().Equal("unlock")
+	entries.Element(4).Object().Value("reason").String().Contains("锁定")
+	entries.Element(7).Object().Value("user_agent").String().Equal("lockout-test")
+
+	e.GET("/v1/user/"+cast.ToString(id)+"/login").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusForbidden)
+	e.GET("/v1/user/"+cast.ToString(id)+"/login").WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("total").Number().Equal(8)
+}
+
+func TestOIDCLoginRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+
+	// a mock provider, whose codes are granted by the test instead of a login page
+	type grant struct {
+		challenge string
+		nonce     string
+		claims    map[string]any
+	}
+	var lock sync.Mutex
+	grants := map[string]*grant{}
+	userinfos := map[string]map[string]any{}
+	mux := http.NewServeMux()
+	provider := nethttptest.NewServer(mux)
+	defer provider.Close()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 provider.URL,
+			"authorization_endpoint": provider.URL + "/authorize",
+			"token_endpoint":         provider.URL + "/token",
+			"userinfo_endpoint":      provider.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		r.ParseForm()
+		code := r.PostForm.Get("code")
+		lock.Lock()
+		g := grants[code]
+		delete(grants, code)
+		lock.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if id != "maintainman" || secret != "oidc_secret" || g == nil || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		payload, _ := json.Marshal(map[string]any{
+			"iss":   provider.URL,
+			"aud":   "maintainman",
+			"exp":   time.Now().Add(5 * time.Minute).Unix(),
+			"nonce": g.nonce,
+			"sub":   g.claims["sub"],
+		})
+		lock.Lock()
+		userinfos[code] = g.claims
+		lock.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": code,
+			"token_type":   "Bearer",
+			"id_token":     "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl",
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		claims := userinfos[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		lock.Unlock()
+		if claims == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_token"})
+			return
+		}
+		json.NewEncoder(w).Encode(claims)
+	})
+
+	randomNumToString := cast.ToString(rand.Intn(10000))
+	division := uint(e.POST("/v1/division").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(user.CreateDivisionRequest{Name: "TestDivisionOIDC " + randomNumToString}).
+		Expect().Status(httptest.StatusCreated).JSON().Object().Value("data").Object().Value("id").Number().Raw())
+
+	config := user.Module.ModuleConfig
+	config.Set("oidc.issuer", provider.URL)
+	config.Set("oidc.client_id", "maintainman")
+	config.Set("oidc.client_secret", "oidc_secret")
+	config.Set("oidc.redirect_uri", "http://localhost/oidc/callback")
+	config.Set("oidc.mapping", []map[string]any{
+		{"claim": "groups", "value": "maintainers", "role": "maintainer", "division": division},
+	})
+	defer config.Set("oidc.mapping", []any{})
+	e.GET("/v1/oidc/login").Expect().Status(httptest.StatusBadRequest)
+	config.Set("oidc.enable", true)
+	defer config.Set("oidc.enable", false)
+
+	// authorize simulates granting at the provider, returning the code and state sent to the redirect_uri
+	authorize := func(token string, claims map[string]any) (string, string) {
+		req := e.GET("/v1/oidc/login")
+		if token != "" {
+			req = req.WithHeader("Authorization", "Bearer "+token)
+		}
+		u, err := url.Parse(req.Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("url").String().Raw())
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := u.Query()
+		if u.Path != "/authorize" || query.Get("client_id") != "maintainman" || query.Get("code_challenge_method") != "S256" ||
+			query.Get("redirect_uri") != "http://localhost/oidc/callback" {
+			t.Fatalf("unexpected authorization url %s", u)
+		}
+		code := util.RandomString(32)
+		lock.Lock()
+		grants[code] = &grant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), claims: claims}
+		lock.Unlock()
+		return code, query.Get("state")
+	}
+	callback := func(token, code, state string, status int) *httpexpect.Response {
+		req := e.POST("/v1/oidc/callback").WithJSON(user.OIDCCallbackRequest{Code: code, State: state})
+		if token != "" {
+			req = req.WithHeader("Authorization", "Bearer "+token)
+		}
+		response := req.Expect().Status(status)
+		t.Log(response.Body().Raw())
+		return response
+	}
+	getUser := func(token string) *httpexpect.Object {
+		return e.GET("/v1/user").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	}
+
+	// unlinked accounts are refused unless autocreate is enabled
+	subject := "sso_" + util.RandomString(8)
+	claims := map[string]any{
+		"sub":                subject,
+		"preferred_username": subject,
+		"name":               "SSO User",
+		"email":              subject + "@example.com",
+		"groups":             []string{"staff", "maintainers"},
+	}
+	code, state := authorize("", claims)
+	callback("", code, state, httptest.StatusForbidden)
+	config.Set("oidc.autocreate", true)
+	defer config.Set("oidc.autocreate", false)
+
+	// created with the mapped role and division, the state can be used only once
+	code, state = authorize("", claims)
+	token := callback("", code, state, httptest.StatusOK).JSON().Object().Value("data").Object().Value("token").String().Raw()
+	callback("", code, state, httptest.StatusForbidden)
+	created := getUser(token)
+	created.Value("name").String().Equal(subject)
+	created.Value("user_role").String().Equal("maintainer")
+	id := created.Value("id").Number().Raw()
+	e.GET("/v1/user/division/"+cast.ToString(division)).WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("total").Number().Equal(1)
+
+	code, state = authorize("", claims)
+	token = callback("", code, state, httptest.StatusOK).JSON().Object().Value("data").Object().Value("token").String().Raw()
+	getUser(token).Value("id").Number().Equal(id)
+
+	// the code is bound to the PKCE verifier of its own state
+	code, _ = authorize("", claims)
+	_, state = authorize("", claims)
+	callback("", code, state, httptest.StatusForbidden)
+
+	// link an external account to an existing user
+	config.Set("oidc.autocreate", false)
+	usr := generateRandomUsers("oidcUser", 1)[0]
+	e.POST("/v1/register").WithJSON(usr).Expect().Status(httptest.StatusCreated)
+	userToken := e.POST("/v1/login").WithJSON(user.LoginRequest{Account: usr.Name, Password: usr.Password}).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+	linked := map[string]any{"sub": "sso_" + util.RandomString(8)}
+	code, state = authorize(userToken, linked)
+	callback("", code, state, httptest.StatusForbidden)
+	code, state = authorize(userToken, linked)
+	callback(userToken, code, state, httptest.StatusNoContent)
+	code, state = authorize("", linked)
+	token = callback("", code, state, httptest.StatusOK).JSON().Object().Value("data").Object().Value("token").String().Raw()
+	getUser(token).Value("name").String().Equal(usr.Name)
+
+	code, state = authorize(userToken, claims)
+	callback(userToken, code, state, httptest.StatusBadRequest)
+}
+
+func TestLDAPLoginRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	suffix := strings.ToLower(util.RandomString(6))
+	alice, bob, local := "alice_"+suffix, "bob_"+suffix, generateRandomUsers("ldapLocal", 1)[0]
+	e.POST("/v1/register").WithJSON(local).Expect().Status(httptest.StatusCreated)
+
+	directory := ldap.NewServer()
+	directory.AddEntry(&ldap.Entry{DN: "cn=reader,dc=example,dc=com", Attributes: map[string][]string{"cn": {"reader"}}}, "reader_password")
+	for name, groups := range map[string][]string{
+		alice:      {"cn=staff,ou=groups,dc=example,dc=com", "CN=Maintainers,ou=groups,dc=example,dc=com"},
+		bob:        {"cn=staff,ou=groups,dc=example,dc=com"},
+		local.Name: {},
+	} {
+		directory.AddEntry(&ldap.Entry{DN: "uid=" + name + ",ou=people,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {name},
+			"cn":          {"LDAP " + name},
+			"mail":        {name + "@example.com"},
+			"memberOf":    groups,
+		}}, name+"_password")
+	}
+	directoryURL, err := directory.Start("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer directory.Close()
+
+	config := user.Module.ModuleConfig
+	config.Set("ldap.url", directoryURL)
+	config.Set("ldap.bind_dn", "cn=reader,dc=example,dc=com")
+	config.Set("ldap.bind_password", "reader_password")
+	config.Set("ldap.mapping", []map[string]any{
+		{"group": "cn=maintainers,ou=groups,dc=example,dc=com", "role": "maintainer"},
+	})
+	defer config.Set("ldap.mapping", []any{})
+	config.Set("ldap.enable", true)
+	defer config.Set("ldap.enable", false)
+
+	login := func(account, password string, status int) *httpexpect.Response {
+		response := e.POST("/v1/login").WithJSON(user.LoginRequest{Account: account, Password: password}).Expect().Status(status)
+		t.Log(response.Body().Raw())
+		return response
+	}
+	getUser := func(response *httpexpect.Response) *httpexpect.Object {
+		token := response.JSON().Object().Value("data").String().Raw()
+		return e.GET("/v1/user").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	}
+
+	// created on the first bind with the mapped role
+	login(alice, "wrong_password", httptest.StatusForbidden)
+	created := getUser(login(alice, alice+"_password", httptest.StatusOK))
+	created.Value("name").String().Equal(alice)
+	created.Value("display_name").String().Equal("LDAP " + alice)
+	created.Value("email").String().Equal(alice + "@example.com")
+	created.Value("user_role").String().Equal("maintainer")
+	getUser(login(alice, alice+"_password", httptest.StatusOK)).Value("id").Number().Equal(created.Value("id").Number().Raw())
+	getUser(login(bob, bob+"_password", httptest.StatusOK)).Value("user_role").String().Equal("user")
+
+	// local users of the same name are never taken over
+	login(local.Name, local.Name+"_password", httptest.StatusForbidden)
+	login(local.Name, local.Password, httptest.StatusOK)
+	login("nobody_"+suffix, "nobody_password", httptest.StatusNotFound)
+
+	// the directory is unavailable
+	directory.Close()
+	login(alice, alice+"_password", httptest.StatusForbidden)
+}
+
+func TestAPIKeyRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	usr := generateRandomUsers("apiKeyUser", 1)[0]
+	e.POST("/v1/register").WithJSON(usr).Expect().Status(httptest.StatusCreated)
+	token := e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Name,
+		Password: usr.Password,
+	}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+	createKey := func(permissions []string, expiresAt int64, status int) *httpexpect.Response {
+		return e.POST("/v1/user/apikey").WithHeader("Authorization", "Bearer "+token).WithJSON(user.CreateAPIKeyRequest{
+			Name:        "test key",
+			Permissions: permissions,
+			ExpiresAt:   expiresAt,
+		}).Expect().Status(status)
+	}
+
+	// permissions the owner does not hold and invalid expirations are refused
+	createKey([]string{"user.viewall"}, 0, httptest.StatusForbidden)
+	createKey([]string{"user.view"}, time.Now().Add(-time.Hour).Unix(), httptest.StatusBadRequest)
+	createKey([]string{"user.view"}, time.Now().Add(24*366*time.Hour).Unix(), httptest.StatusBadRequest)
+
+	created := createKey([]string{"user.view"}, 0, httptest.StatusCreated).JSON().Object().Value("data").Object()
+	key := created.Value("key").String().Raw()
+	id := cast.ToString(created.Value("id").Number().Raw())
+	created.Value("prefix").String().Equal(key[:11])
+	wildcard := createKey([]string{"*"}, time.Now().Add(time.Hour).Unix(), httptest.StatusCreated).
+		JSON().Object().Value("data").Object().Value("key").String().Raw()
+
+	// the key is shown only once
+	keys := e.GET("/v1/user/apikey").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	keys.Value("total").Number().Equal(2)
+	keys.Value("entries").Array().Element(0).Object().NotContainsKey("key")
+	keys.Value("entries").Array().Element(0).Object().NotContainsKey("last_used_at")
+
+	// the permissions are limited to the key's
+	e.GET("/v1/user").WithHeader("X-API-Key", key).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("name").String().Equal(usr.Name)
+	e.PUT("/v1/user").WithHeader("X-API-Key", key).WithJSON(user.UpdateUserRequest{DisplayName: "api key"}).
+		Expect().Status(httptest.StatusForbidden)
+	// and to the owner's role, even with a wildcard
+	e.PUT("/v1/user").WithHeader("X-API-Key", wildcard).WithJSON(user.UpdateUserRequest{DisplayName: "api key"}).
+		Expect().Status(httptest.StatusNoContent)
+	e.GET("/v1/user/all").WithHeader("X-API-Key", wildcard).Expect().Status(httptest.StatusForbidden)
+	// a key can not manage the keys
+	e.GET("/v1/user/apikey").WithHeader("X-API-Key", wildcard).Expect().Status(httptest.StatusForbidden)
+	// nor renew into a token without its scopes
+	renewKey := createKey([]string{"user.renew"}, 0, httptest.StatusCreated).
+		JSON().Object().Value("data").Object().Value("key").String().Raw()
+	e.GET("/v1/renew").WithHeader("X-API-Key", renewKey).Expect().Status(httptest.StatusForbidden)
+	e.GET("/v1/renew").WithHeader("X-API-Key", wildcard).Expect().Status(httptest.StatusForbidden)
+
+	e.GET("/v1/user").WithHeader("X-API-Key", key+"0").Expect().Status(httptest.StatusUnauthorized)
+	e.GET("/v1/user").WithHeader("X-API-Key", key).WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusUnauthorized)
+
+	e.GET("/v1/user/apikey").WithHeader("Authorization", "Bearer "+token).WithQuery("order_by", "id asc").
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("entries").Array().Element(0).Object().
+		Value("last_used_at").Number().Gt(0)
+
+	e.DELETE("/v1/user/apikey/"+id).WithHeader("Authorization", "Bearer "+token).Expect().Status(httptest.StatusNoContent)
+	e.DELETE("/v1/user/apikey/"+id).WithHeader("Authorization", "Bearer "+token).Expect().Status(httptest.StatusNotFound)
+	e.GET("/v1/user").WithHeader("X-API-Key", key).Expect().Status(httptest.StatusUnauthorized)
+}
+
+func TestSessionRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+
+	usr := generateRandomUsers("sessionUser", 1)[0]
+	id := cast.ToString(e.POST("/v1/register").WithJSON(usr).Expect().Status(httptest.StatusCreated).
+		JSON().Object().Value("data").Object().Value("id").Number().Raw())
+	login := func(ua string) (string, string) {
+		data := e.POST("/v1/login").WithHeader("User-Agent", ua).WithJSON(user.LoginRequest{
+			Account:  usr.Name,
+			Password: usr.Password,
+			Refresh:  true,
+		}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+		return data.Value("token").String().Raw(), data.Value("refresh_token").String().Raw()
+	}
+	view := func(token string, status int) {
+		e.GET("/v1/user").WithHeader("Authorization", "Bearer "+token).Expect().Status(status)
+	}
+
+	tokenA, _ := login("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36")
+	tokenB, refreshTokenB := login("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0")
+	sessions := e.GET("/v1/user/session").WithHeader("Authorization", "Bearer "+tokenA).WithQuery("order_by", "id asc").
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	sessions.Value("total").Number().Equal(2)
+	entries := sessions.Value("entries").Array()
+	entries.Element(0).Object().Value("device_name").String().Equal("Windows Chrome")
+	entries.Element(0).Object().Value("current").Boolean().True()
+	entries.Element(0).Object().Value("last_active_at").Number().Gt(0)
+	entries.Element(1).Object().Value("device_name").String().Equal("iPhone 微信")
+	entries.Element(1).Object().Value("current").Boolean().False()
+	sessionB := cast.ToString(entries.Element(1).Object().Value("id").Number().Raw())
+
+	// a revoked session is rejected immediately, including its refresh token
+	other := generateRandomUsers("sessionOther", 1)[0]
+	e.POST("/v1/register").WithJSON(other).Expect().Status(httptest.StatusCreated)
+	otherToken := e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  other.Name,
+		Password: other.Password,
+	}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+	e.DELETE("/v1/user/session/"+sessionB).WithHeader("Authorization", "Bearer "+otherToken).Expect().Status(httptest.StatusNotFound)
+	e.DELETE("/v1/user/session/"+sessionB).WithHeader("Authorization", "Bearer "+tokenA).Expect().Status(httptest.StatusNoContent)
+	e.DELETE("/v1/user/session/"+sessionB).WithHeader("Authorization", "Bearer "+tokenA).Expect().Status(httptest.StatusNotFound)
+	view(tokenB, httptest.StatusUnauthorized)
+	view(tokenA, httptest.StatusOK)
+	e.POST("/v1/token/refresh").WithJSON(user.RefreshTokenRequest{RefreshToken: refreshTokenB}).Expect().Status(httptest.StatusForbidden)
+	e.GET("/v1/user/session").WithHeader("Authorization", "Bearer "+tokenA).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("total").Number().Equal(1)
+
+	// an admin revokes all sessions of the user
+	tokenC, _ := login("curl/8.0.1")
+	e.DELETE("/v1/user/"+id+"/session").WithHeader("Authorization", "Bearer "+tokenA).Expect().Status(httptest.StatusForbidden)
+	e.DELETE("/v1/user/"+id+"/session").WithHeader("Authorization", "Bearer "+superAdminToken).Expect().Status(httptest.StatusNoContent)
+	e.DELETE("/v1/user/0/session").WithHeader("Authorization", "Bearer "+superAdminToken).Expect().Status(httptest.StatusNotFound)
+	view(tokenA, httptest.StatusUnauthorized)
+	view(tokenC, httptest.StatusUnauthorized)
+	view(otherToken, httptest.StatusOK)
+
+	token, _ := login("curl/8.0.1")
+	e.GET("/v1/user/session").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("entries").Array().
+		Element(0).Object().Value("device_name").String().Equal("curl/8.0.1")
+}
+
+func TestPrivacyRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+
+	// the default user role lacks comment.create, and evidences are attached by repairers
+	roleName := "privacy_role_" + cast.ToString(rand.Intn(100000))
+	e.POST("/v1/role").WithHeader("Authorization", "Bearer "+superAdminToken).WithJSON(rbac.CreateRoleRequest{
+		Name:        roleName,
+		DisplayName: roleName,
+		Permissions: []string{"comment.create", "order.evidence"},
+		Inheritance: []string{"user"},
+	}).Expect().Status(httptest.StatusCreated)
+	usr := generateRandomUsers("privacyUser", 1)[0]
+	usr.Phone = "13800000000"
+	id := cast.ToString(e.POST("/v1/user").WithHeader("Authorization", "Bearer "+superAdminToken).WithJSON(user.CreateUserRequest{
+		RegisterUserRequest: usr,
+		RoleName:            roleName,
+	}).Expect().Status(httptest.StatusCreated).JSON().Object().Value("data").Object().Value("id").Number().Raw())
+	token := e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Name,
+		Password: usr.Password,
+	}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+	orderID := cast.ToString(e.POST("/v1/order").WithHeader("Authorization", "Bearer "+token).WithJSON(order.CreateOrderRequest{
+		Title:        "privacy order",
+		Address:      "Room 101",
+		ContactName:  "Alice",
+		ContactPhone: "13800000000",
+	}).Expect().Status(httptest.StatusCreated).JSON().Object().Value("data").Object().Value("id").Number().Raw())
+	e.POST("/v1/order/"+orderID+"/comment").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(order.CreateCommentRequest{Content: "privacy comment"}).Expect().Status(httptest.StatusCreated)
+	photo := &bytes.Buffer{}
+	png.Encode(photo, image.NewGray(image.Rect(0, 0, 16, 16)))
+	upload := func() string {
+		return e.POST("/v1/image").WithHeader("Authorization", "Bearer "+token).
+			WithMultipart().WithFileBytes("image", "privacy.png", photo.Bytes()).
+			Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+	}
+	uploaded := upload()
+	e.POST("/v1/order/"+orderID+"/assign").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("repairer", id).Expect().Status(httptest.StatusNoContent)
+	attached := upload()
+	e.POST("/v1/order/"+orderID+"/evidence").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(order.CreateEvidenceRequest{Kind: order.EvidenceAfter, Image: attached}).Expect().Status(httptest.StatusCreated)
+
+	// the archive holds a JSON file for each kind of data
+	e.GET("/v1/user/export").Expect().Status(httptest.StatusForbidden)
+	response := e.GET("/v1/user/export").WithHeader("Authorization", "Bearer "+token).Expect().Status(httptest.StatusOK)
+	response.ContentType("application/zip")
+	response.Header("Content-Disposition").Contains("maintainman-user-" + id)
+	body := []byte(response.Body().Raw())
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]any{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v any
+		if err := json.NewDecoder(r).Decode(&v); err != nil {
+			t.Fatalf("decode %s: %v", f.Name, err)
+		}
+		r.Close()
+		files[f.Name] = v
+	}
+	for _, name := range []string{"user/profile.json", "user/login_records.json", "user/sessions.json", "user/api_keys.json",
+		"user/external_identities.json", "order/orders.json", "order/comments.json", "order/images.json", "image/images.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s not found in the archive", name)
+		}
+	}
+	if name := cast.ToStringMap(files["user/profile.json"])["name"]; name != usr.Name {
+		t.Errorf("unexpected profile name %v", name)
+	}
+	if records := cast.ToSlice(files["user/login_records.json"]); len(records) != 1 {
+		t.Errorf("unexpected login records %v", records)
+	}
+	if orders := cast.ToSlice(files["order/orders.json"]); len(orders) != 1 || cast.ToStringMap(orders[0])["contact_phone"] != "13800000000" {
+		t.Errorf("unexpected orders %v", orders)
+	}
+	if comments := cast.ToSlice(files["order/comments.json"]); len(comments) != 1 || cast.ToStringMap(comments[0])["content"] != "privacy comment" {
+		t.Errorf("unexpected comments %v", comments)
+	}
+	// the uploaded images are exported even if not attached to any order
+	images := []string{uploaded, attached}
+	sort.Strings(images)
+	if uploads := cast.ToStringSlice(files["image/images.json"]); strings.Join(uploads, ",") != strings.Join(images, ",") {
+		t.Errorf("unexpected uploaded images %v", uploads)
+	}
+	if evidences := cast.ToStringSlice(files["order/images.json"]); len(evidences) != 1 || evidences[0] != attached {
+		t.Errorf("unexpected evidence images %v", evidences)
+	}
+
+	// the account is anonymised, while the orders are kept
+	e.DELETE("/v1/user").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.DeleteAccountRequest{Password: "wrong_password"}).Expect().Status(httptest.StatusForbidden)
+	e.DELETE("/v1/user").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(user.DeleteAccountRequest{Password: usr.Password}).Expect().Status(httptest.StatusNoContent)
+	e.GET("/v1/user").WithHeader("Authorization", "Bearer "+token).Expect().Status(httptest.StatusUnauthorized)
+	e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  usr.Name,
+		Password: usr.Password,
+	}).Expect().Status(httptest.StatusNotFound)
+
+	profile := e.GET("/v1/user/"+id).WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	profile.Value("name").String().Equal("deleted_" + id)
+	profile.Value("display_name").String().Equal("已注销用户")
+	profile.Value("phone").String().Empty()
+	e.GET("/v1/user/"+id+"/login").WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("total").Number().Equal(0)
+
+	anonymised := e.GET("/v1/order/"+orderID).WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	anonymised.Value("title").String().Equal("privacy order")
+	anonymised.Value("contact_name").String().Empty()
+	anonymised.Value("contact_phone").String().Empty()
+	anonymised.Value("comments").Array().Element(0).Object().Value("user_name").String().Equal("deleted_" + id)
+}
+
 // Test Tag Router
 func TestTagCreateRouter(t *testing.T) {
 	app := newApp()
@@ -292,6 +1124,272 @@ func TestTagDeleteRouter(t *testing.T) {
 	}
 }
 
+func TestTagHierarchyRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+	for _, tag := range getTestTags() {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+
+	createTag := func(name string, parentID uint) uint {
+		response := e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(order.CreateTagRequest{Sort: "hierarchy" + randomNumToString, Name: name, Level: 1, ParentID: parentID}).
+			Expect().Status(httptest.StatusCreated)
+		response.JSON().Object().Value("data").Object().Value("parent_id").Equal(parentID)
+		return uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	}
+	parentID := createTag("parent", 0)
+	childID := createTag("child", parentID)
+	grandchildID := createTag("grandchild", childID)
+	otherID := createTag("other", 0)
+	e.POST("/v1/tag").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.CreateTagRequest{Sort: "hierarchy" + randomNumToString, Name: "orphan", Level: 1, ParentID: 1 << 30}).
+		Expect().Status(httptest.StatusNotFound)
+
+	e.GET(fmt.Sprintf("/v1/tag/%d/children", parentID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("data").Array().Length().Equal(1)
+
+	orderReq := initOrder("TestTagHierarchy "+randomNumToString, "Test", "Earth", "Admin", 0)
+	orderReq.Tags = append(orderReq.Tags, grandchildID)
+	e.POST("/v1/order").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(orderReq).
+		Expect().Status(httptest.StatusCreated)
+	checkOrders := func(tag uint, descendants bool, total int) {
+		e.GET("/v1/order/all").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithQuery("tags", tag).
+			WithQuery("descendants", descendants).
+			Expect().Status(http.StatusOK).
+			JSON().Object().Value("data").Object().Value("total").Equal(total)
+	}
+	checkOrders(parentID, false, 0)
+	checkOrders(parentID, true, 1)
+	checkOrders(otherID, true, 0)
+
+	// rename, change level and move under another parent
+	level := uint(2)
+	e.PUT(fmt.Sprintf("/v1/tag/%d", childID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.UpdateTagRequest{Name: "renamed", Level: &level, ParentID: int64(otherID)}).
+		Expect().Status(httptest.StatusNoContent)
+	child := e.GET(fmt.Sprintf("/v1/tag/%d", childID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object()
+	child.Value("name").Equal("renamed")
+	child.Value("level").Equal(2)
+	child.Value("parent_id").Equal(otherID)
+	checkOrders(parentID, true, 0)
+	checkOrders(otherID, true, 1)
+
+	// duplicated name and cycles are rejected
+	e.PUT(fmt.Sprintf("/v1/tag/%d", childID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.UpdateTagRequest{Name: "parent"}).
+		Expect().Status(httptest.StatusInternalServerError)
+	e.PUT(fmt.Sprintf("/v1/tag/%d", otherID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.UpdateTagRequest{ParentID: int64(grandchildID)}).
+		Expect().Status(httptest.StatusInternalServerError)
+
+	// merge the grandchild into parent, the order follows
+	e.POST(fmt.Sprintf("/v1/tag/%d/merge", otherID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.MergeTagRequest{TargetID: grandchildID}).
+		Expect().Status(httptest.StatusInternalServerError)
+	e.POST(fmt.Sprintf("/v1/tag/%d/merge", grandchildID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.MergeTagRequest{TargetID: parentID}).
+		Expect().Status(httptest.StatusNoContent)
+	e.GET(fmt.Sprintf("/v1/tag/%d", grandchildID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNotFound)
+	checkOrders(parentID, false, 1)
+	checkOrders(otherID, true, 0)
+
+	// deleting a tag detaches it from orders and lifts its children
+	e.PUT(fmt.Sprintf("/v1/tag/%d", otherID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.UpdateTagRequest{ParentID: int64(parentID)}).
+		Expect().Status(httptest.StatusNoContent)
+	e.DELETE(fmt.Sprintf("/v1/tag/%d", otherID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent)
+	e.GET(fmt.Sprintf("/v1/tag/%d", childID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("data").Object().Value("parent_id").Equal(parentID)
+	e.DELETE(fmt.Sprintf("/v1/tag/%d", parentID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent)
+	checkOrders(parentID, false, 0)
+}
+
+func TestTagRuleRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+	for _, tag := range getTestTags() {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+
+	createTag := func(sort, name string, level, congener uint) uint {
+		response := e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(order.CreateTagRequest{Sort: sort + randomNumToString, Name: name, Level: level, Congener: congener}).
+			Expect().Status(httptest.StatusCreated)
+		return uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	}
+	pipeID := createTag("autotag", "水管", 1, 0)
+	secretID := createTag("autotag", "机密", 3, 0)
+	bulbID := createTag("autotag", "灯泡", 1, 0)
+	power1ID := createTag("autopower", "停电", 1, 1)
+	power2ID := createTag("autopower", "断电", 1, 1)
+
+	createRule := func(tagID uint, auto bool, keywords ...string) uint {
+		response := e.POST("/v1/tag/rule").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(order.CreateTagRuleRequest{TagID: tagID, Keywords: keywords, Auto: auto}).
+			Expect().Status(httptest.StatusCreated)
+		return uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	}
+	createRule(pipeID, true, "水管", "漏水")
+	createRule(secretID, true, "水管")
+	createRule(bulbID, false, "灯泡")
+	createRule(power1ID, true, "停电")
+	power2RuleID := createRule(power2ID, true, "断电")
+	e.POST("/v1/tag/rule").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.CreateTagRuleRequest{TagID: 1 << 30, Keywords: []string{"不存在"}}).
+		Expect().Status(httptest.StatusNotFound)
+	e.POST("/v1/tag/rule").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.CreateTagRuleRequest{TagID: pipeID}).
+		Expect().Status(httptest.StatusUnprocessableEntity)
+
+	e.PUT(fmt.Sprintf("/v1/tag/rule/%d", power2RuleID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.UpdateTagRuleRequest{Keywords: []string{"断电", " 跳闸 ", "断电"}}).
+		Expect().Status(httptest.StatusNoContent)
+	e.GET(fmt.Sprintf("/v1/tag/rule/%d", power2RuleID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("data").Object().Value("keywords").Array().Equal([]string{"断电", "跳闸"})
+	e.GET("/v1/tag/rule/all").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("tag_id", pipeID).
+		Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("data").Object().Value("total").Equal(1)
+
+	normalUser := generateRandomUsers("tagRuleUser", 1)[0]
+	e.POST("/v1/register").WithJSON(normalUser).Expect().Status(httptest.StatusCreated)
+	userToken := e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  normalUser.Name,
+		Password: normalUser.Password,
+	}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+	e.GET("/v1/tag/rule/all").
+		WithHeader("Authorization", "Bearer "+userToken).
+		Expect().Status(httptest.StatusForbidden)
+
+	orderReq := initOrder("厨房水管漏水 "+randomNumToString, "灯泡也坏了, 跳闸后一直断电", "Earth", "User", 0)
+	e.POST("/v1/tag/suggest").
+		WithHeader("Authorization", "Bearer "+userToken).
+		WithJSON(order.SuggestTagRequest{Title: orderReq.Title, Content: orderReq.Content, Tags: orderReq.Tags}).
+		Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("data").Array().Path("$[*].id").Array().ContainsOnly(pipeID, power2ID, bulbID)
+	e.POST("/v1/tag/suggest").
+		WithHeader("Authorization", "Bearer "+userToken).
+		WithJSON(order.SuggestTagRequest{Title: orderReq.Title, Content: orderReq.Content, Tags: append([]uint{power1ID}, orderReq.Tags...)}).
+		Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("data").Array().Path("$[*].id").Array().ContainsOnly(pipeID, bulbID)
+
+	// an API key only suggests and adds the tags within its scopes
+	createKey := func(permissions ...string) string {
+		return e.POST("/v1/user/apikey").WithHeader("Authorization", "Bearer "+userToken).
+			WithJSON(user.CreateAPIKeyRequest{Name: "tag key", Permissions: permissions}).
+			Expect().Status(httptest.StatusCreated).JSON().Object().Value("data").Object().Value("key").String().Raw()
+	}
+	e.POST("/v1/tag/suggest").
+		WithHeader("X-API-Key", createKey("tag.suggest")).
+		WithJSON(order.SuggestTagRequest{Title: orderReq.Title, Content: orderReq.Content, Tags: orderReq.Tags}).
+		Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("data").Null()
+	e.POST("/v1/order").
+		WithHeader("X-API-Key", createKey("order.create")).
+		WithJSON(orderReq).
+		Expect().Status(httptest.StatusForbidden)
+
+	createOrder := func() uint {
+		response := e.POST("/v1/order").
+			WithHeader("Authorization", "Bearer "+userToken).
+			WithJSON(orderReq).
+			Expect().Status(httptest.StatusCreated)
+		return uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	}
+	orderTags := func(id uint) (ids []uint) {
+		data := e.GET(fmt.Sprintf("/v1/order/%d", id)).
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Raw()
+		tags, _ := data["tags"].([]any)
+		for _, tag := range tags {
+			ids = append(ids, uint(tag.(map[string]any)["id"].(float64)))
+		}
+		return
+	}
+
+	checkTags := func(got []uint, want ...uint) {
+		count := map[uint]int{}
+		for _, id := range want {
+			count[id]++
+		}
+		for _, id := range got {
+			count[id]--
+		}
+		for _, c := range count {
+			if c != 0 {
+				t.Errorf("unexpected order tags %v, want %v", got, want)
+				return
+			}
+		}
+	}
+
+	// the auto rules are applied asynchronously after the order is created
+	orderID := createOrder()
+	tags := orderTags(orderID)
+	for i := 0; i < 100 && len(tags) < len(orderReq.Tags)+2; i++ {
+		time.Sleep(100 * time.Millisecond)
+		tags = orderTags(orderID)
+	}
+	checkTags(tags, append([]uint{pipeID, power2ID}, orderReq.Tags...)...)
+
+	order.Module.ModuleConfig.Set("tag.auto.enable", false)
+	defer order.Module.ModuleConfig.Set("tag.auto.enable", true)
+	orderID = createOrder()
+	time.Sleep(500 * time.Millisecond)
+	checkTags(orderTags(orderID), orderReq.Tags...)
+
+	// rules of deleted tags are removed as well
+	e.DELETE(fmt.Sprintf("/v1/tag/%d", power2ID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent)
+	e.GET(fmt.Sprintf("/v1/tag/rule/%d", power2RuleID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNotFound)
+}
+
 // Test Order Router
 func TestCreateOrderRouter(t *testing.T) {
 	app := newApp()
@@ -603,7 +1701,7 @@ func TestConsumeItemRouter(t *testing.T) {
 
 	itemTest := order.CreateItemRequest{
 		Name:        "test_item" + randomNumToString,
-		Discription: "test_item",
+		Description: "test_item",
 	}
 
 	response = e.POST("/v1/item").
@@ -628,7 +1726,7 @@ func TestConsumeItemRouter(t *testing.T) {
 		WithJSON(order.ConsumeItemRequest{
 			ItemID:  itemID,
 			OrderID: orderID,
-			Num:     uint(rand.Intn(99)),
+			Num:     float64(rand.Intn(99)),
 			Price:   float64(rand.Intn(100)),
 		}).Expect().Status(httptest.StatusForbidden).Body().Raw()
 	t.Log(responseBody)
@@ -649,16 +1747,37 @@ func TestConsumeItemRouter(t *testing.T) {
 		WithJSON(order.ConsumeItemRequest{
 			ItemID:  itemID,
 			OrderID: orderID,
-			Num:     uint(rand.Intn(99)),
+			Num:     float64(rand.Intn(99)),
 			Price:   float64(rand.Intn(100)),
 		}).Expect().Status(httptest.StatusNoContent).Body().Raw()
 	t.Log(responseBody)
+
+	// decimal quantities are used up exactly
+	decimalID := uint(e.POST("/v1/item").
+		WithJSON(order.CreateItemRequest{Name: "test_decimal" + randomNumToString, Precision: 1}).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated).JSON().Object().Value("data").Object().Value("id").Number().Raw())
+	e.POST("/v1/item/"+cast.ToString(decimalID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{ItemID: decimalID, Num: 0.3}).
+		Expect().Status(http.StatusNoContent)
+	for i := 0; i < 3; i++ {
+		e.POST("/v1/order/"+cast.ToString(orderID)+"/consume").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(order.ConsumeItemRequest{ItemID: decimalID, OrderID: orderID, Num: 0.1}).
+			Expect().Status(httptest.StatusNoContent)
+	}
+	e.GET("/v1/item/"+cast.ToString(decimalID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object().Value("count").Equal(0)
 }
 
-func TestReleaseOrderRouter(t *testing.T) {
+func TestReserveItemRouter(t *testing.T) {
 	app := newApp()
 	e := httptest.New(t, app)
 	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+	testOrder := initOrder("TestReserveItem "+randomNumToString, "Test", "Earth", "Admin", 5)
 	tags := getTestTags()
 	for _, tag := range tags {
 		e.POST("/v1/tag").
@@ -667,30 +1786,252 @@ func TestReleaseOrderRouter(t *testing.T) {
 			Expect().Status(httptest.StatusCreated)
 	}
 
-	randomNumToString := cast.ToString(rand.Intn(10000))
-	testOrder := initOrder("TestReleaseOrder "+randomNumToString, "Test", "Earth", "Admin", 5)
 	response := e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
 		WithJSON(testOrder).Expect().Status(httptest.StatusCreated)
 	t.Log(response.Body().Raw())
-	orderCreated := response.JSON().NotNull().Object().Value("data")
-	id := uint(orderCreated.Object().Value("id").NotNull().Raw().(float64))
+	orderID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
 
-	responseBody := e.POST("/v1/order/" + cast.ToString(id) + "/release").
-		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	response = e.POST("/v1/item").
+		WithJSON(order.CreateItemRequest{
+			Name:        "test_item" + randomNumToString,
+			Description: "test_item",
+		}).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+	itemID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{
+			ItemID: itemID,
+			Num:    5,
+		}).Expect().Status(http.StatusNoContent)
+
+	responseBody := e.POST("/v1/order/"+cast.ToString(orderID)+"/reserve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.ReserveItemRequest{
+			ItemID: itemID,
+			Num:    3,
+		}).Expect().Status(httptest.StatusForbidden).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/release").
+	responseBody = e.POST("/v1/order/"+cast.ToString(orderID)+"/assign").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+		WithQuery("repairer", 1).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
 	t.Log(responseBody)
-}
 
-func TestAssignOrderRouter(t *testing.T) {
-	app := newApp()
-	e := httptest.New(t, app)
-	superAdminToken := getSuperAdminToken()
-	tags := getTestTags()
-	for _, tag := range tags {
+	responseBody = e.POST("/v1/order/"+cast.ToString(orderID)+"/reserve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.ReserveItemRequest{
+			ItemID: itemID,
+			Num:    3,
+		}).Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(orderID)+"/reserve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.ReserveItemRequest{
+			ItemID: itemID,
+			Num:    3,
+		}).Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	item := e.GET("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+	item.Value("count").Equal(5)
+	item.Value("reserved").Equal(3)
+	item.Value("available").Equal(2)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(orderID)+"/reserve/return").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.ReserveItemRequest{
+			ItemID: itemID,
+			Num:    1,
+		}).Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	response = e.POST("/v1/order/"+cast.ToString(orderID)+"/consume").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.ConsumeItemRequest{
+			ItemID: itemID,
+			Num:    1,
+		}).Expect().Status(httptest.StatusNoContent)
+	t.Log(response.Body().Raw())
+	item = response.JSON().Object().Value("data").Object()
+	item.Value("count").Equal(4)
+	item.Value("reserved").Equal(1)
+	item.Value("available").Equal(3)
+
+	response = e.GET("/v1/order/"+cast.ToString(orderID)+"/reserve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Array().Length().Equal(1)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(orderID)+"/release").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	item = e.GET("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+	item.Value("count").Equal(4)
+	item.Value("reserved").Equal(0)
+	item.Value("available").Equal(4)
+
+	// the reservations are returned once the order is completed
+	e.POST("/v1/order/"+cast.ToString(orderID)+"/assign").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("repairer", 1).
+		Expect().Status(httptest.StatusNoContent)
+	e.POST("/v1/order/"+cast.ToString(orderID)+"/reserve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.ReserveItemRequest{ItemID: itemID, Num: 2}).
+		Expect().Status(httptest.StatusNoContent)
+	addAfterPhoto(e, superAdminToken, orderID)
+	e.POST("/v1/order/"+cast.ToString(orderID)+"/complete").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent)
+	item = e.GET("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+	item.Value("reserved").Equal(0)
+	item.Value("available").Equal(4)
+	e.GET("/v1/order/"+cast.ToString(orderID)+"/reserve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Null()
+}
+
+func TestItemCostingRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+	testOrder := initOrder("TestItemCosting "+randomNumToString, "Test", "Earth", "Admin", 5)
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+
+	response := e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(testOrder).Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	orderID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+
+	response = e.POST("/v1/item").
+		WithJSON(order.CreateItemRequest{
+			Name:        "test_item" + randomNumToString,
+			Description: "test_item",
+		}).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+	itemID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+
+	for _, price := range []float64{10, 30} {
+		e.POST("/v1/item/"+cast.ToString(itemID)).
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(order.AddItemRequest{
+				ItemID: itemID,
+				Num:    10,
+				Price:  price,
+			}).Expect().Status(http.StatusNoContent)
+	}
+
+	e.POST("/v1/order/"+cast.ToString(orderID)+"/assign").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("repairer", 1).
+		Expect().Status(httptest.StatusNoContent)
+
+	e.POST("/v1/order/"+cast.ToString(orderID)+"/consume").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.ConsumeItemRequest{
+			ItemID: itemID,
+			Num:    15,
+			Price:  100,
+		}).Expect().Status(httptest.StatusNoContent)
+
+	responseBody := e.GET("/v1/item/valuation").
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	response = e.GET("/v1/item/valuation").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	found := false
+	for _, v := range response.JSON().Object().Value("data").Object().Value("items").Array().Iter() {
+		if uint(v.Object().Value("item_id").Raw().(float64)) == itemID {
+			// weighted average: 40 / 20 * 15 = 30 consumed
+			v.Object().Value("count").Equal(5)
+			v.Object().Value("value").Equal(10)
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("item %d not found in valuation", itemID)
+	}
+
+	response = e.GET("/v1/item/cost").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	found = false
+	for _, v := range response.JSON().Object().Value("data").Object().Value("orders").Array().Iter() {
+		if uint(v.Object().Value("id").Raw().(float64)) == orderID {
+			v.Object().Value("num").Equal(15)
+			v.Object().Value("cost").Equal(30)
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("order %d not found in cost report", orderID)
+	}
+}
+
+func TestReleaseOrderRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+
+	randomNumToString := cast.ToString(rand.Intn(10000))
+	testOrder := initOrder("TestReleaseOrder "+randomNumToString, "Test", "Earth", "Admin", 5)
+	response := e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(testOrder).Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	orderCreated := response.JSON().NotNull().Object().Value("data")
+	id := uint(orderCreated.Object().Value("id").NotNull().Raw().(float64))
+
+	responseBody := e.POST("/v1/order/" + cast.ToString(id) + "/release").
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/release").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestAssignOrderRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	tags := getTestTags()
+	for _, tag := range tags {
 		e.POST("/v1/tag").
 			WithHeader("Authorization", "Bearer "+superAdminToken).
 			WithJSON(tag).
@@ -785,6 +2126,318 @@ func TestSelfAssignOrderRouter(t *testing.T) {
 	t.Log(responseBody)
 }
 
+func TestTransferOrderRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	repairerIds := []uint{}
+	repairerTokens := []string{}
+	for _, repairer := range generateRandomUsers("repairerTransfer "+randomNumToString, 2) {
+		response := e.POST("/v1/register").WithJSON(repairer).Expect().Status(httptest.StatusCreated)
+		t.Log(response.Body().Raw())
+		u := response.JSON().NotNull().Object().Value("data")
+		repairerId := uint(u.Object().Value("id").NotNull().Raw().(float64))
+
+		responseBody := e.PUT("/v1/user/"+cast.ToString(repairerId)).WithHeader("Authorization", "Bearer "+superAdminToken).WithJSON(user.UpdateUserRequest{
+			RoleName: "maintainer",
+		}).Expect().Status(httptest.StatusNoContent).Body().Raw()
+		t.Log(responseBody)
+
+		token, _ := util.GetJwtString(repairerId, repairer.Name, "maintainer")
+		repairerIds = append(repairerIds, repairerId)
+		repairerTokens = append(repairerTokens, token)
+	}
+
+	testOrder := initOrder("TestTransferOrder "+randomNumToString, "Test", "Earth", "Admin", 5)
+	response := e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(testOrder).Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	orderCreated := response.JSON().NotNull().Object().Value("data")
+	id := uint(orderCreated.Object().Value("id").NotNull().Raw().(float64))
+
+	responseBody := e.POST("/v1/order/"+cast.ToString(id)+"/transfer").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.TransferOrderRequest{RepairerID: repairerIds[0], Reason: "Test"}).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/assign").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("repairer", repairerIds[0]).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/" + cast.ToString(id) + "/transfer").
+		WithJSON(order.TransferOrderRequest{RepairerID: repairerIds[1], Reason: "Test"}).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		WithJSON(order.TransferOrderRequest{RepairerID: repairerIds[0], Reason: "Test"}).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		WithJSON(order.TransferOrderRequest{RepairerID: repairerIds[1], Reason: "Test"}).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		WithJSON(order.TransferOrderRequest{RepairerID: repairerIds[0], Reason: "Test"}).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer").
+		WithHeader("Authorization", "Bearer "+repairerTokens[1]).
+		WithJSON(order.TransferOrderRequest{RepairerID: repairerIds[0], Reason: "Test", Consent: true}).
+		Expect().Status(httptest.StatusCreated).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.GET("/v1/order/transfer").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusOK).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer/reject").
+		WithHeader("Authorization", "Bearer "+repairerTokens[1]).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer/reject").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer/accept").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusNotFound).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer").
+		WithHeader("Authorization", "Bearer "+repairerTokens[1]).
+		WithJSON(order.TransferOrderRequest{RepairerID: repairerIds[0], Reason: "Test", Consent: true}).
+		Expect().Status(httptest.StatusCreated).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/transfer/accept").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestDivisionAssignOrderRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	response := e.POST("/v1/division").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(user.CreateDivisionRequest{Name: "TestDivisionParent " + randomNumToString}).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	parentId := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+
+	response = e.POST("/v1/division").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(user.CreateDivisionRequest{Name: "TestDivisionChild " + randomNumToString, ParentID: parentId}).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	childId := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+
+	repairerTokens := []string{}
+	for i, repairer := range generateRandomUsers("repairerDivision "+randomNumToString, 2) {
+		response := e.POST("/v1/register").WithJSON(repairer).Expect().Status(httptest.StatusCreated)
+		t.Log(response.Body().Raw())
+		u := response.JSON().NotNull().Object().Value("data")
+		repairerId := uint(u.Object().Value("id").NotNull().Raw().(float64))
+
+		responseBody := e.PUT("/v1/user/"+cast.ToString(repairerId)).WithHeader("Authorization", "Bearer "+superAdminToken).WithJSON(user.UpdateUserRequest{
+			RoleName:   "maintainer",
+			DivisionID: util.Tenary(i == 0, int64(childId), 0),
+		}).Expect().Status(httptest.StatusNoContent).Body().Raw()
+		t.Log(responseBody)
+
+		token, _ := util.GetJwtString(repairerId, repairer.Name, "maintainer")
+		repairerTokens = append(repairerTokens, token)
+	}
+
+	testOrder := initOrder("TestDivisionAssignOrder "+randomNumToString, "Test", "Earth", "Admin", 5)
+	response = e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(testOrder).Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	orderCreated := response.JSON().NotNull().Object().Value("data")
+	id := uint(orderCreated.Object().Value("id").NotNull().Raw().(float64))
+
+	responseBody := e.POST("/v1/order/"+cast.ToString(id)+"/claim").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/assign/division").
+		WithQuery("division", parentId).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/assign/division").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("division", parentId).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	response = e.GET("/v1/order/division/"+cast.ToString(parentId)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("total").Equal(1)
+
+	response = e.GET("/v1/order/division").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("total").Equal(1)
+
+	response = e.GET("/v1/order/repairer").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		WithQuery("current", true).
+		WithQuery("division", true).
+		Expect().Status(httptest.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("total").Equal(1)
+
+	responseBody = e.GET("/v1/order/division").
+		WithHeader("Authorization", "Bearer "+repairerTokens[1]).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/claim").
+		WithHeader("Authorization", "Bearer "+repairerTokens[1]).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/claim").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	response = e.GET("/v1/order/division").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("total").Equal(0)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/claim").
+		WithHeader("Authorization", "Bearer "+repairerTokens[0]).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestWorkSessionRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	ids := []uint{}
+	for i := 0; i < 2; i++ {
+		testOrder := initOrder("TestWorkSession "+randomNumToString, "Test", "Earth", "Admin", 5)
+		response := e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(testOrder).Expect().Status(httptest.StatusCreated)
+		t.Log(response.Body().Raw())
+		orderCreated := response.JSON().NotNull().Object().Value("data")
+		ids = append(ids, uint(orderCreated.Object().Value("id").NotNull().Raw().(float64)))
+	}
+
+	responseBody := e.POST("/v1/order/"+cast.ToString(ids[0])+"/work/start").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	for _, id := range ids {
+		responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/selfassign").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			Expect().Status(httptest.StatusNoContent).Body().Raw()
+		t.Log(responseBody)
+	}
+
+	responseBody = e.POST("/v1/order/" + cast.ToString(ids[0]) + "/work/start").
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	latitude, longitude := 31.2304, 121.4737
+	responseBody = e.POST("/v1/order/"+cast.ToString(ids[0])+"/work/start").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.CheckWorkRequest{Latitude: &latitude, Longitude: &longitude}).
+		Expect().Status(httptest.StatusCreated).Body().Raw()
+	t.Log(responseBody)
+
+	// overlapping session of the same repairer
+	responseBody = e.POST("/v1/order/"+cast.ToString(ids[1])+"/work/start").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(ids[1])+"/work/stop").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNotFound).Body().Raw()
+	t.Log(responseBody)
+
+	time.Sleep(1 * time.Second)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(ids[0])+"/work/stop").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(ids[1])+"/work/start").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusCreated).Body().Raw()
+	t.Log(responseBody)
+
+	response := e.GET("/v1/order/"+cast.ToString(ids[0])+"/work").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("total").Equal(1)
+
+	response = e.GET("/v1/order/"+cast.ToString(ids[0])).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("labour_time").Number().Ge(1)
+
+	response = e.GET("/v1/order/work/stat").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("repairer", 1).
+		Expect().Status(httptest.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("labour_time").Number().Ge(1)
+}
+
 func TestCompleteOrderRouter(t *testing.T) {
 	app := newApp()
 	e := httptest.New(t, app)
@@ -809,20 +2462,97 @@ func TestCompleteOrderRouter(t *testing.T) {
 		Expect().Status(httptest.StatusForbidden).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/selfassign").
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/selfassign").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/release").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/selfassign").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	// an "after" photo is required by default
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/complete").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	addAfterPhoto(e, superAdminToken, id)
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/complete").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestOrderEvidenceRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	testOrder := initOrder("TestOrderEvidence "+randomNumToString, "Test", "Earth", "Admin", 5)
+	response := e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(testOrder).Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	orderCreated := response.JSON().NotNull().Object().Value("data")
+	id := uint(orderCreated.Object().Value("id").NotNull().Raw().(float64))
+
+	signature := &bytes.Buffer{}
+	png.Encode(signature, image.NewGray(image.Rect(0, 0, 16, 16)))
+
+	responseBody := e.POST("/v1/order/"+cast.ToString(id)+"/signature").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithMultipart().WithFileBytes("signature", "signature.png", signature.Bytes()).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/selfassign").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/signature").
+		WithMultipart().WithFileBytes("signature", "signature.png", signature.Bytes()).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	response = e.POST("/v1/order/"+cast.ToString(id)+"/signature").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithMultipart().WithFileBytes("signature", "signature.png", signature.Bytes()).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	imageID := response.JSON().Object().Value("data").Object().Value("image_id").String().Raw()
+
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/evidence").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(httptest.StatusNoContent).Body().Raw()
+		WithJSON(order.CreateEvidenceRequest{Kind: order.EvidenceAfter, Image: "00000000-0000-0000-0000-000000000000"}).
+		Expect().Status(httptest.StatusNotFound).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/release").
+	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/evidence").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(httptest.StatusNoContent).Body().Raw()
+		WithJSON(order.CreateEvidenceRequest{Kind: order.EvidenceAfter, Image: imageID}).
+		Expect().Status(httptest.StatusCreated).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/selfassign").
+	response = e.GET("/v1/order/"+cast.ToString(id)).
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(httptest.StatusNoContent).Body().Raw()
-	t.Log(responseBody)
+		Expect().Status(httptest.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("evidences").Array().Length().Equal(2)
 
 	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/complete").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
@@ -943,6 +2673,102 @@ func TestReportOrderRouter(t *testing.T) {
 		WithHeader("Authorization", "Bearer "+superAdminToken).
 		Expect().Status(httptest.StatusNoContent).Body().Raw()
 	t.Log(responseBody)
+
+	// no escalation rule configured by default
+	responseBody = e.GET("/v1/order/"+cast.ToString(id)+"/escalation").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNotFound).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestEscalationRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
+	}
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	supervisors := []uint{}
+	for _, u := range generateRandomUsers("supervisorUser", 2) {
+		response := e.POST("/v1/user").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(user.CreateUserRequest{
+				RegisterUserRequest: u,
+				RoleName:            "maintainer",
+			}).Expect().Status(httptest.StatusCreated)
+		supervisors = append(supervisors, uint(response.JSON().Object().Value("data").Object().Value("id").Number().Raw()))
+	}
+	order.Module.ModuleConfig.Set("escalation.rules", []map[string]any{
+		{"name": "test-escalation", "levels": []map[string]any{
+			{"supervisor": supervisors[0], "timeout": "0s"},
+			{"supervisor": supervisors[1], "timeout": "1h"},
+		}},
+	})
+	defer order.Module.ModuleConfig.Set("escalation.rules", []any{})
+
+	report := func() uint {
+		testOrder := initOrder("TestEscalateOrder "+randomNumToString, "Test", "Earth", "Admin", 5)
+		id := uint(e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(testOrder).Expect().Status(httptest.StatusCreated).
+			JSON().Object().Value("data").Object().Value("id").Number().Raw())
+		e.POST("/v1/order/"+cast.ToString(id)+"/selfassign").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			Expect().Status(httptest.StatusNoContent)
+		e.POST("/v1/order/"+cast.ToString(id)+"/report").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			Expect().Status(httptest.StatusNoContent)
+		return id
+	}
+	escalation := func(id uint) *httpexpect.Object {
+		response := e.GET("/v1/order/"+cast.ToString(id)+"/escalation").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			Expect().Status(httptest.StatusOK)
+		t.Log(response.Body().Raw())
+		return response.JSON().Object().Value("data").Object()
+	}
+	// the orders whose current status is assigned to the supervisor
+	supervised := func(supervisor uint) *httpexpect.Value {
+		return e.GET("/v1/order/repairer/"+cast.ToString(supervisor)).
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithQuery("current", true).
+			Expect().Status(httptest.StatusOK).JSON().Object().Value("data").Object().Value("entries")
+	}
+
+	// routed to the first supervisor once reported, then escalated to the last one
+	id := report()
+	level := escalation(id)
+	level.Value("level").Equal(1)
+	level.Value("supervisor_id").Equal(supervisors[0])
+	level.Value("deadline").Number().Gt(0)
+	supervised(supervisors[0]).Array().Element(0).Object().Value("id").Equal(id)
+	order.AutoEscalateOrderService()
+	level = escalation(id)
+	level.Value("level").Equal(2)
+	level.Value("supervisor_id").Equal(supervisors[1])
+	level.Value("deadline").Equal(0)
+	supervised(supervisors[0]).Null()
+	supervised(supervisors[1]).Array().Element(0).Object().Value("id").Equal(id)
+	// the last level is never escalated further
+	order.AutoEscalateOrderService()
+	escalation(id).Value("level").Equal(2)
+
+	// no longer escalated once the order leaves the reported status
+	id = report()
+	e.POST("/v1/order/"+cast.ToString(id)+"/hold").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent)
+	order.AutoEscalateOrderService()
+	level = escalation(id)
+	level.Value("level").Equal(1)
+	level.Value("deadline").Equal(0)
+	supervised(supervisors[0]).Null()
+	supervised(supervisors[1]).Array().Length().Equal(1)
 }
 
 func TestHoldOrderRouter(t *testing.T) {
@@ -1025,6 +2851,7 @@ func TestAppraiseOrderRouter(t *testing.T) {
 		Expect().Status(httptest.StatusNoContent).Body().Raw()
 	t.Log(responseBody)
 
+	addAfterPhoto(e, superAdminToken, id)
 	responseBody = e.POST("/v1/order/"+cast.ToString(id)+"/complete").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
 		Expect().Status(httptest.StatusNoContent).Body().Raw()
@@ -1148,192 +2975,845 @@ func TestCreateItemRouter(t *testing.T) {
 
 	itemTest := order.CreateItemRequest{
 		Name:        "test_item" + randomNumToString,
-		Discription: "test_item",
+		Description: "test_item",
+	}
+
+	responseBody := e.POST("/v1/item").
+		WithJSON(itemTest).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/item").
+		WithJSON(itemTest).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestGetAllItemRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+
+	responseBody := e.GET("/v1/item/all").
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.GET("/v1/item/all").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestGetItemByIDRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	itemTest := order.CreateItemRequest{
+		Name:        "test_item" + randomNumToString,
+		Description: "test_item",
+	}
+
+	response := e.POST("/v1/item").
+		WithJSON(itemTest).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+
+	item := response.JSON().NotNull().Object().Value("data")
+	id := uint(item.Object().Value("id").NotNull().Raw().(float64))
+
+	responseBody := e.GET("/v1/item/" + cast.ToString(id)).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.GET("/v1/item/"+cast.ToString(id)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestAddItemByIDRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	itemTest := order.CreateItemRequest{
+		Name:        "test_item" + randomNumToString,
+		Description: "test_item",
+	}
+
+	response := e.POST("/v1/item").
+		WithJSON(itemTest).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+
+	item := response.JSON().NotNull().Object().Value("data")
+	id := uint(item.Object().Value("id").NotNull().Raw().(float64))
+
+	responseBody := e.POST("/v1/item/" + cast.ToString(id)).
+		WithJSON(order.AddItemRequest{
+			ItemID: id,
+			Num:    float64(rand.Intn(100)),
+			Price:  float64(rand.Intn(100)),
+		}).Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/item/"+cast.ToString(id)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{
+			ItemID: id,
+			Num:    float64(rand.Intn(100)),
+			Price:  float64(rand.Intn(100)),
+		}).Expect().Status(http.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestDeleteItemByIDRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	itemTest := order.CreateItemRequest{
+		Name:        "test_item" + randomNumToString,
+		Description: "test_item",
+	}
+
+	response := e.POST("/v1/item").
+		WithJSON(itemTest).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+
+	item := response.JSON().NotNull().Object().Value("data")
+	id := uint(item.Object().Value("id").NotNull().Raw().(float64))
+
+	responseBody := e.DELETE("/v1/item/" + cast.ToString(id)).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.DELETE("/v1/item/"+cast.ToString(id)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestGetItemByNameRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	itemTest := order.CreateItemRequest{
+		Name:        "test_item" + randomNumToString,
+		Description: "test_item",
+	}
+
+	response := e.POST("/v1/item").
+		WithJSON(itemTest).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+
+	item := response.JSON().NotNull().Object().Value("data")
+	id := item.Object().Value("name").NotNull().Raw().(string)
+
+	responseBody := e.GET("/v1/item/name/" + id).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.GET("/v1/item/name/"+id).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestGetItemByNameFuzzyRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	itemTest := order.CreateItemRequest{
+		Name:        "test_item" + randomNumToString,
+		Description: "test_item",
+	}
+
+	response := e.POST("/v1/item").
+		WithJSON(itemTest).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+
+	item := response.JSON().NotNull().Object().Value("data")
+	id := item.Object().Value("name").NotNull().Raw().(string)
+
+	responseBody := e.GET("/v1/item/name/" + id + "/fuzzy").
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.GET("/v1/item/name/"+id+"/fuzzy").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).Body().Raw()
+	t.Log(responseBody)
+}
+
+func TestItemReorderRouter(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
+
+	itemTest := order.CreateItemRequest{
+		Name:        "test_item" + randomNumToString,
+		Description: "test_item",
+		MinCount:    10,
+		ReorderNum:  20,
+	}
+
+	response := e.POST("/v1/item").
+		WithJSON(itemTest).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+
+	item := response.JSON().NotNull().Object().Value("data")
+	item.Object().Value("min_count").Equal(10)
+	id := uint(item.Object().Value("id").NotNull().Raw().(float64))
+
+	responseBody := e.POST("/v1/item/"+cast.ToString(id)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{
+			ItemID: id,
+			Num:    3,
+		}).Expect().Status(http.StatusNoContent).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.GET("/v1/item/reorder").
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
+
+	response = e.GET("/v1/item/reorder").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	found := false
+	for _, v := range response.JSON().Object().Value("data").Array().Iter() {
+		if uint(v.Object().Value("item_id").Raw().(float64)) == id {
+			v.Object().Value("count").Equal(3)
+			v.Object().Value("suggested").Equal(20)
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("item %d not found in reorder report", id)
 	}
 
-	responseBody := e.POST("/v1/item").
-		WithJSON(itemTest).
-		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	responseBody = e.PUT("/v1/item/"+cast.ToString(id)+"/threshold").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.UpdateItemThresholdRequest{
+			MinCount:   0,
+			ReorderNum: 0,
+		}).Expect().Status(http.StatusNoContent).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.POST("/v1/item").
-		WithJSON(itemTest).
+	response = e.GET("/v1/item/reorder").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(http.StatusCreated).Body().Raw()
-	t.Log(responseBody)
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	report, _ := response.JSON().Object().Value("data").Raw().([]any)
+	for _, v := range report {
+		if uint(v.(map[string]any)["item_id"].(float64)) == id {
+			t.Errorf("item %d should not be in reorder report", id)
+		}
+	}
 }
 
-func TestGetAllItemRouter(t *testing.T) {
+func TestItemCatalogueRouter(t *testing.T) {
 	app := newApp()
 	e := httptest.New(t, app)
 	superAdminToken := getSuperAdminToken()
+	randomNumToString := cast.ToString(rand.Intn(10000))
 
-	responseBody := e.GET("/v1/item/all").
+	responseBody := e.POST("/v1/item/category").
+		WithJSON(order.CreateCategoryRequest{Name: "test_category" + randomNumToString}).
 		Expect().Status(httptest.StatusForbidden).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.GET("/v1/item/all").
+	response := e.POST("/v1/item/category").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(http.StatusOK).Body().Raw()
+		WithJSON(order.CreateCategoryRequest{Name: "test_category" + randomNumToString}).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	categoryID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+
+	response = e.POST("/v1/item").
+		WithJSON(order.CreateItemRequest{
+			Name:        "test_cable" + randomNumToString,
+			Description: "test_cable",
+			CategoryID:  categoryID,
+			Unit:        "米",
+			Precision:   1,
+			SKU:         "SKU-" + randomNumToString,
+			Barcodes:    []string{"690" + randomNumToString},
+		}).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusCreated)
+	t.Log(response.Body().Raw())
+	itemID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{ItemID: itemID, Num: 2.5, Price: 5}).
+		Expect().Status(http.StatusNoContent)
+
+	responseBody = e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{ItemID: itemID, Num: 2.55, Price: 5}).
+		Expect().Status(http.StatusInternalServerError).Body().Raw()
 	t.Log(responseBody)
+
+	item := e.GET("/v1/item/barcode/690"+randomNumToString).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+	item.Value("id").Equal(itemID)
+	item.Value("unit").Equal("米")
+	item.Value("count").Equal(2.5)
+
+	e.GET("/v1/item/barcode/SKU-"+randomNumToString).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object().Value("id").Equal(itemID)
+
+	e.GET("/v1/item/barcode/none"+randomNumToString).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusNotFound)
+
+	response = e.GET("/v1/item/all").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("category_id", categoryID).
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("total").Equal(1)
+
+	e.PUT("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.UpdateItemRequest{Barcodes: []string{"691" + randomNumToString}}).
+		Expect().Status(http.StatusNoContent)
+
+	e.GET("/v1/item/barcode/690"+randomNumToString).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusNotFound)
+
+	e.DELETE("/v1/item/category/"+cast.ToString(categoryID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusNoContent)
+
+	e.GET("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object().Value("category_id").Equal(0)
 }
 
-func TestGetItemByIDRouter(t *testing.T) {
+func TestItemUsageRouter(t *testing.T) {
 	app := newApp()
 	e := httptest.New(t, app)
 	superAdminToken := getSuperAdminToken()
 	randomNumToString := cast.ToString(rand.Intn(10000))
-
-	itemTest := order.CreateItemRequest{
-		Name:        "test_item" + randomNumToString,
-		Discription: "test_item",
+	testOrder := initOrder("TestItemUsage "+randomNumToString, "Test", "Earth", "Admin", 5)
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
 	}
 
-	response := e.POST("/v1/item").
-		WithJSON(itemTest).
+	response := e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(testOrder).Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	orderID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+
+	response = e.POST("/v1/item").
+		WithJSON(order.CreateItemRequest{
+			Name:        "test_item" + randomNumToString,
+			Description: "test_item",
+		}).
 		WithHeader("Authorization", "Bearer "+superAdminToken).
 		Expect().Status(http.StatusCreated)
 	t.Log(response.Body().Raw())
+	itemID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
 
-	item := response.JSON().NotNull().Object().Value("data")
-	id := uint(item.Object().Value("id").NotNull().Raw().(float64))
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{ItemID: itemID, Num: 20, Price: 40}).
+		Expect().Status(http.StatusNoContent)
 
-	responseBody := e.GET("/v1/item/" + cast.ToString(id)).
+	e.POST("/v1/order/"+cast.ToString(orderID)+"/assign").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("repairer", 1).
+		Expect().Status(httptest.StatusNoContent)
+
+	for _, num := range []float64{3, 5} {
+		e.POST("/v1/order/"+cast.ToString(orderID)+"/consume").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(order.ConsumeItemRequest{ItemID: itemID, OrderID: orderID, Num: num}).
+			Expect().Status(httptest.StatusNoContent)
+	}
+
+	responseBody := e.GET("/v1/item/usage").
 		Expect().Status(httptest.StatusForbidden).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.GET("/v1/item/"+cast.ToString(id)).
+	response = e.GET("/v1/item/usage").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(http.StatusOK).Body().Raw()
-	t.Log(responseBody)
+		WithQuery("item_id", itemID).
+		WithQuery("period", "week").
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	usage := response.JSON().Object().Value("data").Object().Value("items").Array().First().Object()
+	usage.Value("item_id").Equal(itemID)
+	usage.Value("num").Equal(8)
+	usage.Value("cost").Equal(16)
+	usage.Value("periods").Array().Length().Equal(12)
+	usage.Value("periods").Array().Last().Object().Value("num").Equal(8)
+
+	response = e.GET("/v1/item/usage/top").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("tag_id", testOrder.Tags[0]).
+		WithQuery("limit", 100).
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	found := false
+	for _, v := range response.JSON().Object().Value("data").Array().Iter() {
+		if uint(v.Object().Value("item_id").Raw().(float64)) == itemID {
+			v.Object().Value("num").Equal(8)
+			v.Object().Value("orders").Equal(1)
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("item %d not found in top usage", itemID)
+	}
+
+	response = e.GET("/v1/item/"+cast.ToString(itemID)+"/forecast").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("period", "week").
+		WithQuery("periods", 4).
+		WithQuery("method", "smoothing").
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	forecast := response.JSON().Object().Value("data").Object()
+	forecast.Value("history").Array().Length().Equal(4)
+	forecast.Value("forecast").Equal(0)
 }
 
-func TestAddItemByIDRouter(t *testing.T) {
+func TestWarehouseRouter(t *testing.T) {
 	app := newApp()
 	e := httptest.New(t, app)
 	superAdminToken := getSuperAdminToken()
 	randomNumToString := cast.ToString(rand.Intn(10000))
 
-	itemTest := order.CreateItemRequest{
-		Name:        "test_item" + randomNumToString,
-		Discription: "test_item",
-	}
+	responseBody := e.POST("/v1/warehouse").
+		WithJSON(order.CreateWarehouseRequest{
+			Name: "test_warehouse" + randomNumToString,
+		}).
+		Expect().Status(httptest.StatusForbidden).Body().Raw()
+	t.Log(responseBody)
 
-	response := e.POST("/v1/item").
-		WithJSON(itemTest).
+	response := e.POST("/v1/warehouse").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.CreateWarehouseRequest{
+			Name:    "test_warehouse" + randomNumToString,
+			Address: "Earth",
+			Keepers: []uint{1},
+		}).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	warehouse := response.JSON().Object().Value("data").Object()
+	warehouse.Value("keepers").Array().Elements(1)
+	warehouseID := uint(warehouse.Value("id").NotNull().Raw().(float64))
+
+	response = e.POST("/v1/item").
+		WithJSON(order.CreateItemRequest{
+			Name:        "test_item" + randomNumToString,
+			Description: "test_item",
+		}).
 		WithHeader("Authorization", "Bearer "+superAdminToken).
 		Expect().Status(http.StatusCreated)
 	t.Log(response.Body().Raw())
+	itemID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
 
-	item := response.JSON().NotNull().Object().Value("data")
-	id := uint(item.Object().Value("id").NotNull().Raw().(float64))
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{
+			ItemID: itemID,
+			Num:    10,
+		}).Expect().Status(http.StatusNoContent)
 
-	responseBody := e.POST("/v1/item/" + cast.ToString(id)).
+	e.POST("/v1/warehouse/"+cast.ToString(warehouseID)+"/stock").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
 		WithJSON(order.AddItemRequest{
-			ItemID: id,
-			Num:    uint(rand.Intn(100)),
-			Price:  float64(rand.Intn(100)),
-		}).Expect().Status(httptest.StatusForbidden).Body().Raw()
+			ItemID: itemID,
+			Num:    5,
+		}).Expect().Status(http.StatusNoContent)
+
+	response = e.POST("/v1/warehouse/transfer").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.StockTransferRequest{
+			ItemID: itemID,
+			FromID: 0,
+			ToID:   warehouseID,
+			Num:    3,
+		}).Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+
+	responseBody = e.POST("/v1/warehouse/transfer").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.StockTransferRequest{
+			ItemID: itemID,
+			FromID: warehouseID,
+			ToID:   0,
+			Num:    20,
+		}).Expect().Status(httptest.StatusInternalServerError).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.POST("/v1/item/"+cast.ToString(id)).
+	stockOf := func(warehouseID uint) float64 {
+		response := e.GET("/v1/warehouse/"+cast.ToString(warehouseID)+"/stock").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			Expect().Status(http.StatusOK)
+		t.Log(response.Body().Raw())
+		for _, v := range response.JSON().Object().Value("data").Array().Iter() {
+			if uint(v.Object().Value("item_id").Raw().(float64)) == itemID {
+				return v.Object().Value("count").Raw().(float64)
+			}
+		}
+		return 0
+	}
+	if count := stockOf(warehouseID); count != 8 {
+		t.Errorf("expected 8 items in warehouse, got %v", count)
+	}
+	if count := stockOf(0); count != 7 {
+		t.Errorf("expected 7 items in central warehouse, got %v", count)
+	}
+
+	item := e.GET("/v1/item/"+cast.ToString(itemID)).
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		WithJSON(order.AddItemRequest{
-			ItemID: id,
-			Num:    uint(rand.Intn(100)),
-			Price:  float64(rand.Intn(100)),
-		}).Expect().Status(http.StatusNoContent).Body().Raw()
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+	item.Value("count").Equal(15)
+
+	// stocking a warehouse through the item route is limited to its keepers as well
+	otherID := uint(e.POST("/v1/warehouse").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.CreateWarehouseRequest{Name: "other_warehouse" + randomNumToString}).
+		Expect().Status(httptest.StatusCreated).JSON().Object().Value("data").Object().Value("id").Number().Raw())
+	itemKey := e.POST("/v1/user/apikey").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(user.CreateAPIKeyRequest{Name: "item key", Permissions: []string{"item.update"}}).
+		Expect().Status(httptest.StatusCreated).JSON().Object().Value("data").Object().Value("key").String().Raw()
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("X-API-Key", itemKey).
+		WithJSON(order.AddItemRequest{ItemID: itemID, WarehouseID: otherID, Num: 1}).
+		Expect().Status(httptest.StatusForbidden)
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("X-API-Key", itemKey).
+		WithJSON(order.AddItemRequest{ItemID: itemID, WarehouseID: warehouseID, Num: 1}).
+		Expect().Status(http.StatusNoContent)
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("X-API-Key", itemKey).
+		WithJSON(order.AddItemRequest{ItemID: itemID, Num: 1}).
+		Expect().Status(http.StatusNoContent)
+
+	responseBody = e.DELETE("/v1/warehouse/"+cast.ToString(warehouseID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
 	t.Log(responseBody)
 }
 
-func TestDeleteItemByIDRouter(t *testing.T) {
+func TestPurchaseRouter(t *testing.T) {
 	app := newApp()
 	e := httptest.New(t, app)
 	superAdminToken := getSuperAdminToken()
 	randomNumToString := cast.ToString(rand.Intn(10000))
 
-	itemTest := order.CreateItemRequest{
-		Name:        "test_item" + randomNumToString,
-		Discription: "test_item",
-	}
+	response := e.POST("/v1/supplier").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.CreateSupplierRequest{
+			Name:  "test_supplier" + randomNumToString,
+			Phone: "12345678",
+		}).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	supplierID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
 
-	response := e.POST("/v1/item").
-		WithJSON(itemTest).
+	response = e.POST("/v1/item").
+		WithJSON(order.CreateItemRequest{
+			Name:        "test_item" + randomNumToString,
+			Description: "test_item",
+		}).
 		WithHeader("Authorization", "Bearer "+superAdminToken).
 		Expect().Status(http.StatusCreated)
 	t.Log(response.Body().Raw())
+	itemID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
 
-	item := response.JSON().NotNull().Object().Value("data")
-	id := uint(item.Object().Value("id").NotNull().Raw().(float64))
-
-	responseBody := e.DELETE("/v1/item/" + cast.ToString(id)).
+	responseBody := e.POST("/v1/purchase").
+		WithJSON(order.CreatePurchaseRequest{}).
 		Expect().Status(httptest.StatusForbidden).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.DELETE("/v1/item/"+cast.ToString(id)).
+	response = e.POST("/v1/purchase").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(http.StatusNoContent).Body().Raw()
+		WithJSON(order.CreatePurchaseRequest{
+			SupplierID: supplierID,
+			Lines: []*order.PurchaseLineRequest{
+				{ItemID: itemID, Num: 10, Price: 2.5},
+			},
+		}).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	purchase := response.JSON().Object().Value("data").Object()
+	purchase.Value("state").Equal(order.PurchaseDraft)
+	purchaseID := uint(purchase.Value("id").NotNull().Raw().(float64))
+
+	receive := order.ReceivePurchaseRequest{
+		Lines: []*order.ReceiveLineRequest{
+			{ItemID: itemID, Num: 4},
+		},
+	}
+
+	responseBody = e.POST("/v1/purchase/"+cast.ToString(purchaseID)+"/receive").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(receive).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	responseBody = e.POST("/v1/purchase/"+cast.ToString(purchaseID)+"/order").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent).Body().Raw()
 	t.Log(responseBody)
+
+	response = e.POST("/v1/purchase/"+cast.ToString(purchaseID)+"/receive").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(receive).
+		Expect().Status(httptest.StatusNoContent)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("state").Equal(order.PurchasePartial)
+
+	receive.Lines[0].Num = 7
+	responseBody = e.POST("/v1/purchase/"+cast.ToString(purchaseID)+"/receive").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(receive).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
+	t.Log(responseBody)
+
+	receive.Lines[0].Num = 6
+	response = e.POST("/v1/purchase/"+cast.ToString(purchaseID)+"/receive").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(receive).
+		Expect().Status(httptest.StatusNoContent)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("state").Equal(order.PurchaseReceived)
+
+	item := e.GET("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+	item.Value("count").Equal(10)
 }
 
-func TestGetItemByNameRouter(t *testing.T) {
+func TestStocktakeRouter(t *testing.T) {
 	app := newApp()
 	e := httptest.New(t, app)
 	superAdminToken := getSuperAdminToken()
 	randomNumToString := cast.ToString(rand.Intn(10000))
 
-	itemTest := order.CreateItemRequest{
-		Name:        "test_item" + randomNumToString,
-		Discription: "test_item",
-	}
-
 	response := e.POST("/v1/item").
-		WithJSON(itemTest).
+		WithJSON(order.CreateItemRequest{
+			Name:        "test_item" + randomNumToString,
+			Description: "test_item",
+		}).
 		WithHeader("Authorization", "Bearer "+superAdminToken).
 		Expect().Status(http.StatusCreated)
 	t.Log(response.Body().Raw())
+	itemID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
 
-	item := response.JSON().NotNull().Object().Value("data")
-	id := item.Object().Value("name").NotNull().Raw().(string)
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{
+			ItemID: itemID,
+			Num:    10,
+			Price:  20,
+		}).Expect().Status(http.StatusNoContent)
 
-	responseBody := e.GET("/v1/item/name/" + id).
+	responseBody := e.POST("/v1/stocktake").
+		WithJSON(order.CreateStocktakeRequest{Items: []uint{itemID}}).
 		Expect().Status(httptest.StatusForbidden).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.GET("/v1/item/name/"+id).
+	response = e.POST("/v1/stocktake").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(http.StatusOK).Body().Raw()
+		WithJSON(order.CreateStocktakeRequest{Items: []uint{itemID}}).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	stocktakeID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	response.JSON().Object().Value("data").Object().Value("lines").Array().First().Object().Value("expected").Equal(10)
+
+	responseBody = e.POST("/v1/stocktake/"+cast.ToString(stocktakeID)+"/submit").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusInternalServerError).Body().Raw()
 	t.Log(responseBody)
+
+	e.PUT("/v1/stocktake/"+cast.ToString(stocktakeID)+"/count").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.CountStocktakeRequest{
+			Lines: []*order.CountLineRequest{{ItemID: itemID, Counted: 8}},
+		}).Expect().Status(httptest.StatusNoContent)
+
+	e.POST("/v1/stocktake/"+cast.ToString(stocktakeID)+"/submit").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent)
+
+	e.POST("/v1/stocktake/"+cast.ToString(stocktakeID)+"/approve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(httptest.StatusNoContent)
+
+	stocktake := e.GET("/v1/stocktake/"+cast.ToString(stocktakeID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+	stocktake.Value("state").Equal(order.StocktakeApproved)
+	line := stocktake.Value("lines").Array().First().Object()
+	line.Value("counted").Equal(8)
+	line.Value("variance").Equal(-2)
+
+	item := e.GET("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+	item.Value("count").Equal(8)
 }
 
-func TestGetItemByNameFuzzyRouter(t *testing.T) {
+func TestApprovalRouter(t *testing.T) {
 	app := newApp()
 	e := httptest.New(t, app)
 	superAdminToken := getSuperAdminToken()
 	randomNumToString := cast.ToString(rand.Intn(10000))
+	order.Module.ModuleConfig.Set("approval.rules", []map[string]any{
+		{"name": "expensive-consume", "kind": order.ApprovalConsume, "amount": 100},
+		{"name": "cancel-assigned", "kind": order.ApprovalCancel, "status": order.StatusAssigned},
+	})
+	defer order.Module.ModuleConfig.Set("approval.rules", []any{})
 
-	itemTest := order.CreateItemRequest{
-		Name:        "test_item" + randomNumToString,
-		Discription: "test_item",
+	repairer := generateRandomUsers("approvalUser", 1)[0]
+	response := e.POST("/v1/user").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(user.CreateUserRequest{
+			RegisterUserRequest: repairer,
+			RoleName:            "maintainer",
+		}).Expect().Status(httptest.StatusCreated)
+	repairerID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	repairerToken := e.POST("/v1/login").WithJSON(user.LoginRequest{
+		Account:  repairer.Name,
+		Password: repairer.Password,
+	}).Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+
+	tags := getTestTags()
+	for _, tag := range tags {
+		e.POST("/v1/tag").
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			WithJSON(tag).
+			Expect().Status(httptest.StatusCreated)
 	}
+	response = e.POST("/v1/order").WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(initOrder("TestApproval "+randomNumToString, "Test", "Earth", "Admin", 5)).
+		Expect().Status(httptest.StatusCreated)
+	orderID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	e.POST("/v1/order/"+cast.ToString(orderID)+"/assign").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithQuery("repairer", repairerID).
+		Expect().Status(httptest.StatusNoContent)
 
-	response := e.POST("/v1/item").
-		WithJSON(itemTest).
+	response = e.POST("/v1/item").
+		WithJSON(order.CreateItemRequest{
+			Name:        "test_item" + randomNumToString,
+			Description: "test_item",
+		}).
 		WithHeader("Authorization", "Bearer "+superAdminToken).
 		Expect().Status(http.StatusCreated)
+	itemID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	e.POST("/v1/item/"+cast.ToString(itemID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.AddItemRequest{ItemID: itemID, Num: 10, Price: 100}).
+		Expect().Status(http.StatusNoContent)
+
+	consume := func(num, price float64) *httptest.Request {
+		return e.POST("/v1/order/"+cast.ToString(orderID)+"/consume").
+			WithHeader("Authorization", "Bearer "+repairerToken).
+			WithJSON(order.ConsumeItemRequest{ItemID: itemID, OrderID: orderID, Num: num, Price: price})
+	}
+	checkItemCount := func(count float64) {
+		e.GET("/v1/item/"+cast.ToString(itemID)).
+			WithHeader("Authorization", "Bearer "+superAdminToken).
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Object().Value("count").Equal(count)
+	}
+
+	consume(1, 50).Expect().Status(httptest.StatusNoContent)
+	checkItemCount(9)
+
+	response = consume(2, 200).Expect().Status(httptest.StatusCreated)
 	t.Log(response.Body().Raw())
+	approvalID := uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	checkItemCount(9)
 
-	item := response.JSON().NotNull().Object().Value("data")
-	id := item.Object().Value("name").NotNull().Raw().(string)
+	response = e.GET("/v1/approval/user").
+		WithHeader("Authorization", "Bearer "+repairerToken).
+		Expect().Status(http.StatusOK)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("total").Equal(1)
 
-	responseBody := e.GET("/v1/item/name/" + id + "/fuzzy").
+	responseBody := e.POST("/v1/approval/"+cast.ToString(approvalID)+"/approve").
+		WithHeader("Authorization", "Bearer "+repairerToken).
+		WithJSON(order.DecideApprovalRequest{}).
 		Expect().Status(httptest.StatusForbidden).Body().Raw()
 	t.Log(responseBody)
 
-	responseBody = e.GET("/v1/item/name/"+id+"/fuzzy").
+	response = e.POST("/v1/approval/"+cast.ToString(approvalID)+"/approve").
 		WithHeader("Authorization", "Bearer "+superAdminToken).
-		Expect().Status(http.StatusOK).Body().Raw()
-	t.Log(responseBody)
+		WithJSON(order.DecideApprovalRequest{Comment: "ok"}).
+		Expect().Status(httptest.StatusNoContent)
+	t.Log(response.Body().Raw())
+	response.JSON().Object().Value("data").Object().Value("state").Equal(order.ApprovalApproved)
+	checkItemCount(7)
+
+	e.POST("/v1/approval/"+cast.ToString(approvalID)+"/approve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.DecideApprovalRequest{}).
+		Expect().Status(httptest.StatusInternalServerError)
+
+	response = consume(1, 300).Expect().Status(httptest.StatusCreated)
+	approvalID = uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	e.POST("/v1/approval/"+cast.ToString(approvalID)+"/deny").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.DecideApprovalRequest{Comment: "too expensive"}).
+		Expect().Status(httptest.StatusNoContent)
+	checkItemCount(7)
+
+	response = e.POST("/v1/order/"+cast.ToString(orderID)+"/cancel").
+		WithHeader("Authorization", "Bearer "+repairerToken).
+		Expect().Status(httptest.StatusCreated)
+	t.Log(response.Body().Raw())
+	approvalID = uint(response.JSON().Object().Value("data").Object().Value("id").NotNull().Raw().(float64))
+	e.POST("/v1/order/"+cast.ToString(orderID)+"/cancel").
+		WithHeader("Authorization", "Bearer "+repairerToken).
+		Expect().Status(httptest.StatusInternalServerError)
+
+	e.POST("/v1/approval/"+cast.ToString(approvalID)+"/approve").
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		WithJSON(order.DecideApprovalRequest{}).
+		Expect().Status(httptest.StatusNoContent)
+	e.GET("/v1/order/"+cast.ToString(orderID)).
+		WithHeader("Authorization", "Bearer "+superAdminToken).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Object().Value("status").Equal(order.StatusCanceled)
 }
 
 // Test Comment Router
@@ -1889,6 +4369,18 @@ func getSuperAdminAuthInfo() *model.AuthInfo {
 	}
 }
 
+// addAfterPhoto uploads an image and attaches it to the order as the "after" photo required for completion.
+func addAfterPhoto(e *httpexpect.Expect, token string, id uint) {
+	photo := &bytes.Buffer{}
+	png.Encode(photo, image.NewGray(image.Rect(0, 0, 16, 16)))
+	imageID := e.POST("/v1/image").WithHeader("Authorization", "Bearer "+token).
+		WithMultipart().WithFileBytes("image", "after.png", photo.Bytes()).
+		Expect().Status(httptest.StatusOK).JSON().Object().Value("data").String().Raw()
+	e.POST("/v1/order/"+cast.ToString(id)+"/evidence").WithHeader("Authorization", "Bearer "+token).
+		WithJSON(order.CreateEvidenceRequest{Kind: order.EvidenceAfter, Image: imageID}).
+		Expect().Status(httptest.StatusCreated)
+}
+
 func getTestTags() []order.CreateTagRequest {
 	return []order.CreateTagRequest{
 		{
@@ -0,0 +1,17 @@
+package imagehost
+
+import (
+	"mime/multipart"
+
+	"github.com/xaxys/maintainman/core/model"
+)
+
+// UploadImage saves the uploaded image file. The image ID is returned as data on success.
+func UploadImage(file multipart.File, auth *model.AuthInfo) *model.ApiJson {
+	return uploadImageService(file, auth)
+}
+
+// ExistImage reports whether the image with the given ID exists.
+func ExistImage(id string) bool {
+	return existImage(id, false)
+}
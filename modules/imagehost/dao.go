@@ -90,6 +90,11 @@ func existImage(id string, cached bool) bool {
 	return stExistImage(store, id)
 }
 
+func listImages(cached bool) ([]string, error) {
+	store := util.Tenary(cached, imageCacheStorage, imageStorage)
+	return stListImages(store)
+}
+
 func loadImage(id string, cached bool) (img image.Image, data []byte, format string, err error) {
 	store := util.Tenary(cached, imageCacheStorage, imageStorage)
 	return stLoadImage(store, id)
@@ -171,6 +176,10 @@ func stSaveImage(store storage.IStorage, id, format string, img image.Image) ([]
 	return data, stSaveImageBytes(store, id, imgType, data)
 }
 
+func stListImages(store storage.IStorage) ([]string, error) {
+	return store.List()
+}
+
 func stDeleteImage(store storage.IStorage, id string) error {
 	return store.Delete(id)
 }
@@ -14,7 +14,9 @@ var Module = module.Module{
 	ModuleEnv: map[string]any{
 		"cache.evict": onEvict,
 	},
-	ModuleExport: map[string]any{},
+	ModuleExport: map[string]any{
+		"privacy.export": module.PrivacyExporter(exportPrivacyData),
+	},
 	ModulePerm: map[string]string{
 		"image.upload": "上传图片",
 		"image.view":   "查看图片",
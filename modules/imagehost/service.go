@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/ioutil"
 	"mime/multipart"
+	"sort"
 
 	"github.com/xaxys/maintainman/core/model"
 	"github.com/xaxys/maintainman/core/rbac"
@@ -27,7 +28,7 @@ func getImageService(id, param string, auth *model.AuthInfo) *imageResponse {
 	// parse param to transformation
 	trans, ok := getTransformation(param)
 	if !ok {
-		if err := rbac.CheckPermission(auth.Role, "image.custom"); err != nil {
+		if err := rbac.CheckAuthPermission(auth, "image.custom"); err != nil {
 			return &imageResponse{ApiRes: model.ErrorNoPermissions(err)}
 		}
 		transParam, err := parseParameters(param)
@@ -173,3 +174,23 @@ func parseUUID(str string) uint {
 	copy(bigint24[4:], uuidv1[12:])
 	return uint(binary.BigEndian.Uint64(bigint24[:]))
 }
+
+// exportPrivacyData returns the IDs of the images uploaded by the user, whose IDs carry the
+// uploader, which is exported as "privacy.export" to be put into the user's data archive.
+func exportPrivacyData(userID uint) (map[string]any, error) {
+	ids, err := listImages(false)
+	if err != nil {
+		return nil, err
+	}
+	images := []string{}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			continue
+		}
+		if parseUUID(id) == userID {
+			images = append(images, id)
+		}
+	}
+	sort.Strings(images)
+	return map[string]any{"images": images}, nil
+}
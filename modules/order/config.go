@@ -6,11 +6,28 @@ var orderConfig = viper.New()
 
 func init() {
 	orderConfig.SetDefault("item_can_negative", true)
+	orderConfig.SetDefault("item.reorder.at", "08:00")
+	orderConfig.SetDefault("item.reorder.days", 30)
+	orderConfig.SetDefault("item.reorder.forecast", false)
+	orderConfig.SetDefault("item.forecast.method", "average")
+	orderConfig.SetDefault("item.forecast.periods", 6)
+	orderConfig.SetDefault("item.forecast.alpha", 0.5)
+	orderConfig.SetDefault("item.costing", "average")
 
 	orderConfig.SetDefault("appraise.timeout", "72h")
 	orderConfig.SetDefault("appraise.purge", "1m")
 	orderConfig.SetDefault("appraise.default", 5)
 
+	orderConfig.SetDefault("completion.photo", 1)
+	orderConfig.SetDefault("completion.rules", []any{})
+
+	orderConfig.SetDefault("escalation.check", "1m")
+	orderConfig.SetDefault("escalation.rules", []any{})
+
+	orderConfig.SetDefault("approval.rules", []any{})
+
+	orderConfig.SetDefault("tag.auto.enable", true)
+
 	orderConfig.SetDefault("notify.wechat.status.tmpl", "订阅消息模板id")
 	orderConfig.SetDefault("notify.wechat.status.order", "模板中 订单编号 字段名")
 	orderConfig.SetDefault("notify.wechat.status.title", "模板中 订单标题 字段名")
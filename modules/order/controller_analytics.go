@@ -0,0 +1,95 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getUsage godoc
+// @Summary      获取物品消耗统计
+// @Description  按周或按月统计各物品的消耗数量与消耗成本 不含调拨与盘点调整
+// @Description  每个物品返回统计区间内每个周期的消耗 第一个周期从开始时间算起
+// @Tags         item
+// @Produce      json
+// @Param        item_id  query     uint                           false  "物品ID 0:全部"
+// @Param        period   query     string                         false  "统计周期 week:周 month:月 默认为月"
+// @Param        start    query     int64                          false  "开始时间 unix timestamp in seconds (UTC) 默认为12个周期前"
+// @Param        end      query     int64                          false  "结束时间 unix timestamp in seconds (UTC) 默认为当前时间"
+// @Success      200      {object}  model.ApiJson{data=UsageJson}
+// @Failure      400      {object}  model.ApiJson{data=[]string}
+// @Failure      401      {object}  model.ApiJson{data=[]string}
+// @Failure      403      {object}  model.ApiJson{data=[]string}
+// @Failure      404      {object}  model.ApiJson{data=[]string}
+// @Failure      422      {object}  model.ApiJson{data=[]string}
+// @Failure      500      {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/usage [get]
+func getUsage(ctx iris.Context) {
+	req := &UsageRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getUsageService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getTopUsage godoc
+// @Summary      获取消耗最多的物品
+// @Description  获取统计区间内消耗数量最多的物品 可只统计带有某标签或指派给某分组的订单
+// @Tags         item
+// @Produce      json
+// @Param        tag_id       query     uint                                false  "标签ID 0:不限"
+// @Param        division_id  query     uint                                false  "分组ID 0:不限"
+// @Param        start        query     int64                               false  "开始时间 unix timestamp in seconds (UTC) 默认为0"
+// @Param        end          query     int64                               false  "结束时间 unix timestamp in seconds (UTC) 默认为当前时间"
+// @Param        limit        query     uint                                false  "返回的物品数量 默认为10 最多100"
+// @Success      200          {object}  model.ApiJson{data=[]TopUsageJson}
+// @Failure      400          {object}  model.ApiJson{data=[]string}
+// @Failure      401          {object}  model.ApiJson{data=[]string}
+// @Failure      403          {object}  model.ApiJson{data=[]string}
+// @Failure      404          {object}  model.ApiJson{data=[]string}
+// @Failure      422          {object}  model.ApiJson{data=[]string}
+// @Failure      500          {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/usage/top [get]
+func getTopUsage(ctx iris.Context) {
+	req := &TopUsageRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getTopUsageService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getForecast godoc
+// @Summary      预测物品需求
+// @Description  根据过去若干个完整周期的消耗 以移动平均或指数平滑预测下一周期的需求量
+// @Tags         item
+// @Produce      json
+// @Param        id       path      uint                              true  "物品ID"
+// @Param        period   query     string                            false  "预测周期 week:周 month:月 默认为月"
+// @Param        periods  query     uint                              false  "参与预测的历史周期数 默认为配置值"
+// @Param        method   query     string                            false  "预测方法 average:移动平均 smoothing:指数平滑 默认为配置值"
+// @Success      200      {object}  model.ApiJson{data=ForecastJson}
+// @Failure      400      {object}  model.ApiJson{data=[]string}
+// @Failure      401      {object}  model.ApiJson{data=[]string}
+// @Failure      403      {object}  model.ApiJson{data=[]string}
+// @Failure      404      {object}  model.ApiJson{data=[]string}
+// @Failure      422      {object}  model.ApiJson{data=[]string}
+// @Failure      500      {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/{id}/forecast [get]
+func getForecast(ctx iris.Context) {
+	req := &ForecastRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getForecastService(id, req, auth)
+	ctx.Values().Set("response", response)
+}
@@ -0,0 +1,144 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getApprovalByID godoc
+// @Summary      获取审批申请
+// @Description  通过ID获取审批申请 只能获取自己的申请 有查看所有审批权限时可获取所有申请
+// @Tags         approval
+// @Produce      json
+// @Param        id   path      uint                              true  "审批ID"
+// @Success      200  {object}  model.ApiJson{data=ApprovalJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/approval/{id} [get]
+func getApprovalByID(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getApprovalByIDService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getUserApprovals godoc
+// @Summary      获取我的审批申请
+// @Description  获取当前用户提交的审批申请 分页 可按照 类型 状态 过滤
+// @Tags         approval
+// @Produce      json
+// @Param        kind      query     string                                                  false  "类型 consume:消耗零件 cancel:取消订单"
+// @Param        state     query     uint                                                    false  "状态 1:待审批 2:已通过 3:已驳回 4:执行失败"
+// @Param        order_by  query     string                                                  false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint                                                    false  "偏移量 (默认为0)"
+// @Param        limit     query     uint                                                    false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]ApprovalJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/approval/user [get]
+func getUserApprovals(ctx iris.Context) {
+	req := &AllApprovalRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getUserApprovalsService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getAllApprovals godoc
+// @Summary      获取所有审批申请
+// @Description  获取所有审批申请 分页 可按照 类型 状态 申请人 过滤
+// @Tags         approval
+// @Produce      json
+// @Param        kind          query     string                                                  false  "类型 consume:消耗零件 cancel:取消订单"
+// @Param        state         query     uint                                                    false  "状态 1:待审批 2:已通过 3:已驳回 4:执行失败"
+// @Param        requester_id  query     uint                                                    false  "申请人ID"
+// @Param        order_by      query     string                                                  false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset        query     uint                                                    false  "偏移量 (默认为0)"
+// @Param        limit         query     uint                                                    false  "每页数据量 (默认为50)"
+// @Success      200           {object}  model.ApiJson{data=model.Page{entries=[]ApprovalJson}}
+// @Failure      400           {object}  model.ApiJson{data=[]string}
+// @Failure      401           {object}  model.ApiJson{data=[]string}
+// @Failure      403           {object}  model.ApiJson{data=[]string}
+// @Failure      404           {object}  model.ApiJson{data=[]string}
+// @Failure      422           {object}  model.ApiJson{data=[]string}
+// @Failure      500           {object}  model.ApiJson{data=[]string}
+// @Router       /v1/approval/all [get]
+func getAllApprovals(ctx iris.Context) {
+	req := &AllApprovalRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getAllApprovalsService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// approveApproval godoc
+// @Summary      通过审批申请
+// @Description  通过待审批的申请 并以申请人身份执行原操作 执行失败时申请变为执行失败状态
+// @Tags         approval
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                              true  "审批ID"
+// @Param        body  body      DecideApprovalRequest             true  "审批意见"
+// @Success      204   {object}  model.ApiJson{data=ApprovalJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/approval/{id}/approve [post]
+func approveApproval(ctx iris.Context) {
+	aul := &DecideApprovalRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := approveApprovalService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// denyApproval godoc
+// @Summary      驳回审批申请
+// @Description  驳回待审批的申请 原操作不会执行
+// @Tags         approval
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                              true  "审批ID"
+// @Param        body  body      DecideApprovalRequest             true  "审批意见"
+// @Success      204   {object}  model.ApiJson{data=ApprovalJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/approval/{id}/deny [post]
+func denyApproval(ctx iris.Context) {
+	aul := &DecideApprovalRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := denyApprovalService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
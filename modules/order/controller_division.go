@@ -0,0 +1,114 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getDivisionOrders godoc
+// @Summary      获取当前维修工所在分组的待认领订单
+// @Description  获取指派给当前维修工所在分组 (及其上级分组) 且尚未被认领的订单 分页 默认逆序
+// @Tags         order
+// @Produce      json
+// @Param        tags        query     []string                                             false  "若干 Tag 的 ID"
+// @Param        disjunctve  query     bool                                                 false  "false: 查询包含所有Tag的订单, true: 查询包含任一Tag的订单"
+// @Param        order_by    query     string                                               false  "排序字段 (默认为ID逆序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset      query     uint                                                 false  "偏移量 (默认为0)"
+// @Param        limit       query     uint                                                 false  "每页数据量 (默认为50)"
+// @Success      200         {object}  model.ApiJson{data=model.Page{entries=[]OrderJson}}  "返回结果 带Tag"
+// @Failure      400         {object}  model.ApiJson{data=[]string}
+// @Failure      401         {object}  model.ApiJson{data=[]string}
+// @Failure      403         {object}  model.ApiJson{data=[]string}
+// @Failure      404         {object}  model.ApiJson{data=[]string}
+// @Failure      422         {object}  model.ApiJson{data=[]string}
+// @Failure      500         {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/division [get]
+func getDivisionOrders(ctx iris.Context) {
+	req := &DivisionOrderRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getOrderByUserDivisionService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// forceGetDivisionOrders godoc
+// @Summary      获取某分组的待认领订单 (管理员)
+// @Description  通过分组ID获取指派给该分组且尚未被认领的订单 分页 默认逆序
+// @Tags         order
+// @Produce      json
+// @Param        id          path      uint                                                 true   "分组ID"
+// @Param        tags        query     []string                                             false  "若干 Tag 的 ID"
+// @Param        disjunctve  query     bool                                                 false  "false: 查询包含所有Tag的订单, true: 查询包含任一Tag的订单"
+// @Param        order_by    query     string                                               false  "排序字段 (默认为ID逆序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset      query     uint                                                 false  "偏移量 (默认为0)"
+// @Param        limit       query     uint                                                 false  "每页数据量 (默认为50)"
+// @Success      200         {object}  model.ApiJson{data=model.Page{entries=[]OrderJson}}  "返回结果 带Tag"
+// @Failure      400         {object}  model.ApiJson{data=[]string}
+// @Failure      401         {object}  model.ApiJson{data=[]string}
+// @Failure      403         {object}  model.ApiJson{data=[]string}
+// @Failure      404         {object}  model.ApiJson{data=[]string}
+// @Failure      422         {object}  model.ApiJson{data=[]string}
+// @Failure      500         {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/division/{id} [get]
+func forceGetDivisionOrders(ctx iris.Context) {
+	req := &DivisionOrderRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getOrderByDivisionService(id, req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// assignOrderToDivision godoc
+// @Summary      指派订单给分组
+// @Description  指派订单给分组 订单仍为待处理状态 由该分组 (及其下级分组) 的维修工认领
+// @Tags         order
+// @Accept       json
+// @Produce      json
+// @Param        id        path      uint  true  "订单ID"
+// @Param        division  query     uint  true  "分组ID"
+// @Success      204       {object}  model.ApiJson{data=[]string}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/assign/division [post]
+func assignOrderToDivision(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	division := util.ToUint(ctx.URLParamIntDefault("division", 0))
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := assignOrderToDivisionService(id, division, auth)
+	ctx.Values().Set("response", response)
+}
+
+// claimOrder godoc
+// @Summary      认领订单
+// @Description  认领指派给本人所在分组 (及其上级分组) 的订单 从 待处理 到 已接单
+// @Tags         order
+// @Accept       json
+// @Produce      json
+// @Param        id   path      uint  true  "订单ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/claim [post]
+func claimOrder(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := claimOrderService(id, auth)
+	ctx.Values().Set("response", response)
+}
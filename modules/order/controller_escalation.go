@@ -0,0 +1,29 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getEscalationByOrder godoc
+// @Summary      获取订单的主管升级进度
+// @Description  获取上报订单当前所在的升级规则 层级 主管 及下次升级时间
+// @Tags         order
+// @Produce      json
+// @Param        id   path      uint  true  "订单ID"
+// @Success      200  {object}  model.ApiJson{data=EscalationJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/escalation [get]
+func getEscalationByOrder(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getEscalationByOrderService(id, auth)
+	ctx.Values().Set("response", response)
+}
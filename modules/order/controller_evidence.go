@@ -0,0 +1,86 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getEvidencesByOrder godoc
+// @Summary      获取订单的完工凭证
+// @Description  获取订单的维修前后照片及客户签名
+// @Tags         order
+// @Produce      json
+// @Param        id   path      uint  true  "订单ID"
+// @Success      200  {object}  model.ApiJson{data=[]EvidenceJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/evidence [get]
+func getEvidencesByOrder(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getEvidencesByOrderService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// createEvidence godoc
+// @Summary      添加完工凭证
+// @Description  为订单添加已上传的图片作为凭证 操作者必须是订单当前维修工
+// @Tags         order
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                   true  "订单ID"
+// @Param        body  body      CreateEvidenceRequest  true  "凭证信息"
+// @Success      201   {object}  model.ApiJson{data=EvidenceJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/evidence [post]
+func createEvidence(ctx iris.Context) {
+	aul := &CreateEvidenceRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := createEvidenceService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// uploadSignature godoc
+// @Summary      上传客户签名
+// @Description  上传客户签名图片 并作为订单的完工凭证 操作者必须是订单当前维修工
+// @Tags         order
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id         path      uint                          true  "订单ID"
+// @Param        signature  formData  file                          true  "签名图片"
+// @Success      201        {object}  model.ApiJson{data=EvidenceJson}
+// @Failure      400        {object}  model.ApiJson{data=[]string}
+// @Failure      401        {object}  model.ApiJson{data=[]string}
+// @Failure      403        {object}  model.ApiJson{data=[]string}
+// @Failure      404        {object}  model.ApiJson{data=[]string}
+// @Failure      422        {object}  model.ApiJson{data=[]string}
+// @Failure      500        {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/signature [post]
+func uploadSignature(ctx iris.Context) {
+	file, _, err := ctx.FormFile("signature")
+	if err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	defer file.Close()
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := uploadSignatureService(id, file, auth)
+	ctx.Values().Set("response", response)
+}
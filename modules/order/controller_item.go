@@ -1,7 +1,6 @@
 package order
 
 import (
-	"github.com/xaxys/maintainman/core/controller"
 	"github.com/xaxys/maintainman/core/model"
 	"github.com/xaxys/maintainman/core/util"
 
@@ -50,6 +49,27 @@ func getItemByName(ctx iris.Context) {
 	ctx.Values().Set("response", response)
 }
 
+// getItemByBarcode godoc
+// @Summary      通过条码获取物品信息
+// @Description  通过条码获取物品信息 扫码消耗零件时使用 条码未登记时按库存编码(SKU)查找
+// @Tags         item
+// @Produce      json
+// @Param        code  path      string                            true  "条码或库存编码"
+// @Success      200   {object}  model.ApiJson{data=ItemInfoJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/barcode/{code} [get]
+func getItemByBarcode(ctx iris.Context) {
+	code := ctx.Params().Get("code")
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getItemByBarcodeService(code, auth)
+	ctx.Values().Set("response", response)
+}
+
 // getItemsByFuzzyName godoc
 // @Summary      获取大概是某些名称的物品们的信息
 // @Description  通过名称获取大概是某些名称的物品们的信息
@@ -73,24 +93,29 @@ func getItemsByFuzzyName(ctx iris.Context) {
 
 // getAllItems godoc
 // @Summary      获取所有物品信息
-// @Description  获取所有物品信息 分页
-// @Tags         item
-// @Produce      json
-// @Param        order_by  query     string  false  "排序字段"
-// @Param        offset    query     uint    false  "偏移量"
-// @Param        limit     query     uint    false  "每页数据量"
-// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]ItemJson}}
-// @Failure      400       {object}  model.ApiJson{data=[]string}
-// @Failure      401       {object}  model.ApiJson{data=[]string}
-// @Failure      403       {object}  model.ApiJson{data=[]string}
-// @Failure      404       {object}  model.ApiJson{data=[]string}
-// @Failure      422       {object}  model.ApiJson{data=[]string}
-// @Failure      500       {object}  model.ApiJson{data=[]string}
-// @Router       /v1/item [get]
+// @Description  获取所有物品信息 分页 可按照 分类 过滤
+// @Tags         item
+// @Produce      json
+// @Param        category_id  query     uint                                                false  "分类ID 0:全部"
+// @Param        order_by     query     string                                              false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset       query     uint                                                false  "偏移量 (默认为0)"
+// @Param        limit        query     uint                                                false  "每页数据量 (默认为50)"
+// @Success      200          {object}  model.ApiJson{data=model.Page{entries=[]ItemJson}}
+// @Failure      400          {object}  model.ApiJson{data=[]string}
+// @Failure      401          {object}  model.ApiJson{data=[]string}
+// @Failure      403          {object}  model.ApiJson{data=[]string}
+// @Failure      404          {object}  model.ApiJson{data=[]string}
+// @Failure      422          {object}  model.ApiJson{data=[]string}
+// @Failure      500          {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/all [get]
 func getAllItems(ctx iris.Context) {
-	param := controller.ExtractPageParam(ctx)
+	req := &AllItemRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
 	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
-	response := getAllItemsService(param, auth)
+	response := getAllItemsService(req, auth)
 	ctx.Values().Set("response", response)
 }
 
@@ -120,6 +145,35 @@ func createItem(ctx iris.Context) {
 	ctx.Values().Set("response", response)
 }
 
+// updateItem godoc
+// @Summary      更新物品信息
+// @Description  更新物品的名称 描述 分类 计量单位 库存编码 条码 为空的字段不修改
+// @Description  传入条码时以传入的条码替换原有条码
+// @Tags         item
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                              true  "物品ID"
+// @Param        body  body      UpdateItemRequest                 true  "物品信息"
+// @Success      204   {object}  model.ApiJson{data=ItemInfoJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/{id} [put]
+func updateItem(ctx iris.Context) {
+	aul := &UpdateItemRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := updateItemService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
 // deleteItem godoc
 // @Summary      删除物品
 // @Description  删除物品
@@ -197,3 +251,216 @@ func consumeItem(ctx iris.Context) {
 	response := consumeItemService(aul, auth)
 	ctx.Values().Set("response", response)
 }
+
+// updateItemThreshold godoc
+// @Summary      设置物品库存预警
+// @Description  设置物品的最低库存与最小补货数量 库存低于最低库存时触发预警 最低库存为0时不预警
+// @Tags         item
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                        true  "物品ID"
+// @Param        body  body      UpdateItemThresholdRequest  true  "库存预警信息"
+// @Success      204   {object}  model.ApiJson{data=ItemInfoJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/{id}/threshold [put]
+func updateItemThreshold(ctx iris.Context) {
+	aul := &UpdateItemThresholdRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := updateItemThresholdService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getReorderReport godoc
+// @Summary      获取补货报告
+// @Description  获取库存低于最低库存的物品 及根据近期消耗计算的建议补货数量
+// @Tags         item
+// @Produce      json
+// @Success      200  {object}  model.ApiJson{data=[]ReorderJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/reorder [get]
+func getReorderReport(ctx iris.Context) {
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getReorderReportService(auth)
+	ctx.Values().Set("response", response)
+}
+
+// getValuation godoc
+// @Summary      获取库存估值
+// @Description  获取某一时刻各物品的库存数量与价值 价值按配置的计价方式 (移动加权平均 或 先进先出) 计算
+// @Tags         item
+// @Produce      json
+// @Param        at   query     int64  false  "时间 unix timestamp in seconds (UTC) 默认为当前时间"
+// @Success      200  {object}  model.ApiJson{data=ValuationJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/valuation [get]
+func getValuation(ctx iris.Context) {
+	req := &ValuationRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getValuationService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getConsumptionCost godoc
+// @Summary      获取消耗成本
+// @Description  获取时间段内消耗物品的成本 按物品与订单汇总 成本按配置的计价方式 (移动加权平均 或 先进先出) 计算
+// @Tags         item
+// @Produce      json
+// @Param        start  query     int64  false  "开始时间 unix timestamp in seconds (UTC) 默认为0"
+// @Param        end    query     int64  false  "结束时间 unix timestamp in seconds (UTC) 默认为当前时间"
+// @Success      200    {object}  model.ApiJson{data=CostJson}
+// @Failure      400    {object}  model.ApiJson{data=[]string}
+// @Failure      401    {object}  model.ApiJson{data=[]string}
+// @Failure      403    {object}  model.ApiJson{data=[]string}
+// @Failure      404    {object}  model.ApiJson{data=[]string}
+// @Failure      422    {object}  model.ApiJson{data=[]string}
+// @Failure      500    {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/cost [get]
+func getConsumptionCost(ctx iris.Context) {
+	req := &CostRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getConsumptionCostService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getCategoryByID godoc
+// @Summary      获取物品分类
+// @Description  通过ID获取物品分类
+// @Tags         item
+// @Produce      json
+// @Param        id   path      uint                              true  "分类ID"
+// @Success      200  {object}  model.ApiJson{data=CategoryJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/category/{id} [get]
+func getCategoryByID(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getCategoryByIDService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getAllCategories godoc
+// @Summary      获取所有物品分类
+// @Description  获取所有物品分类
+// @Tags         item
+// @Produce      json
+// @Success      200  {object}  model.ApiJson{data=[]CategoryJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/category/all [get]
+func getAllCategories(ctx iris.Context) {
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getAllCategoriesService(auth)
+	ctx.Values().Set("response", response)
+}
+
+// createCategory godoc
+// @Summary      创建物品分类
+// @Description  创建物品分类
+// @Tags         item
+// @Accept       json
+// @Produce      json
+// @Param        body  body      CreateCategoryRequest             true  "分类信息"
+// @Success      201   {object}  model.ApiJson{data=CategoryJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/category [post]
+func createCategory(ctx iris.Context) {
+	aul := &CreateCategoryRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := createCategoryService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// updateCategory godoc
+// @Summary      更新物品分类
+// @Description  更新物品分类 为空的字段不修改
+// @Tags         item
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                              true  "分类ID"
+// @Param        body  body      UpdateCategoryRequest             true  "分类信息"
+// @Success      204   {object}  model.ApiJson{data=CategoryJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/category/{id} [put]
+func updateCategory(ctx iris.Context) {
+	aul := &UpdateCategoryRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := updateCategoryService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// deleteCategory godoc
+// @Summary      删除物品分类
+// @Description  删除物品分类 该分类下的物品变为未分类
+// @Tags         item
+// @Produce      json
+// @Param        id   path      uint                          true  "分类ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/item/category/{id} [delete]
+func deleteCategory(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := deleteCategoryService(id, auth)
+	ctx.Values().Set("response", response)
+}
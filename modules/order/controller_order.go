@@ -47,6 +47,7 @@ func getUserOrders(ctx iris.Context) {
 // @Param        disjunctve  query     bool                                                 false  "false: 查询包含所有Tag的订单, true: 查询包含任一Tag的订单"
 // @Param        status      query     int                                                  false  "订单状态 0:所有 1:待处理 2:已接单 3:已完成 4:上报中 5:挂单 6:已取消 7:已拒绝 8:已评价"
 // @Param        current     query     bool                                                 true   "是否本人正在维修"
+// @Param        division    query     bool                                                 false  "是否包含指派给本人所在分组 (及其上级分组) 待认领的订单"
 // @Param        order_by    query     string                                               false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
 // @Param        offset      query     uint                                                 false  "偏移量 (默认为0)"
 // @Param        limit       query     uint                                                 false  "每页数据量 (默认为50)"
@@ -79,6 +80,7 @@ func getRepairerOrders(ctx iris.Context) {
 // @Param        disjunctve  query     bool                                                 false  "false: 查询包含所有Tag的订单, true: 查询包含任一Tag的订单"
 // @Param        status      query     int                                                  false  "订单状态 0:所有 1:待处理 2:已接单 3:已完成 4:上报中 5:挂单 6:已取消 7:已拒绝 8:已评价"
 // @Param        current     query     bool                                                 true   "是否本人正在维修"
+// @Param        division    query     bool                                                 false  "是否包含指派给本人所在分组 (及其上级分组) 待认领的订单"
 // @Param        order_by    query     string                                               false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
 // @Param        offset      query     uint                                                 false  "偏移量 (默认为0)"
 // @Param        limit       query     uint                                                 false  "每页数据量 (默认为50)"
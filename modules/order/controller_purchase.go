@@ -0,0 +1,304 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/controller"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getSupplierByID godoc
+// @Summary      获取供应商
+// @Description  通过ID获取供应商
+// @Tags         purchase
+// @Produce      json
+// @Param        id   path      uint                              true  "供应商ID"
+// @Success      200  {object}  model.ApiJson{data=SupplierJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/supplier/{id} [get]
+func getSupplierByID(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getSupplierByIDService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getAllSuppliers godoc
+// @Summary      获取所有供应商
+// @Description  获取所有供应商 分页
+// @Tags         purchase
+// @Produce      json
+// @Param        order_by  query     string                                                  false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint                                                    false  "偏移量 (默认为0)"
+// @Param        limit     query     uint                                                    false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]SupplierJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/supplier/all [get]
+func getAllSuppliers(ctx iris.Context) {
+	param := controller.ExtractPageParam(ctx)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getAllSuppliersService(param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// createSupplier godoc
+// @Summary      创建供应商
+// @Description  创建供应商
+// @Tags         purchase
+// @Accept       json
+// @Produce      json
+// @Param        body  body      CreateSupplierRequest             true  "供应商信息"
+// @Success      201   {object}  model.ApiJson{data=SupplierJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/supplier [post]
+func createSupplier(ctx iris.Context) {
+	aul := &CreateSupplierRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := createSupplierService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// updateSupplier godoc
+// @Summary      更新供应商
+// @Description  更新供应商信息
+// @Tags         purchase
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                              true  "供应商ID"
+// @Param        body  body      UpdateSupplierRequest             true  "供应商信息"
+// @Success      204   {object}  model.ApiJson{data=SupplierJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/supplier/{id} [put]
+func updateSupplier(ctx iris.Context) {
+	aul := &UpdateSupplierRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := updateSupplierService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// deleteSupplier godoc
+// @Summary      删除供应商
+// @Description  删除供应商
+// @Tags         purchase
+// @Produce      json
+// @Param        id   path      uint                          true  "供应商ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/supplier/{id} [delete]
+func deleteSupplier(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := deleteSupplierService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getPurchaseByID godoc
+// @Summary      获取采购单
+// @Description  通过ID获取采购单 含采购明细
+// @Tags         purchase
+// @Produce      json
+// @Param        id   path      uint                                   true  "采购单ID"
+// @Success      200  {object}  model.ApiJson{data=PurchaseOrderJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/purchase/{id} [get]
+func getPurchaseByID(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getPurchaseByIDService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getAllPurchases godoc
+// @Summary      获取所有采购单
+// @Description  获取所有采购单 分页 可按照 供应商 状态 过滤
+// @Description  状态 0:非法 1:草稿 2:已下单 3:部分到货 4:已到货
+// @Tags         purchase
+// @Produce      json
+// @Param        supplier_id  query     uint                                                         false  "供应商ID"
+// @Param        state        query     uint                                                         false  "状态 1:草稿 2:已下单 3:部分到货 4:已到货"
+// @Param        order_by     query     string                                                       false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset       query     uint                                                         false  "偏移量 (默认为0)"
+// @Param        limit        query     uint                                                         false  "每页数据量 (默认为50)"
+// @Success      200          {object}  model.ApiJson{data=model.Page{entries=[]PurchaseOrderJson}}
+// @Failure      400          {object}  model.ApiJson{data=[]string}
+// @Failure      401          {object}  model.ApiJson{data=[]string}
+// @Failure      403          {object}  model.ApiJson{data=[]string}
+// @Failure      404          {object}  model.ApiJson{data=[]string}
+// @Failure      422          {object}  model.ApiJson{data=[]string}
+// @Failure      500          {object}  model.ApiJson{data=[]string}
+// @Router       /v1/purchase/all [get]
+func getAllPurchases(ctx iris.Context) {
+	req := &AllPurchaseRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getAllPurchasesService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// createPurchase godoc
+// @Summary      创建采购单
+// @Description  创建草稿状态的采购单
+// @Tags         purchase
+// @Accept       json
+// @Produce      json
+// @Param        body  body      CreatePurchaseRequest                  true  "采购单信息"
+// @Success      201   {object}  model.ApiJson{data=PurchaseOrderJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/purchase [post]
+func createPurchase(ctx iris.Context) {
+	aul := &CreatePurchaseRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := createPurchaseService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// updatePurchase godoc
+// @Summary      更新采购单
+// @Description  更新草稿状态的采购单 lines 不为空时替换采购明细
+// @Tags         purchase
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                                   true  "采购单ID"
+// @Param        body  body      UpdatePurchaseRequest                  true  "采购单信息"
+// @Success      204   {object}  model.ApiJson{data=PurchaseOrderJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/purchase/{id} [put]
+func updatePurchase(ctx iris.Context) {
+	aul := &UpdatePurchaseRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := updatePurchaseService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// deletePurchase godoc
+// @Summary      删除采购单
+// @Description  删除草稿状态的采购单
+// @Tags         purchase
+// @Produce      json
+// @Param        id   path      uint                          true  "采购单ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/purchase/{id} [delete]
+func deletePurchase(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := deletePurchaseService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// placePurchase godoc
+// @Summary      采购单下单
+// @Description  将草稿状态的采购单变为已下单状态 下单后不能再修改
+// @Tags         purchase
+// @Produce      json
+// @Param        id   path      uint                          true  "采购单ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/purchase/{id}/order [post]
+func placePurchase(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := placePurchaseService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// receivePurchase godoc
+// @Summary      采购单收货
+// @Description  采购单到货入库 自动记录带有供应商与采购单的入库日志 可分多次收货
+// @Description  只能入库到自己负责的仓库 总库需要管理所有仓库的权限
+// @Tags         purchase
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                                   true  "采购单ID"
+// @Param        body  body      ReceivePurchaseRequest                 true  "到货信息"
+// @Success      204   {object}  model.ApiJson{data=PurchaseOrderJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/purchase/{id}/receive [post]
+func receivePurchase(ctx iris.Context) {
+	aul := &ReceivePurchaseRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := receivePurchaseService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
@@ -0,0 +1,86 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getReservationsByOrder godoc
+// @Summary      获取订单预留的物品
+// @Description  获取订单当前预留的物品 (不含已消耗或已归还的)
+// @Tags         item
+// @Produce      json
+// @Param        id   path      uint  true  "订单ID"
+// @Success      200  {object}  model.ApiJson{data=[]ReservationJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/reserve [get]
+func getReservationsByOrder(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getReservationsByOrderService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// reserveItem godoc
+// @Summary      为订单预留物品
+// @Description  为已接单的订单预留物品 减少物品可用数量但不减少实际库存 消耗物品时优先使用预留
+// @Description  订单被释放或取消时自动归还所有预留
+// @Tags         item
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                true  "订单ID"
+// @Param        body  body      ReserveItemRequest  true  "预留物品数量"
+// @Success      204   {object}  model.ApiJson{data=ReservationJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/reserve [post]
+func reserveItem(ctx iris.Context) {
+	aul := &ReserveItemRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	aul.OrderID = ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := reserveItemService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// returnReservation godoc
+// @Summary      归还订单预留的物品
+// @Description  归还订单预留的部分物品 恢复物品可用数量
+// @Tags         item
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                true  "订单ID"
+// @Param        body  body      ReserveItemRequest  true  "归还物品数量"
+// @Success      204   {object}  model.ApiJson{data=ReservationJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/reserve/return [post]
+func returnReservation(ctx iris.Context) {
+	aul := &ReserveItemRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	aul.OrderID = ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := returnReservationService(aul, auth)
+	ctx.Values().Set("response", response)
+}
@@ -0,0 +1,197 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getStocktakeByID godoc
+// @Summary      获取盘点
+// @Description  通过ID获取盘点 含各物品的账面数量 实盘数量 与差异
+// @Tags         stocktake
+// @Produce      json
+// @Param        id   path      uint                               true  "盘点ID"
+// @Success      200  {object}  model.ApiJson{data=StocktakeJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/stocktake/{id} [get]
+func getStocktakeByID(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getStocktakeByIDService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getAllStocktakes godoc
+// @Summary      获取所有盘点
+// @Description  获取所有盘点 分页 可按照 仓库 状态 过滤 不含盘点明细
+// @Description  状态 0:非法 1:盘点中 2:待审核 3:已通过 4:已取消
+// @Tags         stocktake
+// @Produce      json
+// @Param        warehouse_id  query     uint                                                     false  "仓库ID 0:总库"
+// @Param        state         query     uint                                                     false  "状态 1:盘点中 2:待审核 3:已通过 4:已取消"
+// @Param        order_by      query     string                                                   false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset        query     uint                                                     false  "偏移量 (默认为0)"
+// @Param        limit         query     uint                                                     false  "每页数据量 (默认为50)"
+// @Success      200           {object}  model.ApiJson{data=model.Page{entries=[]StocktakeJson}}
+// @Failure      400           {object}  model.ApiJson{data=[]string}
+// @Failure      401           {object}  model.ApiJson{data=[]string}
+// @Failure      403           {object}  model.ApiJson{data=[]string}
+// @Failure      404           {object}  model.ApiJson{data=[]string}
+// @Failure      422           {object}  model.ApiJson{data=[]string}
+// @Failure      500           {object}  model.ApiJson{data=[]string}
+// @Router       /v1/stocktake/all [get]
+func getAllStocktakes(ctx iris.Context) {
+	req := &AllStocktakeRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getAllStocktakesService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// createStocktake godoc
+// @Summary      创建盘点
+// @Description  创建盘点 冻结仓库中各物品的账面数量 只能盘点自己负责的仓库
+// @Tags         stocktake
+// @Accept       json
+// @Produce      json
+// @Param        body  body      CreateStocktakeRequest             true  "盘点信息"
+// @Success      201   {object}  model.ApiJson{data=StocktakeJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/stocktake [post]
+func createStocktake(ctx iris.Context) {
+	aul := &CreateStocktakeRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := createStocktakeService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// countStocktake godoc
+// @Summary      录入盘点数量
+// @Description  录入物品的实盘数量 可多次录入 以最后一次为准
+// @Tags         stocktake
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                               true  "盘点ID"
+// @Param        body  body      CountStocktakeRequest              true  "实盘数量"
+// @Success      204   {object}  model.ApiJson{data=StocktakeJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/stocktake/{id}/count [put]
+func countStocktake(ctx iris.Context) {
+	aul := &CountStocktakeRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := countStocktakeService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// submitStocktake godoc
+// @Summary      提交盘点
+// @Description  所有物品录入实盘数量后 提交盘点等待审核
+// @Tags         stocktake
+// @Produce      json
+// @Param        id   path      uint                          true  "盘点ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/stocktake/{id}/submit [post]
+func submitStocktake(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := submitStocktakeService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// approveStocktake godoc
+// @Summary      审核通过盘点
+// @Description  审核通过盘点 按实盘数量与账面数量的差异写入盘点调整日志
+// @Tags         stocktake
+// @Produce      json
+// @Param        id   path      uint                               true  "盘点ID"
+// @Success      204  {object}  model.ApiJson{data=StocktakeJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/stocktake/{id}/approve [post]
+func approveStocktake(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := approveStocktakeService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// rejectStocktake godoc
+// @Summary      驳回盘点
+// @Description  驳回待审核的盘点 盘点回到盘点中状态
+// @Tags         stocktake
+// @Produce      json
+// @Param        id   path      uint                          true  "盘点ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/stocktake/{id}/reject [post]
+func rejectStocktake(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := rejectStocktakeService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// cancelStocktake godoc
+// @Summary      取消盘点
+// @Description  取消未结束的盘点 不会修改库存
+// @Tags         stocktake
+// @Produce      json
+// @Param        id   path      uint                          true  "盘点ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/stocktake/{id}/cancel [post]
+func cancelStocktake(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := cancelStocktakeService(id, auth)
+	ctx.Values().Set("response", response)
+}
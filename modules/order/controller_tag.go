@@ -68,6 +68,27 @@ func getAllTagsBySort(ctx iris.Context) {
 	ctx.Values().Set("response", response)
 }
 
+// getTagsByParentID godoc
+// @Summary      获取某标签的子标签
+// @Description  通过ID获取某标签的直接子标签, ID为0时获取所有顶级标签
+// @Tags         tag
+// @Produce      json
+// @Param        id   path      uint                           true  "标签ID"
+// @Success      200  {object}  model.ApiJson{data=[]TagJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/{id}/children [get]
+func getTagsByParentID(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getTagsByParentIDService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
 // createTag godoc
 // @Summary      创建标签
 // @Description  创建标签
@@ -94,9 +115,68 @@ func createTag(ctx iris.Context) {
 	ctx.Values().Set("response", response)
 }
 
+// updateTag godoc
+// @Summary      更新标签
+// @Description  通过ID更新标签的分类、名称、等级、同类型数量或父标签
+// @Description  重命名后与已有标签重复时请使用合并
+// @Tags         tag
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                          true  "标签ID"
+// @Param        body  body      UpdateTagRequest              true  "更新标签请求"
+// @Success      204   {object}  model.ApiJson{data=TagJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/{id} [put]
+func updateTag(ctx iris.Context) {
+	aul := &UpdateTagRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := updateTagService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// mergeTag godoc
+// @Summary      合并标签
+// @Description  将某标签合并到目标标签
+// @Description  原标签关联的订单与子标签转移到目标标签, 随后删除原标签
+// @Tags         tag
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                          true  "标签ID"
+// @Param        body  body      MergeTagRequest               true  "合并标签请求"
+// @Success      204   {object}  model.ApiJson{data=TagJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/{id}/merge [post]
+func mergeTag(ctx iris.Context) {
+	aul := &MergeTagRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := mergeTagService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
 // deleteTag godoc
 // @Summary      删除标签
 // @Description  通过ID删除标签
+// @Description  同时移除该标签与订单的关联, 其子标签转移到该标签的父标签下
 // @Tags         tag
 // @Accept       json
 // @Produce      json
@@ -0,0 +1,159 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getTagRuleByID godoc
+// @Summary      获取某自动标签规则
+// @Description  通过ID获取某自动标签规则
+// @Tags         tag
+// @Produce      json
+// @Param        id   path      uint                             true  "规则ID"
+// @Success      200  {object}  model.ApiJson{data=TagRuleJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/rule/{id} [get]
+func getTagRuleByID(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getTagRuleByIDService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getAllTagRules godoc
+// @Summary      获取自动标签规则列表
+// @Description  获取自动标签规则列表, 可按标签筛选
+// @Tags         tag
+// @Produce      json
+// @Param        tag_id    query     uint                                                   false  "标签ID"
+// @Param        order_by  query     string                                                 false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint                                                   false  "偏移量 (默认为0)"
+// @Param        limit     query     uint                                                   false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]TagRuleJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/rule/all [get]
+func getAllTagRules(ctx iris.Context) {
+	req := &AllTagRuleRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getAllTagRulesService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// createTagRule godoc
+// @Summary      创建自动标签规则
+// @Description  创建自动标签规则, 订单标题与内容分词后命中任一关键词即匹配该规则
+// @Tags         tag
+// @Accept       json
+// @Produce      json
+// @Param        body  body      CreateTagRuleRequest             true  "创建自动标签规则请求"
+// @Success      201   {object}  model.ApiJson{data=TagRuleJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/rule [post]
+func createTagRule(ctx iris.Context) {
+	aul := &CreateTagRuleRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := createTagRuleService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// updateTagRule godoc
+// @Summary      更新自动标签规则
+// @Description  通过ID更新自动标签规则
+// @Tags         tag
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                             true  "规则ID"
+// @Param        body  body      UpdateTagRuleRequest             true  "更新自动标签规则请求"
+// @Success      204   {object}  model.ApiJson{data=TagRuleJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/rule/{id} [put]
+func updateTagRule(ctx iris.Context) {
+	aul := &UpdateTagRuleRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := updateTagRuleService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// deleteTagRule godoc
+// @Summary      删除自动标签规则
+// @Description  通过ID删除自动标签规则
+// @Tags         tag
+// @Produce      json
+// @Param        id   path      uint                          true  "规则ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/rule/{id} [delete]
+func deleteTagRule(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := deleteTagRuleService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// suggestTags godoc
+// @Summary      获取建议标签
+// @Description  根据标题与内容获取建议添加的标签
+// @Description  仅返回当前用户有权限添加且不超过同类型数量限制的标签
+// @Tags         tag
+// @Accept       json
+// @Produce      json
+// @Param        body  body      SuggestTagRequest              true  "获取建议标签请求"
+// @Success      200   {object}  model.ApiJson{data=[]TagJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/tag/suggest [post]
+func suggestTags(ctx iris.Context) {
+	aul := &SuggestTagRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := suggestTagsService(aul, auth)
+	ctx.Values().Set("response", response)
+}
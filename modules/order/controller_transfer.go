@@ -0,0 +1,104 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/controller"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getPendingTransfers godoc
+// @Summary      获取待我确认的转单请求
+// @Description  获取转给当前维修工且待确认的转单请求 分页 默认逆序
+// @Tags         order
+// @Produce      json
+// @Param        order_by  query     string  false  "排序字段 (默认为ID逆序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint    false  "偏移量 (默认为0)"
+// @Param        limit     query     uint    false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]TransferJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/transfer [get]
+func getPendingTransfers(ctx iris.Context) {
+	param := controller.ExtractPageParam(ctx)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getPendingTransfersService(param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// transferOrder godoc
+// @Summary      转单
+// @Description  将已接单的订单转给另一位维修工 操作者必须是订单当前维修工 或 拥有分配订单权限
+// @Description  consent 为 false 时直接转单 为 true 时需接收人确认后才转单
+// @Tags         order
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                  true  "订单ID"
+// @Param        body  body      TransferOrderRequest  true  "转单信息"
+// @Success      201   {object}  model.ApiJson{data=TransferJson}  "需接收人确认 返回转单请求"
+// @Success      204   {object}  model.ApiJson{data=[]string}      "直接转单成功"
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/transfer [post]
+func transferOrder(ctx iris.Context) {
+	aul := &TransferOrderRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := transferOrderService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// acceptTransfer godoc
+// @Summary      接受转单
+// @Description  接受转给当前维修工的转单请求 订单的维修工变更为当前维修工
+// @Tags         order
+// @Produce      json
+// @Param        id   path      uint  true  "订单ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/transfer/accept [post]
+func acceptTransfer(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := acceptTransferService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// rejectTransfer godoc
+// @Summary      拒绝转单
+// @Description  拒绝转给当前维修工的转单请求 订单维修工不变
+// @Tags         order
+// @Produce      json
+// @Param        id   path      uint  true  "订单ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/transfer/reject [post]
+func rejectTransfer(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := rejectTransferService(id, auth)
+	ctx.Values().Set("response", response)
+}
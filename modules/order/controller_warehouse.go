@@ -0,0 +1,203 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/controller"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getWarehouseByID godoc
+// @Summary      获取仓库信息
+// @Description  通过ID获取仓库信息
+// @Tags         warehouse
+// @Produce      json
+// @Param        id   path      uint  true  "仓库ID"
+// @Success      200  {object}  model.ApiJson{data=WarehouseJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/warehouse/{id} [get]
+func getWarehouseByID(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getWarehouseByIDService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getAllWarehouses godoc
+// @Summary      获取所有仓库
+// @Description  获取所有仓库 分页 不含总库
+// @Tags         warehouse
+// @Produce      json
+// @Param        order_by  query     string  false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint    false  "偏移量 (默认为0)"
+// @Param        limit     query     uint    false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]WarehouseJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/warehouse/all [get]
+func getAllWarehouses(ctx iris.Context) {
+	param := controller.ExtractPageParam(ctx)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getAllWarehousesService(param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// createWarehouse godoc
+// @Summary      创建仓库
+// @Description  创建仓库 并指定仓库管理员
+// @Tags         warehouse
+// @Accept       json
+// @Produce      json
+// @Param        body  body      CreateWarehouseRequest  true  "仓库信息"
+// @Success      201   {object}  model.ApiJson{data=WarehouseJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/warehouse [post]
+func createWarehouse(ctx iris.Context) {
+	aul := &CreateWarehouseRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := createWarehouseService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// updateWarehouse godoc
+// @Summary      更新仓库
+// @Description  更新仓库信息 keepers 不为空时替换仓库管理员
+// @Tags         warehouse
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint                    true  "仓库ID"
+// @Param        body  body      UpdateWarehouseRequest  true  "仓库信息"
+// @Success      204   {object}  model.ApiJson{data=WarehouseJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/warehouse/{id} [put]
+func updateWarehouse(ctx iris.Context) {
+	aul := &UpdateWarehouseRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := updateWarehouseService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// deleteWarehouse godoc
+// @Summary      删除仓库
+// @Description  删除没有库存的仓库
+// @Tags         warehouse
+// @Produce      json
+// @Param        id   path      uint  true  "仓库ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/warehouse/{id} [delete]
+func deleteWarehouse(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := deleteWarehouseService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getWarehouseStocks godoc
+// @Summary      获取仓库库存
+// @Description  获取仓库中各物品的库存 ID为0时获取总库库存
+// @Tags         warehouse
+// @Produce      json
+// @Param        id   path      uint  true  "仓库ID 0:总库"
+// @Success      200  {object}  model.ApiJson{data=[]WarehouseStockJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/warehouse/{id}/stock [get]
+func getWarehouseStocks(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getWarehouseStocksService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// stockWarehouse godoc
+// @Summary      仓库进货
+// @Description  添加物品到仓库 只能管理自己负责的仓库 总库需要管理所有仓库的权限
+// @Tags         warehouse
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint            true  "仓库ID 0:总库"
+// @Param        body  body      AddItemRequest  true  "物品数量"
+// @Success      204   {object}  model.ApiJson{data=ItemJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/warehouse/{id}/stock [post]
+func stockWarehouse(ctx iris.Context) {
+	aul := &AddItemRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	aul.WarehouseID = ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := stockWarehouseService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// transferStock godoc
+// @Summary      仓库调拨
+// @Description  将物品从一个仓库调拨到另一个仓库 只能从自己负责的仓库调出 总库需要管理所有仓库的权限
+// @Tags         warehouse
+// @Accept       json
+// @Produce      json
+// @Param        body  body      StockTransferRequest  true  "调拨信息"
+// @Success      201   {object}  model.ApiJson{data=StockTransferJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/warehouse/transfer [post]
+func transferStock(ctx iris.Context) {
+	aul := &StockTransferRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := transferStockService(aul, auth)
+	ctx.Values().Set("response", response)
+}
@@ -0,0 +1,140 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/controller"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getRepairerWorkSessions godoc
+// @Summary      获取当前维修工的工作记录
+// @Description  获取当前维修工的工作记录 分页 默认逆序
+// @Tags         order
+// @Produce      json
+// @Param        order_by  query     string  false  "排序字段 (默认为ID逆序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint    false  "偏移量 (默认为0)"
+// @Param        limit     query     uint    false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]WorkSessionJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/work [get]
+func getRepairerWorkSessions(ctx iris.Context) {
+	param := controller.ExtractPageParam(ctx)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getWorkSessionsByRepairerService(param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getLabourStat godoc
+// @Summary      获取工时统计
+// @Description  统计时间段内已结束的工作记录 可按维修工过滤 以工作开始时间计入时间段
+// @Tags         order
+// @Produce      json
+// @Param        repairer  query     uint   false  "维修工ID (默认为所有维修工)"
+// @Param        start     query     int64  false  "开始时间 unix timestamp in seconds (UTC)"
+// @Param        end       query     int64  false  "结束时间 unix timestamp in seconds (UTC)"
+// @Success      200       {object}  model.ApiJson{data=LabourStatJson}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/work/stat [get]
+func getLabourStat(ctx iris.Context) {
+	req := &LabourStatRequest{}
+	if err := ctx.ReadQuery(req); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getLabourStatService(req, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getWorkSessionsByOrder godoc
+// @Summary      获取订单的工作记录
+// @Description  获取订单的工作记录 分页
+// @Tags         order
+// @Produce      json
+// @Param        id        path      uint    true   "订单ID"
+// @Param        order_by  query     string  false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint    false  "偏移量 (默认为0)"
+// @Param        limit     query     uint    false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]WorkSessionJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/work [get]
+func getWorkSessionsByOrder(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	param := controller.ExtractPageParam(ctx)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getWorkSessionsByOrderService(id, param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// startWork godoc
+// @Summary      开始工作 (签到)
+// @Description  订单当前维修工开始一段工作记录 可附带签到照片及坐标 同一维修工同时只能有一段进行中的工作记录
+// @Tags         order
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint              true   "订单ID"
+// @Param        body  body      CheckWorkRequest  false  "签到信息"
+// @Success      201   {object}  model.ApiJson{data=WorkSessionJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/work/start [post]
+func startWork(ctx iris.Context) {
+	aul := &CheckWorkRequest{}
+	if err := ctx.ReadJSON(aul); err != nil && !iris.IsErrEmptyJSON(err) {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := startWorkService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// stopWork godoc
+// @Summary      结束工作 (签退)
+// @Description  结束当前维修工在该订单上进行中的工作记录 可附带签退照片及坐标 工时计入订单累计工时
+// @Tags         order
+// @Accept       json
+// @Produce      json
+// @Param        id    path      uint              true   "订单ID"
+// @Param        body  body      CheckWorkRequest  false  "签退信息"
+// @Success      204   {object}  model.ApiJson{data=WorkSessionJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/order/{id}/work/stop [post]
+func stopWork(ctx iris.Context) {
+	aul := &CheckWorkRequest{}
+	if err := ctx.ReadJSON(aul); err != nil && !iris.IsErrEmptyJSON(err) {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := stopWorkService(id, aul, auth)
+	ctx.Values().Set("response", response)
+}
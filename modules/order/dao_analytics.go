@@ -0,0 +1,65 @@
+package order
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func dbGetConsumptionLogs(ids []uint, start, end time.Time) ([]*ItemLog, error) {
+	return txGetConsumptionLogs(mctx.Database, ids, start, end)
+}
+
+// txGetConsumptionLogs returns the consumption logs created in [start, end) in time order,
+// of the given items or of all items if ids is empty.
+func txGetConsumptionLogs(tx *gorm.DB, ids []uint, start, end time.Time) (logs []*ItemLog, err error) {
+	tx = tx.Model(&ItemLog{}).
+		Select("item_id, change_num, cost, created_at").
+		Where("change_num < 0 AND type IN (?) AND transfer_id = 0 AND created_at >= ? AND created_at < ?", []uint{ItemLogUnknown, ItemLogConsume}, start, end)
+	if len(ids) > 0 {
+		tx = tx.Where("item_id IN (?)", ids)
+	}
+	if err = tx.Order("created_at").Find(&logs).Error; err != nil {
+		mctx.Logger.Warnf("GetConsumptionLogsErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
+
+func dbGetItemsByIDs(ids []uint) ([]*Item, error) {
+	return txGetItemsByIDs(mctx.Database, ids)
+}
+
+func txGetItemsByIDs(tx *gorm.DB, ids []uint) (items []*Item, err error) {
+	if len(ids) == 0 {
+		return []*Item{}, nil
+	}
+	if err = tx.Where("id IN (?)", ids).Order("id").Find(&items).Error; err != nil {
+		mctx.Logger.Warnf("GetItemsByIDsErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
+
+func dbGetTopUsage(aul *TopUsageRequest, start, end time.Time, limit int) ([]*TopUsageJson, error) {
+	return txGetTopUsage(mctx.Database, aul, start, end, limit)
+}
+
+// txGetTopUsage returns the most consumed items in [start, end), optionally only for
+// the orders with the tag or the orders that have been assigned to the division.
+func txGetTopUsage(tx *gorm.DB, aul *TopUsageRequest, start, end time.Time, limit int) (usages []*TopUsageJson, err error) {
+	query := tx.Model(&ItemLog{}).
+		Select("item_id, -SUM(change_num) AS num, -SUM(cost) AS cost, COUNT(DISTINCT order_id) AS orders").
+		Where("change_num < 0 AND type IN (?) AND transfer_id = 0 AND created_at >= ? AND created_at < ?", []uint{ItemLogUnknown, ItemLogConsume}, start, end)
+	if aul.TagID != 0 {
+		query = query.Where("order_id IN (?)", tx.Table("order_tags").Select("order_id").Where("tag_id = ?", aul.TagID))
+	}
+	if aul.DivisionID != 0 {
+		query = query.Where("order_id IN (?)", tx.Model(&Status{}).Select("order_id").Where("division_id = ?", aul.DivisionID))
+	}
+	if err = query.Group("item_id").Order("num desc, item_id").Limit(limit).Scan(&usages).Error; err != nil {
+		mctx.Logger.Warnf("GetTopUsageErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,126 @@
+package order
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xaxys/maintainman/core/dao"
+
+	"gorm.io/gorm"
+)
+
+func dbGetApprovalByID(id uint) (*Approval, error) {
+	return txGetApprovalByID(mctx.Database, id)
+}
+
+func txGetApprovalByID(tx *gorm.DB, id uint) (*Approval, error) {
+	approval := &Approval{}
+	if err := tx.First(approval, id).Error; err != nil {
+		mctx.Logger.Warnf("GetApprovalByIDErr: %v\n", err)
+		return nil, err
+	}
+	return approval, nil
+}
+
+func dbGetAllApprovals(aul *AllApprovalRequest) (approvals []*Approval, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if approvals, count, err = txGetAllApprovals(tx, aul); err != nil {
+			mctx.Logger.Warnf("GetAllApprovalsErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetAllApprovals(tx *gorm.DB, aul *AllApprovalRequest) (approvals []*Approval, count uint, err error) {
+	approval := &Approval{
+		Kind:        aul.Kind,
+		State:       aul.State,
+		RequesterID: aul.RequesterID,
+	}
+	tx = dao.TxPageFilter(tx, &aul.PageParam).Model(approval).Where(approval)
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Find(&approvals).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbHasPendingApproval(kind string, orderID uint) (bool, error) {
+	return txHasPendingApproval(mctx.Database, kind, orderID)
+}
+
+func txHasPendingApproval(tx *gorm.DB, kind string, orderID uint) (bool, error) {
+	count := int64(0)
+	if err := tx.Model(&Approval{}).Where("kind = ? AND order_id = ? AND state = ?", kind, orderID, ApprovalPending).Count(&count).Error; err != nil {
+		mctx.Logger.Warnf("HasPendingApprovalErr: %v\n", err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func dbCreateApproval(approval *Approval, operator uint) (*Approval, error) {
+	return txCreateApproval(mctx.Database, approval, operator)
+}
+
+func txCreateApproval(tx *gorm.DB, approval *Approval, operator uint) (*Approval, error) {
+	approval.State = ApprovalPending
+	approval.CreatedBy = operator
+	if err := tx.Create(approval).Error; err != nil {
+		mctx.Logger.Warnf("CreateApprovalErr: %v\n", err)
+		return nil, err
+	}
+	return approval, nil
+}
+
+func dbDecideApproval(id, state uint, comment string, operator uint) (approval *Approval, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if approval, err = txDecideApproval(tx, id, state, comment, operator); err != nil {
+			mctx.Logger.Warnf("DecideApprovalErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txDecideApproval approves or denies a pending approval, an approval can only be decided once.
+func txDecideApproval(tx *gorm.DB, id, state uint, comment string, operator uint) (approval *Approval, err error) {
+	if approval, err = txGetApprovalByID(tx, id); err != nil {
+		return
+	}
+	result := tx.Model(&Approval{}).
+		Where("id = ? AND state = ?", id, ApprovalPending).
+		Updates(map[string]any{
+			"state":       state,
+			"approver_id": operator,
+			"comment":     comment,
+			"decided_at":  time.Now(),
+			"updated_by":  operator,
+		})
+	if err = result.Error; err != nil {
+		return
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("审批申请已被处理")
+	}
+	return txGetApprovalByID(tx, id)
+}
+
+func dbFailApproval(id uint, reason string) error {
+	return txFailApproval(mctx.Database, id, reason)
+}
+
+func txFailApproval(tx *gorm.DB, id uint, reason string) error {
+	if err := tx.Model(&Approval{}).Where("id = ?", id).Updates(map[string]any{
+		"state":  ApprovalFailed,
+		"result": reason,
+	}).Error; err != nil {
+		mctx.Logger.Warnf("FailApprovalErr: %v\n", err)
+		return err
+	}
+	return nil
+}
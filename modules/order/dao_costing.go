@@ -0,0 +1,124 @@
+package order
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// txCreateStockLot records an inbound item log as a lot for FIFO costing
+func txCreateStockLot(tx *gorm.DB, itemlog *ItemLog) error {
+	lot := &StockLot{
+		ItemID:    itemlog.ItemID,
+		ItemLogID: itemlog.ID,
+		Num:       itemlog.ChangeNum,
+		Remain:    itemlog.ChangeNum,
+		UnitCost:  itemlog.Cost / itemlog.ChangeNum,
+	}
+	lot.CreatedBy = itemlog.CreatedBy
+	return tx.Create(lot).Error
+}
+
+// txConsumeStockLots takes num items from the oldest lots of the item, and returns
+// the cost of them in FIFO and in weighted average. Items not covered by any lot
+// are priced at the unit cost of the latest lot.
+func txConsumeStockLots(tx *gorm.DB, item *Item, num float64) (fifo, average float64, err error) {
+	lots := []*StockLot{}
+	if err = tx.Where("item_id = ? AND remain > 0", item.ID).Order("id").Find(&lots).Error; err != nil {
+		return
+	}
+	unit, err := txGetUnitCost(tx, item)
+	if err != nil {
+		return
+	}
+	last := &StockLot{}
+	if err = tx.Where("item_id = ?", item.ID).Order("id desc").Limit(1).Find(last).Error; err != nil {
+		return
+	}
+	rest := num
+	for _, lot := range lots {
+		if rest == 0 {
+			break
+		}
+		taken := rest
+		if lot.Remain < taken {
+			taken = lot.Remain
+		}
+		lot.Remain = roundItemNum(item, lot.Remain-taken)
+		rest = roundItemNum(item, rest-taken)
+		fifo += taken * lot.UnitCost
+		if err = tx.Model(lot).Update("remain", lot.Remain).Error; err != nil {
+			return
+		}
+	}
+	fifo += rest * last.UnitCost
+	average = unit * num
+	return
+}
+
+// txGetUnitCost returns the weighted average unit cost of the item, or the
+// unit cost of the latest lot if the item is out of stock.
+func txGetUnitCost(tx *gorm.DB, item *Item) (float64, error) {
+	if item.Count > 0 {
+		return item.Cost / item.Count, nil
+	}
+	last := &StockLot{}
+	if err := tx.Where("item_id = ?", item.ID).Order("id desc").Limit(1).Find(last).Error; err != nil {
+		return 0, err
+	}
+	return last.UnitCost, nil
+}
+
+// txCostConsumption returns the cost of consuming num items with the configured costing method
+func txCostConsumption(tx *gorm.DB, item *Item, num float64) (float64, error) {
+	fifo, average, err := txConsumeStockLots(tx, item, num)
+	if err != nil {
+		return 0, err
+	}
+	if orderConfig.GetString("item.costing") == CostingFIFO {
+		return fifo, nil
+	}
+	return average, nil
+}
+
+func dbGetValuation(at time.Time) ([]*ItemValuationJson, error) {
+	return txGetValuation(mctx.Database, at)
+}
+
+// txGetValuation sums up the item logs before the given time
+func txGetValuation(tx *gorm.DB, at time.Time) (valuations []*ItemValuationJson, err error) {
+	if err = tx.Model(&ItemLog{}).
+		Select("item_logs.item_id, items.name, SUM(item_logs.change_num) AS count, SUM(item_logs.cost) AS value").
+		Joins("JOIN items ON items.id = item_logs.item_id AND items.deleted_at IS NULL").
+		Where("item_logs.created_at <= ?", at).
+		Group("item_logs.item_id, items.name").
+		Order("item_logs.item_id").
+		Scan(&valuations).Error; err != nil {
+		mctx.Logger.Warnf("GetValuationErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
+
+type costEntry struct {
+	ItemID  uint
+	OrderID uint
+	Num     float64
+	Cost    float64
+}
+
+func dbGetConsumptionCost(start, end time.Time) ([]*costEntry, error) {
+	return txGetConsumptionCost(mctx.Database, start, end)
+}
+
+func txGetConsumptionCost(tx *gorm.DB, start, end time.Time) (entries []*costEntry, err error) {
+	if err = tx.Model(&ItemLog{}).
+		Select("item_id, order_id, -SUM(change_num) AS num, -SUM(cost) AS cost").
+		Where("change_num < 0 AND type IN (?) AND transfer_id = 0 AND created_at >= ? AND created_at <= ?", []uint{ItemLogUnknown, ItemLogConsume}, start, end).
+		Group("item_id, order_id").
+		Scan(&entries).Error; err != nil {
+		mctx.Logger.Warnf("GetConsumptionCostErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package order
+
+import (
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func dbGetEscalationByOrder(id uint) (*Escalation, error) {
+	return txGetEscalationByOrder(mctx.Database, id)
+}
+
+func txGetEscalationByOrder(tx *gorm.DB, id uint) (*Escalation, error) {
+	escalation := &Escalation{}
+	if err := tx.Where("order_id = ?", id).First(escalation).Error; err != nil {
+		mctx.Logger.Warnf("GetEscalationByOrderErr: %v\n", err)
+		return nil, err
+	}
+	return escalation, nil
+}
+
+func dbGetTimeoutEscalations() (escalations []*Escalation, err error) {
+	return txGetTimeoutEscalations(mctx.Database)
+}
+
+func txGetTimeoutEscalations(tx *gorm.DB) (escalations []*Escalation, err error) {
+	if err = tx.Where("deadline <= ?", time.Now()).Find(&escalations).Error; err != nil {
+		mctx.Logger.Warnf("GetTimeoutEscalationsErr: %v\n", err)
+	}
+	return
+}
+
+func dbEscalateOrder(id uint, rule *EscalationRule, level, operator uint) (err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err = txEscalateOrder(tx, id, rule, level, operator); err != nil {
+			mctx.Logger.Warnf("EscalateOrderErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txEscalateOrder hands the reported order over to the supervisor of the given level (from 1)
+func txEscalateOrder(tx *gorm.DB, id uint, rule *EscalationRule, level, operator uint) error {
+	escalation := &Escalation{}
+	if err := tx.Where("order_id = ?", id).FirstOrInit(escalation).Error; err != nil {
+		return err
+	}
+	lv := rule.Levels[level-1]
+	escalation.OrderID = id
+	escalation.Rule = rule.Name
+	escalation.Level = level
+	escalation.SupervisorID = lv.Supervisor
+	escalation.Deadline = sql.NullTime{Time: time.Now().Add(lv.Timeout), Valid: int(level) < len(rule.Levels)}
+	escalation.UpdatedBy = operator
+	if escalation.ID == 0 {
+		escalation.CreatedBy = operator
+	}
+	if err := tx.Save(escalation).Error; err != nil {
+		return err
+	}
+	status := NewStatusEscalated(lv.Supervisor, operator, level)
+	return txChangeOrderStatus(tx, id, status)
+}
+
+func dbStopEscalation(id uint) error {
+	return txStopEscalation(mctx.Database, id)
+}
+
+func txStopEscalation(tx *gorm.DB, id uint) error {
+	if err := tx.Model(&Escalation{}).Where("id = ?", id).Update("deadline", sql.NullTime{}).Error; err != nil {
+		mctx.Logger.Warnf("StopEscalationErr: %v\n", err)
+		return err
+	}
+	return nil
+}
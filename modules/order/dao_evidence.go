@@ -0,0 +1,29 @@
+package order
+
+import (
+	"gorm.io/gorm"
+)
+
+func dbGetEvidencesByOrder(id uint) ([]*Evidence, error) {
+	return txGetEvidencesByOrder(mctx.Database, id)
+}
+
+func txGetEvidencesByOrder(tx *gorm.DB, id uint) (evidences []*Evidence, err error) {
+	if err = tx.Where("order_id = ?", id).Find(&evidences).Error; err != nil {
+		mctx.Logger.Warnf("GetEvidencesByOrderErr: %v\n", err)
+	}
+	return
+}
+
+func dbCreateEvidence(evidence *Evidence, operator uint) error {
+	return txCreateEvidence(mctx.Database, evidence, operator)
+}
+
+func txCreateEvidence(tx *gorm.DB, evidence *Evidence, operator uint) error {
+	evidence.CreatedBy = operator
+	if err := tx.Create(evidence).Error; err != nil {
+		mctx.Logger.Warnf("CreateEvidenceErr: %v\n", err)
+		return err
+	}
+	return nil
+}
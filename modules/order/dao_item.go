@@ -1,10 +1,13 @@
 package order
 
 import (
+	"database/sql"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/xaxys/maintainman/core/dao"
-	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
 
 	"gorm.io/gorm"
 )
@@ -48,6 +51,30 @@ func txGetItemByName(tx *gorm.DB, name string) (*Item, error) {
 	return item, nil
 }
 
+func dbGetItemByBarcode(code string) (*Item, error) {
+	return txGetItemByBarcode(mctx.Database, code)
+}
+
+// txGetItemByBarcode finds the item by one of its barcodes, or by its SKU
+// for labels printed with the SKU.
+func txGetItemByBarcode(tx *gorm.DB, code string) (*Item, error) {
+	item := &Item{}
+	barcode := &ItemBarcode{}
+	if err := tx.Where("code = ?", code).Limit(1).Find(barcode).Error; err != nil {
+		mctx.Logger.Warnf("GetItemByBarcodeErr: %v\n", err)
+		return nil, err
+	}
+	query := tx.Where("sku = ?", code)
+	if barcode.ItemID != 0 {
+		query = tx.Where("id = ?", barcode.ItemID)
+	}
+	if err := query.Preload("Barcodes").First(item).Error; err != nil {
+		mctx.Logger.Warnf("GetItemByBarcodeErr: %v\n", err)
+		return nil, err
+	}
+	return item, nil
+}
+
 func dbGetItemsByFuzzyName(name string) (items []*Item, err error) {
 	return TxGetItemsByFuzzyName(mctx.Database, name)
 }
@@ -60,9 +87,9 @@ func TxGetItemsByFuzzyName(tx *gorm.DB, name string) (items []*Item, err error)
 	return
 }
 
-func dbGetAllItems(param *model.PageParam) (items []*Item, count uint, err error) {
+func dbGetAllItems(aul *AllItemRequest) (items []*Item, count uint, err error) {
 	mctx.Database.Transaction(func(tx *gorm.DB) error {
-		if items, count, err = txGetAllItems(tx, param); err != nil {
+		if items, count, err = txGetAllItems(tx, aul); err != nil {
 			mctx.Logger.Warnf("GetAllItemsErr: %v\n", err)
 		}
 		return err
@@ -70,16 +97,17 @@ func dbGetAllItems(param *model.PageParam) (items []*Item, count uint, err error
 	return
 }
 
-func txGetAllItems(tx *gorm.DB, param *model.PageParam) (items []*Item, count uint, err error) {
-	tx = dao.TxPageFilter(tx, param)
-	if err = tx.Find(&items).Error; err != nil {
-		return
-	}
+func txGetAllItems(tx *gorm.DB, aul *AllItemRequest) (items []*Item, count uint, err error) {
+	item := &Item{CategoryID: aul.CategoryID}
+	tx = dao.TxPageFilter(tx, &aul.PageParam).Model(item).Where(item)
 	cnt := int64(0)
-	if err = tx.Count(&cnt).Error; err != nil {
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
 		return
 	}
 	count = uint(cnt)
+	if err = tx.Find(&items).Error; err != nil {
+		return
+	}
 	return
 }
 
@@ -90,6 +118,13 @@ func dbCreateItem(aul *CreateItemRequest, operator uint) (*Item, error) {
 func TxCreateItem(tx *gorm.DB, aul *CreateItemRequest, operator uint) (*Item, error) {
 	item := jsonToItem(aul)
 	item.CreatedBy = operator
+	if err := txCheckItemCategory(tx, item.CategoryID); err != nil {
+		mctx.Logger.Warnf("CreateItemErr: %v\n", err)
+		return nil, err
+	}
+	if err := checkItemNum(item, item.MinCount, item.ReorderNum); err != nil {
+		return nil, err
+	}
 	if err := tx.Create(item).Error; err != nil {
 		mctx.Logger.Warnf("CreateItemErr: %v\n", err)
 		return nil, err
@@ -97,6 +132,60 @@ func TxCreateItem(tx *gorm.DB, aul *CreateItemRequest, operator uint) (*Item, er
 	return item, nil
 }
 
+func dbUpdateItem(id uint, aul *UpdateItemRequest, operator uint) (item *Item, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if item, err = txUpdateItem(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("UpdateItemErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txUpdateItem(tx *gorm.DB, id uint, aul *UpdateItemRequest, operator uint) (item *Item, err error) {
+	if item, err = txGetItemByID(tx, id); err != nil {
+		return
+	}
+	if err = txCheckItemCategory(tx, aul.CategoryID); err != nil {
+		return
+	}
+	update := &Item{
+		Name:        aul.Name,
+		Description: aul.Description,
+		CategoryID:  aul.CategoryID,
+		Unit:        aul.Unit,
+		SKU:         sql.NullString{String: aul.SKU, Valid: aul.SKU != ""},
+	}
+	update.UpdatedBy = operator
+	if err = tx.Model(item).Updates(update).Error; err != nil {
+		return
+	}
+	if aul.Precision != nil {
+		if err = tx.Model(item).Update("precision", *aul.Precision).Error; err != nil {
+			return
+		}
+	}
+	if aul.Barcodes != nil {
+		if err = tx.Where("item_id = ?", id).Delete(&ItemBarcode{}).Error; err != nil {
+			return
+		}
+		barcodes := jsonToBarcodes(aul.Barcodes)
+		for _, barcode := range barcodes {
+			barcode.ItemID = id
+		}
+		if len(barcodes) > 0 {
+			if err = tx.Create(barcodes).Error; err != nil {
+				return
+			}
+		}
+	}
+	item = &Item{}
+	if err = tx.Preload("Barcodes").First(item, id).Error; err != nil {
+		return
+	}
+	return
+}
+
 func dbDeleteItem(id uint) error {
 	return TxDeleteItem(mctx.Database, id)
 }
@@ -106,6 +195,10 @@ func TxDeleteItem(tx *gorm.DB, id uint) error {
 		mctx.Logger.Warnf("DeleteItemErr: %v\n", err)
 		return err
 	}
+	if err := tx.Where("item_id = ?", id).Delete(&ItemBarcode{}).Error; err != nil {
+		mctx.Logger.Warnf("DeleteItemErr: %v\n", err)
+		return err
+	}
 	return nil
 }
 
@@ -121,15 +214,28 @@ func dbAddItem(itemlog *ItemLog, operator uint) (item *Item, err error) {
 
 func txAddItem(tx *gorm.DB, itemlog *ItemLog, operator uint) (item *Item, err error) {
 	itemlog.CreatedBy = operator
-	if item, err = dbGetItemByID(itemlog.ItemID); err != nil {
+	if item, err = txGetItemByID(tx, itemlog.ItemID); err != nil {
 		return
 	}
-	item.Count += itemlog.ChangeNum
+	if err = checkItemNum(item, itemlog.ChangeNum); err != nil {
+		return
+	}
+	item.Count = roundItemNum(item, item.Count+itemlog.ChangeNum)
 	item.Price += itemlog.ChangePrice
 	item.UpdatedBy = operator
+	itemlog.Cost = itemlog.ChangePrice
+	item.Cost += itemlog.Cost
+	if err = txChangeWarehouseStock(tx, itemlog.WarehouseID, item, itemlog.ChangeNum); err != nil {
+		return
+	}
 	if err = tx.Create(itemlog).Error; err != nil {
 		return
 	}
+	if itemlog.ChangeNum > 0 {
+		if err = txCreateStockLot(tx, itemlog); err != nil {
+			return
+		}
+	}
 	if err = tx.Save(item).Error; err != nil {
 		return
 	}
@@ -148,13 +254,38 @@ func dbConsumeItem(itemlog *ItemLog, operator uint) (item *Item, err error) {
 
 func txConsumeItem(tx *gorm.DB, itemlog *ItemLog, operator uint) (item *Item, err error) {
 	itemlog.CreatedBy = operator
-	if item, err = dbGetItemByID(itemlog.ItemID); err != nil {
+	if item, err = txGetItemByID(tx, itemlog.ItemID); err != nil {
+		return
+	}
+	if err = checkItemNum(item, itemlog.ChangeNum); err != nil {
+		return
+	}
+	// itemlog.ChangeNum is negative when consuming, items reserved for the order are taken first
+	reserved, err := txConsumeReservation(tx, itemlog.OrderID, item, -itemlog.ChangeNum, operator)
+	if err != nil {
 		return
 	}
-	if item.Count < itemlog.ChangeNum && !orderConfig.GetBool("item_can_negative") {
+	item.Reserved = roundItemNum(item, item.Reserved-reserved)
+	if roundItemNum(item, item.Count-item.Reserved+itemlog.ChangeNum) < 0 && !orderConfig.GetBool("item_can_negative") {
 		return nil, fmt.Errorf("item count is not enough")
 	}
-	item.Count -= itemlog.ChangeNum
+	stock, err := txGetWarehouseItemCount(tx, itemlog.WarehouseID, item)
+	if err != nil {
+		return
+	}
+	if roundItemNum(item, stock+itemlog.ChangeNum) < 0 && !orderConfig.GetBool("item_can_negative") {
+		return nil, fmt.Errorf("item count in warehouse is not enough")
+	}
+	if err = txChangeWarehouseStock(tx, itemlog.WarehouseID, item, itemlog.ChangeNum); err != nil {
+		return
+	}
+	cost, err := txCostConsumption(tx, item, -itemlog.ChangeNum)
+	if err != nil {
+		return
+	}
+	itemlog.Cost = -cost
+	item.Cost -= cost
+	item.Count = roundItemNum(item, item.Count+itemlog.ChangeNum)
 	item.Income += -itemlog.ChangePrice
 	item.UpdatedBy = operator
 	if err = tx.Create(itemlog).Error; err != nil {
@@ -166,9 +297,205 @@ func txConsumeItem(tx *gorm.DB, itemlog *ItemLog, operator uint) (item *Item, er
 	return
 }
 
+func dbUpdateItemThreshold(id uint, aul *UpdateItemThresholdRequest, operator uint) (item *Item, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if item, err = txUpdateItemThreshold(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("UpdateItemThresholdErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txUpdateItemThreshold(tx *gorm.DB, id uint, aul *UpdateItemThresholdRequest, operator uint) (item *Item, err error) {
+	if item, err = txGetItemByID(tx, id); err != nil {
+		return
+	}
+	if err = checkItemNum(item, aul.MinCount, aul.ReorderNum); err != nil {
+		return
+	}
+	item.MinCount = aul.MinCount
+	item.ReorderNum = aul.ReorderNum
+	item.UpdatedBy = operator
+	if err = tx.Model(item).Select("min_count", "reorder_num", "updated_by").Updates(item).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbGetLowStockItems() ([]*Item, error) {
+	return txGetLowStockItems(mctx.Database)
+}
+
+func txGetLowStockItems(tx *gorm.DB) (items []*Item, err error) {
+	if err = tx.Where("min_count > 0 AND count < min_count").Order("id").Find(&items).Error; err != nil {
+		mctx.Logger.Warnf("GetLowStockItemsErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
+
+func dbGetItemConsumption(ids []uint, since time.Time) (map[uint]float64, error) {
+	return txGetItemConsumption(mctx.Database, ids, since)
+}
+
+func txGetItemConsumption(tx *gorm.DB, ids []uint, since time.Time) (map[uint]float64, error) {
+	type result struct {
+		ItemID uint
+		Total  float64
+	}
+	results := []*result{}
+	consumed := make(map[uint]float64)
+	if len(ids) == 0 {
+		return consumed, nil
+	}
+	if err := tx.Model(&ItemLog{}).
+		Select("item_id, -SUM(change_num) AS total").
+		Where("item_id IN (?) AND change_num < 0 AND type IN (?) AND transfer_id = 0 AND created_at >= ?", ids, []uint{ItemLogUnknown, ItemLogConsume}, since).
+		Group("item_id").
+		Scan(&results).Error; err != nil {
+		mctx.Logger.Warnf("GetItemConsumptionErr: %v\n", err)
+		return nil, err
+	}
+	for _, r := range results {
+		consumed[r.ItemID] = r.Total
+	}
+	return consumed, nil
+}
+
+// checkItemNum checks that the quantities have no more decimal places than the
+// unit of the item allows.
+func checkItemNum(item *Item, nums ...float64) error {
+	scale := math.Pow10(int(item.Precision))
+	for _, num := range nums {
+		scaled := num * scale
+		if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+			return fmt.Errorf("物品 %s 的数量最多保留 %d 位小数", item.Name, item.Precision)
+		}
+	}
+	return nil
+}
+
+// roundItemNum rounds num to the decimal places that the unit of the item allows,
+// quantities are rounded after every calculation so that float errors do not add up.
+func roundItemNum(item *Item, num float64) float64 {
+	scale := math.Pow10(int(item.Precision))
+	return math.Round(num*scale) / scale
+}
+
+// ceilItemNum rounds num up to the decimal places that the unit of the item allows.
+func ceilItemNum(item *Item, num float64) float64 {
+	scale := math.Pow10(int(item.Precision))
+	return math.Ceil(num*scale-1e-6) / scale
+}
+
+func txCheckItemCategory(tx *gorm.DB, id uint) error {
+	if id == 0 {
+		return nil
+	}
+	_, err := txGetCategoryByID(tx, id)
+	return err
+}
+
+func dbGetCategoryByID(id uint) (*ItemCategory, error) {
+	return txGetCategoryByID(mctx.Database, id)
+}
+
+func txGetCategoryByID(tx *gorm.DB, id uint) (*ItemCategory, error) {
+	category := &ItemCategory{}
+	if err := tx.First(category, id).Error; err != nil {
+		mctx.Logger.Warnf("GetCategoryByIDErr: %v\n", err)
+		return nil, err
+	}
+	return category, nil
+}
+
+func dbGetAllCategories() ([]*ItemCategory, error) {
+	return txGetAllCategories(mctx.Database)
+}
+
+func txGetAllCategories(tx *gorm.DB) (categories []*ItemCategory, err error) {
+	if err = tx.Order("id").Find(&categories).Error; err != nil {
+		mctx.Logger.Warnf("GetAllCategoriesErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
+
+func dbCreateCategory(aul *CreateCategoryRequest, operator uint) (*ItemCategory, error) {
+	return txCreateCategory(mctx.Database, aul, operator)
+}
+
+func txCreateCategory(tx *gorm.DB, aul *CreateCategoryRequest, operator uint) (*ItemCategory, error) {
+	category := &ItemCategory{
+		Name:        aul.Name,
+		Description: aul.Description,
+	}
+	category.CreatedBy = operator
+	if err := tx.Create(category).Error; err != nil {
+		mctx.Logger.Warnf("CreateCategoryErr: %v\n", err)
+		return nil, err
+	}
+	return category, nil
+}
+
+func dbUpdateCategory(id uint, aul *UpdateCategoryRequest, operator uint) (category *ItemCategory, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if category, err = txUpdateCategory(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("UpdateCategoryErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txUpdateCategory(tx *gorm.DB, id uint, aul *UpdateCategoryRequest, operator uint) (category *ItemCategory, err error) {
+	if category, err = txGetCategoryByID(tx, id); err != nil {
+		return
+	}
+	update := &ItemCategory{
+		Name:        aul.Name,
+		Description: aul.Description,
+	}
+	update.UpdatedBy = operator
+	if err = tx.Model(category).Updates(update).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbDeleteCategory(id uint) error {
+	return mctx.Database.Transaction(func(tx *gorm.DB) error {
+		err := txDeleteCategory(tx, id)
+		if err != nil {
+			mctx.Logger.Warnf("DeleteCategoryErr: %v\n", err)
+		}
+		return err
+	})
+}
+
+// txDeleteCategory deletes the category, and its items become uncategorized.
+func txDeleteCategory(tx *gorm.DB, id uint) error {
+	if err := tx.Delete(&ItemCategory{}, id).Error; err != nil {
+		return err
+	}
+	return tx.Model(&Item{}).Where("category_id = ?", id).Update("category_id", 0).Error
+}
+
 func jsonToItem(item *CreateItemRequest) *Item {
 	return &Item{
 		Name:        item.Name,
-		Description: item.Discription,
+		Description: item.Description,
+		CategoryID:  item.CategoryID,
+		Unit:        item.Unit,
+		Precision:   item.Precision,
+		SKU:         sql.NullString{String: item.SKU, Valid: item.SKU != ""},
+		Barcodes:    jsonToBarcodes(item.Barcodes),
+		MinCount:    item.MinCount,
+		ReorderNum:  item.ReorderNum,
 	}
 }
+
+func jsonToBarcodes(codes []string) []*ItemBarcode {
+	return util.TransSlice(codes, func(code string) *ItemBarcode { return &ItemBarcode{Code: code} })
+}
@@ -2,8 +2,10 @@ package order
 
 func dbItemLogAdd(aul *AddItemRequest) *ItemLog {
 	itemlog := &ItemLog{
+		Type:        ItemLogAdd,
 		ItemID:      aul.ItemID,
-		ChangeNum:   int(aul.Num),
+		WarehouseID: aul.WarehouseID,
+		ChangeNum:   aul.Num,
 		ChangePrice: aul.Price,
 	}
 	return itemlog
@@ -11,8 +13,11 @@ func dbItemLogAdd(aul *AddItemRequest) *ItemLog {
 
 func dbItemLogConsume(aul *ConsumeItemRequest) *ItemLog {
 	itemlog := &ItemLog{
+		Type:        ItemLogConsume,
 		ItemID:      aul.ItemID,
-		ChangeNum:   -int(aul.Num),
+		OrderID:     aul.OrderID,
+		WarehouseID: aul.WarehouseID,
+		ChangeNum:   -aul.Num,
 		ChangePrice: -aul.Price,
 	}
 	return itemlog
@@ -42,7 +42,7 @@ func dbGetOrderByID(id uint) (*Order, error) {
 
 func txGetOrderByID(tx *gorm.DB, id uint) (*Order, error) {
 	order := &Order{}
-	if err := tx.Preload("Tags").Preload("Comments").First(order, id).Error; err != nil {
+	if err := tx.Preload("Tags").Preload("Comments").Preload("Evidences").First(order, id).Error; err != nil {
 		mctx.Logger.Warnf("TxGetOrderByIDErr: %v\n", err)
 		return nil, err
 	}
@@ -64,13 +64,25 @@ func txGetAllOrdersWithParam(tx *gorm.DB, aul *AllOrderRequest) (orders []*Order
 		UserID: aul.UserID,
 		Status: aul.Status,
 	}
+	tags := util.TransSlice(aul.Tags, func(id uint) []uint { return []uint{id} })
+	if aul.Descendants {
+		for i, id := range aul.Tags {
+			if tags[i], err = txGetTagDescendantIDs(tx, id); err != nil {
+				return
+			}
+		}
+	}
 	tx = dao.TxPageFilter(tx, &aul.PageParam).Model(order).Where(order)
-	if len(aul.Tags) > 0 {
+	if len(tags) > 0 {
 		if aul.Disjunctive {
-			tx = tx.Where("id IN (?)", mctx.Database.Table("order_tags").Select("order_id").Where("tag_id IN (?)", aul.Tags))
+			ids := []uint{}
+			for _, t := range tags {
+				ids = append(ids, t...)
+			}
+			tx = tx.Where("id IN (?)", mctx.Database.Table("order_tags").Select("order_id").Where("tag_id IN (?)", ids))
 		} else {
-			for _, tag := range aul.Tags {
-				tx = tx.Where("EXISTS (?)", mctx.Database.Table("order_tags").Select("order_id").Where("tag_id = (?)", tag).Where("order_id = orders.id"))
+			for _, t := range tags {
+				tx = tx.Where("EXISTS (?)", mctx.Database.Table("order_tags").Select("order_id").Where("tag_id IN (?)", t).Where("order_id = orders.id"))
 			}
 		}
 	}
@@ -181,6 +193,17 @@ func TxUpdateOrder(tx *gorm.DB, id uint, aul *UpdateOrderRequest, operator uint)
 	return
 }
 
+func dbAddOrderTags(order *Order, tags []*Tag) error {
+	return txAddOrderTags(mctx.Database, order, tags)
+}
+
+func txAddOrderTags(tx *gorm.DB, order *Order, tags []*Tag) (err error) {
+	if err = tx.Model(order).Association("Tags").Append(tags); err != nil {
+		mctx.Logger.Warnf("AddOrderTagsErr: %v\n", err)
+	}
+	return
+}
+
 func dbDeleteOrder(id uint) error {
 	return txDeleteOrder(mctx.Database, id)
 }
@@ -222,7 +245,7 @@ func txChangeOrderStatus(tx *gorm.DB, id uint, status *Status) error {
 	lstatus := &Status{}
 	lstatus.UpdatedBy = status.CreatedBy
 	lstatus.Current = false
-	if err := tx.Model(lastStatus).Updates(lstatus).Error; err != nil {
+	if err := tx.Model(lastStatus).Select("current", "updated_by").Updates(lstatus).Error; err != nil {
 		return err
 	}
 
@@ -230,6 +253,13 @@ func txChangeOrderStatus(tx *gorm.DB, id uint, status *Status) error {
 	if err := tx.Model(order).Association("StatusList").Append(status); err != nil {
 		return err
 	}
+
+	// reserved items can only be consumed by an assigned order, return them otherwise
+	if status.Status != StatusAssigned {
+		if err := txReleaseReservations(tx, id, status.CreatedBy); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,47 @@
+package order
+
+import (
+	"gorm.io/gorm"
+)
+
+func dbGetAllOrdersByUser(id uint) (orders []*Order, err error) {
+	if err = mctx.Database.Preload("Tags").Preload("Evidences").Where("user_id = ?", id).Order("id").Find(&orders).Error; err != nil {
+		mctx.Logger.Warnf("GetAllOrdersByUserErr: %v\n", err)
+	}
+	return
+}
+
+func dbGetAllCommentsByUser(id uint) (comments []*Comment, err error) {
+	if err = mctx.Database.Where("user_id = ?", id).Order("id").Find(&comments).Error; err != nil {
+		mctx.Logger.Warnf("GetAllCommentsByUserErr: %v\n", err)
+	}
+	return
+}
+
+// dbGetImageIDsByUser returns the IDs of the images attached as evidences by the user.
+func dbGetImageIDsByUser(id uint) (ids []string, err error) {
+	if err = mctx.Database.Model(&Evidence{}).Where("created_by = ?", id).Distinct().Order("image_id").Pluck("image_id", &ids).Error; err != nil {
+		mctx.Logger.Warnf("GetImageIDsByUserErr: %v\n", err)
+	}
+	return
+}
+
+// dbEraseUserData clears the contact fields of the orders of the user and renames the
+// comments of the user. The update time is kept, so are the statistics.
+func dbEraseUserData(id uint, name string) error {
+	return mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err := txEraseUserData(tx, id, name); err != nil {
+			mctx.Logger.Warnf("EraseUserDataErr: %v\n", err)
+			return err
+		}
+		return nil
+	})
+}
+
+func txEraseUserData(tx *gorm.DB, id uint, name string) error {
+	if err := tx.Model(&Order{}).Where("user_id = ?", id).
+		UpdateColumns(map[string]any{"contact_name": "", "contact_phone": ""}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&Comment{}).Where("user_id = ?", id).UpdateColumn("user_name", name).Error
+}
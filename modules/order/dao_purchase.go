@@ -0,0 +1,345 @@
+package order
+
+import (
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/dao"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func dbGetSupplierByID(id uint) (*Supplier, error) {
+	return txGetSupplierByID(mctx.Database, id)
+}
+
+func txGetSupplierByID(tx *gorm.DB, id uint) (*Supplier, error) {
+	supplier := &Supplier{}
+	if err := tx.First(supplier, id).Error; err != nil {
+		mctx.Logger.Warnf("GetSupplierByIDErr: %v\n", err)
+		return nil, err
+	}
+	return supplier, nil
+}
+
+func dbGetAllSuppliers(param *model.PageParam) (suppliers []*Supplier, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if suppliers, count, err = txGetAllSuppliers(tx, param); err != nil {
+			mctx.Logger.Warnf("GetAllSuppliersErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetAllSuppliers(tx *gorm.DB, param *model.PageParam) (suppliers []*Supplier, count uint, err error) {
+	tx = dao.TxPageFilter(tx, param).Model(&Supplier{})
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Find(&suppliers).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbCreateSupplier(aul *CreateSupplierRequest, operator uint) (*Supplier, error) {
+	return txCreateSupplier(mctx.Database, aul, operator)
+}
+
+func txCreateSupplier(tx *gorm.DB, aul *CreateSupplierRequest, operator uint) (*Supplier, error) {
+	supplier := &Supplier{
+		Name:    aul.Name,
+		Contact: aul.Contact,
+		Phone:   aul.Phone,
+		Address: aul.Address,
+		Note:    aul.Note,
+	}
+	supplier.CreatedBy = operator
+	if err := tx.Create(supplier).Error; err != nil {
+		mctx.Logger.Warnf("CreateSupplierErr: %v\n", err)
+		return nil, err
+	}
+	return supplier, nil
+}
+
+func dbUpdateSupplier(id uint, aul *UpdateSupplierRequest, operator uint) (supplier *Supplier, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if supplier, err = txUpdateSupplier(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("UpdateSupplierErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txUpdateSupplier(tx *gorm.DB, id uint, aul *UpdateSupplierRequest, operator uint) (supplier *Supplier, err error) {
+	if supplier, err = txGetSupplierByID(tx, id); err != nil {
+		return
+	}
+	update := &Supplier{
+		Name:    aul.Name,
+		Contact: aul.Contact,
+		Phone:   aul.Phone,
+		Address: aul.Address,
+		Note:    aul.Note,
+	}
+	update.UpdatedBy = operator
+	if err = tx.Model(supplier).Updates(update).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbDeleteSupplier(id uint) error {
+	return txDeleteSupplier(mctx.Database, id)
+}
+
+func txDeleteSupplier(tx *gorm.DB, id uint) error {
+	if err := tx.Delete(&Supplier{}, id).Error; err != nil {
+		mctx.Logger.Warnf("DeleteSupplierErr: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func dbGetPurchaseByID(id uint) (*PurchaseOrder, error) {
+	return txGetPurchaseByID(mctx.Database, id)
+}
+
+func txGetPurchaseByID(tx *gorm.DB, id uint) (*PurchaseOrder, error) {
+	purchase := &PurchaseOrder{}
+	if err := tx.Preload("Lines").First(purchase, id).Error; err != nil {
+		mctx.Logger.Warnf("GetPurchaseByIDErr: %v\n", err)
+		return nil, err
+	}
+	return purchase, nil
+}
+
+func dbGetAllPurchases(aul *AllPurchaseRequest) (purchases []*PurchaseOrder, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if purchases, count, err = txGetAllPurchases(tx, aul); err != nil {
+			mctx.Logger.Warnf("GetAllPurchasesErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetAllPurchases(tx *gorm.DB, aul *AllPurchaseRequest) (purchases []*PurchaseOrder, count uint, err error) {
+	purchase := &PurchaseOrder{
+		SupplierID: aul.SupplierID,
+		State:      aul.State,
+	}
+	tx = dao.TxPageFilter(tx, &aul.PageParam).Model(purchase).Where(purchase)
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Preload("Lines").Find(&purchases).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbCreatePurchase(aul *CreatePurchaseRequest, operator uint) (purchase *PurchaseOrder, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if purchase, err = txCreatePurchase(tx, aul, operator); err != nil {
+			mctx.Logger.Warnf("CreatePurchaseErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txCreatePurchase(tx *gorm.DB, aul *CreatePurchaseRequest, operator uint) (purchase *PurchaseOrder, err error) {
+	if _, err = txGetSupplierByID(tx, aul.SupplierID); err != nil {
+		return
+	}
+	lines, err := txJsonToPurchaseLines(tx, aul.Lines, operator)
+	if err != nil {
+		return
+	}
+	purchase = &PurchaseOrder{
+		SupplierID: aul.SupplierID,
+		State:      PurchaseDraft,
+		Note:       aul.Note,
+		Lines:      lines,
+	}
+	purchase.CreatedBy = operator
+	if err = tx.Create(purchase).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbUpdatePurchase(id uint, aul *UpdatePurchaseRequest, operator uint) (purchase *PurchaseOrder, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if purchase, err = txUpdatePurchase(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("UpdatePurchaseErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txUpdatePurchase replaces the lines of the draft purchase order if lines are given
+func txUpdatePurchase(tx *gorm.DB, id uint, aul *UpdatePurchaseRequest, operator uint) (purchase *PurchaseOrder, err error) {
+	if purchase, err = txGetPurchaseByID(tx, id); err != nil {
+		return
+	}
+	if purchase.State != PurchaseDraft {
+		return nil, fmt.Errorf("采购单不处于草稿状态，不能修改")
+	}
+	if aul.SupplierID != 0 {
+		if _, err = txGetSupplierByID(tx, aul.SupplierID); err != nil {
+			return
+		}
+	}
+	update := &PurchaseOrder{
+		SupplierID: aul.SupplierID,
+		Note:       aul.Note,
+	}
+	update.UpdatedBy = operator
+	if err = tx.Model(purchase).Updates(update).Error; err != nil {
+		return
+	}
+	if len(aul.Lines) > 0 {
+		lines, err := txJsonToPurchaseLines(tx, aul.Lines, operator)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Where("purchase_order_id = ?", id).Delete(&PurchaseLine{}).Error; err != nil {
+			return nil, err
+		}
+		if err = tx.Model(purchase).Association("Lines").Append(lines); err != nil {
+			return nil, err
+		}
+	}
+	return txGetPurchaseByID(tx, id)
+}
+
+func dbChangePurchaseState(id, state, operator uint) error {
+	return txChangePurchaseState(mctx.Database, id, state, operator)
+}
+
+func txChangePurchaseState(tx *gorm.DB, id, state, operator uint) error {
+	purchase := &PurchaseOrder{}
+	purchase.ID = id
+	purchase.State = state
+	purchase.UpdatedBy = operator
+	if err := tx.Model(purchase).Updates(purchase).Error; err != nil {
+		mctx.Logger.Warnf("ChangePurchaseStateErr: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func dbDeletePurchase(id uint) error {
+	return mctx.Database.Transaction(func(tx *gorm.DB) error {
+		err := txDeletePurchase(tx, id)
+		if err != nil {
+			mctx.Logger.Warnf("DeletePurchaseErr: %v\n", err)
+		}
+		return err
+	})
+}
+
+func txDeletePurchase(tx *gorm.DB, id uint) error {
+	if err := tx.Where("purchase_order_id = ?", id).Delete(&PurchaseLine{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&PurchaseOrder{}, id).Error
+}
+
+func dbReceivePurchase(id uint, aul *ReceivePurchaseRequest, operator uint) (purchase *PurchaseOrder, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if purchase, err = txReceivePurchase(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("ReceivePurchaseErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txReceivePurchase adds the received items to the warehouse, each with an item log
+// referencing the supplier and the purchase order.
+func txReceivePurchase(tx *gorm.DB, id uint, aul *ReceivePurchaseRequest, operator uint) (purchase *PurchaseOrder, err error) {
+	if purchase, err = txGetPurchaseByID(tx, id); err != nil {
+		return
+	}
+	if !util.In(purchase.State, PurchaseOrdered, PurchasePartial) {
+		return nil, fmt.Errorf("采购单未下单或已全部到货，不能收货")
+	}
+	lines := make(map[uint]*PurchaseLine)
+	for _, line := range purchase.Lines {
+		lines[line.ItemID] = line
+	}
+	for _, receive := range aul.Lines {
+		line, ok := lines[receive.ItemID]
+		if !ok {
+			return nil, fmt.Errorf("采购单中没有物品 %d", receive.ItemID)
+		}
+		if line.Received+receive.Num > line.Num {
+			return nil, fmt.Errorf("物品 %d 到货数量超过采购数量", receive.ItemID)
+		}
+		line.Received += receive.Num
+		line.UpdatedBy = operator
+		if err = tx.Model(line).Select("received", "updated_by").Updates(line).Error; err != nil {
+			return
+		}
+		itemlog := &ItemLog{
+			Type:        ItemLogAdd,
+			ItemID:      receive.ItemID,
+			WarehouseID: aul.WarehouseID,
+			SupplierID:  purchase.SupplierID,
+			PurchaseID:  purchase.ID,
+			ChangeNum:   receive.Num,
+			ChangePrice: line.Price * receive.Num,
+		}
+		if _, err = txAddItem(tx, itemlog, operator); err != nil {
+			return
+		}
+	}
+	state := uint(PurchaseReceived)
+	for _, line := range purchase.Lines {
+		if line.Received < line.Num {
+			state = PurchasePartial
+		}
+	}
+	if err = txChangePurchaseState(tx, id, state, operator); err != nil {
+		return
+	}
+	purchase.State = state
+	return
+}
+
+func txJsonToPurchaseLines(tx *gorm.DB, aul []*PurchaseLineRequest, operator uint) ([]*PurchaseLine, error) {
+	lines := []*PurchaseLine{}
+	seen := make(map[uint]bool)
+	for _, l := range aul {
+		if seen[l.ItemID] {
+			return nil, fmt.Errorf("采购单中物品 %d 重复", l.ItemID)
+		}
+		seen[l.ItemID] = true
+		item, err := txGetItemByID(tx, l.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkItemNum(item, l.Num); err != nil {
+			return nil, err
+		}
+		line := &PurchaseLine{
+			ItemID: l.ItemID,
+			Num:    l.Num,
+			Price:  l.Price,
+		}
+		line.CreatedBy = operator
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -0,0 +1,172 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+func dbGetReservationsByOrder(id uint) ([]*Reservation, error) {
+	return txGetReservationsByOrder(mctx.Database, id)
+}
+
+func txGetReservationsByOrder(tx *gorm.DB, id uint) (reservations []*Reservation, err error) {
+	if err = tx.Where("order_id = ? AND num > 0", id).Find(&reservations).Error; err != nil {
+		mctx.Logger.Warnf("GetReservationsByOrderErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
+
+func txGetReservation(tx *gorm.DB, orderID, itemID uint) (*Reservation, error) {
+	reservation := &Reservation{
+		OrderID: orderID,
+		ItemID:  itemID,
+	}
+	if err := tx.Where(reservation).First(reservation).Error; err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+func dbReserveItem(aul *ReserveItemRequest, operator uint) (reservation *Reservation, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if reservation, err = txReserveItem(tx, aul, operator); err != nil {
+			mctx.Logger.Warnf("ReserveItemErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txReserveItem reserves items only if the available count (count - reserved) is enough,
+// regardless of item_can_negative.
+func txReserveItem(tx *gorm.DB, aul *ReserveItemRequest, operator uint) (reservation *Reservation, err error) {
+	item, err := txGetItemByID(tx, aul.ItemID)
+	if err != nil {
+		return
+	}
+	if err = checkItemNum(item, aul.Num); err != nil {
+		return
+	}
+	result := tx.Model(&Item{}).
+		Where("id = ? AND ROUND(count - reserved, ?) >= ?", aul.ItemID, item.Precision, aul.Num).
+		Updates(map[string]any{
+			"reserved":   gorm.Expr("ROUND(reserved + ?, ?)", aul.Num, item.Precision),
+			"updated_by": operator,
+		})
+	if err = result.Error; err != nil {
+		return
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("item available count is not enough")
+	}
+	reservation = &Reservation{
+		OrderID: aul.OrderID,
+		ItemID:  aul.ItemID,
+	}
+	if err = tx.Where(reservation).FirstOrInit(reservation).Error; err != nil {
+		return
+	}
+	if reservation.ID == 0 {
+		reservation.CreatedBy = operator
+	}
+	reservation.Num = roundItemNum(item, reservation.Num+aul.Num)
+	reservation.UpdatedBy = operator
+	if err = tx.Save(reservation).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbReturnReservation(aul *ReserveItemRequest, operator uint) (reservation *Reservation, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if reservation, err = txReturnReservation(tx, aul, operator); err != nil {
+			mctx.Logger.Warnf("ReturnReservationErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txReturnReservation(tx *gorm.DB, aul *ReserveItemRequest, operator uint) (reservation *Reservation, err error) {
+	if reservation, err = txGetReservation(tx, aul.OrderID, aul.ItemID); err != nil {
+		return
+	}
+	item, err := txGetItemByID(tx, aul.ItemID)
+	if err != nil {
+		return
+	}
+	if err = checkItemNum(item, aul.Num); err != nil {
+		return
+	}
+	if reservation.Num < aul.Num {
+		return nil, fmt.Errorf("return count is more than reserved")
+	}
+	reservation.Num = roundItemNum(item, reservation.Num-aul.Num)
+	reservation.UpdatedBy = operator
+	if err = tx.Model(reservation).Select("num", "updated_by").Updates(reservation).Error; err != nil {
+		return
+	}
+	if err = txUnreserveItem(tx, item, aul.Num, operator); err != nil {
+		return
+	}
+	return
+}
+
+func txReleaseReservations(tx *gorm.DB, orderID, operator uint) error {
+	reservations, err := txGetReservationsByOrder(tx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, reservation := range reservations {
+		item, err := txGetItemByID(tx, reservation.ItemID)
+		if err != nil {
+			return err
+		}
+		if err := txUnreserveItem(tx, item, reservation.Num, operator); err != nil {
+			return err
+		}
+		reservation.Num = 0
+		reservation.UpdatedBy = operator
+		if err := tx.Model(reservation).Select("num", "updated_by").Updates(reservation).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// txConsumeReservation takes up to num items from the reservation of the order,
+// and returns the number taken.
+func txConsumeReservation(tx *gorm.DB, orderID uint, item *Item, num float64, operator uint) (float64, error) {
+	reservation, err := txGetReservation(tx, orderID, item.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	used := num
+	if reservation.Num < used {
+		used = reservation.Num
+	}
+	if used == 0 {
+		return 0, nil
+	}
+	reservation.Num = roundItemNum(item, reservation.Num-used)
+	reservation.UpdatedBy = operator
+	if err := tx.Model(reservation).Select("num", "updated_by").Updates(reservation).Error; err != nil {
+		return 0, err
+	}
+	return used, nil
+}
+
+func txUnreserveItem(tx *gorm.DB, item *Item, num float64, operator uint) error {
+	return tx.Model(&Item{}).
+		Where("id = ?", item.ID).
+		Updates(map[string]any{
+			"reserved":   gorm.Expr("ROUND(reserved - ?, ?)", num, item.Precision),
+			"updated_by": operator,
+		}).Error
+}
@@ -2,6 +2,7 @@ package order
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/xaxys/maintainman/core/dao"
@@ -11,9 +12,9 @@ import (
 	"gorm.io/gorm"
 )
 
-func dbGetOrderByRepairer(id uint, json *RepairerOrderRequest) (orders []*Order, count uint, err error) {
+func dbGetOrderByRepairer(id uint, divisions []uint, json *RepairerOrderRequest) (orders []*Order, count uint, err error) {
 	mctx.Database.Transaction(func(tx *gorm.DB) error {
-		if orders, count, err = txGetOrderByRepairer(tx, id, json); err != nil {
+		if orders, count, err = txGetOrderByRepairer(tx, id, divisions, json); err != nil {
 			mctx.Logger.Warnf("GetOrderByRepairer: %v\n", err)
 		}
 		return err
@@ -21,13 +22,19 @@ func dbGetOrderByRepairer(id uint, json *RepairerOrderRequest) (orders []*Order,
 	return
 }
 
-func txGetOrderByRepairer(tx *gorm.DB, id uint, json *RepairerOrderRequest) (orders []*Order, count uint, err error) {
+// txGetOrderByRepairer also returns the unclaimed orders assigned to the given divisions
+func txGetOrderByRepairer(tx *gorm.DB, id uint, divisions []uint, json *RepairerOrderRequest) (orders []*Order, count uint, err error) {
 	status := &Status{
 		RepairerID: sql.NullInt64{Int64: int64(id), Valid: true},
 		Current:    json.Current,
 	}
 	statuses := []*Status{}
-	tx = dao.TxPageFilter(tx, &json.PageParam).Model(status).Where(status)
+	tx = dao.TxPageFilter(tx, &json.PageParam).Model(status)
+	if len(divisions) > 0 {
+		tx = tx.Where(mctx.Database.Where(status).Or("statuses.division_id IN (?) AND statuses.current = TRUE", divisions))
+	} else {
+		tx = tx.Where(status)
+	}
 	if json.Status != 0 {
 		tx = tx.Joins("Order", Order{Status: json.Status})
 	}
@@ -52,6 +59,44 @@ func txGetOrderByRepairer(tx *gorm.DB, id uint, json *RepairerOrderRequest) (ord
 	return
 }
 
+func dbGetOrderByDivisions(divisions []uint, json *DivisionOrderRequest) (orders []*Order, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if orders, count, err = txGetOrderByDivisions(tx, divisions, json); err != nil {
+			mctx.Logger.Warnf("GetOrderByDivisions: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txGetOrderByDivisions returns the unclaimed orders assigned to the given divisions
+func txGetOrderByDivisions(tx *gorm.DB, divisions []uint, json *DivisionOrderRequest) (orders []*Order, count uint, err error) {
+	status := &Status{
+		Current: true,
+	}
+	statuses := []*Status{}
+	tx = dao.TxPageFilter(tx, &json.PageParam).Model(status).Where(status).Where("division_id IN (?)", divisions)
+	if len(json.Tags) > 0 {
+		if json.Disjunctive {
+			tx = tx.Where("order_id IN (?)", mctx.Database.Table("order_tags").Select("order_id").Where("tag_id IN (?)", json.Tags))
+		} else {
+			for _, tag := range json.Tags {
+				tx = tx.Where("EXISTS (?)", mctx.Database.Table("order_tags").Select("order_id").Where("tag_id = (?)", tag).Where("order_id = statuses.order_id"))
+			}
+		}
+	}
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Preload("Order.Tags").Find(&statuses).Error; err != nil {
+		return
+	}
+	orders = util.TransSlice(statuses, func(status *Status) *Order { return status.Order })
+	return
+}
+
 func txGetAppraiseTimeoutOrder(tx *gorm.DB) (ids []uint, err error) {
 	status := &Status{
 		Status:  StatusCompleted,
@@ -88,11 +133,25 @@ func NewStatusWaiting(operator uint) *Status {
 	return NewStatus(1, 0, operator)
 }
 
+// StatusWaiting 待处理 (指派给分组 待组员认领)
+func NewStatusDivisionAssigned(division, operator uint) *Status {
+	status := NewStatus(1, 0, operator)
+	status.DivisionID = sql.NullInt64{Int64: int64(division), Valid: division != 0}
+	return status
+}
+
 // StatusAccepted 已接单
 func NewStatusAssigned(repairer, operator uint) *Status {
 	return NewStatus(2, repairer, operator)
 }
 
+// StatusTransferred 转单 (仍为已接单状态)
+func NewStatusTransferred(repairer, operator uint, reason string) *Status {
+	status := NewStatus(2, repairer, operator)
+	status.Reason = reason
+	return status
+}
+
 // StatusCompleted 已完成
 func NewStatusCompleted(operator uint) *Status {
 	return NewStatus(3, 0, operator)
@@ -103,6 +162,13 @@ func NewStatusReported(operator uint) *Status {
 	return NewStatus(4, 0, operator)
 }
 
+// StatusReported 上报中 (升级至指定层级主管)
+func NewStatusEscalated(supervisor, operator, level uint) *Status {
+	status := NewStatus(4, supervisor, operator)
+	status.Reason = fmt.Sprintf("升级至第%d级主管", level)
+	return status
+}
+
 // StatusHold 挂单
 func NewStatusHold(operator uint) *Status {
 	return NewStatus(5, 0, operator)
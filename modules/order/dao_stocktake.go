@@ -0,0 +1,250 @@
+package order
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/xaxys/maintainman/core/dao"
+
+	"gorm.io/gorm"
+)
+
+func dbGetStocktakeByID(id uint) (*Stocktake, error) {
+	return txGetStocktakeByID(mctx.Database, id)
+}
+
+func txGetStocktakeByID(tx *gorm.DB, id uint) (*Stocktake, error) {
+	stocktake := &Stocktake{}
+	if err := tx.Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("item_id") }).First(stocktake, id).Error; err != nil {
+		mctx.Logger.Warnf("GetStocktakeByIDErr: %v\n", err)
+		return nil, err
+	}
+	return stocktake, nil
+}
+
+func dbGetAllStocktakes(aul *AllStocktakeRequest) (stocktakes []*Stocktake, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if stocktakes, count, err = txGetAllStocktakes(tx, aul); err != nil {
+			mctx.Logger.Warnf("GetAllStocktakesErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetAllStocktakes(tx *gorm.DB, aul *AllStocktakeRequest) (stocktakes []*Stocktake, count uint, err error) {
+	stocktake := &Stocktake{
+		WarehouseID: aul.WarehouseID,
+		State:       aul.State,
+	}
+	tx = dao.TxPageFilter(tx, &aul.PageParam).Model(stocktake).Where(stocktake)
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Find(&stocktakes).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbCreateStocktake(aul *CreateStocktakeRequest, operator uint) (stocktake *Stocktake, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if stocktake, err = txCreateStocktake(tx, aul, operator); err != nil {
+			mctx.Logger.Warnf("CreateStocktakeErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txCreateStocktake freezes the expected count of the items in the warehouse
+func txCreateStocktake(tx *gorm.DB, aul *CreateStocktakeRequest, operator uint) (stocktake *Stocktake, err error) {
+	if aul.WarehouseID != 0 {
+		if _, err = txGetWarehouseByID(tx, aul.WarehouseID); err != nil {
+			return
+		}
+	}
+	items := []*Item{}
+	if len(aul.Items) > 0 {
+		err = tx.Where("id IN (?)", aul.Items).Order("id").Find(&items).Error
+	} else {
+		err = tx.Order("id").Find(&items).Error
+	}
+	if err != nil {
+		return
+	}
+	if len(items) != len(aul.Items) && len(aul.Items) > 0 {
+		return nil, fmt.Errorf("部分物品不存在")
+	}
+	lines := []*StocktakeLine{}
+	for _, item := range items {
+		expected, err := txGetWarehouseItemCount(tx, aul.WarehouseID, item)
+		if err != nil {
+			return nil, err
+		}
+		line := &StocktakeLine{
+			ItemID:   item.ID,
+			Expected: expected,
+		}
+		line.CreatedBy = operator
+		lines = append(lines, line)
+	}
+	stocktake = &Stocktake{
+		WarehouseID: aul.WarehouseID,
+		State:       StocktakeCounting,
+		Note:        aul.Note,
+		Lines:       lines,
+	}
+	stocktake.CreatedBy = operator
+	if err = tx.Create(stocktake).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbCountStocktake(id uint, aul *CountStocktakeRequest, operator uint) (stocktake *Stocktake, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if stocktake, err = txCountStocktake(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("CountStocktakeErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txCountStocktake(tx *gorm.DB, id uint, aul *CountStocktakeRequest, operator uint) (stocktake *Stocktake, err error) {
+	if stocktake, err = txGetStocktakeByID(tx, id); err != nil {
+		return
+	}
+	if stocktake.State != StocktakeCounting {
+		return nil, fmt.Errorf("盘点不处于盘点中状态，不能录入")
+	}
+	lines := make(map[uint]*StocktakeLine)
+	for _, line := range stocktake.Lines {
+		lines[line.ItemID] = line
+	}
+	for _, count := range aul.Lines {
+		line, ok := lines[count.ItemID]
+		if !ok {
+			return nil, fmt.Errorf("盘点中没有物品 %d", count.ItemID)
+		}
+		var item *Item
+		if item, err = txGetItemByID(tx, count.ItemID); err != nil {
+			return
+		}
+		if err = checkItemNum(item, count.Counted); err != nil {
+			return
+		}
+		line.Counted = sql.NullFloat64{Float64: count.Counted, Valid: true}
+		line.UpdatedBy = operator
+		if err = tx.Model(line).Select("counted", "updated_by").Updates(line).Error; err != nil {
+			return
+		}
+	}
+	return
+}
+
+func dbChangeStocktakeState(id, state, operator uint) error {
+	return txChangeStocktakeState(mctx.Database, id, state, operator)
+}
+
+func txChangeStocktakeState(tx *gorm.DB, id, state, operator uint) error {
+	stocktake := &Stocktake{}
+	stocktake.ID = id
+	stocktake.State = state
+	stocktake.UpdatedBy = operator
+	if err := tx.Model(stocktake).Updates(stocktake).Error; err != nil {
+		mctx.Logger.Warnf("ChangeStocktakeStateErr: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func dbApproveStocktake(id, operator uint) (stocktake *Stocktake, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if stocktake, err = txApproveStocktake(tx, id, operator); err != nil {
+			mctx.Logger.Warnf("ApproveStocktakeErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txApproveStocktake writes an adjustment item log for each variance between the
+// counted and the frozen expected count.
+func txApproveStocktake(tx *gorm.DB, id, operator uint) (stocktake *Stocktake, err error) {
+	if stocktake, err = txGetStocktakeByID(tx, id); err != nil {
+		return
+	}
+	if stocktake.State != StocktakeReviewing {
+		return nil, fmt.Errorf("盘点不处于待审核状态，不能审核")
+	}
+	for _, line := range stocktake.Lines {
+		variance := line.Counted.Float64 - line.Expected
+		if variance == 0 {
+			continue
+		}
+		itemlog := &ItemLog{
+			Type:        ItemLogAdjust,
+			ItemID:      line.ItemID,
+			WarehouseID: stocktake.WarehouseID,
+			StocktakeID: stocktake.ID,
+			ChangeNum:   variance,
+		}
+		if err = txAdjustItem(tx, itemlog, operator); err != nil {
+			return
+		}
+	}
+	stocktake.State = StocktakeApproved
+	stocktake.ApprovedBy = operator
+	stocktake.ApprovedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	stocktake.UpdatedBy = operator
+	if err = tx.Model(stocktake).Select("state", "approved_by", "approved_at", "updated_by").Updates(stocktake).Error; err != nil {
+		return
+	}
+	return
+}
+
+// txAdjustItem corrects the count of the item. Surplus is valued at the current
+// average unit cost, and shortage is costed like consumption.
+func txAdjustItem(tx *gorm.DB, itemlog *ItemLog, operator uint) error {
+	itemlog.CreatedBy = operator
+	item, err := txGetItemByID(tx, itemlog.ItemID)
+	if err != nil {
+		return err
+	}
+	if itemlog.ChangeNum = roundItemNum(item, itemlog.ChangeNum); itemlog.ChangeNum == 0 {
+		return nil
+	}
+	if err := txChangeWarehouseStock(tx, itemlog.WarehouseID, item, itemlog.ChangeNum); err != nil {
+		return err
+	}
+	if itemlog.ChangeNum < 0 {
+		cost, err := txCostConsumption(tx, item, -itemlog.ChangeNum)
+		if err != nil {
+			return err
+		}
+		itemlog.Cost = -cost
+	} else {
+		unit, err := txGetUnitCost(tx, item)
+		if err != nil {
+			return err
+		}
+		itemlog.Cost = unit * itemlog.ChangeNum
+	}
+	item.Cost += itemlog.Cost
+	item.Count = roundItemNum(item, item.Count+itemlog.ChangeNum)
+	item.UpdatedBy = operator
+	if err := tx.Create(itemlog).Error; err != nil {
+		return err
+	}
+	if itemlog.ChangeNum > 0 {
+		if err := txCreateStockLot(tx, itemlog); err != nil {
+			return err
+		}
+	}
+	return tx.Save(item).Error
+}
@@ -1,10 +1,12 @@
 package order
 
 import (
+	"database/sql"
 	"fmt"
 
+	"github.com/xaxys/maintainman/core/util"
+
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 func dbGetTagByID(id uint) (*Tag, error) {
@@ -60,11 +62,65 @@ func txGetAllTagsBySort(tx *gorm.DB, sort string) (tags []*Tag, err error) {
 	return
 }
 
+func dbGetTagsByParentID(id uint) ([]*Tag, error) {
+	return txGetTagsByParentID(mctx.Database, id)
+}
+
+func txGetTagsByParentID(tx *gorm.DB, id uint) (tags []*Tag, err error) {
+	if id != 0 {
+		tx = tx.Where("parent_id = (?)", id)
+	} else {
+		tx = tx.Where("parent_id is null")
+	}
+	if err = tx.Find(&tags).Error; err != nil {
+		mctx.Logger.Warnf("GetTagsByParentIDErr: %v\n", err)
+	}
+	return
+}
+
+func txGetTagAncestorIDs(tx *gorm.DB, id uint) (ids []uint, err error) {
+	for id != 0 && !util.In(id, ids...) {
+		tag := &Tag{}
+		if err = tx.First(tag, id).Error; err != nil {
+			mctx.Logger.Warnf("GetTagAncestorIDsErr: %v\n", err)
+			return
+		}
+		ids = append(ids, id)
+		id = uint(tag.ParentID.Int64)
+	}
+	return
+}
+
+// txGetTagDescendantIDs returns the id itself followed by all its descendants.
+func txGetTagDescendantIDs(tx *gorm.DB, id uint) (ids []uint, err error) {
+	ids = []uint{id}
+	for parents := ids; len(parents) > 0; {
+		children := []uint{}
+		if err = tx.Model(&Tag{}).Where("parent_id IN (?)", parents).Pluck("id", &children).Error; err != nil {
+			mctx.Logger.Warnf("GetTagDescendantIDsErr: %v\n", err)
+			return
+		}
+		parents = []uint{}
+		for _, child := range children {
+			if !util.In(child, ids...) {
+				ids = append(ids, child)
+				parents = append(parents, child)
+			}
+		}
+	}
+	return
+}
+
 func dbCreateTag(aul *CreateTagRequest, operator uint) (*Tag, error) {
 	return txCreateTag(mctx.Database, aul, operator)
 }
 
 func txCreateTag(tx *gorm.DB, aul *CreateTagRequest, operator uint) (tag *Tag, err error) {
+	if aul.ParentID != 0 {
+		if _, err = txGetTagByID(tx, aul.ParentID); err != nil {
+			return
+		}
+	}
 	tag = jsonToTag(aul)
 	tag.CreatedBy = operator
 	cond := &Tag{
@@ -77,28 +133,140 @@ func txCreateTag(tx *gorm.DB, aul *CreateTagRequest, operator uint) (tag *Tag, e
 	return
 }
 
-func dbUpdateTag(id uint, aul *CreateTagRequest, operator uint) (*Tag, error) {
-	return txUpdateTag(mctx.Database, id, aul, operator)
+func dbUpdateTag(id uint, aul *UpdateTagRequest, operator uint) (tag *Tag, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if tag, err = txUpdateTag(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("UpdateTagErr: %v\n", err)
+		}
+		return err
+	})
+	return
 }
 
-func txUpdateTag(tx *gorm.DB, id uint, aul *CreateTagRequest, operator uint) (tag *Tag, err error) {
-	tag = jsonToTag(aul)
-	tag.ID = id
-	tag.UpdatedBy = operator
-	if err = tx.Model(tag).Updates(tag).Error; err != nil {
-		mctx.Logger.Warnf("UpdateTagErr: %v\n", err)
+func txUpdateTag(tx *gorm.DB, id uint, aul *UpdateTagRequest, operator uint) (tag *Tag, err error) {
+	if tag, err = txGetTagByID(tx, id); err != nil {
+		return
+	}
+	cond := &Tag{
+		Sort: util.Tenary(aul.Sort != "", aul.Sort, tag.Sort),
+		Name: util.Tenary(aul.Name != "", aul.Name, tag.Name),
+	}
+	cnt := int64(0)
+	if err = tx.Model(&Tag{}).Where(cond).Where("id <> ?", id).Count(&cnt).Error; err != nil {
+		return
+	}
+	if cnt > 0 {
+		return nil, fmt.Errorf("[%s] %s 标签已存在, 请使用合并", cond.Sort, cond.Name)
+	}
+	if aul.ParentID > 0 {
+		ancestors, err := txGetTagAncestorIDs(tx, uint(aul.ParentID))
+		if err != nil {
+			return nil, err
+		}
+		if util.In(id, ancestors...) {
+			return nil, fmt.Errorf("不能将标签移动到自身或其子标签下")
+		}
+	}
+	update := &Tag{
+		Sort: aul.Sort,
+		Name: aul.Name,
 	}
+	update.UpdatedBy = operator
+	if err = tx.Model(tag).Updates(update).Error; err != nil {
+		return
+	}
+	if aul.Level != nil {
+		if err = tx.Model(tag).Update("level", *aul.Level).Error; err != nil {
+			return
+		}
+	}
+	if aul.Congener != nil {
+		if err = tx.Model(tag).Update("congener", *aul.Congener).Error; err != nil {
+			return
+		}
+	}
+	if aul.ParentID != 0 {
+		if err = tx.Model(tag).Update("parent_id", sql.NullInt64{Int64: aul.ParentID, Valid: aul.ParentID != -1}).Error; err != nil {
+			return
+		}
+	}
+	return txGetTagByID(tx, id)
+}
+
+func dbMergeTag(id uint, aul *MergeTagRequest) (tag *Tag, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if tag, err = txMergeTag(tx, id, aul); err != nil {
+			mctx.Logger.Warnf("MergeTagErr: %v\n", err)
+		}
+		return err
+	})
 	return
 }
 
-func dbDeleteTag(id uint) error {
-	return txDeleteTag(mctx.Database, id)
+// txMergeTag moves the orders, children and rules of tag id onto the target tag and deletes tag id.
+func txMergeTag(tx *gorm.DB, id uint, aul *MergeTagRequest) (*Tag, error) {
+	if id == aul.TargetID {
+		return nil, fmt.Errorf("不能将标签合并到自身")
+	}
+	if _, err := txGetTagByID(tx, id); err != nil {
+		return nil, err
+	}
+	ancestors, err := txGetTagAncestorIDs(tx, aul.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if util.In(id, ancestors...) {
+		return nil, fmt.Errorf("不能将标签合并到其子标签")
+	}
+	tagged := []uint{}
+	if err := tx.Table("order_tags").Where("tag_id = ?", aul.TargetID).Pluck("order_id", &tagged).Error; err != nil {
+		return nil, err
+	}
+	moved := tx.Table("order_tags").Where("tag_id = ?", id)
+	if len(tagged) > 0 {
+		moved = moved.Where("order_id NOT IN (?)", tagged)
+	}
+	if err := moved.Update("tag_id", aul.TargetID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&Tag{}).Where("parent_id = ?", id).Update("parent_id", aul.TargetID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&TagRule{}).Where("tag_id = ?", id).Update("tag_id", aul.TargetID).Error; err != nil {
+		return nil, err
+	}
+	if err := txDeleteTag(tx, id); err != nil {
+		return nil, err
+	}
+	return txGetTagByID(tx, aul.TargetID)
+}
+
+func dbDeleteTag(id uint) (err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err = txDeleteTag(tx, id); err != nil {
+			mctx.Logger.Warnf("DeleteTagErr: %v\n", err)
+		}
+		return err
+	})
+	return
 }
 
+// txDeleteTag removes the tag from all orders, drops its rules and hands its children over to its parent.
 func txDeleteTag(tx *gorm.DB, id uint) (err error) {
-	if err = tx.Select(clause.Associations).Delete(&Tag{}, id).Error; err != nil {
-		mctx.Logger.Warnf("DeleteTagErr: %v\n", err)
+	tag, err := txGetTagByID(tx, id)
+	if err != nil {
+		return
+	}
+	if err = tx.Model(tag).Association("Orders").Clear(); err != nil {
+		return
+	}
+	if err = txDeleteTagRulesByTag(tx, id); err != nil {
+		return
+	}
+	if err = tx.Model(&Tag{}).Where("parent_id = ?", id).Update("parent_id", tag.ParentID).Error; err != nil {
+		return
 	}
+	err = tx.Delete(tag).Error
 	return
 }
 
@@ -121,5 +289,6 @@ func jsonToTag(aul *CreateTagRequest) *Tag {
 		Sort:     aul.Sort,
 		Level:    aul.Level,
 		Congener: aul.Congener,
+		ParentID: sql.NullInt64{Int64: int64(aul.ParentID), Valid: aul.ParentID != 0},
 	}
 }
@@ -0,0 +1,189 @@
+package order
+
+import (
+	"strings"
+
+	"github.com/xaxys/maintainman/core/dao"
+
+	"gorm.io/gorm"
+)
+
+func dbGetTagRuleByID(id uint) (*TagRule, error) {
+	return txGetTagRuleByID(mctx.Database, id)
+}
+
+func txGetTagRuleByID(tx *gorm.DB, id uint) (*TagRule, error) {
+	rule := &TagRule{}
+	if err := tx.Preload("Tag").Preload("Keywords").First(rule, id).Error; err != nil {
+		mctx.Logger.Warnf("GetTagRuleByIDErr: %v\n", err)
+		return nil, err
+	}
+	return rule, nil
+}
+
+func dbGetAllTagRules(aul *AllTagRuleRequest) (rules []*TagRule, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if rules, count, err = txGetAllTagRules(tx, aul); err != nil {
+			mctx.Logger.Warnf("GetAllTagRulesErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetAllTagRules(tx *gorm.DB, aul *AllTagRuleRequest) (rules []*TagRule, count uint, err error) {
+	rule := &TagRule{
+		TagID: aul.TagID,
+	}
+	tx = dao.TxPageFilter(tx, &aul.PageParam).Model(rule).Where(rule)
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Preload("Tag").Preload("Keywords").Find(&rules).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbGetTagRules(auto bool) ([]*TagRule, error) {
+	return txGetTagRules(mctx.Database, auto)
+}
+
+// txGetTagRules returns all the rules, or only the automatic ones if auto is set.
+func txGetTagRules(tx *gorm.DB, auto bool) (rules []*TagRule, err error) {
+	if auto {
+		tx = tx.Where("auto = ?", true)
+	}
+	if err = tx.Preload("Tag").Preload("Keywords").Order("id").Find(&rules).Error; err != nil {
+		mctx.Logger.Warnf("GetTagRulesErr: %v\n", err)
+	}
+	return
+}
+
+func dbCreateTagRule(aul *CreateTagRuleRequest, operator uint) (rule *TagRule, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if rule, err = txCreateTagRule(tx, aul, operator); err != nil {
+			mctx.Logger.Warnf("CreateTagRuleErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txCreateTagRule(tx *gorm.DB, aul *CreateTagRuleRequest, operator uint) (*TagRule, error) {
+	if _, err := txGetTagByID(tx, aul.TagID); err != nil {
+		return nil, err
+	}
+	rule := &TagRule{
+		TagID:    aul.TagID,
+		Auto:     aul.Auto,
+		Keywords: jsonToTagKeywords(aul.Keywords),
+	}
+	rule.CreatedBy = operator
+	if err := tx.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return txGetTagRuleByID(tx, rule.ID)
+}
+
+func dbUpdateTagRule(id uint, aul *UpdateTagRuleRequest, operator uint) (rule *TagRule, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if rule, err = txUpdateTagRule(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("UpdateTagRuleErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txUpdateTagRule(tx *gorm.DB, id uint, aul *UpdateTagRuleRequest, operator uint) (rule *TagRule, err error) {
+	if rule, err = txGetTagRuleByID(tx, id); err != nil {
+		return
+	}
+	if aul.TagID != 0 {
+		if _, err = txGetTagByID(tx, aul.TagID); err != nil {
+			return
+		}
+	}
+	update := &TagRule{
+		TagID: aul.TagID,
+	}
+	update.UpdatedBy = operator
+	if err = tx.Model(rule).Updates(update).Error; err != nil {
+		return
+	}
+	if aul.Auto != nil {
+		if err = tx.Model(rule).Update("auto", *aul.Auto).Error; err != nil {
+			return
+		}
+	}
+	if len(aul.Keywords) > 0 {
+		if err = tx.Where("rule_id = ?", id).Delete(&TagKeyword{}).Error; err != nil {
+			return
+		}
+		keywords := jsonToTagKeywords(aul.Keywords)
+		for _, keyword := range keywords {
+			keyword.RuleID = id
+		}
+		if len(keywords) > 0 {
+			if err = tx.Create(keywords).Error; err != nil {
+				return
+			}
+		}
+	}
+	return txGetTagRuleByID(tx, id)
+}
+
+func dbDeleteTagRule(id uint) (err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err = txDeleteTagRule(tx, id); err != nil {
+			mctx.Logger.Warnf("DeleteTagRuleErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txDeleteTagRule(tx *gorm.DB, id uint) error {
+	rule, err := txGetTagRuleByID(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Where("rule_id = ?", id).Delete(&TagKeyword{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(rule).Error
+}
+
+// txDeleteTagRulesByTag removes the rules pointing to a tag which is being deleted.
+func txDeleteTagRulesByTag(tx *gorm.DB, tagID uint) error {
+	ids := []uint{}
+	if err := tx.Model(&TagRule{}).Where("tag_id = ?", tagID).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("rule_id IN (?)", ids).Delete(&TagKeyword{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&TagRule{}, ids).Error
+}
+
+// normalizeTagKeyword makes keywords comparable with the segmented words.
+func normalizeTagKeyword(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
+
+func jsonToTagKeywords(words []string) (keywords []*TagKeyword) {
+	seen := map[string]bool{}
+	for _, word := range words {
+		if word = normalizeTagKeyword(word); word != "" && !seen[word] {
+			seen[word] = true
+			keywords = append(keywords, &TagKeyword{Word: word})
+		}
+	}
+	return
+}
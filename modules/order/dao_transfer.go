@@ -0,0 +1,133 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/dao"
+	"github.com/xaxys/maintainman/core/model"
+
+	"gorm.io/gorm"
+)
+
+func dbGetPendingTransferByOrder(id uint) (*Transfer, error) {
+	return txGetPendingTransferByOrder(mctx.Database, id)
+}
+
+func txGetPendingTransferByOrder(tx *gorm.DB, id uint) (*Transfer, error) {
+	transfer := &Transfer{
+		OrderID: id,
+		State:   TransferPending,
+	}
+	if err := tx.Where(transfer).Last(transfer).Error; err != nil {
+		mctx.Logger.Warnf("GetPendingTransferByOrderErr: %v\n", err)
+		return nil, err
+	}
+	return transfer, nil
+}
+
+func dbGetPendingTransfersByRepairer(id uint, param *model.PageParam) (transfers []*Transfer, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if transfers, count, err = txGetPendingTransfersByRepairer(tx, id, param); err != nil {
+			mctx.Logger.Warnf("GetPendingTransfersByRepairerErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetPendingTransfersByRepairer(tx *gorm.DB, id uint, param *model.PageParam) (transfers []*Transfer, count uint, err error) {
+	transfer := &Transfer{
+		ToID:  id,
+		State: TransferPending,
+	}
+	tx = dao.TxPageFilter(tx, param).Model(transfer).Where(transfer)
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Find(&transfers).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbCreateTransfer(transfer *Transfer, operator uint) (err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err = txCreateTransfer(tx, transfer, operator); err != nil {
+			mctx.Logger.Warnf("CreateTransferErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txCreateTransfer expires the previous pending transfer of the order, if any.
+func txCreateTransfer(tx *gorm.DB, transfer *Transfer, operator uint) error {
+	if err := txExpirePendingTransfers(tx, transfer.OrderID, operator); err != nil {
+		return err
+	}
+	transfer.State = TransferPending
+	transfer.CreatedBy = operator
+	return tx.Create(transfer).Error
+}
+
+func dbChangeTransferState(id, state, operator uint) error {
+	return txChangeTransferState(mctx.Database, id, state, operator)
+}
+
+func txChangeTransferState(tx *gorm.DB, id, state, operator uint) error {
+	transfer := &Transfer{}
+	transfer.ID = id
+	transfer.State = state
+	transfer.UpdatedBy = operator
+	if err := tx.Model(transfer).Updates(transfer).Error; err != nil {
+		mctx.Logger.Warnf("ChangeTransferStateErr: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func txExpirePendingTransfers(tx *gorm.DB, orderID, operator uint) error {
+	cond := &Transfer{
+		OrderID: orderID,
+		State:   TransferPending,
+	}
+	transfer := &Transfer{State: TransferExpired}
+	transfer.UpdatedBy = operator
+	return tx.Model(&Transfer{}).Where(cond).Updates(transfer).Error
+}
+
+func dbTransferOrder(id, repairer, operator uint, reason string) (err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err = txTransferOrder(tx, id, repairer, operator, reason); err != nil {
+			mctx.Logger.Warnf("TransferOrderErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txTransferOrder(tx *gorm.DB, id, repairer, operator uint, reason string) error {
+	if err := txExpirePendingTransfers(tx, id, operator); err != nil {
+		return err
+	}
+	status := NewStatusTransferred(repairer, operator, reason)
+	return txChangeOrderStatus(tx, id, status)
+}
+
+func dbAcceptTransfer(transfer *Transfer, operator uint) (err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err = txAcceptTransfer(tx, transfer, operator); err != nil {
+			mctx.Logger.Warnf("AcceptTransferErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txAcceptTransfer(tx *gorm.DB, transfer *Transfer, operator uint) error {
+	if err := txChangeTransferState(tx, transfer.ID, TransferAccepted, operator); err != nil {
+		return err
+	}
+	status := NewStatusTransferred(transfer.ToID, operator, transfer.Reason)
+	return txChangeOrderStatus(tx, transfer.OrderID, status)
+}
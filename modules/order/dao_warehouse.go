@@ -0,0 +1,279 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/dao"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func dbGetWarehouseByID(id uint) (*Warehouse, error) {
+	return txGetWarehouseByID(mctx.Database, id)
+}
+
+func txGetWarehouseByID(tx *gorm.DB, id uint) (*Warehouse, error) {
+	warehouse := &Warehouse{}
+	if err := tx.Preload("Keepers").First(warehouse, id).Error; err != nil {
+		mctx.Logger.Warnf("GetWarehouseByIDErr: %v\n", err)
+		return nil, err
+	}
+	return warehouse, nil
+}
+
+func dbGetAllWarehouses(param *model.PageParam) (warehouses []*Warehouse, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if warehouses, count, err = txGetAllWarehouses(tx, param); err != nil {
+			mctx.Logger.Warnf("GetAllWarehousesErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetAllWarehouses(tx *gorm.DB, param *model.PageParam) (warehouses []*Warehouse, count uint, err error) {
+	tx = dao.TxPageFilter(tx, param).Model(&Warehouse{})
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Preload("Keepers").Find(&warehouses).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbIsWarehouseKeeper(id, user uint) bool {
+	keeper := &WarehouseKeeper{
+		WarehouseID: id,
+		UserID:      user,
+	}
+	cnt := int64(0)
+	if err := mctx.Database.Model(keeper).Where(keeper).Count(&cnt).Error; err != nil {
+		mctx.Logger.Warnf("IsWarehouseKeeperErr: %v\n", err)
+		return false
+	}
+	return cnt > 0
+}
+
+func dbCreateWarehouse(aul *CreateWarehouseRequest, operator uint) (warehouse *Warehouse, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if warehouse, err = txCreateWarehouse(tx, aul, operator); err != nil {
+			mctx.Logger.Warnf("CreateWarehouseErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txCreateWarehouse(tx *gorm.DB, aul *CreateWarehouseRequest, operator uint) (*Warehouse, error) {
+	warehouse := &Warehouse{
+		Name:    aul.Name,
+		Address: aul.Address,
+		Keepers: jsonToKeepers(aul.Keepers),
+	}
+	warehouse.CreatedBy = operator
+	if err := tx.Create(warehouse).Error; err != nil {
+		return nil, err
+	}
+	return warehouse, nil
+}
+
+func dbUpdateWarehouse(id uint, aul *UpdateWarehouseRequest, operator uint) (warehouse *Warehouse, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if warehouse, err = txUpdateWarehouse(tx, id, aul, operator); err != nil {
+			mctx.Logger.Warnf("UpdateWarehouseErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txUpdateWarehouse(tx *gorm.DB, id uint, aul *UpdateWarehouseRequest, operator uint) (warehouse *Warehouse, err error) {
+	if warehouse, err = txGetWarehouseByID(tx, id); err != nil {
+		return
+	}
+	update := &Warehouse{
+		Name:    aul.Name,
+		Address: aul.Address,
+	}
+	update.UpdatedBy = operator
+	if err = tx.Model(warehouse).Updates(update).Error; err != nil {
+		return
+	}
+	if aul.Keepers != nil {
+		if err = tx.Where("warehouse_id = ?", id).Delete(&WarehouseKeeper{}).Error; err != nil {
+			return
+		}
+		keepers := jsonToKeepers(aul.Keepers)
+		for _, keeper := range keepers {
+			keeper.WarehouseID = id
+		}
+		if len(keepers) > 0 {
+			if err = tx.Create(keepers).Error; err != nil {
+				return
+			}
+		}
+	}
+	return txGetWarehouseByID(tx, id)
+}
+
+func dbDeleteWarehouse(id uint) error {
+	return mctx.Database.Transaction(func(tx *gorm.DB) error {
+		err := txDeleteWarehouse(tx, id)
+		if err != nil {
+			mctx.Logger.Warnf("DeleteWarehouseErr: %v\n", err)
+		}
+		return err
+	})
+}
+
+// txDeleteWarehouse only deletes warehouses without stock
+func txDeleteWarehouse(tx *gorm.DB, id uint) error {
+	cnt := int64(0)
+	if err := tx.Model(&WarehouseStock{}).Where("warehouse_id = ? AND count <> 0", id).Count(&cnt).Error; err != nil {
+		return err
+	}
+	if cnt > 0 {
+		return fmt.Errorf("仓库中仍有库存，不能删除")
+	}
+	if err := tx.Where("warehouse_id = ?", id).Delete(&WarehouseKeeper{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("warehouse_id = ?", id).Delete(&WarehouseStock{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&Warehouse{}, id).Error
+}
+
+func dbGetWarehouseStocks(id uint) ([]*WarehouseStockJson, error) {
+	return txGetWarehouseStocks(mctx.Database, id)
+}
+
+// txGetWarehouseStocks returns the stock of the central warehouse when id is 0
+func txGetWarehouseStocks(tx *gorm.DB, id uint) (stocks []*WarehouseStockJson, err error) {
+	if id == 0 {
+		sum := tx.Model(&WarehouseStock{}).Select("COALESCE(SUM(count), 0)").Where("item_id = items.id")
+		err = tx.Model(&Item{}).
+			Select("0 AS warehouse_id, items.id AS item_id, items.name AS name, ROUND(items.count - (?), items.precision) AS count", sum).
+			Order("items.id").
+			Scan(&stocks).Error
+	} else {
+		err = tx.Model(&WarehouseStock{}).
+			Select("warehouse_stocks.warehouse_id, warehouse_stocks.item_id, items.name, warehouse_stocks.count").
+			Joins("JOIN items ON items.id = warehouse_stocks.item_id AND items.deleted_at IS NULL").
+			Where("warehouse_stocks.warehouse_id = ?", id).
+			Order("warehouse_stocks.item_id").
+			Scan(&stocks).Error
+	}
+	if err != nil {
+		mctx.Logger.Warnf("GetWarehouseStocksErr: %v\n", err)
+		return nil, err
+	}
+	return
+}
+
+// txGetWarehouseItemCount returns the count of the item in the warehouse,
+// the count in the central warehouse is the rest of all warehouses.
+func txGetWarehouseItemCount(tx *gorm.DB, id uint, item *Item) (float64, error) {
+	count := 0.0
+	if id == 0 {
+		if err := tx.Model(&WarehouseStock{}).Select("COALESCE(SUM(count), 0)").Where("item_id = ?", item.ID).Scan(&count).Error; err != nil {
+			return 0, err
+		}
+		return roundItemNum(item, item.Count-count), nil
+	}
+	stock := &WarehouseStock{
+		WarehouseID: id,
+		ItemID:      item.ID,
+	}
+	if err := tx.Where(stock).First(stock).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return stock.Count, nil
+}
+
+// txChangeWarehouseStock does nothing for the central warehouse, since its stock is derived.
+func txChangeWarehouseStock(tx *gorm.DB, id uint, item *Item, num float64) error {
+	if id == 0 {
+		return nil
+	}
+	if _, err := txGetWarehouseByID(tx, id); err != nil {
+		return err
+	}
+	stock := &WarehouseStock{
+		WarehouseID: id,
+		ItemID:      item.ID,
+		Count:       num,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "warehouse_id"}, {Name: "item_id"}},
+		DoUpdates: clause.Assignments(map[string]any{"count": gorm.Expr("ROUND(warehouse_stocks.count + ?, ?)", num, item.Precision)}),
+	}).Create(stock).Error
+}
+
+func dbTransferStock(aul *StockTransferRequest, operator uint) (transfer *StockTransfer, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if transfer, err = txTransferStock(tx, aul, operator); err != nil {
+			mctx.Logger.Warnf("TransferStockErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txTransferStock moves items between warehouses, writing a pair of item logs.
+// The total count of the item is unchanged.
+func txTransferStock(tx *gorm.DB, aul *StockTransferRequest, operator uint) (transfer *StockTransfer, err error) {
+	item, err := txGetItemByID(tx, aul.ItemID)
+	if err != nil {
+		return
+	}
+	if err = checkItemNum(item, aul.Num); err != nil {
+		return
+	}
+	stock, err := txGetWarehouseItemCount(tx, aul.FromID, item)
+	if err != nil {
+		return
+	}
+	if roundItemNum(item, stock-aul.Num) < 0 {
+		return nil, fmt.Errorf("item count in warehouse is not enough")
+	}
+	transfer = &StockTransfer{
+		ItemID: aul.ItemID,
+		FromID: aul.FromID,
+		ToID:   aul.ToID,
+		Num:    aul.Num,
+		Note:   aul.Note,
+	}
+	transfer.CreatedBy = operator
+	if err = tx.Create(transfer).Error; err != nil {
+		return
+	}
+	logs := []*ItemLog{
+		{Type: ItemLogTransfer, ItemID: aul.ItemID, WarehouseID: aul.FromID, TransferID: transfer.ID, ChangeNum: -aul.Num},
+		{Type: ItemLogTransfer, ItemID: aul.ItemID, WarehouseID: aul.ToID, TransferID: transfer.ID, ChangeNum: aul.Num},
+	}
+	for _, log := range logs {
+		log.CreatedBy = operator
+		if err = txChangeWarehouseStock(tx, log.WarehouseID, item, log.ChangeNum); err != nil {
+			return
+		}
+	}
+	if err = tx.Create(logs).Error; err != nil {
+		return
+	}
+	return
+}
+
+func jsonToKeepers(ids []uint) []*WarehouseKeeper {
+	return util.TransSlice(ids, func(id uint) *WarehouseKeeper { return &WarehouseKeeper{UserID: id} })
+}
@@ -0,0 +1,125 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xaxys/maintainman/core/dao"
+	"github.com/xaxys/maintainman/core/model"
+
+	"gorm.io/gorm"
+)
+
+func dbGetOpenWorkSessionByRepairer(id uint) (*WorkSession, error) {
+	return txGetOpenWorkSessionByRepairer(mctx.Database, id)
+}
+
+func txGetOpenWorkSessionByRepairer(tx *gorm.DB, id uint) (*WorkSession, error) {
+	session := &WorkSession{}
+	if err := tx.Where("repairer_id = ? AND end_at IS NULL", id).Last(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func dbGetWorkSessionsByOrder(id uint, param *model.PageParam) (sessions []*WorkSession, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if sessions, count, err = txGetWorkSessions(tx, &WorkSession{OrderID: id}, param); err != nil {
+			mctx.Logger.Warnf("GetWorkSessionsByOrderErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func dbGetWorkSessionsByRepairer(id uint, param *model.PageParam) (sessions []*WorkSession, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if sessions, count, err = txGetWorkSessions(tx, &WorkSession{RepairerID: id}, param); err != nil {
+			mctx.Logger.Warnf("GetWorkSessionsByRepairerErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetWorkSessions(tx *gorm.DB, cond *WorkSession, param *model.PageParam) (sessions []*WorkSession, count uint, err error) {
+	tx = dao.TxPageFilter(tx, param).Model(cond).Where(cond)
+	cnt := int64(0)
+	if err = tx.Count(&cnt).Error; err != nil || cnt == 0 {
+		return
+	}
+	count = uint(cnt)
+	if err = tx.Find(&sessions).Error; err != nil {
+		return
+	}
+	return
+}
+
+func dbStartWorkSession(session *WorkSession, operator uint) (err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err = txStartWorkSession(tx, session, operator); err != nil {
+			mctx.Logger.Warnf("StartWorkSessionErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txStartWorkSession rejects the session if the repairer already has one in progress
+func txStartWorkSession(tx *gorm.DB, session *WorkSession, operator uint) error {
+	if open, err := txGetOpenWorkSessionByRepairer(tx, session.RepairerID); err == nil {
+		return fmt.Errorf("维修工有尚未结束的工作记录 (订单ID: %d)", open.OrderID)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	session.StartAt = time.Now()
+	session.CreatedBy = operator
+	return tx.Create(session).Error
+}
+
+func dbStopWorkSession(session *WorkSession, operator uint) (err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err = txStopWorkSession(tx, session, operator); err != nil {
+			mctx.Logger.Warnf("StopWorkSessionErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txStopWorkSession closes the session and adds its duration to the labour time of the order
+func txStopWorkSession(tx *gorm.DB, session *WorkSession, operator uint) error {
+	now := time.Now()
+	session.EndAt = sql.NullTime{Time: now, Valid: true}
+	session.Duration = uint(now.Sub(session.StartAt) / time.Second)
+	session.UpdatedBy = operator
+	if err := tx.Select("end_at", "duration", "check_out_image", "check_out_lat", "check_out_lng", "updated_by").Updates(session).Error; err != nil {
+		return err
+	}
+	return tx.Model(&Order{}).Where("id = ?", session.OrderID).Update("labour_time", gorm.Expr("labour_time + ?", session.Duration)).Error
+}
+
+func dbGetLabourStat(aul *LabourStatRequest) (*LabourStatJson, error) {
+	return txGetLabourStat(mctx.Database, aul)
+}
+
+func txGetLabourStat(tx *gorm.DB, aul *LabourStatRequest) (*LabourStatJson, error) {
+	stat := &LabourStatJson{RepairerID: aul.RepairerID}
+	tx = tx.Model(&WorkSession{}).Where("end_at IS NOT NULL")
+	if aul.RepairerID != 0 {
+		tx = tx.Where("repairer_id = ?", aul.RepairerID)
+	}
+	if aul.Start != 0 {
+		tx = tx.Where("start_at >= ?", time.Unix(aul.Start, 0))
+	}
+	if aul.End != 0 {
+		tx = tx.Where("start_at < ?", time.Unix(aul.End, 0))
+	}
+	if err := tx.Select("COUNT(DISTINCT order_id) AS orders, COUNT(*) AS sessions, COALESCE(SUM(duration), 0) AS labour_time").Scan(stat).Error; err != nil {
+		mctx.Logger.Warnf("GetLabourStatErr: %v\n", err)
+		return nil, err
+	}
+	return stat, nil
+}
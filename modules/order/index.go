@@ -13,16 +13,36 @@ var Module module.Module
 func init() {
 	Module = module.Module{
 		ModuleName:    "order",
-		ModuleVersion: "1.1.0",
+		ModuleVersion: "1.9.1",
 		ModuleConfig:  orderConfig,
 		ModuleEnv: map[string]any{
 			"orm.model": []any{
 				&Tag{},
+				&TagRule{},
+				&TagKeyword{},
 				&Order{},
 				&Status{},
 				&Comment{},
 				&Item{},
+				&ItemBarcode{},
+				&ItemCategory{},
 				&ItemLog{},
+				&Transfer{},
+				&Escalation{},
+				&WorkSession{},
+				&Evidence{},
+				&Reservation{},
+				&Warehouse{},
+				&WarehouseKeeper{},
+				&WarehouseStock{},
+				&StockTransfer{},
+				&Supplier{},
+				&PurchaseOrder{},
+				&PurchaseLine{},
+				&StockLot{},
+				&Stocktake{},
+				&StocktakeLine{},
+				&Approval{},
 			},
 		},
 		ModuleExport: map[string]any{
@@ -39,36 +59,70 @@ func init() {
 			"wechat.comment.time":    "",
 		},
 		ModulePerm: map[string]string{
-			"order.view":        "查看我的订单",
-			"order.viewfix":     "查看我维修的订单",
-			"order.create":      "创建订单",
-			"order.cancel":      "取消订单",
-			"order.update":      "更新订单",
-			"order.updateall":   "更新所有订单",
-			"order.assign":      "分配订单",
-			"order.selfassign":  "给自己分配订单",
-			"order.release":     "释放订单",
-			"order.reject":      "拒绝订单",
-			"order.report":      "上报订单",
-			"order.hold":        "挂起订单",
-			"order.complete":    "完成订单",
-			"order.appraise":    "评价订单",
-			"order.viewall":     "查看所有订单",
-			"comment.view":      "查看我的评论",
-			"comment.create":    "创建评论",
-			"comment.delete":    "删除评论",
-			"comment.viewall":   "查看所有评论",
-			"comment.createall": "创建所有评论",
-			"comment.deleteall": "删除所有评论",
-			"tag.create":        "创建标签",
-			"tag.delete":        "删除标签",
-			"tag.view":          "查看标签",
-			"tag.add":           "添加标签",
-			"item.create":       "创建零件",
-			"item.delete":       "删除零件",
-			"item.viewall":      "查看所有零件",
-			"item.update":       "更新零件",
-			"item.consume":      "消耗零件",
+			"order.view":          "查看我的订单",
+			"order.viewfix":       "查看我维修的订单",
+			"order.create":        "创建订单",
+			"order.cancel":        "取消订单",
+			"order.update":        "更新订单",
+			"order.updateall":     "更新所有订单",
+			"order.assign":        "分配订单",
+			"order.selfassign":    "给自己分配订单",
+			"order.release":       "释放订单",
+			"order.reject":        "拒绝订单",
+			"order.report":        "上报订单",
+			"order.hold":          "挂起订单",
+			"order.complete":      "完成订单",
+			"order.appraise":      "评价订单",
+			"order.transfer":      "转单",
+			"order.claim":         "认领分组订单",
+			"order.work":          "记录工时",
+			"order.evidence":      "上传完工凭证",
+			"order.viewall":       "查看所有订单",
+			"comment.view":        "查看我的评论",
+			"comment.create":      "创建评论",
+			"comment.delete":      "删除评论",
+			"comment.viewall":     "查看所有评论",
+			"comment.createall":   "创建所有评论",
+			"comment.deleteall":   "删除所有评论",
+			"tag.create":          "创建标签",
+			"tag.update":          "更新标签",
+			"tag.merge":           "合并标签",
+			"tag.suggest":         "获取建议标签",
+			"tag.rule.viewall":    "查看所有自动标签规则",
+			"tag.rule.create":     "创建自动标签规则",
+			"tag.rule.update":     "更新自动标签规则",
+			"tag.rule.delete":     "删除自动标签规则",
+			"tag.delete":          "删除标签",
+			"tag.view":            "查看标签",
+			"tag.add":             "添加标签",
+			"item.create":         "创建零件",
+			"item.delete":         "删除零件",
+			"item.viewall":        "查看所有零件",
+			"item.update":         "更新零件",
+			"item.consume":        "消耗零件",
+			"warehouse.viewall":   "查看所有仓库",
+			"warehouse.create":    "创建仓库",
+			"warehouse.update":    "更新仓库",
+			"warehouse.delete":    "删除仓库",
+			"warehouse.manage":    "管理我负责的仓库库存",
+			"warehouse.manageall": "管理所有仓库库存",
+			"supplier.viewall":    "查看所有供应商",
+			"supplier.create":     "创建供应商",
+			"supplier.update":     "更新供应商",
+			"supplier.delete":     "删除供应商",
+			"purchase.viewall":    "查看所有采购单",
+			"purchase.create":     "创建采购单",
+			"purchase.update":     "更新采购单",
+			"purchase.delete":     "删除采购单",
+			"purchase.order":      "采购单下单",
+			"purchase.receive":    "采购单收货",
+			"stocktake.viewall":   "查看所有盘点",
+			"stocktake.create":    "创建盘点",
+			"stocktake.count":     "录入盘点数量",
+			"stocktake.approve":   "审核盘点",
+			"approval.view":       "查看我的审批申请",
+			"approval.viewall":    "查看所有审批申请",
+			"approval.approve":    "审批申请",
 		},
 		EntryPoint: entry,
 	}
@@ -92,7 +146,14 @@ func entry(ctx *module.ModuleContext) {
 	Module.ModuleExport["wechat.comment.message"] = orderConfig.GetString("notify.wechat.comment.message")
 	Module.ModuleExport["wechat.comment.time"] = orderConfig.GetString("notify.wechat.comment.time")
 
+	Module.ModuleExport["privacy.export"] = module.PrivacyExporter(exportPrivacyData)
+	Module.ModuleExport["privacy.erase"] = module.PrivacyEraser(erasePrivacyData)
+
 	mctx.Scheduler.Every(orderConfig.GetString("appraise.purge")).SingletonMode().Do(autoAppraiseOrderService)
+	mctx.Scheduler.Every(orderConfig.GetString("escalation.check")).SingletonMode().Do(AutoEscalateOrderService)
+	mctx.Scheduler.Every(1).Day().At(orderConfig.GetString("item.reorder.at")).SingletonMode().Do(autoReorderReportService)
+	autoTagOnce.Do(func() { go autoTagListener() })
+	refreshTagKeywords()
 
 	mctx.Route.Get("/wxtmpl/status", getWxStatusTemplateID)
 	mctx.Route.Get("/wxtmpl/comment", getWxCommentTemplateID)
@@ -102,6 +163,11 @@ func entry(ctx *module.ModuleContext) {
 		order.Get("/repairer", rbac.PermInterceptor("order.viewfix"), getRepairerOrders)
 		order.Get("/repairer/{id:uint}", rbac.PermInterceptor("order.viewall"), forceGetRepairerOrders)
 		order.Get("/all", rbac.PermInterceptor("order.viewall"), getAllOrders)
+		order.Get("/division", rbac.PermInterceptor("order.viewfix"), getDivisionOrders)
+		order.Get("/division/{id:uint}", rbac.PermInterceptor("order.viewall"), forceGetDivisionOrders)
+		order.Get("/work", rbac.PermInterceptor("order.viewfix"), getRepairerWorkSessions)
+		order.Get("/work/stat", rbac.PermInterceptor("order.viewall"), getLabourStat)
+		order.Get("/transfer", rbac.PermInterceptor("order.viewfix"), getPendingTransfers)
 		order.Post("/", rbac.PermInterceptor("order.create"), createOrder)
 
 		order.PartyFunc("/{id:uint}", func(orderID iris.Party) {
@@ -109,16 +175,31 @@ func entry(ctx *module.ModuleContext) {
 			orderID.Put("/", rbac.PermInterceptor("order.update"), updateOrder)
 			orderID.Put("/force", rbac.PermInterceptor("order.updateall"), forceUpdateOrder)
 			orderID.Post("/consume", rbac.PermInterceptor("item.consume"), consumeItem)
+			orderID.Get("/reserve", rbac.PermInterceptor("order.viewall"), getReservationsByOrder)
+			orderID.Post("/reserve", rbac.PermInterceptor("item.consume"), reserveItem)
+			orderID.Post("/reserve/return", rbac.PermInterceptor("item.consume"), returnReservation)
 			// change order status
 			orderID.Post("/release", rbac.PermInterceptor("order.update"), releaseOrder)
 			orderID.Post("/assign", rbac.PermInterceptor("order.assign"), assignOrder)
+			orderID.Post("/assign/division", rbac.PermInterceptor("order.assign"), assignOrderToDivision)
+			orderID.Post("/claim", rbac.PermInterceptor("order.claim"), claimOrder)
 			orderID.Post("/selfassign", rbac.PermInterceptor("order.selfassign"), selfAssignOrder)
+			orderID.Get("/evidence", rbac.PermInterceptor("order.viewall"), getEvidencesByOrder)
+			orderID.Post("/evidence", rbac.PermInterceptor("order.evidence"), createEvidence)
+			orderID.Post("/signature", rbac.PermInterceptor("order.evidence"), uploadSignature)
 			orderID.Post("/complete", rbac.PermInterceptor("order.complete"), completeOrder)
 			orderID.Post("/cancel", rbac.PermInterceptor("order.cancel"), cancelOrder)
 			orderID.Post("/reject", rbac.PermInterceptor("order.reject"), rejectOrder)
 			orderID.Post("/report", rbac.PermInterceptor("order.report"), reportOrder)
+			orderID.Get("/escalation", rbac.PermInterceptor("order.viewall"), getEscalationByOrder)
 			orderID.Post("/hold", rbac.PermInterceptor("order.hold"), holdOrder)
 			orderID.Post("/appraise", rbac.PermInterceptor("order.appraise"), appraiseOrder)
+			orderID.Get("/work", rbac.PermInterceptor("order.viewall"), getWorkSessionsByOrder)
+			orderID.Post("/work/start", rbac.PermInterceptor("order.work"), startWork)
+			orderID.Post("/work/stop", rbac.PermInterceptor("order.work"), stopWork)
+			orderID.Post("/transfer", rbac.PermInterceptor("order.transfer"), transferOrder)
+			orderID.Post("/transfer/accept", rbac.PermInterceptor("order.viewfix"), acceptTransfer)
+			orderID.Post("/transfer/reject", rbac.PermInterceptor("order.viewfix"), rejectTransfer)
 
 			orderID.PartyFunc("/comment", func(comment iris.Party) {
 				comment.Get("/", rbac.PermInterceptor("comment.view"), getCommentsByOrder)
@@ -133,7 +214,18 @@ func entry(ctx *module.ModuleContext) {
 		tag.Get("/{id:uint}", middleware.LoginInterceptor, getTagByID)
 		tag.Get("/sort", middleware.LoginInterceptor, getAllTagSorts)
 		tag.Get("/sort/{name:string}", middleware.LoginInterceptor, getAllTagsBySort)
+		tag.Get("/{id:uint}/children", middleware.LoginInterceptor, getTagsByParentID)
 		tag.Post("/", rbac.PermInterceptor("tag.create"), createTag)
+		tag.Put("/{id:uint}", rbac.PermInterceptor("tag.update"), updateTag)
+		tag.Post("/{id:uint}/merge", rbac.PermInterceptor("tag.merge"), mergeTag)
+		tag.Post("/suggest", rbac.PermInterceptor("tag.suggest"), suggestTags)
+		tag.PartyFunc("/rule", func(rule iris.Party) {
+			rule.Get("/all", rbac.PermInterceptor("tag.rule.viewall"), getAllTagRules)
+			rule.Get("/{id:uint}", rbac.PermInterceptor("tag.rule.viewall"), getTagRuleByID)
+			rule.Post("/", rbac.PermInterceptor("tag.rule.create"), createTagRule)
+			rule.Put("/{id:uint}", rbac.PermInterceptor("tag.rule.update"), updateTagRule)
+			rule.Delete("/{id:uint}", rbac.PermInterceptor("tag.rule.delete"), deleteTagRule)
+		})
 		tag.Delete("/{id:uint}", rbac.PermInterceptor("tag.delete"), deleteTag)
 	})
 
@@ -141,12 +233,76 @@ func entry(ctx *module.ModuleContext) {
 		item.Get("/name/{name:string}", rbac.PermInterceptor("item.viewall"), getItemByName)
 		item.Get("/name/{name:string}/fuzzy", rbac.PermInterceptor("item.viewall"), getItemsByFuzzyName)
 		item.Get("/all", rbac.PermInterceptor("item.viewall"), getAllItems)
+		item.Get("/barcode/{code:string}", rbac.PermInterceptor("item.viewall"), getItemByBarcode)
+		item.PartyFunc("/category", func(category iris.Party) {
+			category.Get("/all", rbac.PermInterceptor("item.viewall"), getAllCategories)
+			category.Get("/{id:uint}", rbac.PermInterceptor("item.viewall"), getCategoryByID)
+			category.Post("/", rbac.PermInterceptor("item.create"), createCategory)
+			category.Put("/{id:uint}", rbac.PermInterceptor("item.update"), updateCategory)
+			category.Delete("/{id:uint}", rbac.PermInterceptor("item.delete"), deleteCategory)
+		})
+		item.Get("/reorder", rbac.PermInterceptor("item.viewall"), getReorderReport)
+		item.Get("/valuation", rbac.PermInterceptor("item.viewall"), getValuation)
+		item.Get("/cost", rbac.PermInterceptor("item.viewall"), getConsumptionCost)
+		item.Get("/usage", rbac.PermInterceptor("item.viewall"), getUsage)
+		item.Get("/usage/top", rbac.PermInterceptor("item.viewall"), getTopUsage)
+		item.Get("/{id:uint}/forecast", rbac.PermInterceptor("item.viewall"), getForecast)
 		item.Get("/{id:uint}", rbac.PermInterceptor("item.viewall"), getItemByID)
 		item.Post("/", rbac.PermInterceptor("item.create"), createItem)
 		item.Post("/{id:uint}", rbac.PermInterceptor("item.update"), addItem)
+		item.Put("/{id:uint}", rbac.PermInterceptor("item.update"), updateItem)
+		item.Put("/{id:uint}/threshold", rbac.PermInterceptor("item.update"), updateItemThreshold)
 		item.Delete("/{id:uint}", rbac.PermInterceptor("item.delete"), deleteItem)
 	})
 
+	mctx.Route.PartyFunc("/warehouse", func(warehouse iris.Party) {
+		warehouse.Get("/all", rbac.PermInterceptor("warehouse.viewall"), getAllWarehouses)
+		warehouse.Get("/{id:uint}", rbac.PermInterceptor("warehouse.viewall"), getWarehouseByID)
+		warehouse.Get("/{id:uint}/stock", rbac.PermInterceptor("warehouse.viewall"), getWarehouseStocks)
+		warehouse.Post("/", rbac.PermInterceptor("warehouse.create"), createWarehouse)
+		warehouse.Post("/transfer", rbac.PermInterceptor("warehouse.manage"), transferStock)
+		warehouse.Post("/{id:uint}/stock", rbac.PermInterceptor("warehouse.manage"), stockWarehouse)
+		warehouse.Put("/{id:uint}", rbac.PermInterceptor("warehouse.update"), updateWarehouse)
+		warehouse.Delete("/{id:uint}", rbac.PermInterceptor("warehouse.delete"), deleteWarehouse)
+	})
+
+	mctx.Route.PartyFunc("/supplier", func(supplier iris.Party) {
+		supplier.Get("/all", rbac.PermInterceptor("supplier.viewall"), getAllSuppliers)
+		supplier.Get("/{id:uint}", rbac.PermInterceptor("supplier.viewall"), getSupplierByID)
+		supplier.Post("/", rbac.PermInterceptor("supplier.create"), createSupplier)
+		supplier.Put("/{id:uint}", rbac.PermInterceptor("supplier.update"), updateSupplier)
+		supplier.Delete("/{id:uint}", rbac.PermInterceptor("supplier.delete"), deleteSupplier)
+	})
+
+	mctx.Route.PartyFunc("/purchase", func(purchase iris.Party) {
+		purchase.Get("/all", rbac.PermInterceptor("purchase.viewall"), getAllPurchases)
+		purchase.Get("/{id:uint}", rbac.PermInterceptor("purchase.viewall"), getPurchaseByID)
+		purchase.Post("/", rbac.PermInterceptor("purchase.create"), createPurchase)
+		purchase.Put("/{id:uint}", rbac.PermInterceptor("purchase.update"), updatePurchase)
+		purchase.Delete("/{id:uint}", rbac.PermInterceptor("purchase.delete"), deletePurchase)
+		purchase.Post("/{id:uint}/order", rbac.PermInterceptor("purchase.order"), placePurchase)
+		purchase.Post("/{id:uint}/receive", rbac.PermInterceptor("purchase.receive"), receivePurchase)
+	})
+
+	mctx.Route.PartyFunc("/stocktake", func(stocktake iris.Party) {
+		stocktake.Get("/all", rbac.PermInterceptor("stocktake.viewall"), getAllStocktakes)
+		stocktake.Get("/{id:uint}", rbac.PermInterceptor("stocktake.viewall"), getStocktakeByID)
+		stocktake.Post("/", rbac.PermInterceptor("stocktake.create"), createStocktake)
+		stocktake.Put("/{id:uint}/count", rbac.PermInterceptor("stocktake.count"), countStocktake)
+		stocktake.Post("/{id:uint}/submit", rbac.PermInterceptor("stocktake.count"), submitStocktake)
+		stocktake.Post("/{id:uint}/cancel", rbac.PermInterceptor("stocktake.create"), cancelStocktake)
+		stocktake.Post("/{id:uint}/approve", rbac.PermInterceptor("stocktake.approve"), approveStocktake)
+		stocktake.Post("/{id:uint}/reject", rbac.PermInterceptor("stocktake.approve"), rejectStocktake)
+	})
+
+	mctx.Route.PartyFunc("/approval", func(approval iris.Party) {
+		approval.Get("/all", rbac.PermInterceptor("approval.viewall"), getAllApprovals)
+		approval.Get("/user", rbac.PermInterceptor("approval.view"), getUserApprovals)
+		approval.Get("/{id:uint}", rbac.PermInterceptor("approval.view"), getApprovalByID)
+		approval.Post("/{id:uint}/approve", rbac.PermInterceptor("approval.approve"), approveApproval)
+		approval.Post("/{id:uint}/deny", rbac.PermInterceptor("approval.approve"), denyApproval)
+	})
+
 	mctx.Route.PartyFunc("/comment", func(comment iris.Party) {
 		comment.Delete("/{id:uint}", rbac.PermInterceptor("comment.delete"), deleteComment)
 		comment.Delete("/{id:uint}/force", rbac.PermInterceptor("comment.deleteall"), forceDeleteComment)
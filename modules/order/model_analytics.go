@@ -0,0 +1,70 @@
+package order
+
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+const (
+	ForecastAverage   = "average"
+	ForecastSmoothing = "smoothing"
+)
+
+type UsageRequest struct {
+	ItemID uint   `json:"item_id" url:"item_id"`                                      // 物品ID 0:全部
+	Period string `json:"period"  url:"period" validate:"omitempty,oneof=week month"` // 统计周期 week:周 month:月 默认为月
+	Start  int64  `json:"start"   url:"start"`                                        // unix timestamp in seconds (UTC) 默认为12个周期前
+	End    int64  `json:"end"     url:"end"`                                          // unix timestamp in seconds (UTC) 默认为当前时间
+}
+
+type TopUsageRequest struct {
+	TagID      uint  `json:"tag_id"      url:"tag_id"`                   // 只统计带有该标签的订单 0:不限
+	DivisionID uint  `json:"division_id" url:"division_id"`              // 只统计指派给该分组的订单 0:不限
+	Start      int64 `json:"start"       url:"start"`                    // unix timestamp in seconds (UTC) 默认为0
+	End        int64 `json:"end"         url:"end"`                      // unix timestamp in seconds (UTC) 默认为当前时间
+	Limit      uint  `json:"limit"       url:"limit" validate:"lte=100"` // 返回的物品数量 默认为10
+}
+
+type ForecastRequest struct {
+	Period  string `json:"period"  url:"period" validate:"omitempty,oneof=week month"`        // 预测周期 week:周 month:月 默认为月
+	Periods uint   `json:"periods" url:"periods" validate:"lte=60"`                           // 参与预测的历史周期数 默认为配置值
+	Method  string `json:"method"  url:"method" validate:"omitempty,oneof=average smoothing"` // 预测方法 average:移动平均 smoothing:指数平滑 默认为配置值
+}
+
+type UsageJson struct {
+	Period string           `json:"period"` // 统计周期 week:周 month:月
+	Start  int64            `json:"start"`  // unix timestamp in seconds (UTC)
+	End    int64            `json:"end"`    // unix timestamp in seconds (UTC)
+	Items  []*ItemUsageJson `json:"items"`
+}
+
+type ItemUsageJson struct {
+	ItemID  uint               `json:"item_id"`
+	Name    string             `json:"name"`
+	Num     float64            `json:"num"`  // 统计区间内的消耗数量
+	Cost    float64            `json:"cost"` // 统计区间内的消耗成本
+	Periods []*PeriodUsageJson `json:"periods"`
+}
+
+type PeriodUsageJson struct {
+	Start int64   `json:"start"` // 周期开始时间 unix timestamp in seconds (UTC)
+	Num   float64 `json:"num"`   // 消耗数量
+	Cost  float64 `json:"cost"`  // 消耗成本
+}
+
+type TopUsageJson struct {
+	ItemID uint    `json:"item_id"`
+	Name   string  `json:"name"`
+	Num    float64 `json:"num"`    // 消耗数量
+	Cost   float64 `json:"cost"`   // 消耗成本
+	Orders uint    `json:"orders"` // 消耗该物品的订单数
+}
+
+type ForecastJson struct {
+	ItemID   uint               `json:"item_id"`
+	Period   string             `json:"period"`   // 预测周期 week:周 month:月
+	Method   string             `json:"method"`   // 预测方法 average:移动平均 smoothing:指数平滑
+	History  []*PeriodUsageJson `json:"history"`  // 参与预测的历史周期 不含当前周期
+	Start    int64              `json:"start"`    // 被预测周期的开始时间 unix timestamp in seconds (UTC)
+	Forecast float64            `json:"forecast"` // 被预测周期的需求量
+}
@@ -0,0 +1,71 @@
+package order
+
+import (
+	"database/sql"
+
+	"github.com/xaxys/maintainman/core/model"
+)
+
+const (
+	ApprovalConsume = "consume"
+	ApprovalCancel  = "cancel"
+)
+
+const (
+	_ = iota
+	ApprovalPending
+	ApprovalApproved
+	ApprovalDenied
+	ApprovalFailed
+)
+
+// Approval 审批申请 命中审批规则的操作不会立即执行 审批通过后以申请人身份执行
+type Approval struct {
+	model.BaseModel
+	Kind        string       `gorm:"not null; size:32; index; comment:类型 consume:消耗零件 cancel:取消订单"`
+	Rule        string       `gorm:"not null; size:191; comment:命中的审批规则名称"`
+	OrderID     uint         `gorm:"not null; index; comment:订单ID"`
+	RequesterID uint         `gorm:"not null; index; comment:申请人ID"`
+	Amount      float64      `gorm:"not null; default:0; comment:涉及金额"`
+	Payload     string       `gorm:"not null; type:text; comment:原操作参数 JSON"`
+	State       uint         `gorm:"not null; size:5; default:1; index; comment:状态 0:非法 1:待审批 2:已通过 3:已驳回 4:执行失败"`
+	ApproverID  uint         `gorm:"not null; default:0; comment:审批人ID"`
+	Comment     string       `gorm:"not null; size:191; default:''; comment:审批意见"`
+	Result      string       `gorm:"not null; size:191; default:''; comment:执行失败的原因"`
+	DecidedAt   sql.NullTime `gorm:"comment:审批时间"`
+}
+
+type ApprovalRule struct {
+	Name   string  `mapstructure:"name"`
+	Kind   string  `mapstructure:"kind"`   // consume:消耗零件 cancel:取消订单
+	Amount float64 `mapstructure:"amount"` // 消耗零件的收费金额超过该值时需要审批 0:不限
+	Status uint    `mapstructure:"status"` // 订单处于该状态时需要审批 0:不限
+	Tag    uint    `mapstructure:"tag"`    // 订单包含的 Tag 0:不限
+}
+
+type DecideApprovalRequest struct {
+	Comment string `json:"comment" validate:"lte=191"` // 审批意见
+}
+
+type AllApprovalRequest struct {
+	Kind        string `json:"kind"         url:"kind" validate:"omitempty,oneof=consume cancel"` // 类型 为空时不限
+	State       uint   `json:"state"        url:"state"`                                          // 状态 0:全部 1:待审批 2:已通过 3:已驳回 4:执行失败
+	RequesterID uint   `json:"requester_id" url:"requester_id"`                                   // 申请人ID 0:全部
+	model.PageParam
+}
+
+type ApprovalJson struct {
+	ID          uint    `json:"id"`
+	Kind        string  `json:"kind"` // 类型 consume:消耗零件 cancel:取消订单
+	Rule        string  `json:"rule"` // 命中的审批规则名称
+	OrderID     uint    `json:"order_id"`
+	RequesterID uint    `json:"requester_id"`
+	Amount      float64 `json:"amount"`      // 涉及金额
+	Payload     string  `json:"payload"`     // 原操作参数 JSON
+	State       uint    `json:"state"`       // 状态 0:非法 1:待审批 2:已通过 3:已驳回 4:执行失败
+	ApproverID  uint    `json:"approver_id"` // 审批人ID
+	Comment     string  `json:"comment"`     // 审批意见
+	Result      string  `json:"result"`      // 执行失败的原因
+	DecidedAt   int64   `json:"decided_at"`  // unix timestamp in seconds (UTC)
+	CreatedAt   int64   `json:"created_at"`  // unix timestamp in seconds (UTC)
+}
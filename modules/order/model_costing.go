@@ -0,0 +1,54 @@
+package order
+
+import "github.com/xaxys/maintainman/core/model"
+
+const (
+	CostingAverage = "average"
+	CostingFIFO    = "fifo"
+)
+
+// StockLot 入库批次 用于先进先出计价 无论采用何种计价方式都会按先进先出扣减批次剩余数量
+type StockLot struct {
+	model.BaseModel
+	ItemID    uint    `gorm:"not null; index; comment:物品ID"`
+	ItemLogID uint    `gorm:"not null; comment:入库日志ID"`
+	Num       float64 `gorm:"not null; comment:入库数量"`
+	Remain    float64 `gorm:"not null; comment:剩余数量"`
+	UnitCost  float64 `gorm:"not null; default:0; comment:单位成本"`
+}
+
+type ValuationRequest struct {
+	At int64 `json:"at" url:"at"` // unix timestamp in seconds (UTC) 默认为当前时间
+}
+
+type CostRequest struct {
+	Start int64 `json:"start" url:"start"` // unix timestamp in seconds (UTC) 默认为0
+	End   int64 `json:"end"   url:"end"`   // unix timestamp in seconds (UTC) 默认为当前时间
+}
+
+type ValuationJson struct {
+	At    int64                `json:"at"`    // unix timestamp in seconds (UTC)
+	Total float64              `json:"total"` // 库存总价值
+	Items []*ItemValuationJson `json:"items"`
+}
+
+type ItemValuationJson struct {
+	ItemID uint    `json:"item_id"`
+	Name   string  `json:"name"`
+	Count  float64 `json:"count"`
+	Value  float64 `json:"value"` // 库存价值
+}
+
+type CostJson struct {
+	Start  int64            `json:"start"` // unix timestamp in seconds (UTC)
+	End    int64            `json:"end"`   // unix timestamp in seconds (UTC)
+	Total  float64          `json:"total"` // 消耗成本合计
+	Items  []*CostEntryJson `json:"items"`
+	Orders []*CostEntryJson `json:"orders"`
+}
+
+type CostEntryJson struct {
+	ID   uint    `json:"id"`   // 物品ID 或 订单ID
+	Num  float64 `json:"num"`  // 消耗数量
+	Cost float64 `json:"cost"` // 消耗成本
+}
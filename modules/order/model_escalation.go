@@ -0,0 +1,39 @@
+package order
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+)
+
+// Escalation 上报订单的主管升级进度 每个订单至多一条
+type Escalation struct {
+	model.BaseModel
+	OrderID      uint         `gorm:"not null; uniqueIndex; comment:订单ID"`
+	Order        *Order       `gorm:"foreignkey:OrderID"`
+	Rule         string       `gorm:"not null; size:191; comment:升级规则名称"`
+	Level        uint         `gorm:"not null; default:0; comment:当前层级 从1开始"`
+	SupervisorID uint         `gorm:"not null; index; comment:当前主管ID"`
+	Deadline     sql.NullTime `gorm:"index; comment:升级截止时间 为空表示不再升级"`
+}
+
+type EscalationRule struct {
+	Name     string             `mapstructure:"name"`
+	Division uint               `mapstructure:"division"` // 上报人所在分组 (含下级分组) 0:不限
+	Tag      uint               `mapstructure:"tag"`      // 订单包含的 Tag 0:不限
+	Levels   []*EscalationLevel `mapstructure:"levels"`
+}
+
+type EscalationLevel struct {
+	Supervisor uint          `mapstructure:"supervisor"` // 主管用户ID
+	Timeout    time.Duration `mapstructure:"timeout"`    // 超过该时长仍未处理则升级至下一级主管
+}
+
+type EscalationJson struct {
+	OrderID      uint   `json:"order_id"`
+	Rule         string `json:"rule"`
+	Level        uint   `json:"level"`
+	SupervisorID uint   `json:"supervisor_id"`
+	Deadline     int64  `json:"deadline"` // unix timestamp in seconds (UTC) 0:不再升级
+}
@@ -0,0 +1,37 @@
+package order
+
+import "github.com/xaxys/maintainman/core/model"
+
+const (
+	EvidenceIllegal = iota
+	EvidenceBefore
+	EvidenceAfter
+	EvidenceSignature
+)
+
+type Evidence struct {
+	model.BaseModel
+	OrderID uint   `gorm:"not null; index; comment:订单ID"`
+	Kind    uint   `gorm:"not null; size:5; default:0; comment:类型 0:非法 1:维修前照片 2:维修后照片 3:客户签名"`
+	ImageID string `gorm:"not null; size:191; comment:图片ID"`
+}
+
+type CompletionRule struct {
+	Tag       uint `mapstructure:"tag"`
+	Photo     uint `mapstructure:"photo"`     // 至少需要的维修后照片数
+	Signature bool `mapstructure:"signature"` // 是否需要客户签名
+}
+
+type CreateEvidenceRequest struct {
+	Kind  uint   `json:"kind" validate:"required,oneof=1 2 3"` // 类型 1:维修前照片 2:维修后照片 3:客户签名
+	Image string `json:"image" validate:"required,uuid"`       // 图片ID
+}
+
+type EvidenceJson struct {
+	ID        uint   `json:"id"`
+	OrderID   uint   `json:"order_id"`
+	Kind      uint   `json:"kind"` // 类型 0:非法 1:维修前照片 2:维修后照片 3:客户签名
+	ImageID   string `json:"image_id"`
+	CreatedAt int64  `json:"created_at"` // unix timestamp in seconds (UTC)
+	CreatedBy uint   `json:"created_by"`
+}
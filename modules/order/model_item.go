@@ -1,29 +1,102 @@
 package order
 
-import "github.com/xaxys/maintainman/core/model"
+import (
+	"database/sql"
+
+	"github.com/xaxys/maintainman/core/model"
+)
 
 type Item struct {
 	model.BaseModel
-	Name        string     `gorm:"not null; size:191; unique; comment:物品名称"`
-	Description string     `gorm:"not null; comment:物品描述"`
-	Price       float64    `gorm:"not null; default:0; comment:物品总价值"`
-	Income      float64    `gorm:"not null; default:0; comment:维修收入"`
-	Count       int        `gorm:"not null; default:0; comment:物品数量"`
-	ItemLogs    []*ItemLog `gorm:"foreignkey:ItemID"`
+	Name        string         `gorm:"not null; size:191; unique; comment:物品名称"`
+	Description string         `gorm:"not null; comment:物品描述"`
+	CategoryID  uint           `gorm:"not null; default:0; index; comment:分类ID 0:未分类"`
+	Unit        string         `gorm:"not null; size:32; default:''; comment:计量单位"`
+	Precision   uint           `gorm:"not null; size:8; default:0; comment:数量允许的小数位数"`
+	SKU         sql.NullString `gorm:"size:64; uniqueIndex; comment:库存编码"`
+	Barcodes    []*ItemBarcode `gorm:"foreignkey:ItemID"`
+	Price       float64        `gorm:"not null; default:0; comment:物品总价值"`
+	Income      float64        `gorm:"not null; default:0; comment:维修收入"`
+	Cost        float64        `gorm:"not null; default:0; comment:库存成本"`
+	Count       float64        `gorm:"not null; default:0; comment:物品数量"`
+	Reserved    float64        `gorm:"not null; default:0; comment:已预留数量"`
+	MinCount    float64        `gorm:"not null; default:0; comment:最低库存 0:不预警"`
+	ReorderNum  float64        `gorm:"not null; default:0; comment:最小补货数量"`
+	ItemLogs    []*ItemLog     `gorm:"foreignkey:ItemID"`
+}
+
+// ItemBarcode 物品条码 一个物品可以有多个条码 如厂商条码与自贴条码
+type ItemBarcode struct {
+	Code   string `gorm:"primaryKey; size:128; comment:条码"`
+	ItemID uint   `gorm:"not null; index; comment:物品ID"`
+}
+
+// ItemCategory 物品分类
+type ItemCategory struct {
+	model.BaseModel
+	Name        string `gorm:"not null; size:191; uniqueIndex; comment:分类名称"`
+	Description string `gorm:"not null; size:191; default:''; comment:分类描述"`
 }
 
 type CreateItemRequest struct {
+	Name        string   `json:"name" validate:"required,lte=191"`
+	Description string   `json:"description" validate:"lte=65535"`
+	CategoryID  uint     `json:"category_id"`                                      // 分类ID 0:未分类
+	Unit        string   `json:"unit" validate:"lte=32"`                           // 计量单位 如 个 米 升
+	Precision   uint     `json:"precision" validate:"lte=6"`                       // 数量允许的小数位数 0:只能为整数
+	SKU         string   `json:"sku" validate:"lte=64"`                            // 库存编码
+	Barcodes    []string `json:"barcodes" validate:"unique,dive,required,lte=128"` // 条码
+	MinCount    float64  `json:"min_count" validate:"gte=0"`                       // 最低库存 0:不预警
+	ReorderNum  float64  `json:"reorder_num" validate:"gte=0"`                     // 最小补货数量
+}
+
+type UpdateItemRequest struct {
+	Name        string   `json:"name" validate:"lte=191"`
+	Description string   `json:"description" validate:"lte=65535"`
+	CategoryID  uint     `json:"category_id"`                                                // 分类ID 0:不修改
+	Unit        string   `json:"unit" validate:"lte=32"`                                     // 计量单位
+	Precision   *uint    `json:"precision" validate:"omitempty,lte=6"`                       // 数量允许的小数位数 为空时不修改
+	SKU         string   `json:"sku" validate:"lte=64"`                                      // 库存编码
+	Barcodes    []string `json:"barcodes" validate:"omitempty,unique,dive,required,lte=128"` // 条码 不传时不修改
+}
+
+type AllItemRequest struct {
+	CategoryID uint `json:"category_id" url:"category_id"` // 分类ID 0:全部
+	model.PageParam
+}
+
+type CreateCategoryRequest struct {
 	Name        string `json:"name" validate:"required,lte=191"`
-	Discription string `json:"discription" validate:"lte=65535"`
+	Description string `json:"description" validate:"lte=191"`
+}
+
+type UpdateCategoryRequest struct {
+	Name        string `json:"name" validate:"lte=191"`
+	Description string `json:"description" validate:"lte=191"`
+}
+
+type UpdateItemThresholdRequest struct {
+	MinCount   float64 `json:"min_count" validate:"gte=0"`   // 最低库存 0:不预警
+	ReorderNum float64 `json:"reorder_num" validate:"gte=0"` // 最小补货数量
 }
 
 type ItemInfoJson struct {
 	ID          uint           `json:"id"`
 	Name        string         `json:"name"`
-	Description string         `json:"discription"`
+	Description string         `json:"description"`
+	CategoryID  uint           `json:"category_id"` // 分类ID 0:未分类
+	Unit        string         `json:"unit"`        // 计量单位
+	Precision   uint           `json:"precision"`   // 数量允许的小数位数
+	SKU         string         `json:"sku"`         // 库存编码
+	Barcodes    []string       `json:"barcodes"`    // 条码
 	Price       float64        `json:"price"`
 	Income      float64        `json:"income"`
-	Count       int            `json:"count"`
+	Cost        float64        `json:"cost"`        // 库存成本
+	Count       float64        `json:"count"`       // 实际库存
+	Reserved    float64        `json:"reserved"`    // 已预留数量
+	Available   float64        `json:"available"`   // 可用数量 实际库存-已预留数量
+	MinCount    float64        `json:"min_count"`   // 最低库存 0:不预警
+	ReorderNum  float64        `json:"reorder_num"` // 最小补货数量
 	ItemLogs    []*ItemLogJson `json:"item_log"`
 	CreatedAt   int64          `json:"created_at"` // unix timestamp in seconds (UTC)
 	UpdatedAt   int64          `json:"updated_at"` // unix timestamp in seconds (UTC)
@@ -32,8 +105,32 @@ type ItemInfoJson struct {
 }
 
 type ItemJson struct {
+	ID          uint    `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	CategoryID  uint    `json:"category_id"` // 分类ID 0:未分类
+	Unit        string  `json:"unit"`        // 计量单位
+	SKU         string  `json:"sku"`         // 库存编码
+	Count       float64 `json:"count"`       // 实际库存
+	Reserved    float64 `json:"reserved"`    // 已预留数量
+	Available   float64 `json:"available"`   // 可用数量 实际库存-已预留数量
+	MinCount    float64 `json:"min_count"`   // 最低库存 0:不预警
+}
+
+type CategoryJson struct {
 	ID          uint   `json:"id"`
 	Name        string `json:"name"`
-	Description string `json:"discription"`
-	Count       int    `json:"count"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"created_at"` // unix timestamp in seconds (UTC)
+	UpdatedAt   int64  `json:"updated_at"` // unix timestamp in seconds (UTC)
+}
+
+type ReorderJson struct {
+	ItemID    uint    `json:"item_id"`
+	Name      string  `json:"name"`
+	Count     float64 `json:"count"`
+	MinCount  float64 `json:"min_count"`
+	Consumed  float64 `json:"consumed"`  // 统计周期内的消耗数量
+	Forecast  float64 `json:"forecast"`  // 下一统计周期的预测需求 未启用预测时为0
+	Suggested float64 `json:"suggested"` // 建议补货数量
 }
@@ -2,35 +2,59 @@ package order
 
 import "github.com/xaxys/maintainman/core/model"
 
+const (
+	ItemLogUnknown = iota
+	ItemLogAdd
+	ItemLogConsume
+	ItemLogTransfer
+	ItemLogAdjust
+)
+
 type ItemLog struct {
 	model.BaseModel
+	Type        uint    `gorm:"not null; size:5; default:0; index; comment:类型 0:未知 1:入库 2:消耗 3:调拨 4:盘点调整"`
 	ItemID      uint    `gorm:"not null; comment:物品ID"`
 	Item        *Item   `gorm:"foreignkey:ItemID;"`
 	OrderID     uint    `gorm:"not null; comment:订单ID"`
 	Order       *Order  `gorm:"foreignkey:OrderID;"`
-	ChangeNum   int     `gorm:"not null; default:0; comment:增加/消耗数量 正:增加 负:减少"`
+	WarehouseID uint    `gorm:"not null; default:0; comment:仓库ID 0:总库"`
+	TransferID  uint    `gorm:"not null; default:0; index; comment:调拨ID 0:非调拨"`
+	SupplierID  uint    `gorm:"not null; default:0; index; comment:供应商ID 0:无"`
+	PurchaseID  uint    `gorm:"not null; default:0; index; comment:采购单ID 0:无"`
+	StocktakeID uint    `gorm:"not null; default:0; index; comment:盘点ID 0:无"`
+	ChangeNum   float64 `gorm:"not null; default:0; comment:增加/消耗数量 正:增加 负:减少"`
 	ChangePrice float64 `gorm:"not null; default:0; comment:开销 正:进货 负:订单收费"`
+	Cost        float64 `gorm:"not null; default:0; comment:成本 正:入库价值 负:消耗成本"`
 }
 
 type AddItemRequest struct {
-	ItemID uint    `json:"item_id"`
-	Num    uint    `json:"num"`
-	Price  float64 `json:"price"`
+	ItemID      uint    `json:"item_id"`
+	WarehouseID uint    `json:"warehouse_id"` // 0:总库
+	Num         float64 `json:"num" validate:"gte=0"`
+	Price       float64 `json:"price"`
 }
 
 type ConsumeItemRequest struct {
-	ItemID  uint    `json:"item_id"`
-	OrderID uint    `json:"order_id"`
-	Num     uint    `json:"num"`
-	Price   float64 `json:"price"`
+	ItemID      uint    `json:"item_id"`
+	OrderID     uint    `json:"order_id"`
+	WarehouseID uint    `json:"warehouse_id"` // 0:总库
+	Num         float64 `json:"num" validate:"gte=0"`
+	Price       float64 `json:"price"`
 }
 
 type ItemLogJson struct {
 	ID          uint    `json:"id"`
+	Type        uint    `json:"type"` // 类型 0:未知 1:入库 2:消耗 3:调拨 4:盘点调整
 	ItemID      uint    `json:"item_id"`
 	OrderID     uint    `json:"order_id"`
-	ChangeNum   int     `json:"change_num"`   // 增加/消耗数量 正:增加 负:减少
+	WarehouseID uint    `json:"warehouse_id"` // 0:总库
+	TransferID  uint    `json:"transfer_id"`  // 0:非调拨
+	SupplierID  uint    `json:"supplier_id"`  // 0:无
+	PurchaseID  uint    `json:"purchase_id"`  // 采购单ID 0:无
+	StocktakeID uint    `json:"stocktake_id"` // 盘点ID 0:无
+	ChangeNum   float64 `json:"change_num"`   // 增加/消耗数量 正:增加 负:减少
 	ChangePrice float64 `json:"change_price"` // 开销 正:进货 负:订单收费
+	Cost        float64 `json:"cost"`         // 成本 正:入库价值 负:消耗成本
 	CreatedAt   int64   `json:"created_at"`   // unix timestamp in seconds (UTC)
 	CreatedBy   uint    `json:"created_by"`   // unix timestamp in seconds (UTC)
 }
@@ -7,20 +7,22 @@ import (
 
 type Order struct {
 	model.BaseModel
-	UserID       uint       `gorm:"not null; index:idx_order_user_status,priority:1; comment:用户ID"`
-	User         *user.User `gorm:"foreignkey:UserID"`
-	Title        string     `gorm:"not null; index; size:191; comment:标题"`
-	Content      string     `gorm:"not null; comment:内容"`
-	Address      string     `gorm:"not null; comment:地址"`
-	ContactName  string     `gorm:"not null; size:191; comment:联系人"`
-	ContactPhone string     `gorm:"not null; size:191; comment:联系电话"`
-	Status       uint       `gorm:"not null; size:5; default:0; index:idx_order_user_status,priority:2; comment:状态 0:非法 1:待处理 2:已接单 3:已完成 4:上报中 5:挂单 6:已取消 7:已拒绝 8:已评价"`
-	StatusList   []*Status  `gorm:"foreignkey:OrderID"`
-	AllowComment uint       `gorm:"not null; size:2 default:1; comment:是否允许评论 1:允许 2:不允许"`
-	Comments     []*Comment `gorm:"foreignkey:OrderID"`
-	ItemLogs     []*ItemLog `gorm:"foreignkey:OrderID"`
-	Tags         []*Tag     `gorm:"many2many:order_tags;"`
-	Appraisal    uint       `gorm:"not null; size:5 default:0; comment:评价 0:未评价 1-5:已评价"`
+	UserID       uint        `gorm:"not null; index:idx_order_user_status,priority:1; comment:用户ID"`
+	User         *user.User  `gorm:"foreignkey:UserID"`
+	Title        string      `gorm:"not null; index; size:191; comment:标题"`
+	Content      string      `gorm:"not null; comment:内容"`
+	Address      string      `gorm:"not null; comment:地址"`
+	ContactName  string      `gorm:"not null; size:191; comment:联系人"`
+	ContactPhone string      `gorm:"not null; size:191; comment:联系电话"`
+	Status       uint        `gorm:"not null; size:5; default:0; index:idx_order_user_status,priority:2; comment:状态 0:非法 1:待处理 2:已接单 3:已完成 4:上报中 5:挂单 6:已取消 7:已拒绝 8:已评价"`
+	StatusList   []*Status   `gorm:"foreignkey:OrderID"`
+	AllowComment uint        `gorm:"not null; size:2 default:1; comment:是否允许评论 1:允许 2:不允许"`
+	Comments     []*Comment  `gorm:"foreignkey:OrderID"`
+	ItemLogs     []*ItemLog  `gorm:"foreignkey:OrderID"`
+	Evidences    []*Evidence `gorm:"foreignkey:OrderID"`
+	Tags         []*Tag      `gorm:"many2many:order_tags;"`
+	Appraisal    uint        `gorm:"not null; size:5 default:0; comment:评价 0:未评价 1-5:已评价"`
+	LabourTime   uint        `gorm:"not null; default:0; comment:累计工时(秒)"`
 }
 
 type CreateOrderRequest struct {
@@ -48,6 +50,7 @@ type AllOrderRequest struct {
 	Status      uint   `json:"status"      url:"status"`      // 状态 0:非法 1:待处理 2:已接单 3:已完成 4:上报中 5:挂单 6:已取消 7:已拒绝 8:已评价
 	Tags        []uint `json:"tags"        url:"tags"`        // 若干 Tag 的 ID
 	Disjunctive bool   `json:"disjunctive" url:"disjunctive"` // false: 查询包含所有Tag的订单, true: 查询包含任一Tag的订单
+	Descendants bool   `json:"descendants" url:"descendants"` // 是否将各Tag的子标签视为与该Tag等同
 	model.PageParam
 }
 
@@ -61,25 +64,34 @@ type UserOrderRequest struct {
 type RepairerOrderRequest struct {
 	Status      uint   `url:"status"`
 	Current     bool   `url:"current"`
+	Division    bool   `url:"division"` // 是否包含指派给本人所在分组 (及其上级分组) 待认领的订单
+	Tags        []uint `url:"tags"`
+	Disjunctive bool   `url:"disjunctive"`
+	model.PageParam
+}
+
+type DivisionOrderRequest struct {
 	Tags        []uint `url:"tags"`
 	Disjunctive bool   `url:"disjunctive"`
 	model.PageParam
 }
 
 type OrderJson struct {
-	ID           uint           `json:"id"`
-	UserID       uint           `json:"user_id"`
-	User         *user.UserJson `json:"user,omitempty"`
-	Title        string         `json:"title"`
-	Content      string         `json:"content"`
-	Address      string         `json:"address"`
-	ContactName  string         `json:"contact_name"`
-	ContactPhone string         `json:"contact_phone"`
-	Status       uint           `json:"status"`
-	AllowComment bool           `json:"allow_comment"`
-	CreatedAt    int64          `json:"created_at"` // unix timestamp in seconds (UTC)
-	UpdatedAt    int64          `json:"updated_at"` // unix timestamp in seconds (UTC)
-	Appraisal    uint           `json:"appraisal"`
-	Tags         []*TagJson     `json:"tags,omitempty"`
-	Comments     []*CommentJson `json:"comments,omitempty"`
+	ID           uint            `json:"id"`
+	UserID       uint            `json:"user_id"`
+	User         *user.UserJson  `json:"user,omitempty"`
+	Title        string          `json:"title"`
+	Content      string          `json:"content"`
+	Address      string          `json:"address"`
+	ContactName  string          `json:"contact_name"`
+	ContactPhone string          `json:"contact_phone"`
+	Status       uint            `json:"status"`
+	AllowComment bool            `json:"allow_comment"`
+	CreatedAt    int64           `json:"created_at"` // unix timestamp in seconds (UTC)
+	UpdatedAt    int64           `json:"updated_at"` // unix timestamp in seconds (UTC)
+	Appraisal    uint            `json:"appraisal"`
+	LabourTime   uint            `json:"labour_time"` // 累计工时 单位秒
+	Tags         []*TagJson      `json:"tags,omitempty"`
+	Comments     []*CommentJson  `json:"comments,omitempty"`
+	Evidences    []*EvidenceJson `json:"evidences,omitempty"`
 }
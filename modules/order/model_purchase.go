@@ -0,0 +1,119 @@
+package order
+
+import "github.com/xaxys/maintainman/core/model"
+
+const (
+	PurchaseIllegal = iota
+	PurchaseDraft
+	PurchaseOrdered
+	PurchasePartial
+	PurchaseReceived
+)
+
+type Supplier struct {
+	model.BaseModel
+	Name    string `gorm:"not null; size:191; uniqueIndex; comment:供应商名称"`
+	Contact string `gorm:"not null; size:191; default:''; comment:联系人"`
+	Phone   string `gorm:"not null; size:191; default:''; comment:联系电话"`
+	Address string `gorm:"not null; size:191; default:''; comment:地址"`
+	Note    string `gorm:"not null; size:191; default:''; comment:备注"`
+}
+
+type PurchaseOrder struct {
+	model.BaseModel
+	SupplierID uint            `gorm:"not null; index; comment:供应商ID"`
+	Supplier   *Supplier       `gorm:"foreignkey:SupplierID"`
+	State      uint            `gorm:"not null; size:5; default:1; index; comment:状态 0:非法 1:草稿 2:已下单 3:部分到货 4:已到货"`
+	Note       string          `gorm:"not null; size:191; default:''; comment:备注"`
+	Lines      []*PurchaseLine `gorm:"foreignkey:PurchaseOrderID"`
+}
+
+type PurchaseLine struct {
+	model.BaseModel
+	PurchaseOrderID uint    `gorm:"not null; index; comment:采购单ID"`
+	ItemID          uint    `gorm:"not null; comment:物品ID"`
+	Item            *Item   `gorm:"foreignkey:ItemID"`
+	Num             float64 `gorm:"not null; comment:采购数量"`
+	Received        float64 `gorm:"not null; default:0; comment:已到货数量"`
+	Price           float64 `gorm:"not null; default:0; comment:单价"`
+}
+
+type CreateSupplierRequest struct {
+	Name    string `json:"name" validate:"required,lte=191"`
+	Contact string `json:"contact" validate:"lte=191"`
+	Phone   string `json:"phone" validate:"lte=191"`
+	Address string `json:"address" validate:"lte=191"`
+	Note    string `json:"note" validate:"lte=191"`
+}
+
+type UpdateSupplierRequest struct {
+	Name    string `json:"name" validate:"lte=191"`
+	Contact string `json:"contact" validate:"lte=191"`
+	Phone   string `json:"phone" validate:"lte=191"`
+	Address string `json:"address" validate:"lte=191"`
+	Note    string `json:"note" validate:"lte=191"`
+}
+
+type PurchaseLineRequest struct {
+	ItemID uint    `json:"item_id" validate:"required"`
+	Num    float64 `json:"num" validate:"gt=0"`
+	Price  float64 `json:"price" validate:"gte=0"` // 单价
+}
+
+type CreatePurchaseRequest struct {
+	SupplierID uint                   `json:"supplier_id" validate:"required"`
+	Note       string                 `json:"note" validate:"lte=191"`
+	Lines      []*PurchaseLineRequest `json:"lines" validate:"required,min=1,dive"`
+}
+
+type UpdatePurchaseRequest struct {
+	SupplierID uint                   `json:"supplier_id"`
+	Note       string                 `json:"note" validate:"lte=191"`
+	Lines      []*PurchaseLineRequest `json:"lines" validate:"omitempty,dive"` // 为空时不修改
+}
+
+type AllPurchaseRequest struct {
+	SupplierID uint `json:"supplier_id" url:"supplier_id"`
+	State      uint `json:"state"       url:"state"` // 状态 0:全部 1:草稿 2:已下单 3:部分到货 4:已到货
+	model.PageParam
+}
+
+type ReceiveLineRequest struct {
+	ItemID uint    `json:"item_id" validate:"required"`
+	Num    float64 `json:"num" validate:"gt=0"`
+}
+
+type ReceivePurchaseRequest struct {
+	WarehouseID uint                  `json:"warehouse_id"` // 入库仓库ID 0:总库
+	Lines       []*ReceiveLineRequest `json:"lines" validate:"required,min=1,dive"`
+}
+
+type SupplierJson struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Contact   string `json:"contact"`
+	Phone     string `json:"phone"`
+	Address   string `json:"address"`
+	Note      string `json:"note"`
+	CreatedAt int64  `json:"created_at"` // unix timestamp in seconds (UTC)
+	UpdatedAt int64  `json:"updated_at"` // unix timestamp in seconds (UTC)
+}
+
+type PurchaseOrderJson struct {
+	ID         uint                `json:"id"`
+	SupplierID uint                `json:"supplier_id"`
+	State      uint                `json:"state"` // 状态 0:非法 1:草稿 2:已下单 3:部分到货 4:已到货
+	Note       string              `json:"note"`
+	Lines      []*PurchaseLineJson `json:"lines"`
+	CreatedAt  int64               `json:"created_at"` // unix timestamp in seconds (UTC)
+	UpdatedAt  int64               `json:"updated_at"` // unix timestamp in seconds (UTC)
+	CreatedBy  uint                `json:"created_by"`
+	UpdatedBy  uint                `json:"updated_by"`
+}
+
+type PurchaseLineJson struct {
+	ItemID   uint    `json:"item_id"`
+	Num      float64 `json:"num"`
+	Received float64 `json:"received"` // 已到货数量
+	Price    float64 `json:"price"`    // 单价
+}
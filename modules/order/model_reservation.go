@@ -0,0 +1,30 @@
+package order
+
+import "github.com/xaxys/maintainman/core/model"
+
+// Reservation 订单预留的零件 每个订单每种零件至多一条
+type Reservation struct {
+	model.BaseModel
+	OrderID uint    `gorm:"not null; uniqueIndex:idx_reservation_order_item,priority:1; comment:订单ID"`
+	Order   *Order  `gorm:"foreignkey:OrderID"`
+	ItemID  uint    `gorm:"not null; uniqueIndex:idx_reservation_order_item,priority:2; comment:物品ID"`
+	Item    *Item   `gorm:"foreignkey:ItemID"`
+	Num     float64 `gorm:"not null; default:0; comment:预留数量"`
+}
+
+type ReserveItemRequest struct {
+	ItemID  uint    `json:"item_id" validate:"required"`
+	OrderID uint    `json:"order_id"`
+	Num     float64 `json:"num" validate:"gt=0"`
+}
+
+type ReservationJson struct {
+	ID        uint    `json:"id"`
+	OrderID   uint    `json:"order_id"`
+	ItemID    uint    `json:"item_id"`
+	Num       float64 `json:"num"`        // 预留数量
+	CreatedAt int64   `json:"created_at"` // unix timestamp in seconds (UTC)
+	UpdatedAt int64   `json:"updated_at"` // unix timestamp in seconds (UTC)
+	CreatedBy uint    `json:"created_by"`
+	UpdatedBy uint    `json:"updated_by"`
+}
@@ -0,0 +1,78 @@
+package order
+
+import (
+	"database/sql"
+
+	"github.com/xaxys/maintainman/core/model"
+)
+
+const (
+	StocktakeIllegal = iota
+	StocktakeCounting
+	StocktakeReviewing
+	StocktakeApproved
+	StocktakeCanceled
+)
+
+// Stocktake 盘点 创建时冻结各物品的账面数量 审核通过后按差异写入调整日志
+type Stocktake struct {
+	model.BaseModel
+	WarehouseID uint             `gorm:"not null; default:0; comment:仓库ID 0:总库"`
+	State       uint             `gorm:"not null; size:5; default:1; index; comment:状态 0:非法 1:盘点中 2:待审核 3:已通过 4:已取消"`
+	Note        string           `gorm:"not null; size:191; default:''; comment:备注"`
+	ApprovedBy  uint             `gorm:"not null; default:0; comment:审核人ID"`
+	ApprovedAt  sql.NullTime     `gorm:"comment:审核时间"`
+	Lines       []*StocktakeLine `gorm:"foreignkey:StocktakeID"`
+}
+
+type StocktakeLine struct {
+	model.BaseModel
+	StocktakeID uint            `gorm:"not null; index; comment:盘点ID"`
+	ItemID      uint            `gorm:"not null; comment:物品ID"`
+	Item        *Item           `gorm:"foreignkey:ItemID"`
+	Expected    float64         `gorm:"not null; comment:账面数量"`
+	Counted     sql.NullFloat64 `gorm:"comment:实盘数量 为空表示未盘点"`
+}
+
+type CreateStocktakeRequest struct {
+	WarehouseID uint   `json:"warehouse_id"`            // 0:总库
+	Items       []uint `json:"items" validate:"unique"` // 盘点的物品ID 为空时盘点所有物品
+	Note        string `json:"note" validate:"lte=191"`
+}
+
+type CountStocktakeRequest struct {
+	Lines []*CountLineRequest `json:"lines" validate:"required,min=1,dive"`
+}
+
+type CountLineRequest struct {
+	ItemID  uint    `json:"item_id" validate:"required"`
+	Counted float64 `json:"counted" validate:"gte=0"`
+}
+
+type AllStocktakeRequest struct {
+	WarehouseID uint `json:"warehouse_id" url:"warehouse_id"`
+	State       uint `json:"state"        url:"state"` // 状态 0:全部 1:盘点中 2:待审核 3:已通过 4:已取消
+	model.PageParam
+}
+
+type StocktakeJson struct {
+	ID          uint                 `json:"id"`
+	WarehouseID uint                 `json:"warehouse_id"` // 0:总库
+	State       uint                 `json:"state"`        // 状态 0:非法 1:盘点中 2:待审核 3:已通过 4:已取消
+	Note        string               `json:"note"`
+	Lines       []*StocktakeLineJson `json:"lines"`
+	ApprovedBy  uint                 `json:"approved_by"`
+	ApprovedAt  int64                `json:"approved_at"` // unix timestamp in seconds (UTC)
+	CreatedAt   int64                `json:"created_at"`  // unix timestamp in seconds (UTC)
+	UpdatedAt   int64                `json:"updated_at"`  // unix timestamp in seconds (UTC)
+	CreatedBy   uint                 `json:"created_by"`
+	UpdatedBy   uint                 `json:"updated_by"`
+}
+
+type StocktakeLineJson struct {
+	ItemID    uint    `json:"item_id"`
+	Expected  float64 `json:"expected"`   // 账面数量
+	Counted   float64 `json:"counted"`    // 实盘数量
+	IsCounted bool    `json:"is_counted"` // 是否已盘点
+	Variance  float64 `json:"variance"`   // 差异 实盘数量-账面数量
+}
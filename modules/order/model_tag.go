@@ -1,14 +1,20 @@
 package order
 
-import "github.com/xaxys/maintainman/core/model"
+import (
+	"database/sql"
+
+	"github.com/xaxys/maintainman/core/model"
+)
 
 type Tag struct {
 	model.BaseModel
-	Sort     string   `gorm:"not null; size:191; index:idx_tag_sort_name,priority:1; comment:分类"`
-	Name     string   `gorm:"not null; size:191; index:idx_tag_sort_name,priority:2; comment:标签名称"`
-	Level    uint     `gorm:"not null; size:5; default:0; comment:标签等级"`
-	Congener uint     `gorm:"not null; default:0; comment:同类型数量"`
-	Orders   []*Order `gorm:"many2many:order_tags;"`
+	Sort     string        `gorm:"not null; size:191; index:idx_tag_sort_name,priority:1; comment:分类"`
+	Name     string        `gorm:"not null; size:191; index:idx_tag_sort_name,priority:2; comment:标签名称"`
+	Level    uint          `gorm:"not null; size:5; default:0; comment:标签等级"`
+	Congener uint          `gorm:"not null; default:0; comment:同类型数量"`
+	ParentID sql.NullInt64 `gorm:"index; comment:父标签ID"`
+	Children []*Tag        `gorm:"foreignkey:ParentID"`
+	Orders   []*Order      `gorm:"many2many:order_tags;"`
 }
 
 type CreateTagRequest struct {
@@ -16,12 +22,27 @@ type CreateTagRequest struct {
 	Name     string `json:"name" validate:"required,lte=191"`
 	Level    uint   `json:"level" validate:"required,gte=0,lte=63"`
 	Congener uint   `json:"congener" validate:"omitempty,gte=0"` // 允许与同Sort的Tag共存的数量 0:不限 n:只允许n个(含自身)
+	ParentID uint   `json:"parent_id"`                           // 父标签ID 0:无
+}
+
+type UpdateTagRequest struct {
+	Sort     string `json:"sort" validate:"omitempty,lte=191"`
+	Name     string `json:"name" validate:"omitempty,lte=191"`
+	Level    *uint  `json:"level" validate:"omitempty,gte=0,lte=63"`
+	Congener *uint  `json:"congener" validate:"omitempty,gte=0"`
+	ParentID int64  `json:"parent_id" validate:"omitempty,gte=-1"` // -1: 修改为null 0: 不修改 n: 修改为指定的标签
+}
+
+type MergeTagRequest struct {
+	TargetID uint `json:"target_id" validate:"required"` // 合并到的目标标签ID, 原标签的订单与子标签将转移到目标标签, 原标签被删除
 }
 
 type TagJson struct {
-	ID       uint   `json:"id"`
-	Sort     string `json:"sort"`
-	Name     string `json:"name"`
-	Level    uint   `json:"level"`
-	Congener uint   `json:"congener"` // 允许与同Sort的Tag共存的数量 0:不限 n:只允许n个(含自身)
+	ID       uint       `json:"id"`
+	Sort     string     `json:"sort"`
+	Name     string     `json:"name"`
+	Level    uint       `json:"level"`
+	Congener uint       `json:"congener"`  // 允许与同Sort的Tag共存的数量 0:不限 n:只允许n个(含自身)
+	ParentID uint       `json:"parent_id"` // 父标签ID 0:无
+	Children []*TagJson `json:"children,omitempty"`
 }
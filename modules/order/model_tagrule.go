@@ -0,0 +1,49 @@
+package order
+
+import "github.com/xaxys/maintainman/core/model"
+
+type TagRule struct {
+	model.BaseModel
+	TagID    uint          `gorm:"not null; index; comment:标签ID"`
+	Tag      *Tag          `gorm:"foreignkey:TagID"`
+	Auto     bool          `gorm:"not null; default:false; comment:是否自动添加"`
+	Keywords []*TagKeyword `gorm:"foreignkey:RuleID"`
+}
+
+type TagKeyword struct {
+	RuleID uint   `gorm:"primaryKey; comment:规则ID"`
+	Word   string `gorm:"primaryKey; size:191; comment:关键词"`
+}
+
+type CreateTagRuleRequest struct {
+	TagID    uint     `json:"tag_id" validate:"required"`
+	Keywords []string `json:"keywords" validate:"required,min=1,dive,required,lte=191"` // 关键词及其同义词, 分词结果命中任一即匹配
+	Auto     bool     `json:"auto"`                                                     // true: 创建订单时自动添加 false: 仅作为建议
+}
+
+type UpdateTagRuleRequest struct {
+	TagID    uint     `json:"tag_id"`
+	Keywords []string `json:"keywords" validate:"omitempty,dive,required,lte=191"` // 为空时不修改
+	Auto     *bool    `json:"auto"`
+}
+
+type AllTagRuleRequest struct {
+	TagID uint `json:"tag_id" url:"tag_id"`
+	model.PageParam
+}
+
+type SuggestTagRequest struct {
+	Title   string `json:"title" validate:"lte=191"`
+	Content string `json:"content" validate:"lte=65535"`
+	Tags    []uint `json:"tags"` // 已选择的 Tag 的 ID, 用于排除已选标签及检查同类型数量
+}
+
+type TagRuleJson struct {
+	ID        uint     `json:"id"`
+	TagID     uint     `json:"tag_id"`
+	Tag       *TagJson `json:"tag,omitempty"`
+	Keywords  []string `json:"keywords"`
+	Auto      bool     `json:"auto"`
+	CreatedAt int64    `json:"created_at"` // unix timestamp in seconds (UTC)
+	UpdatedAt int64    `json:"updated_at"` // unix timestamp in seconds (UTC)
+}
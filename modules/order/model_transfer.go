@@ -0,0 +1,38 @@
+package order
+
+import "github.com/xaxys/maintainman/core/model"
+
+const (
+	TransferIllegal = iota
+	TransferPending
+	TransferAccepted
+	TransferRejected
+	TransferExpired
+)
+
+type Transfer struct {
+	model.BaseModel
+	OrderID uint   `gorm:"not null; index:idx_transfer_order_state,priority:1; comment:订单ID"`
+	Order   *Order `gorm:"foreignkey:OrderID"`
+	FromID  uint   `gorm:"not null; comment:原维修员ID"`
+	ToID    uint   `gorm:"not null; index; comment:接收维修员ID"`
+	Reason  string `gorm:"not null; size:191; comment:转单原因"`
+	State   uint   `gorm:"not null; size:5; default:0; index:idx_transfer_order_state,priority:2; comment:状态 0:非法 1:待确认 2:已接受 3:已拒绝 4:已失效"`
+}
+
+type TransferOrderRequest struct {
+	RepairerID uint   `json:"repairer_id" validate:"required"`
+	Reason     string `json:"reason" validate:"required,lte=191"`
+	Consent    bool   `json:"consent"` // false: 直接转单 true: 需接收人同意后转单
+}
+
+type TransferJson struct {
+	ID        uint   `json:"id"`
+	OrderID   uint   `json:"order_id"`
+	FromID    uint   `json:"from_id"` // 原维修员ID
+	ToID      uint   `json:"to_id"`   // 接收维修员ID
+	Reason    string `json:"reason"`
+	State     uint   `json:"state"`      // 状态 0:非法 1:待确认 2:已接受 3:已拒绝 4:已失效
+	CreatedAt int64  `json:"created_at"` // unix timestamp in seconds (UTC)
+	UpdatedAt int64  `json:"updated_at"` // unix timestamp in seconds (UTC)
+}
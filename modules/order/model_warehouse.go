@@ -0,0 +1,87 @@
+package order
+
+import "github.com/xaxys/maintainman/core/model"
+
+// Warehouse 仓库 ID为0的仓库为总库 不在表中存储
+// 总库库存为物品总数减去各仓库库存之和
+type Warehouse struct {
+	model.BaseModel
+	Name    string             `gorm:"not null; size:191; uniqueIndex; comment:仓库名称"`
+	Address string             `gorm:"not null; size:191; default:''; comment:仓库地址"`
+	Keepers []*WarehouseKeeper `gorm:"foreignkey:WarehouseID"`
+}
+
+// WarehouseKeeper 仓库管理员 只能管理自己负责的仓库
+type WarehouseKeeper struct {
+	WarehouseID uint `gorm:"primaryKey; autoIncrement:false; comment:仓库ID"`
+	UserID      uint `gorm:"primaryKey; autoIncrement:false; index; comment:管理员ID"`
+}
+
+type WarehouseStock struct {
+	model.BaseModel
+	WarehouseID uint       `gorm:"not null; uniqueIndex:idx_warehouse_stock,priority:1; comment:仓库ID"`
+	Warehouse   *Warehouse `gorm:"foreignkey:WarehouseID"`
+	ItemID      uint       `gorm:"not null; uniqueIndex:idx_warehouse_stock,priority:2; comment:物品ID"`
+	Item        *Item      `gorm:"foreignkey:ItemID"`
+	Count       float64    `gorm:"not null; default:0; comment:库存数量"`
+}
+
+// StockTransfer 仓库间调拨 对应两条 ItemLog
+type StockTransfer struct {
+	model.BaseModel
+	ItemID uint    `gorm:"not null; index; comment:物品ID"`
+	Item   *Item   `gorm:"foreignkey:ItemID"`
+	FromID uint    `gorm:"not null; comment:调出仓库ID 0:总库"`
+	ToID   uint    `gorm:"not null; comment:调入仓库ID 0:总库"`
+	Num    float64 `gorm:"not null; comment:调拨数量"`
+	Note   string  `gorm:"not null; size:191; default:''; comment:备注"`
+}
+
+type CreateWarehouseRequest struct {
+	Name    string `json:"name" validate:"required,lte=191"`
+	Address string `json:"address" validate:"lte=191"`
+	Keepers []uint `json:"keepers" validate:"unique"` // 管理员ID
+}
+
+type UpdateWarehouseRequest struct {
+	Name    string `json:"name" validate:"lte=191"`
+	Address string `json:"address" validate:"lte=191"`
+	Keepers []uint `json:"keepers" validate:"unique"` // 管理员ID 为空时不修改
+}
+
+type StockTransferRequest struct {
+	ItemID uint    `json:"item_id" validate:"required"`
+	FromID uint    `json:"from_id"` // 调出仓库ID 0:总库
+	ToID   uint    `json:"to_id"`   // 调入仓库ID 0:总库
+	Num    float64 `json:"num" validate:"gt=0"`
+	Note   string  `json:"note" validate:"lte=191"`
+}
+
+type WarehouseJson struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	Keepers   []uint `json:"keepers"`    // 管理员ID
+	CreatedAt int64  `json:"created_at"` // unix timestamp in seconds (UTC)
+	UpdatedAt int64  `json:"updated_at"` // unix timestamp in seconds (UTC)
+	CreatedBy uint   `json:"created_by"`
+	UpdatedBy uint   `json:"updated_by"`
+}
+
+type WarehouseStockJson struct {
+	WarehouseID uint    `json:"warehouse_id"` // 0:总库
+	ItemID      uint    `json:"item_id"`
+	Name        string  `json:"name"` // 物品名称
+	Count       float64 `json:"count"`
+}
+
+type StockTransferJson struct {
+	ID        uint    `json:"id"`
+	ItemID    uint    `json:"item_id"`
+	FromID    uint    `json:"from_id"` // 调出仓库ID 0:总库
+	ToID      uint    `json:"to_id"`   // 调入仓库ID 0:总库
+	Num       float64 `json:"num"`
+	Note      string  `json:"note"`
+	CreatedAt int64   `json:"created_at"` // unix timestamp in seconds (UTC)
+	CreatedBy uint    `json:"created_by"`
+}
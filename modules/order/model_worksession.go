@@ -0,0 +1,58 @@
+package order
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+)
+
+type WorkSession struct {
+	model.BaseModel
+	OrderID       uint            `gorm:"not null; index; comment:订单ID"`
+	Order         *Order          `gorm:"foreignkey:OrderID"`
+	RepairerID    uint            `gorm:"not null; index:idx_worksession_repairer_end,priority:1; comment:维修员ID"`
+	StartAt       time.Time       `gorm:"not null; comment:开始时间"`
+	EndAt         sql.NullTime    `gorm:"index:idx_worksession_repairer_end,priority:2; comment:结束时间 为空表示进行中"`
+	Duration      uint            `gorm:"not null; default:0; comment:工时(秒)"`
+	CheckInImage  string          `gorm:"not null; size:191; default:''; comment:签到照片ID"`
+	CheckInLat    sql.NullFloat64 `gorm:"comment:签到纬度"`
+	CheckInLng    sql.NullFloat64 `gorm:"comment:签到经度"`
+	CheckOutImage string          `gorm:"not null; size:191; default:''; comment:签退照片ID"`
+	CheckOutLat   sql.NullFloat64 `gorm:"comment:签退纬度"`
+	CheckOutLng   sql.NullFloat64 `gorm:"comment:签退经度"`
+}
+
+type CheckWorkRequest struct {
+	Image     string   `json:"image" validate:"omitempty,uuid"`                 // 签到/签退照片ID (可选)
+	Latitude  *float64 `json:"latitude" validate:"omitempty,gte=-90,lte=90"`    // 纬度 (可选)
+	Longitude *float64 `json:"longitude" validate:"omitempty,gte=-180,lte=180"` // 经度 (可选)
+}
+
+type LabourStatRequest struct {
+	RepairerID uint  `url:"repairer"` // 维修工ID 0:所有维修工
+	Start      int64 `url:"start"`    // unix timestamp in seconds (UTC) 0:不限
+	End        int64 `url:"end"`      // unix timestamp in seconds (UTC) 0:不限
+}
+
+type WorkSessionJson struct {
+	ID            uint     `json:"id"`
+	OrderID       uint     `json:"order_id"`
+	RepairerID    uint     `json:"repairer_id"`
+	StartAt       int64    `json:"start_at"` // unix timestamp in seconds (UTC)
+	EndAt         int64    `json:"end_at"`   // unix timestamp in seconds (UTC) 0:进行中
+	Duration      uint     `json:"duration"` // 工时 单位秒
+	CheckInImage  string   `json:"check_in_image,omitempty"`
+	CheckInLat    *float64 `json:"check_in_lat,omitempty"`
+	CheckInLng    *float64 `json:"check_in_lng,omitempty"`
+	CheckOutImage string   `json:"check_out_image,omitempty"`
+	CheckOutLat   *float64 `json:"check_out_lat,omitempty"`
+	CheckOutLng   *float64 `json:"check_out_lng,omitempty"`
+}
+
+type LabourStatJson struct {
+	RepairerID uint `json:"repairer_id"` // 0:所有维修工
+	Orders     uint `json:"orders"`      // 涉及订单数
+	Sessions   uint `json:"sessions"`    // 已结束的工作记录数
+	LabourTime uint `json:"labour_time"` // 累计工时 单位秒
+}
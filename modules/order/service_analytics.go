@@ -0,0 +1,187 @@
+package order
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func getUsageService(aul *UsageRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	period := util.NotEmpty(aul.Period, PeriodMonth)
+	end := time.Now()
+	if aul.End != 0 {
+		end = time.Unix(aul.End, 0)
+	}
+	start := addPeriod(periodStart(end, period), period, -11)
+	if aul.Start != 0 {
+		start = time.Unix(aul.Start, 0)
+	}
+	ids := []uint{}
+	if aul.ItemID != 0 {
+		ids = append(ids, aul.ItemID)
+	}
+	logs, err := dbGetConsumptionLogs(ids, start, end)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	usages := map[uint]*ItemUsageJson{}
+	for _, log := range logs {
+		if usages[log.ItemID] == nil {
+			usages[log.ItemID] = &ItemUsageJson{ItemID: log.ItemID, Periods: emptyPeriods(start, end, period)}
+		}
+		usage := usages[log.ItemID]
+		usage.Num -= log.ChangeNum
+		usage.Cost -= log.Cost
+		index := periodIndex(start, log.CreatedAt, period)
+		usage.Periods[index].Num -= log.ChangeNum
+		usage.Periods[index].Cost -= log.Cost
+	}
+	ids = []uint{}
+	for id := range usages {
+		ids = append(ids, id)
+	}
+	items, err := dbGetItemsByIDs(ids)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	json := &UsageJson{
+		Period: period,
+		Start:  start.Unix(),
+		End:    end.Unix(),
+		Items: util.TransSlice(items, func(item *Item) *ItemUsageJson {
+			usages[item.ID].Name = item.Name
+			return usages[item.ID]
+		}),
+	}
+	return model.Success(json, "获取成功")
+}
+
+func getTopUsageService(aul *TopUsageRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	start, end := time.Unix(aul.Start, 0), time.Now()
+	if aul.End != 0 {
+		end = time.Unix(aul.End, 0)
+	}
+	limit := util.Tenary(aul.Limit == 0, 10, int(aul.Limit))
+	usages, err := dbGetTopUsage(aul, start, end, limit)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ids := util.TransSlice(usages, func(u *TopUsageJson) uint { return u.ItemID })
+	items, err := dbGetItemsByIDs(ids)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	names := map[uint]string{}
+	for _, item := range items {
+		names[item.ID] = item.Name
+	}
+	for _, usage := range usages {
+		usage.Name = names[usage.ItemID]
+	}
+	return model.Success(usages, "获取成功")
+}
+
+func getForecastService(id uint, aul *ForecastRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if _, err := dbGetItemByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	period := util.NotEmpty(aul.Period, PeriodMonth)
+	method := util.NotEmpty(aul.Method, orderConfig.GetString("item.forecast.method"))
+	periods := util.Tenary(aul.Periods == 0, orderConfig.GetInt("item.forecast.periods"), int(aul.Periods))
+	end := periodStart(time.Now(), period)
+	start := addPeriod(end, period, -periods)
+	logs, err := dbGetConsumptionLogs([]uint{id}, start, end)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	history := emptyPeriods(start, end, period)
+	for _, log := range logs {
+		index := periodIndex(start, log.CreatedAt, period)
+		history[index].Num -= log.ChangeNum
+		history[index].Cost -= log.Cost
+	}
+	json := &ForecastJson{
+		ItemID:   id,
+		Period:   period,
+		Method:   method,
+		History:  history,
+		Start:    end.Unix(),
+		Forecast: forecastDemand(util.TransSlice(history, func(p *PeriodUsageJson) float64 { return p.Num }), method),
+	}
+	return model.Success(json, "获取成功")
+}
+
+// forecastDemand forecasts the demand of the next period from the demands of the
+// past periods in time order, by moving average or by simple exponential smoothing.
+func forecastDemand(history []float64, method string) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+	switch method {
+	case ForecastSmoothing:
+		alpha := orderConfig.GetFloat64("item.forecast.alpha")
+		level := history[0]
+		for _, demand := range history[1:] {
+			level = alpha*demand + (1-alpha)*level
+		}
+		return level
+	default:
+		total := 0.0
+		for _, demand := range history {
+			total += demand
+		}
+		return total / float64(len(history))
+	}
+}
+
+// periodStart returns the start of the week (on Monday) or the month containing t.
+func periodStart(t time.Time, period string) time.Time {
+	y, m, d := t.Date()
+	if period == PeriodWeek {
+		weekday := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-weekday, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+func addPeriod(t time.Time, period string, n int) time.Time {
+	if period == PeriodWeek {
+		return t.AddDate(0, 0, 7*n)
+	}
+	return t.AddDate(0, n, 0)
+}
+
+// emptyPeriods returns a zero usage for every period overlapping [start, end),
+// the first one starts at start even if it is not the start of a period.
+func emptyPeriods(start, end time.Time, period string) []*PeriodUsageJson {
+	periods := []*PeriodUsageJson{{Start: start.Unix()}}
+	for t := addPeriod(periodStart(start, period), period, 1); t.Before(end); t = addPeriod(t, period, 1) {
+		periods = append(periods, &PeriodUsageJson{Start: t.Unix()})
+	}
+	return periods
+}
+
+// periodIndex returns the index of the period containing t in the periods from emptyPeriods.
+func periodIndex(start, t time.Time, period string) int {
+	index := 0
+	for p := addPeriod(periodStart(start, period), period, 1); !p.After(t); p = addPeriod(p, period, 1) {
+		index++
+	}
+	return index
+}
@@ -0,0 +1,200 @@
+package order
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/rbac"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+// approvalExecutors executes the original operation of an approved request as the requester
+var approvalExecutors = map[string]func(approval *Approval, auth *model.AuthInfo) *model.ApiJson{
+	ApprovalConsume: executeConsumeApproval,
+	ApprovalCancel:  executeCancelApproval,
+}
+
+func getApprovalByIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	approval, err := dbGetApprovalByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if approval.RequesterID != auth.User && !rbac.AuthHasPermission(auth, "approval.viewall") {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是该审批的申请人"))
+	}
+	return model.Success(approvalToJson(approval), "获取成功")
+}
+
+func getAllApprovalsService(aul *AllApprovalRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	approvals, count, err := dbGetAllApprovals(aul)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	as := util.TransSlice(approvals, approvalToJson)
+	return model.SuccessPaged(as, count, "获取成功")
+}
+
+func getUserApprovalsService(aul *AllApprovalRequest, auth *model.AuthInfo) *model.ApiJson {
+	aul.RequesterID = auth.User
+	return getAllApprovalsService(aul, auth)
+}
+
+func approveApprovalService(id uint, aul *DecideApprovalRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	approval, err := dbDecideApproval(id, ApprovalApproved, aul.Comment, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	execute, ok := approvalExecutors[approval.Kind]
+	if !ok {
+		return failApproval(approval, model.ErrorInternalServer(fmt.Errorf("未知的审批类型 %s", approval.Kind)))
+	}
+	if response := execute(approval, &model.AuthInfo{User: approval.RequesterID}); !response.Status {
+		return failApproval(approval, response)
+	}
+	go mctx.EventBus.Emit("approval:update:approved", approval.ID, approval.Kind, approval.RequesterID)
+	return model.SuccessUpdate(approvalToJson(approval), "审批通过")
+}
+
+func denyApprovalService(id uint, aul *DecideApprovalRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	approval, err := dbDecideApproval(id, ApprovalDenied, aul.Comment, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("approval:update:denied", approval.ID, approval.Kind, approval.RequesterID)
+	return model.SuccessUpdate(approvalToJson(approval), "审批驳回")
+}
+
+// failApproval records that the original operation of the approved request failed,
+// and returns the response of the failure.
+func failApproval(approval *Approval, response *model.ApiJson) *model.ApiJson {
+	reason := []rune(strings.TrimSpace(fmt.Sprint(response.Msg, " ", response.Data)))
+	if len(reason) > 191 {
+		reason = reason[:191]
+	}
+	_ = dbFailApproval(approval.ID, string(reason))
+	go mctx.EventBus.Emit("approval:update:failed", approval.ID, approval.Kind, approval.RequesterID)
+	return response
+}
+
+// checkApproval returns the first rule requiring approval for the operation, or nil if
+// the operation can be executed immediately. Users who can approve never need approval.
+func checkApproval(kind string, orderID uint, amount float64, auth *model.AuthInfo) (*ApprovalRule, error) {
+	if rbac.AuthHasPermission(auth, "approval.approve") {
+		return nil, nil
+	}
+	rules := []*ApprovalRule{}
+	for _, rule := range getApprovalRules() {
+		if rule.Kind == kind {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	order, err := dbGetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	tags := util.TransSlice(order.Tags, func(t *Tag) uint { return t.ID })
+	for _, rule := range rules {
+		if rule.Amount != 0 && amount <= rule.Amount {
+			continue
+		}
+		if rule.Status != 0 && rule.Status != order.Status {
+			continue
+		}
+		if rule.Tag != 0 && !util.In(rule.Tag, tags...) {
+			continue
+		}
+		return rule, nil
+	}
+	return nil, nil
+}
+
+func getApprovalRules() (rules []*ApprovalRule) {
+	if err := orderConfig.UnmarshalKey("approval.rules", &rules); err != nil {
+		mctx.Logger.Warnf("invalid approval rules: %v", err)
+	}
+	return
+}
+
+// requestApproval creates a pending approval for the operation instead of executing it
+func requestApproval(kind string, rule *ApprovalRule, orderID uint, amount float64, payload any, auth *model.AuthInfo) *model.ApiJson {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return model.ErrorInternalServer(err)
+	}
+	approval := &Approval{
+		Kind:        kind,
+		Rule:        rule.Name,
+		OrderID:     orderID,
+		RequesterID: auth.User,
+		Amount:      amount,
+		Payload:     string(data),
+	}
+	if approval, err = dbCreateApproval(approval, auth.User); err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	go mctx.EventBus.Emit("approval:create", approval.ID, approval.Kind, approval.RequesterID)
+	return model.SuccessCreate(approvalToJson(approval), "已提交审批")
+}
+
+func executeConsumeApproval(approval *Approval, auth *model.AuthInfo) *model.ApiJson {
+	aul := &ConsumeItemRequest{}
+	if err := json.Unmarshal([]byte(approval.Payload), aul); err != nil {
+		return model.ErrorInvalidData(err)
+	}
+	if resp := checkItemOrder(aul.OrderID, auth); resp != nil {
+		return resp
+	}
+	return execConsumeItem(aul, auth)
+}
+
+func executeCancelApproval(approval *Approval, auth *model.AuthInfo) *model.ApiJson {
+	return execCancelOrder(approval.OrderID, auth)
+}
+
+func approvalToJson(approval *Approval) *ApprovalJson {
+	if approval == nil {
+		return nil
+	} else {
+		return &ApprovalJson{
+			ID:          approval.ID,
+			Kind:        approval.Kind,
+			Rule:        approval.Rule,
+			OrderID:     approval.OrderID,
+			RequesterID: approval.RequesterID,
+			Amount:      approval.Amount,
+			Payload:     approval.Payload,
+			State:       approval.State,
+			ApproverID:  approval.ApproverID,
+			Comment:     approval.Comment,
+			Result:      approval.Result,
+			DecidedAt:   util.Tenary(approval.DecidedAt.Valid, approval.DecidedAt.Time.Unix(), 0),
+			CreatedAt:   approval.CreatedAt.Unix(),
+		}
+	}
+}
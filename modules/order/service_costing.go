@@ -0,0 +1,68 @@
+package order
+
+import (
+	"sort"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+)
+
+func getValuationService(aul *ValuationRequest, auth *model.AuthInfo) *model.ApiJson {
+	at := time.Now()
+	if aul.At != 0 {
+		at = time.Unix(aul.At, 0)
+	}
+	valuations, err := dbGetValuation(at)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	total := 0.0
+	for _, v := range valuations {
+		total += v.Value
+	}
+	json := &ValuationJson{
+		At:    at.Unix(),
+		Total: total,
+		Items: valuations,
+	}
+	return model.Success(json, "获取成功")
+}
+
+func getConsumptionCostService(aul *CostRequest, auth *model.AuthInfo) *model.ApiJson {
+	start, end := time.Unix(aul.Start, 0), time.Now()
+	if aul.End != 0 {
+		end = time.Unix(aul.End, 0)
+	}
+	entries, err := dbGetConsumptionCost(start, end)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	json := &CostJson{
+		Start:  start.Unix(),
+		End:    end.Unix(),
+		Items:  sumCostEntries(entries, func(e *costEntry) uint { return e.ItemID }),
+		Orders: sumCostEntries(entries, func(e *costEntry) uint { return e.OrderID }),
+	}
+	for _, e := range entries {
+		json.Total += e.Cost
+	}
+	return model.Success(json, "获取成功")
+}
+
+func sumCostEntries(entries []*costEntry, key func(*costEntry) uint) []*CostEntryJson {
+	sums := make(map[uint]*CostEntryJson)
+	for _, e := range entries {
+		id := key(e)
+		if _, ok := sums[id]; !ok {
+			sums[id] = &CostEntryJson{ID: id}
+		}
+		sums[id].Num += e.Num
+		sums[id].Cost += e.Cost
+	}
+	result := make([]*CostEntryJson, 0, len(sums))
+	for _, v := range sums {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
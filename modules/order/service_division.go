@@ -0,0 +1,120 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+	"github.com/xaxys/maintainman/modules/user"
+
+	"gorm.io/gorm"
+)
+
+func getOrderByDivisionService(id uint, aul *DivisionOrderRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if _, err := user.GetDivisionByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(fmt.Errorf("分组不存在"))
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return getOrderByDivisionsService([]uint{id}, aul)
+}
+
+func getOrderByUserDivisionService(aul *DivisionOrderRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	divisions, err := getUserDivisionIDs(auth.User)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	if len(divisions) == 0 {
+		return model.ErrorNoPermissions(fmt.Errorf("您不属于任何分组"))
+	}
+	return getOrderByDivisionsService(divisions, aul)
+}
+
+func getOrderByDivisionsService(divisions []uint, aul *DivisionOrderRequest) *model.ApiJson {
+	aul.OrderBy = util.NotEmpty(aul.OrderBy, "id desc")
+	orders, count, err := dbGetOrderByDivisions(divisions, aul)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	os := util.TransSlice(orders, orderToJson)
+	return model.SuccessPaged(os, count, "获取成功")
+}
+
+func assignOrderToDivisionService(id, division uint, auth *model.AuthInfo) *model.ApiJson {
+	if division == 0 {
+		return model.ErrorUpdateDatabase(fmt.Errorf("分组不能为空"))
+	}
+	if _, err := user.GetDivisionByID(division); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(fmt.Errorf("分组不存在"))
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	order, err := dbGetOrderWithLastStatus(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if order.Status != StatusWaiting {
+		return model.ErrorUpdateDatabase(fmt.Errorf("订单不处于待处理状态，不能指派"))
+	}
+	if uint(util.LastElem(order.StatusList).DivisionID.Int64) == division {
+		return model.ErrorUpdateDatabase(fmt.Errorf("订单已指派给该分组"))
+	}
+	status := NewStatusDivisionAssigned(division, auth.User)
+	if err := dbChangeOrderStatus(id, status); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("order:update:division", order.ID, division)
+	return model.SuccessUpdate(nil, "指派成功")
+}
+
+func claimOrderService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	order, err := dbGetOrderWithLastStatus(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	division := util.LastElem(order.StatusList).DivisionID
+	if order.Status != StatusWaiting || !division.Valid {
+		return model.ErrorUpdateDatabase(fmt.Errorf("订单不处于待认领状态，不能认领"))
+	}
+	divisions, err := getUserDivisionIDs(auth.User)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	if !util.In(uint(division.Int64), divisions...) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不属于订单指派的分组，不能认领"))
+	}
+	status := NewStatusAssigned(auth.User, auth.User)
+	if err := dbChangeOrderStatus(id, status); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("order:update:status:assigned", order.ID, StatusAssigned, auth.User)
+	return model.SuccessUpdate(nil, "认领成功")
+}
+
+// getUserDivisionIDs returns the division of the user and all its ancestors,
+// whose unclaimed orders are visible to the user.
+func getUserDivisionIDs(id uint) ([]uint, error) {
+	u, err := user.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !u.DivisionID.Valid {
+		return []uint{}, nil
+	}
+	return user.GetDivisionAncestorIDs(uint(u.DivisionID.Int64))
+}
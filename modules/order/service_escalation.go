@@ -0,0 +1,114 @@
+package order
+
+import (
+	"errors"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func getEscalationByOrderService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	escalation, err := dbGetEscalationByOrder(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(escalationToJson(escalation), "获取成功")
+}
+
+// startEscalation routes the reported order to the first supervisor of the matched rule
+func startEscalation(id, reporter uint) {
+	order, err := dbGetOrderByID(id)
+	if err != nil {
+		return
+	}
+	tags := util.TransSlice(order.Tags, func(t *Tag) uint { return t.ID })
+	divisions, err := getUserDivisionIDs(reporter)
+	if err != nil {
+		return
+	}
+	rule := matchEscalationRule(getEscalationRules(), tags, divisions)
+	if rule == nil {
+		return
+	}
+	if err := dbEscalateOrder(id, rule, 1, reporter); err != nil {
+		return
+	}
+	go mctx.EventBus.Emit("order:update:escalate", id, uint(1), rule.Levels[0].Supervisor)
+}
+
+// AutoEscalateOrderService escalates the reported orders exceeding the timeout of their level,
+// exported to be run on demand besides the scheduled checks.
+func AutoEscalateOrderService() {
+	escalations, err := dbGetTimeoutEscalations()
+	if err != nil {
+		return
+	}
+	rules := getEscalationRules()
+	for _, escalation := range escalations {
+		order, err := dbGetSimpleOrderByID(escalation.OrderID)
+		if err != nil {
+			continue
+		}
+		var rule *EscalationRule
+		for _, r := range rules {
+			if r.Name == escalation.Rule {
+				rule = r
+				break
+			}
+		}
+		// the order has been handled, or the rule has been changed
+		if order.Status != StatusReported || rule == nil || int(escalation.Level) >= len(rule.Levels) {
+			_ = dbStopEscalation(escalation.ID)
+			continue
+		}
+		level := escalation.Level + 1
+		if err := dbEscalateOrder(order.ID, rule, level, 0); err != nil {
+			continue
+		}
+		go mctx.EventBus.Emit("order:update:escalate", order.ID, level, rule.Levels[level-1].Supervisor)
+	}
+}
+
+func getEscalationRules() (rules []*EscalationRule) {
+	if err := orderConfig.UnmarshalKey("escalation.rules", &rules); err != nil {
+		mctx.Logger.Warnf("invalid escalation rules: %v", err)
+	}
+	return
+}
+
+// matchEscalationRule returns the first rule matching both the tags of the order
+// and the divisions of the reporter
+func matchEscalationRule(rules []*EscalationRule, tags, divisions []uint) *EscalationRule {
+	for _, rule := range rules {
+		if len(rule.Levels) == 0 {
+			continue
+		}
+		if rule.Division != 0 && !util.In(rule.Division, divisions...) {
+			continue
+		}
+		if rule.Tag != 0 && !util.In(rule.Tag, tags...) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+func escalationToJson(escalation *Escalation) *EscalationJson {
+	if escalation == nil {
+		return nil
+	} else {
+		return &EscalationJson{
+			OrderID:      escalation.OrderID,
+			Rule:         escalation.Rule,
+			Level:        escalation.Level,
+			SupervisorID: escalation.SupervisorID,
+			Deadline:     util.Tenary(escalation.Deadline.Valid, escalation.Deadline.Time.Unix(), 0),
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+	"github.com/xaxys/maintainman/modules/imagehost"
+
+	"gorm.io/gorm"
+)
+
+func getEvidencesByOrderService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	evidences, err := dbGetEvidencesByOrder(id)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	es := util.TransSlice(evidences, evidenceToJson)
+	return model.Success(es, "获取成功")
+}
+
+func createEvidenceService(id uint, aul *CreateEvidenceRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if errResp := checkEvidenceOperator(id, auth); errResp != nil {
+		return errResp
+	}
+	if !imagehost.ExistImage(aul.Image) {
+		return model.ErrorNotFound(fmt.Errorf("图片不存在"))
+	}
+	evidence := &Evidence{
+		OrderID: id,
+		Kind:    aul.Kind,
+		ImageID: aul.Image,
+	}
+	if err := dbCreateEvidence(evidence, auth.User); err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	return model.SuccessCreate(evidenceToJson(evidence), "上传成功")
+}
+
+func uploadSignatureService(id uint, file multipart.File, auth *model.AuthInfo) *model.ApiJson {
+	if errResp := checkEvidenceOperator(id, auth); errResp != nil {
+		return errResp
+	}
+	response := imagehost.UploadImage(file, auth)
+	if !response.Status {
+		return response
+	}
+	evidence := &Evidence{
+		OrderID: id,
+		Kind:    EvidenceSignature,
+		ImageID: response.Data.(string),
+	}
+	if err := dbCreateEvidence(evidence, auth.User); err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	return model.SuccessCreate(evidenceToJson(evidence), "上传成功")
+}
+
+// checkEvidenceOperator checks the order is assigned to the operator
+func checkEvidenceOperator(id uint, auth *model.AuthInfo) *model.ApiJson {
+	order, err := dbGetOrderWithLastStatus(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if order.Status != StatusAssigned {
+		return model.ErrorUpdateDatabase(fmt.Errorf("订单不处于已接单状态，不能上传凭证"))
+	}
+	if uint(util.LastElem(order.StatusList).RepairerID.Int64) != auth.User {
+		return model.ErrorUpdateDatabase(fmt.Errorf("操作人不是订单当前维修员，不能上传凭证"))
+	}
+	return nil
+}
+
+// checkCompletionRequirements checks the evidences required by the tags of the order
+func checkCompletionRequirements(id uint) *model.ApiJson {
+	order, err := dbGetOrderByID(id)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	photo := util.ToUint(orderConfig.GetInt("completion.photo"))
+	signature := false
+	rules := []*CompletionRule{}
+	if err := orderConfig.UnmarshalKey("completion.rules", &rules); err != nil {
+		mctx.Logger.Warnf("invalid completion rules: %v", err)
+	}
+	tags := util.TransSlice(order.Tags, func(t *Tag) uint { return t.ID })
+	for _, rule := range rules {
+		if !util.In(rule.Tag, tags...) {
+			continue
+		}
+		photo = util.Tenary(rule.Photo > photo, rule.Photo, photo)
+		signature = signature || rule.Signature
+	}
+
+	photos, signatures := uint(0), uint(0)
+	for _, evidence := range order.Evidences {
+		switch evidence.Kind {
+		case EvidenceAfter:
+			photos++
+		case EvidenceSignature:
+			signatures++
+		}
+	}
+	if photos < photo {
+		return model.ErrorUpdateDatabase(fmt.Errorf("维修后照片不足，至少需要%d张", photo))
+	}
+	if signature && signatures == 0 {
+		return model.ErrorUpdateDatabase(fmt.Errorf("缺少客户签名"))
+	}
+	return nil
+}
+
+func evidenceToJson(evidence *Evidence) *EvidenceJson {
+	if evidence == nil {
+		return nil
+	} else {
+		return &EvidenceJson{
+			ID:        evidence.ID,
+			OrderID:   evidence.OrderID,
+			Kind:      evidence.Kind,
+			ImageID:   evidence.ImageID,
+			CreatedAt: evidence.CreatedAt.Unix(),
+			CreatedBy: evidence.CreatedBy,
+		}
+	}
+}
@@ -3,6 +3,7 @@ package order
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/xaxys/maintainman/core/model"
 	"github.com/xaxys/maintainman/core/util"
@@ -32,6 +33,17 @@ func getItemByNameService(name string, auth *model.AuthInfo) *model.ApiJson {
 	return model.Success(itemToJson(item), "获取成功")
 }
 
+func getItemByBarcodeService(code string, auth *model.AuthInfo) *model.ApiJson {
+	item, err := dbGetItemByBarcode(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(itemToInfoJson(item), "获取成功")
+}
+
 func getItemsByFuzzyNameService(name string, auth *model.AuthInfo) *model.ApiJson {
 	items, err := dbGetItemsByFuzzyName(name)
 	if err != nil {
@@ -41,11 +53,11 @@ func getItemsByFuzzyNameService(name string, auth *model.AuthInfo) *model.ApiJso
 	return model.Success(is, "获取成功")
 }
 
-func getAllItemsService(param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
-	if err := util.Validator.Struct(param); err != nil {
+func getAllItemsService(aul *AllItemRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
 		return model.ErrorValidation(err)
 	}
-	items, count, err := dbGetAllItems(param)
+	items, count, err := dbGetAllItems(aul)
 	if err != nil {
 		return model.ErrorQueryDatabase(err)
 	}
@@ -64,6 +76,20 @@ func createItemService(aul *CreateItemRequest, auth *model.AuthInfo) *model.ApiJ
 	return model.SuccessCreate(itemToInfoJson(item), "创建成功")
 }
 
+func updateItemService(id uint, aul *UpdateItemRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	item, err := dbUpdateItem(id, aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(itemToInfoJson(item), "更新成功")
+}
+
 func deleteItemService(id uint, auth *model.AuthInfo) *model.ApiJson {
 	if err := dbDeleteItem(id); err != nil {
 		return model.ErrorDeleteDatabase(err)
@@ -75,11 +101,15 @@ func addItemService(aul *AddItemRequest, auth *model.AuthInfo) *model.ApiJson {
 	if err := util.Validator.Struct(aul); err != nil {
 		return model.ErrorValidation(err)
 	}
+	if aul.WarehouseID != 0 && !canManageWarehouse(aul.WarehouseID, auth) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是该仓库的管理员"))
+	}
 	itemlog := dbItemLogAdd(aul)
 	log, err := dbAddItem(itemlog, auth.User)
 	if err != nil {
 		return model.ErrorInsertDatabase(err)
 	}
+	checkItemStock(log, log.Count-itemlog.ChangeNum, log.MinCount)
 	return model.SuccessUpdate(itemToJson(log), "添加成功")
 }
 
@@ -87,7 +117,32 @@ func consumeItemService(aul *ConsumeItemRequest, auth *model.AuthInfo) *model.Ap
 	if err := util.Validator.Struct(aul); err != nil {
 		return model.ErrorValidation(err)
 	}
-	order, err := dbGetOrderWithLastStatus(aul.OrderID)
+	if resp := checkItemOrder(aul.OrderID, auth); resp != nil {
+		return resp
+	}
+	rule, err := checkApproval(ApprovalConsume, aul.OrderID, aul.Price, auth)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	if rule != nil {
+		return requestApproval(ApprovalConsume, rule, aul.OrderID, aul.Price, aul, auth)
+	}
+	return execConsumeItem(aul, auth)
+}
+
+func execConsumeItem(aul *ConsumeItemRequest, auth *model.AuthInfo) *model.ApiJson {
+	itemlog := dbItemLogConsume(aul)
+	log, err := dbConsumeItem(itemlog, auth.User)
+	if err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	checkItemStock(log, log.Count-itemlog.ChangeNum, log.MinCount)
+	return model.SuccessUpdate(itemToJson(log), "添加成功")
+}
+
+// checkItemOrder checks that items can be consumed or reserved for the order by the user
+func checkItemOrder(id uint, auth *model.AuthInfo) *model.ApiJson {
+	order, err := dbGetOrderWithLastStatus(id)
 	if err != nil {
 		return model.ErrorQueryDatabase(err)
 	}
@@ -97,12 +152,178 @@ func consumeItemService(aul *ConsumeItemRequest, auth *model.AuthInfo) *model.Ap
 	if uint(util.LastElem(order.StatusList).RepairerID.Int64) != auth.User {
 		return model.ErrorNoPermissions(fmt.Errorf("您不是订单的当前维修员"))
 	}
-	itemlog := dbItemLogConsume(aul)
-	log, err := dbConsumeItem(itemlog, auth.User)
+	return nil
+}
+
+func updateItemThresholdService(id uint, aul *UpdateItemThresholdRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	old, err := dbGetItemByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	item, err := dbUpdateItemThreshold(id, aul, auth.User)
+	if err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	checkItemStock(item, old.Count, old.MinCount)
+	return model.SuccessUpdate(itemToInfoJson(item), "更新成功")
+}
+
+func getReorderReportService(auth *model.AuthInfo) *model.ApiJson {
+	report, err := itemReorderReport()
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(report, "获取成功")
+}
+
+func autoReorderReportService() {
+	report, err := itemReorderReport()
+	if err != nil {
+		return
+	}
+	if len(report) == 0 {
+		return
+	}
+	for _, r := range report {
+		mctx.Logger.Infof("Item %s(%d) low in stock: %g/%g, suggest to reorder %g", r.Name, r.ItemID, r.Count, r.MinCount, r.Suggested)
+	}
+	go mctx.EventBus.Emit("item:reorder:report", report)
+}
+
+// checkItemStock emits item:stock:low when the stock of item falls below its minimum
+// from a state (prevCount, prevMin) that was not low.
+func checkItemStock(item *Item, prevCount, prevMin float64) {
+	isLow := func(count, min float64) bool { return min > 0 && count < min }
+	if isLow(item.Count, item.MinCount) && !isLow(prevCount, prevMin) {
+		go mctx.EventBus.Emit("item:stock:low", item.ID, item.Count, item.MinCount)
+	}
+}
+
+// itemReorderReport lists the items below their minimum stock, with the quantity
+// suggested to reorder: enough to cover the consumption of the last item.reorder.days
+// days on top of the minimum, and at least the item's reorder quantity.
+// If item.reorder.forecast is set, the forecast demand of the next item.reorder.days
+// days is used instead of the consumption of the last ones.
+func itemReorderReport() ([]*ReorderJson, error) {
+	items, err := dbGetLowStockItems()
+	if err != nil {
+		return nil, err
+	}
+	days := orderConfig.GetInt("item.reorder.days")
+	since := time.Now().AddDate(0, 0, -days)
+	ids := util.TransSlice(items, func(item *Item) uint { return item.ID })
+	consumed, err := dbGetItemConsumption(ids, since)
+	if err != nil {
+		return nil, err
+	}
+	forecast := map[uint]float64{}
+	if orderConfig.GetBool("item.reorder.forecast") {
+		if forecast, err = forecastItemDemand(ids, days); err != nil {
+			return nil, err
+		}
+	}
+	report := util.TransSlice(items, func(item *Item) *ReorderJson {
+		demand := util.Tenary(orderConfig.GetBool("item.reorder.forecast"), forecast[item.ID], consumed[item.ID])
+		suggested := ceilItemNum(item, item.MinCount+demand-item.Count)
+		if suggested < item.ReorderNum {
+			suggested = item.ReorderNum
+		}
+		return &ReorderJson{
+			ItemID:    item.ID,
+			Name:      item.Name,
+			Count:     item.Count,
+			MinCount:  item.MinCount,
+			Consumed:  consumed[item.ID],
+			Forecast:  forecast[item.ID],
+			Suggested: suggested,
+		}
+	})
+	return report, nil
+}
+
+// forecastItemDemand forecasts the demand of the items in the next days, from their
+// consumption in the last item.forecast.periods windows of the same length.
+func forecastItemDemand(ids []uint, days int) (map[uint]float64, error) {
+	periods := orderConfig.GetInt("item.forecast.periods")
+	window := time.Duration(days) * 24 * time.Hour
+	end := time.Now()
+	start := end.Add(-window * time.Duration(periods))
+	logs, err := dbGetConsumptionLogs(ids, start, end)
+	if err != nil {
+		return nil, err
+	}
+	history := map[uint][]float64{}
+	for _, id := range ids {
+		history[id] = make([]float64, periods)
+	}
+	for _, log := range logs {
+		index := int(log.CreatedAt.Sub(start) / window)
+		history[log.ItemID][util.Tenary(index < periods, index, periods-1)] -= log.ChangeNum
+	}
+	method := orderConfig.GetString("item.forecast.method")
+	forecast := map[uint]float64{}
+	for id, demands := range history {
+		forecast[id] = forecastDemand(demands, method)
+	}
+	return forecast, nil
+}
+
+func getCategoryByIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	category, err := dbGetCategoryByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(categoryToJson(category), "获取成功")
+}
+
+func getAllCategoriesService(auth *model.AuthInfo) *model.ApiJson {
+	categories, err := dbGetAllCategories()
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	cs := util.TransSlice(categories, categoryToJson)
+	return model.Success(cs, "获取成功")
+}
+
+func createCategoryService(aul *CreateCategoryRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	category, err := dbCreateCategory(aul, auth.User)
 	if err != nil {
 		return model.ErrorInsertDatabase(err)
 	}
-	return model.SuccessUpdate(itemToJson(log), "添加成功")
+	return model.SuccessCreate(categoryToJson(category), "创建成功")
+}
+
+func updateCategoryService(id uint, aul *UpdateCategoryRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	category, err := dbUpdateCategory(id, aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(categoryToJson(category), "更新成功")
+}
+
+func deleteCategoryService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if err := dbDeleteCategory(id); err != nil {
+		return model.ErrorDeleteDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "删除成功")
 }
 
 func itemToJson(item *Item) *ItemJson {
@@ -113,7 +334,13 @@ func itemToJson(item *Item) *ItemJson {
 			ID:          item.ID,
 			Name:        item.Name,
 			Description: item.Description,
+			CategoryID:  item.CategoryID,
+			Unit:        item.Unit,
+			SKU:         item.SKU.String,
 			Count:       item.Count,
+			Reserved:    item.Reserved,
+			Available:   item.Count - item.Reserved,
+			MinCount:    item.MinCount,
 		}
 	}
 
@@ -127,9 +354,19 @@ func itemToInfoJson(item *Item) *ItemInfoJson {
 			ID:          item.ID,
 			Name:        item.Name,
 			Description: item.Description,
+			CategoryID:  item.CategoryID,
+			Unit:        item.Unit,
+			Precision:   item.Precision,
+			SKU:         item.SKU.String,
+			Barcodes:    util.TransSlice(item.Barcodes, func(b *ItemBarcode) string { return b.Code }),
 			Price:       item.Price,
 			Income:      item.Income,
+			Cost:        item.Cost,
 			Count:       item.Count,
+			Reserved:    item.Reserved,
+			Available:   item.Count - item.Reserved,
+			MinCount:    item.MinCount,
+			ReorderNum:  item.ReorderNum,
 			ItemLogs:    util.TransSlice(item.ItemLogs, itemLogToJson),
 			CreatedAt:   item.CreatedAt.Unix(),
 			UpdatedAt:   item.UpdatedAt.Unix(),
@@ -140,16 +377,37 @@ func itemToInfoJson(item *Item) *ItemInfoJson {
 
 }
 
+func categoryToJson(category *ItemCategory) *CategoryJson {
+	if category == nil {
+		return nil
+	} else {
+		return &CategoryJson{
+			ID:          category.ID,
+			Name:        category.Name,
+			Description: category.Description,
+			CreatedAt:   category.CreatedAt.Unix(),
+			UpdatedAt:   category.UpdatedAt.Unix(),
+		}
+	}
+}
+
 func itemLogToJson(itemLog *ItemLog) *ItemLogJson {
 	if itemLog == nil {
 		return nil
 	} else {
 		return &ItemLogJson{
 			ID:          itemLog.ID,
+			Type:        itemLog.Type,
 			ItemID:      itemLog.ItemID,
 			OrderID:     itemLog.OrderID,
+			WarehouseID: itemLog.WarehouseID,
+			TransferID:  itemLog.TransferID,
+			SupplierID:  itemLog.SupplierID,
+			PurchaseID:  itemLog.PurchaseID,
+			StocktakeID: itemLog.StocktakeID,
 			ChangeNum:   itemLog.ChangeNum,
 			ChangePrice: itemLog.ChangePrice,
+			Cost:        itemLog.Cost,
 			CreatedAt:   itemLog.CreatedAt.Unix(),
 			CreatedBy:   itemLog.CreatedBy,
 		}
@@ -37,7 +37,15 @@ func getOrderByRepairerService(id uint, aul *RepairerOrderRequest, auth *model.A
 		return model.ErrorValidation(err)
 	}
 	aul.OrderBy = util.NotEmpty(aul.OrderBy, "id desc")
-	orders, count, err := dbGetOrderByRepairer(id, aul)
+	divisions := []uint{}
+	if aul.Division {
+		ids, err := getUserDivisionIDs(id)
+		if err != nil {
+			return model.ErrorQueryDatabase(err)
+		}
+		divisions = ids
+	}
+	orders, count, err := dbGetOrderByRepairer(id, divisions, aul)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return model.ErrorNotFound(err)
@@ -67,15 +75,14 @@ func createOrderService(aul *CreateOrderRequest, auth *model.AuthInfo) *model.Ap
 	if err := util.Validator.Struct(aul); err != nil {
 		return model.ErrorValidation(err)
 	}
-	role := util.NilOrBaseValue(auth, func(v *model.AuthInfo) string { return v.Role }, "")
-	if errResp := checkTagsService(aul.Tags, "tag.view", role); errResp != nil {
+	if errResp := checkTagsService(aul.Tags, "tag.view", auth); errResp != nil {
 		return errResp
 	}
 	order, err := dbCreateOrder(aul, auth.User)
 	if err != nil {
 		return model.ErrorInsertDatabase(err)
 	}
-	go mctx.EventBus.Emit("order:create", order.ID)
+	go mctx.EventBus.Emit("order:create", order.ID, auth)
 	return model.SuccessCreate(orderToJson(order), "创建成功")
 }
 
@@ -90,11 +97,10 @@ func updateOrderService(id uint, aul *UpdateOrderRequest, auth *model.AuthInfo)
 	if order.UserID != auth.User {
 		return model.ErrorUpdateDatabase(fmt.Errorf("操作人不是订单创建者"))
 	}
-	role := util.NilOrBaseValue(auth, func(v *model.AuthInfo) string { return v.Role }, "")
-	if errResp := checkTagsService(aul.AddTags, "tag.add", role); errResp != nil {
+	if errResp := checkTagsService(aul.AddTags, "tag.add", auth); errResp != nil {
 		return errResp
 	}
-	if errResp := checkTagsService(aul.DelTags, "tag.add", role); errResp != nil {
+	if errResp := checkTagsService(aul.DelTags, "tag.add", auth); errResp != nil {
 		return errResp
 	}
 	return forceUpdateOrderService(id, aul, auth)
@@ -180,6 +186,9 @@ func completeOrderService(id uint, auth *model.AuthInfo) *model.ApiJson {
 	if order.UserID != auth.User {
 		return model.ErrorUpdateDatabase(fmt.Errorf("操作人不是订单当前指派人"))
 	}
+	if errResp := checkCompletionRequirements(id); errResp != nil {
+		return errResp
+	}
 	status := NewStatusCompleted(auth.User)
 	if err := dbChangeOrderStatus(id, status); err != nil {
 		return model.ErrorUpdateDatabase(err)
@@ -202,6 +211,34 @@ func cancelOrderService(id uint, auth *model.AuthInfo) *model.ApiJson {
 	if util.In(order.Status, StatusCompleted, StatusAppraised) {
 		return model.ErrorUpdateDatabase(fmt.Errorf("订单已完成，不能取消"))
 	}
+	rule, err := checkApproval(ApprovalCancel, id, 0, auth)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	if rule != nil {
+		pending, err := dbHasPendingApproval(ApprovalCancel, id)
+		if err != nil {
+			return model.ErrorQueryDatabase(err)
+		}
+		if pending {
+			return model.ErrorInsertDatabase(fmt.Errorf("订单已有待审批的取消申请"))
+		}
+		return requestApproval(ApprovalCancel, rule, id, 0, map[string]uint{"order_id": id}, auth)
+	}
+	return execCancelOrder(id, auth)
+}
+
+func execCancelOrder(id uint, auth *model.AuthInfo) *model.ApiJson {
+	order, err := dbGetSimpleOrderByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if util.In(order.Status, StatusCanceled, StatusCompleted, StatusAppraised) {
+		return model.ErrorUpdateDatabase(fmt.Errorf("订单已取消或已完成，不能取消"))
+	}
 	status := NewStatusCanceled(auth.User)
 	if err := dbChangeOrderStatus(id, status); err != nil {
 		return model.ErrorUpdateDatabase(err)
@@ -278,6 +315,7 @@ func reportOrderService(id uint, auth *model.AuthInfo) *model.ApiJson {
 		return model.ErrorUpdateDatabase(err)
 	}
 	go mctx.EventBus.Emit("order:update:status:reported", order.ID, StatusReported)
+	startEscalation(order.ID, auth.User)
 	return model.SuccessUpdate(nil, "上报成功")
 }
 
@@ -331,8 +369,10 @@ func orderToJson(order *Order) *OrderJson {
 		CreatedAt:    order.CreatedAt.Unix(),
 		UpdatedAt:    order.UpdatedAt.Unix(),
 		Appraisal:    order.Appraisal,
+		LabourTime:   order.LabourTime,
 		Tags:         util.TransSlice(order.Tags, tagToJson),
 		AllowComment: order.AllowComment == CommentAllow,
 		Comments:     util.TransSlice(order.Comments, commentToJson),
+		Evidences:    util.TransSlice(order.Evidences, evidenceToJson),
 	}
 }
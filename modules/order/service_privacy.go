@@ -0,0 +1,35 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/util"
+	"github.com/xaxys/maintainman/modules/user"
+)
+
+// exportPrivacyData returns the orders, comments and image IDs of the user, which is
+// exported as "privacy.export" to be put into the user's data archive.
+func exportPrivacyData(id uint) (map[string]any, error) {
+	orders, err := dbGetAllOrdersByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := dbGetAllCommentsByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	images, err := dbGetImageIDsByUser(id)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]any{
+		"orders":   util.TransSlice(orders, orderToJson),
+		"comments": util.TransSlice(comments, commentToJson),
+		"images":   images,
+	}
+	return data, nil
+}
+
+// erasePrivacyData anonymises the orders and comments of the user, which is exported
+// as "privacy.erase" to be called when the account is deleted.
+func erasePrivacyData(id uint) error {
+	return dbEraseUserData(id, user.DeletedUserName(id))
+}
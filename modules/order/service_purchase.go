@@ -0,0 +1,220 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func getSupplierByIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	supplier, err := dbGetSupplierByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(supplierToJson(supplier), "获取成功")
+}
+
+func getAllSuppliersService(param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(param); err != nil {
+		return model.ErrorValidation(err)
+	}
+	suppliers, count, err := dbGetAllSuppliers(param)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ss := util.TransSlice(suppliers, supplierToJson)
+	return model.SuccessPaged(ss, count, "获取成功")
+}
+
+func createSupplierService(aul *CreateSupplierRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	supplier, err := dbCreateSupplier(aul, auth.User)
+	if err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	return model.SuccessCreate(supplierToJson(supplier), "创建成功")
+}
+
+func updateSupplierService(id uint, aul *UpdateSupplierRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	supplier, err := dbUpdateSupplier(id, aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(supplierToJson(supplier), "更新成功")
+}
+
+func deleteSupplierService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if err := dbDeleteSupplier(id); err != nil {
+		return model.ErrorDeleteDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "删除成功")
+}
+
+func getPurchaseByIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	purchase, err := dbGetPurchaseByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(purchaseToJson(purchase), "获取成功")
+}
+
+func getAllPurchasesService(aul *AllPurchaseRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	purchases, count, err := dbGetAllPurchases(aul)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ps := util.TransSlice(purchases, purchaseToJson)
+	return model.SuccessPaged(ps, count, "获取成功")
+}
+
+func createPurchaseService(aul *CreatePurchaseRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	purchase, err := dbCreatePurchase(aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorInsertDatabase(err)
+	}
+	return model.SuccessCreate(purchaseToJson(purchase), "创建成功")
+}
+
+func updatePurchaseService(id uint, aul *UpdatePurchaseRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	purchase, err := dbUpdatePurchase(id, aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(purchaseToJson(purchase), "更新成功")
+}
+
+func deletePurchaseService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	purchase, err := dbGetPurchaseByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if purchase.State != PurchaseDraft {
+		return model.ErrorDeleteDatabase(fmt.Errorf("采购单不处于草稿状态，不能删除"))
+	}
+	if err := dbDeletePurchase(id); err != nil {
+		return model.ErrorDeleteDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "删除成功")
+}
+
+func placePurchaseService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	purchase, err := dbGetPurchaseByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if purchase.State != PurchaseDraft {
+		return model.ErrorUpdateDatabase(fmt.Errorf("采购单不处于草稿状态，不能下单"))
+	}
+	if err := dbChangePurchaseState(id, PurchaseOrdered, auth.User); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("purchase:update:ordered", purchase.ID)
+	return model.SuccessUpdate(nil, "下单成功")
+}
+
+func receivePurchaseService(id uint, aul *ReceivePurchaseRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if !canManageWarehouse(aul.WarehouseID, auth) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是该仓库的管理员"))
+	}
+	purchase, err := dbReceivePurchase(id, aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	if purchase.State == PurchaseReceived {
+		go mctx.EventBus.Emit("purchase:update:received", purchase.ID)
+	}
+	return model.SuccessUpdate(purchaseToJson(purchase), "收货成功")
+}
+
+func supplierToJson(supplier *Supplier) *SupplierJson {
+	if supplier == nil {
+		return nil
+	} else {
+		return &SupplierJson{
+			ID:        supplier.ID,
+			Name:      supplier.Name,
+			Contact:   supplier.Contact,
+			Phone:     supplier.Phone,
+			Address:   supplier.Address,
+			Note:      supplier.Note,
+			CreatedAt: supplier.CreatedAt.Unix(),
+			UpdatedAt: supplier.UpdatedAt.Unix(),
+		}
+	}
+}
+
+func purchaseToJson(purchase *PurchaseOrder) *PurchaseOrderJson {
+	if purchase == nil {
+		return nil
+	} else {
+		return &PurchaseOrderJson{
+			ID:         purchase.ID,
+			SupplierID: purchase.SupplierID,
+			State:      purchase.State,
+			Note:       purchase.Note,
+			Lines:      util.TransSlice(purchase.Lines, purchaseLineToJson),
+			CreatedAt:  purchase.CreatedAt.Unix(),
+			UpdatedAt:  purchase.UpdatedAt.Unix(),
+			CreatedBy:  purchase.CreatedBy,
+			UpdatedBy:  purchase.UpdatedBy,
+		}
+	}
+}
+
+func purchaseLineToJson(line *PurchaseLine) *PurchaseLineJson {
+	if line == nil {
+		return nil
+	} else {
+		return &PurchaseLineJson{
+			ItemID:   line.ItemID,
+			Num:      line.Num,
+			Received: line.Received,
+			Price:    line.Price,
+		}
+	}
+}
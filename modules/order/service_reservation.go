@@ -0,0 +1,60 @@
+package order
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+)
+
+func getReservationsByOrderService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	reservations, err := dbGetReservationsByOrder(id)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	rs := util.TransSlice(reservations, reservationToJson)
+	return model.Success(rs, "获取成功")
+}
+
+func reserveItemService(aul *ReserveItemRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if resp := checkItemOrder(aul.OrderID, auth); resp != nil {
+		return resp
+	}
+	reservation, err := dbReserveItem(aul, auth.User)
+	if err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	return model.SuccessUpdate(reservationToJson(reservation), "预留成功")
+}
+
+func returnReservationService(aul *ReserveItemRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if resp := checkItemOrder(aul.OrderID, auth); resp != nil {
+		return resp
+	}
+	reservation, err := dbReturnReservation(aul, auth.User)
+	if err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(reservationToJson(reservation), "归还成功")
+}
+
+func reservationToJson(reservation *Reservation) *ReservationJson {
+	if reservation == nil {
+		return nil
+	} else {
+		return &ReservationJson{
+			ID:        reservation.ID,
+			OrderID:   reservation.OrderID,
+			ItemID:    reservation.ItemID,
+			Num:       reservation.Num,
+			CreatedAt: reservation.CreatedAt.Unix(),
+			UpdatedAt: reservation.UpdatedAt.Unix(),
+			CreatedBy: reservation.CreatedBy,
+			UpdatedBy: reservation.UpdatedBy,
+		}
+	}
+}
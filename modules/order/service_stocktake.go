@@ -0,0 +1,181 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func getStocktakeByIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	stocktake, err := dbGetStocktakeByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(stocktakeToJson(stocktake), "获取成功")
+}
+
+func getAllStocktakesService(aul *AllStocktakeRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	stocktakes, count, err := dbGetAllStocktakes(aul)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ss := util.TransSlice(stocktakes, stocktakeToJson)
+	return model.SuccessPaged(ss, count, "获取成功")
+}
+
+func createStocktakeService(aul *CreateStocktakeRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if !canManageWarehouse(aul.WarehouseID, auth) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是该仓库的管理员"))
+	}
+	stocktake, err := dbCreateStocktake(aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorInsertDatabase(err)
+	}
+	return model.SuccessCreate(stocktakeToJson(stocktake), "创建成功")
+}
+
+func countStocktakeService(id uint, aul *CountStocktakeRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	stocktake, err := dbGetStocktakeByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if !canManageWarehouse(stocktake.WarehouseID, auth) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是该仓库的管理员"))
+	}
+	stocktake, err = dbCountStocktake(id, aul, auth.User)
+	if err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(stocktakeToJson(stocktake), "录入成功")
+}
+
+func submitStocktakeService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	stocktake, err := dbGetStocktakeByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if !canManageWarehouse(stocktake.WarehouseID, auth) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是该仓库的管理员"))
+	}
+	if stocktake.State != StocktakeCounting {
+		return model.ErrorUpdateDatabase(fmt.Errorf("盘点不处于盘点中状态，不能提交"))
+	}
+	for _, line := range stocktake.Lines {
+		if !line.Counted.Valid {
+			return model.ErrorUpdateDatabase(fmt.Errorf("物品 %d 尚未盘点", line.ItemID))
+		}
+	}
+	if err := dbChangeStocktakeState(id, StocktakeReviewing, auth.User); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("item:stocktake:submitted", stocktake.ID)
+	return model.SuccessUpdate(nil, "提交成功")
+}
+
+func approveStocktakeService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	stocktake, err := dbApproveStocktake(id, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("item:stocktake:approved", stocktake.ID)
+	return model.SuccessUpdate(stocktakeToJson(stocktake), "审核成功")
+}
+
+func rejectStocktakeService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	stocktake, err := dbGetStocktakeByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if stocktake.State != StocktakeReviewing {
+		return model.ErrorUpdateDatabase(fmt.Errorf("盘点不处于待审核状态，不能驳回"))
+	}
+	if err := dbChangeStocktakeState(id, StocktakeCounting, auth.User); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "驳回成功")
+}
+
+func cancelStocktakeService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	stocktake, err := dbGetStocktakeByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if !canManageWarehouse(stocktake.WarehouseID, auth) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是该仓库的管理员"))
+	}
+	if !util.In(stocktake.State, StocktakeCounting, StocktakeReviewing) {
+		return model.ErrorUpdateDatabase(fmt.Errorf("盘点已结束，不能取消"))
+	}
+	if err := dbChangeStocktakeState(id, StocktakeCanceled, auth.User); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "取消成功")
+}
+
+func stocktakeToJson(stocktake *Stocktake) *StocktakeJson {
+	if stocktake == nil {
+		return nil
+	} else {
+		return &StocktakeJson{
+			ID:          stocktake.ID,
+			WarehouseID: stocktake.WarehouseID,
+			State:       stocktake.State,
+			Note:        stocktake.Note,
+			Lines:       util.TransSlice(stocktake.Lines, stocktakeLineToJson),
+			ApprovedBy:  stocktake.ApprovedBy,
+			ApprovedAt:  util.Tenary(stocktake.ApprovedAt.Valid, stocktake.ApprovedAt.Time.Unix(), 0),
+			CreatedAt:   stocktake.CreatedAt.Unix(),
+			UpdatedAt:   stocktake.UpdatedAt.Unix(),
+			CreatedBy:   stocktake.CreatedBy,
+			UpdatedBy:   stocktake.UpdatedBy,
+		}
+	}
+}
+
+func stocktakeLineToJson(line *StocktakeLine) *StocktakeLineJson {
+	if line == nil {
+		return nil
+	} else {
+		return &StocktakeLineJson{
+			ItemID:    line.ItemID,
+			Expected:  line.Expected,
+			Counted:   line.Counted.Float64,
+			IsCounted: line.Counted.Valid,
+			Variance:  util.Tenary(line.Counted.Valid, line.Counted.Float64-line.Expected, 0),
+		}
+	}
+}
@@ -19,8 +19,7 @@ func getTagByIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
 		}
 		return model.ErrorQueryDatabase(err)
 	}
-	role := util.NilOrBaseValue(auth, func(v *model.AuthInfo) string { return v.Role }, "")
-	if err := rbac.CheckPermission(role, fmt.Sprintf("tag.view.%d", tag.Level)); err != nil {
+	if err := rbac.CheckAuthPermission(auth, fmt.Sprintf("tag.view.%d", tag.Level)); err != nil {
 		return model.ErrorNoPermissions(err)
 	}
 	return model.Success(tagToJson(tag), "获取成功")
@@ -39,9 +38,22 @@ func getAllTagsBySortService(sort string, auth *model.AuthInfo) *model.ApiJson {
 	if err != nil {
 		return model.ErrorQueryDatabase(err)
 	}
-	role := util.NilOrBaseValue(auth, func(v *model.AuthInfo) string { return v.Role }, "")
 	ts := util.TransSlice(tags, func(t *Tag) *TagJson {
-		if rbac.HasPermission(role, fmt.Sprintf("tag.view.%d", t.Level)) {
+		if rbac.AuthHasPermission(auth, fmt.Sprintf("tag.view.%d", t.Level)) {
+			return tagToJson(t)
+		}
+		return nil
+	})
+	return model.Success(ts, "获取成功")
+}
+
+func getTagsByParentIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	tags, err := dbGetTagsByParentID(id)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ts := util.TransSlice(tags, func(t *Tag) *TagJson {
+		if rbac.AuthHasPermission(auth, fmt.Sprintf("tag.view.%d", t.Level)) {
 			return tagToJson(t)
 		}
 		return nil
@@ -52,26 +64,61 @@ func getAllTagsBySortService(sort string, auth *model.AuthInfo) *model.ApiJson {
 func createTagService(aul *CreateTagRequest, auth *model.AuthInfo) *model.ApiJson {
 	tag, err := dbCreateTag(aul, auth.User)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
 		return model.ErrorInsertDatabase(err)
 	}
 	return model.SuccessCreate(tagToJson(tag), "创建成功")
 }
 
+func updateTagService(id uint, aul *UpdateTagRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	tag, err := dbUpdateTag(id, aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(tagToJson(tag), "更新成功")
+}
+
+func mergeTagService(id uint, aul *MergeTagRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	tag, err := dbMergeTag(id, aul)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(tagToJson(tag), "合并成功")
+}
+
 func deleteTagService(id uint, auth *model.AuthInfo) *model.ApiJson {
 	err := dbDeleteTag(id)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
 		return model.ErrorDeleteDatabase(err)
 	}
+	refreshTagKeywords()
 	return model.SuccessUpdate(nil, "删除成功")
 }
 
-func checkTagsService(tagIDs []uint, perm, role string) *model.ApiJson {
+func checkTagsService(tagIDs []uint, perm string, auth *model.AuthInfo) *model.ApiJson {
 	tags, err := dbGetTagsByIDs(tagIDs)
 	if err != nil {
 		return model.ErrorQueryDatabase(err)
 	}
 	for _, t := range tags {
-		if err := rbac.CheckPermission(role, fmt.Sprintf("%s.%d", perm, t.Level)); err != nil {
+		if err := rbac.CheckAuthPermission(auth, fmt.Sprintf("%s.%d", perm, t.Level)); err != nil {
 			return model.ErrorNoPermissions(err)
 		}
 	}
@@ -88,6 +135,8 @@ func tagToJson(tag *Tag) *TagJson {
 			Name:     tag.Name,
 			Level:    tag.Level,
 			Congener: tag.Congener,
+			ParentID: uint(tag.ParentID.Int64),
+			Children: util.TransSlice(tag.Children, tagToJson),
 		}
 	}
 }
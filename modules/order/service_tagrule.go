@@ -0,0 +1,247 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/module"
+	"github.com/xaxys/maintainman/core/rbac"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+var (
+	tagKeywordLock sync.Mutex
+	autoTagOnce    sync.Once
+)
+
+func getTagRuleByIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	rule, err := dbGetTagRuleByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(tagRuleToJson(rule), "获取成功")
+}
+
+func getAllTagRulesService(aul *AllTagRuleRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	rules, count, err := dbGetAllTagRules(aul)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	rs := util.TransSlice(rules, tagRuleToJson)
+	return model.SuccessPaged(rs, count, "获取成功")
+}
+
+func createTagRuleService(aul *CreateTagRuleRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	rule, err := dbCreateTagRule(aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorInsertDatabase(err)
+	}
+	refreshTagKeywords()
+	return model.SuccessCreate(tagRuleToJson(rule), "创建成功")
+}
+
+func updateTagRuleService(id uint, aul *UpdateTagRuleRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	rule, err := dbUpdateTagRule(id, aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	refreshTagKeywords()
+	return model.SuccessUpdate(tagRuleToJson(rule), "更新成功")
+}
+
+func deleteTagRuleService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if err := dbDeleteTagRule(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorDeleteDatabase(err)
+	}
+	refreshTagKeywords()
+	return model.SuccessUpdate(nil, "删除成功")
+}
+
+func suggestTagsService(aul *SuggestTagRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	selected, err := dbGetTagsByIDs(aul.Tags)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	tags, err := matchTagRules(aul.Title, aul.Content, selected, auth, false)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(util.TransSlice(tags, tagToJson), "获取成功")
+}
+
+// autoTagListener applies the automatic tag rules to every newly created order.
+func autoTagListener() {
+	for event := range mctx.EventBus.On("order:create") {
+		orderID, _ := event.Args[0].(uint)
+		auth, _ := event.Args[1].(*model.AuthInfo)
+		if err := autoTagOrderService(orderID, auth); err != nil {
+			mctx.Logger.Errorf("auto tag order %d failed: %s", orderID, err)
+		}
+	}
+}
+
+// autoTagOrderService tags the order within the permissions of its creator's auth,
+// so that an order created with an API key gets no tags beyond the key's scopes.
+func autoTagOrderService(id uint, auth *model.AuthInfo) error {
+	if !orderConfig.GetBool("tag.auto.enable") {
+		return nil
+	}
+	order, err := dbGetOrderByID(id)
+	if err != nil {
+		return err
+	}
+	tags, err := matchTagRules(order.Title, order.Content, order.Tags, auth, true)
+	if err != nil || len(tags) == 0 {
+		return err
+	}
+	if err := dbAddOrderTags(order, tags); err != nil {
+		return err
+	}
+	go mctx.EventBus.Emit("order:update:autotag", order.ID, util.TransSlice(tags, func(t *Tag) uint { return t.ID }))
+	return nil
+}
+
+// matchTagRules returns the tags whose rules match the segmented title and content,
+// skipping the tags the auth can not add and those exceeding the congener limits of selected.
+func matchTagRules(title, content string, selected []*Tag, auth *model.AuthInfo, auto bool) ([]*Tag, error) {
+	rules, err := dbGetTagRules(auto)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	words, err := segmentTagText(title, content)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := map[uint]int{}
+	candidates := []*Tag{}
+	for _, rule := range rules {
+		if rule.Tag == nil || util.In(rule.Tag.ID, util.TransSlice(selected, func(t *Tag) uint { return t.ID })...) {
+			continue
+		}
+		if !rbac.AuthHasPermission(auth, fmt.Sprintf("tag.add.%d", rule.Tag.Level)) {
+			continue
+		}
+		hit := 0
+		for _, keyword := range rule.Keywords {
+			if words[keyword.Word] {
+				hit++
+			}
+		}
+		if hit == 0 {
+			continue
+		}
+		if _, ok := hits[rule.Tag.ID]; !ok {
+			candidates = append(candidates, rule.Tag)
+		}
+		hits[rule.Tag.ID] += hit
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return hits[candidates[i].ID] > hits[candidates[j].ID] })
+
+	tags := []*Tag{}
+	current := append([]*Tag{}, selected...)
+	for _, tag := range candidates {
+		if err := dbCheckTagsCongener(append(current, tag)); err != nil {
+			continue
+		}
+		current = append(current, tag)
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// refreshTagKeywords hands the keywords of all the rules to the shared analyzer,
+// so that those of the deleted rules are split again.
+func refreshTagKeywords() {
+	tagKeywordLock.Lock()
+	defer tagKeywordLock.Unlock()
+	analyzer := getWordAnalyzer()
+	if analyzer == nil {
+		return
+	}
+	rules, err := dbGetTagRules(false)
+	if err != nil {
+		return
+	}
+	keywords := []string{}
+	for _, rule := range rules {
+		keywords = append(keywords, util.TransSlice(rule.Keywords, func(k *TagKeyword) string { return k.Word })...)
+	}
+	analyzer.SetWords("tag", keywords)
+}
+
+// segmentTagText cuts the texts into lower-cased words, keeping the rule keywords unsplit.
+func segmentTagText(texts ...string) (map[string]bool, error) {
+	analyzer := getWordAnalyzer()
+	if analyzer == nil {
+		return nil, errors.New("分词模块未启用")
+	}
+	words := map[string]bool{}
+	for _, text := range texts {
+		for _, word := range analyzer.CutForSearch(strings.ToLower(text)) {
+			words[strings.TrimSpace(word)] = true
+		}
+	}
+	return words, nil
+}
+
+// getWordAnalyzer returns the segmenter exported by the word module, or nil if it is not registered.
+func getWordAnalyzer() module.Analyzer {
+	for _, m := range mctx.Registry.GetAll() {
+		if export, ok := m.Export("analyzer"); ok {
+			if analyzer, ok := export.(module.Analyzer); ok {
+				return analyzer
+			}
+		}
+	}
+	return nil
+}
+
+func tagRuleToJson(rule *TagRule) *TagRuleJson {
+	if rule == nil {
+		return nil
+	} else {
+		return &TagRuleJson{
+			ID:        rule.ID,
+			TagID:     rule.TagID,
+			Tag:       tagToJson(rule.Tag),
+			Keywords:  util.TransSlice(rule.Keywords, func(k *TagKeyword) string { return k.Word }),
+			Auto:      rule.Auto,
+			CreatedAt: rule.CreatedAt.Unix(),
+			UpdatedAt: rule.UpdatedAt.Unix(),
+		}
+	}
+}
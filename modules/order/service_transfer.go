@@ -0,0 +1,140 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/rbac"
+	"github.com/xaxys/maintainman/core/util"
+	"github.com/xaxys/maintainman/modules/user"
+
+	"gorm.io/gorm"
+)
+
+func getPendingTransfersService(param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(param); err != nil {
+		return model.ErrorValidation(err)
+	}
+	param.OrderBy = util.NotEmpty(param.OrderBy, "id desc")
+	transfers, count, err := dbGetPendingTransfersByRepairer(auth.User, param)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ts := util.TransSlice(transfers, transferToJson)
+	return model.SuccessPaged(ts, count, "获取成功")
+}
+
+func transferOrderService(id uint, aul *TransferOrderRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	order, err := dbGetOrderWithLastStatus(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if order.Status != StatusAssigned {
+		return model.ErrorUpdateDatabase(fmt.Errorf("订单不处于已接单状态，不能转单"))
+	}
+	current := uint(util.LastElem(order.StatusList).RepairerID.Int64)
+	if current != auth.User && !rbac.AuthHasPermission(auth, "order.assign") {
+		return model.ErrorNoPermissions(fmt.Errorf("操作人不是订单当前维修员，不能转单"))
+	}
+	if aul.RepairerID == current {
+		return model.ErrorUpdateDatabase(fmt.Errorf("接收人已是订单当前维修员"))
+	}
+	if _, err := user.GetUserByID(aul.RepairerID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(fmt.Errorf("接收人不存在"))
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+
+	if aul.Consent {
+		transfer := &Transfer{
+			OrderID: id,
+			FromID:  current,
+			ToID:    aul.RepairerID,
+			Reason:  aul.Reason,
+		}
+		if err := dbCreateTransfer(transfer, auth.User); err != nil {
+			return model.ErrorInsertDatabase(err)
+		}
+		go mctx.EventBus.Emit("order:update:transfer:pending", order.ID, current, aul.RepairerID)
+		return model.SuccessCreate(transferToJson(transfer), "转单请求已发送")
+	}
+
+	if err := dbTransferOrder(id, aul.RepairerID, auth.User, aul.Reason); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("order:update:transfer", order.ID, current, aul.RepairerID)
+	return model.SuccessUpdate(nil, "转单成功")
+}
+
+func acceptTransferService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	transfer, response := getOwnPendingTransfer(id, auth)
+	if response != nil {
+		return response
+	}
+	order, err := dbGetOrderWithLastStatus(id)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	if order.Status != StatusAssigned || uint(util.LastElem(order.StatusList).RepairerID.Int64) != transfer.FromID {
+		if err := dbChangeTransferState(transfer.ID, TransferExpired, auth.User); err != nil {
+			return model.ErrorUpdateDatabase(err)
+		}
+		return model.ErrorUpdateDatabase(fmt.Errorf("订单状态已变化，转单请求已失效"))
+	}
+	if err := dbAcceptTransfer(transfer, auth.User); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("order:update:transfer", order.ID, transfer.FromID, transfer.ToID)
+	return model.SuccessUpdate(nil, "接受转单成功")
+}
+
+func rejectTransferService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	transfer, response := getOwnPendingTransfer(id, auth)
+	if response != nil {
+		return response
+	}
+	if err := dbChangeTransferState(transfer.ID, TransferRejected, auth.User); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("order:update:transfer:rejected", transfer.OrderID, transfer.FromID, transfer.ToID)
+	return model.SuccessUpdate(nil, "拒绝转单成功")
+}
+
+func getOwnPendingTransfer(id uint, auth *model.AuthInfo) (*Transfer, *model.ApiJson) {
+	transfer, err := dbGetPendingTransferByOrder(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.ErrorNotFound(fmt.Errorf("该订单没有待确认的转单请求"))
+		}
+		return nil, model.ErrorQueryDatabase(err)
+	}
+	if transfer.ToID != auth.User {
+		return nil, model.ErrorNoPermissions(fmt.Errorf("您不是转单接收人"))
+	}
+	return transfer, nil
+}
+
+func transferToJson(transfer *Transfer) *TransferJson {
+	if transfer == nil {
+		return nil
+	} else {
+		return &TransferJson{
+			ID:        transfer.ID,
+			OrderID:   transfer.OrderID,
+			FromID:    transfer.FromID,
+			ToID:      transfer.ToID,
+			Reason:    transfer.Reason,
+			State:     transfer.State,
+			CreatedAt: transfer.CreatedAt.Unix(),
+			UpdatedAt: transfer.UpdatedAt.Unix(),
+		}
+	}
+}
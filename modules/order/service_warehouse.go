@@ -0,0 +1,154 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/rbac"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func getWarehouseByIDService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	warehouse, err := dbGetWarehouseByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(warehouseToJson(warehouse), "获取成功")
+}
+
+func getAllWarehousesService(param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(param); err != nil {
+		return model.ErrorValidation(err)
+	}
+	warehouses, count, err := dbGetAllWarehouses(param)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ws := util.TransSlice(warehouses, warehouseToJson)
+	return model.SuccessPaged(ws, count, "获取成功")
+}
+
+func createWarehouseService(aul *CreateWarehouseRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	warehouse, err := dbCreateWarehouse(aul, auth.User)
+	if err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	return model.SuccessCreate(warehouseToJson(warehouse), "创建成功")
+}
+
+func updateWarehouseService(id uint, aul *UpdateWarehouseRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	warehouse, err := dbUpdateWarehouse(id, aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(warehouseToJson(warehouse), "更新成功")
+}
+
+func deleteWarehouseService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if err := dbDeleteWarehouse(id); err != nil {
+		return model.ErrorDeleteDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "删除成功")
+}
+
+func getWarehouseStocksService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if id != 0 {
+		if _, err := dbGetWarehouseByID(id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return model.ErrorNotFound(err)
+			}
+			return model.ErrorQueryDatabase(err)
+		}
+	}
+	stocks, err := dbGetWarehouseStocks(id)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(stocks, "获取成功")
+}
+
+func stockWarehouseService(aul *AddItemRequest, auth *model.AuthInfo) *model.ApiJson {
+	if !canManageWarehouse(aul.WarehouseID, auth) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是该仓库的管理员"))
+	}
+	return addItemService(aul, auth)
+}
+
+func transferStockService(aul *StockTransferRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if aul.FromID == aul.ToID {
+		return model.ErrorValidation(fmt.Errorf("调出仓库与调入仓库不能相同"))
+	}
+	if !canManageWarehouse(aul.FromID, auth) {
+		return model.ErrorNoPermissions(fmt.Errorf("您不是调出仓库的管理员"))
+	}
+	transfer, err := dbTransferStock(aul, auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorInsertDatabase(err)
+	}
+	go mctx.EventBus.Emit("item:transfer", transfer.ID, transfer.ItemID, transfer.FromID, transfer.ToID)
+	return model.SuccessCreate(stockTransferToJson(transfer), "调拨成功")
+}
+
+// canManageWarehouse reports whether the user can manage the stock of the warehouse.
+// The central warehouse can only be managed with warehouse.manageall.
+func canManageWarehouse(id uint, auth *model.AuthInfo) bool {
+	if rbac.AuthHasPermission(auth, "warehouse.manageall") {
+		return true
+	}
+	return id != 0 && dbIsWarehouseKeeper(id, auth.User)
+}
+
+func warehouseToJson(warehouse *Warehouse) *WarehouseJson {
+	if warehouse == nil {
+		return nil
+	} else {
+		return &WarehouseJson{
+			ID:        warehouse.ID,
+			Name:      warehouse.Name,
+			Address:   warehouse.Address,
+			Keepers:   util.TransSlice(warehouse.Keepers, func(k *WarehouseKeeper) uint { return k.UserID }),
+			CreatedAt: warehouse.CreatedAt.Unix(),
+			UpdatedAt: warehouse.UpdatedAt.Unix(),
+			CreatedBy: warehouse.CreatedBy,
+			UpdatedBy: warehouse.UpdatedBy,
+		}
+	}
+}
+
+func stockTransferToJson(transfer *StockTransfer) *StockTransferJson {
+	if transfer == nil {
+		return nil
+	} else {
+		return &StockTransferJson{
+			ID:        transfer.ID,
+			ItemID:    transfer.ItemID,
+			FromID:    transfer.FromID,
+			ToID:      transfer.ToID,
+			Num:       transfer.Num,
+			Note:      transfer.Note,
+			CreatedAt: transfer.CreatedAt.Unix(),
+			CreatedBy: transfer.CreatedBy,
+		}
+	}
+}
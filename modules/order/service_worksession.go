@@ -0,0 +1,135 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func getWorkSessionsByOrderService(id uint, param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(param); err != nil {
+		return model.ErrorValidation(err)
+	}
+	sessions, count, err := dbGetWorkSessionsByOrder(id, param)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ss := util.TransSlice(sessions, workSessionToJson)
+	return model.SuccessPaged(ss, count, "获取成功")
+}
+
+func getWorkSessionsByRepairerService(param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(param); err != nil {
+		return model.ErrorValidation(err)
+	}
+	param.OrderBy = util.NotEmpty(param.OrderBy, "id desc")
+	sessions, count, err := dbGetWorkSessionsByRepairer(auth.User, param)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ss := util.TransSlice(sessions, workSessionToJson)
+	return model.SuccessPaged(ss, count, "获取成功")
+}
+
+func startWorkService(id uint, aul *CheckWorkRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	order, err := dbGetOrderWithLastStatus(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if order.Status != StatusAssigned {
+		return model.ErrorUpdateDatabase(fmt.Errorf("订单不处于已接单状态，不能开始工作"))
+	}
+	if uint(util.LastElem(order.StatusList).RepairerID.Int64) != auth.User {
+		return model.ErrorUpdateDatabase(fmt.Errorf("操作人不是订单当前维修员，不能开始工作"))
+	}
+	session := &WorkSession{
+		OrderID:      id,
+		RepairerID:   auth.User,
+		CheckInImage: aul.Image,
+		CheckInLat:   nullFloat(aul.Latitude),
+		CheckInLng:   nullFloat(aul.Longitude),
+	}
+	if err := dbStartWorkSession(session, auth.User); err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	go mctx.EventBus.Emit("order:update:work:start", order.ID, session.ID)
+	return model.SuccessCreate(workSessionToJson(session), "开始工作")
+}
+
+func stopWorkService(id uint, aul *CheckWorkRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	session, err := dbGetOpenWorkSessionByRepairer(auth.User)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.ErrorQueryDatabase(err)
+	}
+	if session == nil || session.OrderID != id {
+		return model.ErrorNotFound(fmt.Errorf("该订单没有您正在进行的工作记录"))
+	}
+	session.CheckOutImage = aul.Image
+	session.CheckOutLat = nullFloat(aul.Latitude)
+	session.CheckOutLng = nullFloat(aul.Longitude)
+	if err := dbStopWorkSession(session, auth.User); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	go mctx.EventBus.Emit("order:update:work:stop", session.OrderID, session.ID)
+	return model.SuccessUpdate(workSessionToJson(session), "结束工作")
+}
+
+func getLabourStatService(aul *LabourStatRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	stat, err := dbGetLabourStat(aul)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	return model.Success(stat, "获取成功")
+}
+
+func nullFloat(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+func floatOrNil(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+func workSessionToJson(session *WorkSession) *WorkSessionJson {
+	if session == nil {
+		return nil
+	} else {
+		return &WorkSessionJson{
+			ID:            session.ID,
+			OrderID:       session.OrderID,
+			RepairerID:    session.RepairerID,
+			StartAt:       session.StartAt.Unix(),
+			EndAt:         util.Tenary(session.EndAt.Valid, session.EndAt.Time.Unix(), 0),
+			Duration:      session.Duration,
+			CheckInImage:  session.CheckInImage,
+			CheckInLat:    floatOrNil(session.CheckInLat),
+			CheckInLng:    floatOrNil(session.CheckInLng),
+			CheckOutImage: session.CheckOutImage,
+			CheckOutLat:   floatOrNil(session.CheckOutLat),
+			CheckOutLng:   floatOrNil(session.CheckOutLng),
+		}
+	}
+}
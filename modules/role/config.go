@@ -25,6 +25,10 @@ func init() {
 				"user.login",
 				"user.wxlogin",
 				"user.wxregister",
+				"user.oidc",
+				"user.forgot",
+				"user.verify",
+				"user.refresh",
 			},
 			"inheritance": []string{},
 		},
@@ -38,6 +42,13 @@ func init() {
 				"user.view",
 				"user.update",
 				"user.renew",
+				"user.logout",
+				"user.totp",
+				"user.oidc",
+				"user.apikey",
+				"user.session",
+				"user.export",
+				"user.erase",
 				"role.view",
 				"announce.view",
 				"announce.hit",
@@ -50,8 +61,10 @@ func init() {
 				"order.comment.view",
 				"order.comment.create",
 				"order.comment.delete",
+				"approval.view",
 				"tag.view.1",
 				"tag.add.1",
+				"tag.suggest",
 			},
 			"inheritance": []string{
 				"guest",
@@ -65,6 +78,10 @@ func init() {
 				"order.reject",
 				"order.report",
 				"order.complete",
+				"order.transfer",
+				"order.claim",
+				"order.work",
+				"order.evidence",
 				"item.consume",
 				"item.viewall",
 				"tag.view.2",
@@ -85,6 +102,24 @@ func init() {
 				"maintainer",
 			},
 		},
+		map[string]any{
+			"name":         "storekeeper",
+			"display_name": "库管员",
+			"permissions": []string{
+				"item.viewall",
+				"warehouse.viewall",
+				"warehouse.manage",
+				"supplier.viewall",
+				"purchase.viewall",
+				"purchase.receive",
+				"stocktake.viewall",
+				"stocktake.create",
+				"stocktake.count",
+			},
+			"inheritance": []string{
+				"user",
+			},
+		},
 		map[string]any{
 			"name":         "admin",
 			"display_name": "管理员",
@@ -95,6 +130,11 @@ func init() {
 				"order.*",
 				"tag.*",
 				"item.*",
+				"warehouse.*",
+				"supplier.*",
+				"purchase.*",
+				"stocktake.*",
+				"approval.*",
 			},
 			"inheritance": []string{
 				"maintainer",
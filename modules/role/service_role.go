@@ -36,8 +36,8 @@ func updateRoleService(name string, aul *rbac.UpdateRoleRequest, auth *model.Aut
 	if err := util.Validator.Struct(aul); err != nil {
 		return model.ErrorValidation(err)
 	}
-	if rbac.GetRole(name) != nil {
-		return model.ErrorUpdateDatabase(fmt.Errorf("Role %s already exists", name))
+	if rbac.GetRole(name) == nil {
+		return model.ErrorNotFound(fmt.Errorf("Role %s not found", name))
 	}
 
 	err := rbac.UpdateRole(name, aul)
@@ -4,3 +4,19 @@ package user
 func GetUserByID(id uint) (*User, error) {
 	return dbGetUserByID(id)
 }
+
+// GetDivisionByID returns the division with the given ID.
+func GetDivisionByID(id uint) (*Division, error) {
+	return dbGetDivisionByID(id)
+}
+
+// GetDivisionAncestorIDs returns the ID of the given division followed by
+// the IDs of all its ancestors, from the nearest parent up to the root.
+func GetDivisionAncestorIDs(id uint) ([]uint, error) {
+	return dbGetDivisionAncestorIDs(id)
+}
+
+// DeletedUserName returns the name of the user with the given ID after the account is deleted.
+func DeletedUserName(id uint) string {
+	return deletedUserName(id)
+}
@@ -13,6 +13,69 @@ func init() {
 	userConfig.SetDefault("admin.password", "12345678")
 	userConfig.SetDefault("admin.role_name", "super_admin")
 
+	userConfig.SetDefault("oidc.enable", false)
+	userConfig.SetDefault("oidc.provider", "sso")
+	userConfig.SetDefault("oidc.issuer", "")
+	userConfig.SetDefault("oidc.endpoints.authorization", "")
+	userConfig.SetDefault("oidc.endpoints.token", "")
+	userConfig.SetDefault("oidc.endpoints.userinfo", "")
+	userConfig.SetDefault("oidc.client_id", "")
+	userConfig.SetDefault("oidc.client_secret", "")
+	userConfig.SetDefault("oidc.auth_method", "basic")
+	userConfig.SetDefault("oidc.redirect_uri", "")
+	userConfig.SetDefault("oidc.scope", "openid profile email")
+	userConfig.SetDefault("oidc.state_expire", "10m")
+	userConfig.SetDefault("oidc.claims.subject", "sub")
+	userConfig.SetDefault("oidc.claims.name", "preferred_username")
+	userConfig.SetDefault("oidc.claims.display_name", "name")
+	userConfig.SetDefault("oidc.claims.email", "email")
+	userConfig.SetDefault("oidc.claims.phone", "phone_number")
+	userConfig.SetDefault("oidc.autocreate", false)
+	userConfig.SetDefault("oidc.role", "")
+	userConfig.SetDefault("oidc.division", 0)
+	userConfig.SetDefault("oidc.mapping", []any{})
+
+	userConfig.SetDefault("ldap.enable", false)
+	userConfig.SetDefault("ldap.provider", "ldap")
+	userConfig.SetDefault("ldap.url", "ldap://localhost:389")
+	userConfig.SetDefault("ldap.timeout", "5s")
+	userConfig.SetDefault("ldap.insecure_skip_verify", false)
+	userConfig.SetDefault("ldap.bind_dn", "")
+	userConfig.SetDefault("ldap.bind_password", "")
+	userConfig.SetDefault("ldap.base_dn", "dc=example,dc=com")
+	userConfig.SetDefault("ldap.filter", "(&(objectClass=person)(uid={{.Account}}))")
+	userConfig.SetDefault("ldap.attributes.subject", "uid")
+	userConfig.SetDefault("ldap.attributes.name", "uid")
+	userConfig.SetDefault("ldap.attributes.display_name", "cn")
+	userConfig.SetDefault("ldap.attributes.email", "mail")
+	userConfig.SetDefault("ldap.attributes.phone", "telephoneNumber")
+	userConfig.SetDefault("ldap.attributes.groups", "memberOf")
+	userConfig.SetDefault("ldap.role", "")
+	userConfig.SetDefault("ldap.division", 0)
+	userConfig.SetDefault("ldap.mapping", []any{})
+
+	userConfig.SetDefault("refresh.expire", "720h")
+
+	userConfig.SetDefault("apikey.expire", "2160h")
+	userConfig.SetDefault("apikey.max_expire", "8760h")
+
+	userConfig.SetDefault("lockout.attempts", 5)
+	userConfig.SetDefault("lockout.window", "15m")
+	userConfig.SetDefault("lockout.duration", "15m")
+
+	userConfig.SetDefault("totp.issuer", "MaintainMan")
+	userConfig.SetDefault("totp.recovery_codes", 10)
+	userConfig.SetDefault("totp.challenge.expire", "5m")
+	userConfig.SetDefault("totp.challenge.attempts", 5)
+
+	userConfig.SetDefault("email.reset.expire", "30m")
+	userConfig.SetDefault("email.reset.subject", "重置密码")
+	userConfig.SetDefault("email.reset.body", "{{.DisplayName}} 您好:\n\n您正在重置密码, 重置令牌为:\n\n{{.Token}}\n\n令牌在 {{.Expire}} 内有效且只能使用一次。如非本人操作请忽略此邮件。\n")
+	userConfig.SetDefault("email.verify.expire", "24h")
+	userConfig.SetDefault("email.verify.trust_existing", true)
+	userConfig.SetDefault("email.verify.subject", "验证邮箱")
+	userConfig.SetDefault("email.verify.body", "{{.DisplayName}} 您好:\n\n您正在验证邮箱, 验证令牌为:\n\n{{.Token}}\n\n令牌在 {{.Expire}} 内有效且只能使用一次。如非本人操作请忽略此邮件。\n")
+
 	userConfig.SetDefault("cache.driver", "local")
 	userConfig.SetDefault("cache.limit", 268435456) // 256MB
 }
@@ -0,0 +1,83 @@
+package user
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getAPIKeys godoc
+// @Summary      获取当前用户API Key
+// @Description  获取当前用户的API Key, 不包含密钥本身
+// @Tags         user
+// @Produce      json
+// @Param        order_by  query     string                                                     false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint                                                       false  "偏移量 (默认为0)"
+// @Param        limit     query     uint                                                       false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]user.APIKeyJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/apikey [get]
+func getAPIKeys(ctx iris.Context) {
+	param := &model.PageParam{}
+	if err := ctx.ReadQuery(param); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getAPIKeysService(param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// createAPIKey godoc
+// @Summary      创建API Key
+// @Description  创建API Key, 密钥仅在创建时返回一次, 请求时放在 X-API-Key 请求头中
+// @Description  API Key的权限为所选权限与所属角色权限的交集
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.CreateAPIKeyRequest             true  "API Key信息"
+// @Success      201   {object}  model.ApiJson{data=user.APIKeyJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/apikey [post]
+func createAPIKey(ctx iris.Context) {
+	aul := &CreateAPIKeyRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := createAPIKeyService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// deleteAPIKey godoc
+// @Summary      删除API Key
+// @Description  删除当前用户的API Key, 删除后立即失效
+// @Tags         user
+// @Produce      json
+// @Param        id   path      uint                          true  "API Key ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/apikey/{id} [delete]
+func deleteAPIKey(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := deleteAPIKeyService(id, auth)
+	ctx.Values().Set("response", response)
+}
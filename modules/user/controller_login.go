@@ -0,0 +1,86 @@
+package user
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getLoginRecords godoc
+// @Summary      获取当前用户登录记录
+// @Description  获取当前用户的登录记录, 默认按时间倒序
+// @Tags         user
+// @Produce      json
+// @Param        order_by  query     string                                                          false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint                                                            false  "偏移量 (默认为0)"
+// @Param        limit     query     uint                                                            false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]user.LoginRecordJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/login [get]
+func getLoginRecords(ctx iris.Context) {
+	param := &model.PageParam{}
+	if err := ctx.ReadQuery(param); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	id := util.NilOrBaseValue(auth, func(v *model.AuthInfo) uint { return v.User }, 0)
+	response := getLoginRecordsService(id, param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// getLoginRecordsByUser godoc
+// @Summary      获取用户登录记录
+// @Description  获取指定用户的登录记录, 默认按时间倒序
+// @Tags         user
+// @Produce      json
+// @Param        id        path      uint                                                            true  "用户ID"
+// @Param        order_by  query     string                                                          false  "排序字段 (默认为ID正序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint                                                            false  "偏移量 (默认为0)"
+// @Param        limit     query     uint                                                            false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]user.LoginRecordJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/{id}/login [get]
+func getLoginRecordsByUser(ctx iris.Context) {
+	param := &model.PageParam{}
+	if err := ctx.ReadQuery(param); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getLoginRecordsService(id, param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// unlockUser godoc
+// @Summary      解锁用户
+// @Description  解除因登录失败次数过多导致的账号锁定
+// @Tags         user
+// @Produce      json
+// @Param        id   path      uint                          true  "用户ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/{id}/unlock [post]
+func unlockUser(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := unlockUserService(id, ctx.Request().RemoteAddr, ctx.GetHeader("User-Agent"), auth)
+	ctx.Values().Set("response", response)
+}
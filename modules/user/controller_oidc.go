@@ -0,0 +1,57 @@
+package user
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// oidcLogin godoc
+// @Summary      统一身份认证登录
+// @Description  获取跳转至身份提供方的授权地址, 授权后身份提供方携带 code 和 state 跳转至配置的 redirect_uri
+// @Description  已登录时获取的授权地址用于将身份提供方的账号绑定至当前用户
+// @Tags         user
+// @Produce      json
+// @Success      200  {object}  model.ApiJson{data=user.OIDCLoginJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/oidc/login [get]
+func oidcLogin(ctx iris.Context) {
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := oidcLoginService(auth)
+	ctx.Values().Set("response", response)
+}
+
+// oidcCallback godoc
+// @Summary      统一身份认证回调
+// @Description  使用身份提供方返回的 code 和 state 登录, 未绑定的账号在开启自动注册时创建新用户
+// @Description  开启两步验证时返回 status 为 false 的 TOTPChallengeJson
+// @Description  已登录时将身份提供方的账号绑定至当前用户
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      OIDCCallbackRequest                 true  "回调参数"
+// @Success      200   {object}  model.ApiJson{data=user.TokenJson}  "JWT Token"
+// @Success      204   {object}  model.ApiJson{data=[]string}        "绑定成功"
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/oidc/callback [post]
+func oidcCallback(ctx iris.Context) {
+	aul := &OIDCCallbackRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := oidcCallbackService(aul, ctx.Request().RemoteAddr, ctx.GetHeader("User-Agent"), auth)
+	ctx.Values().Set("response", response)
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// exportUserData godoc
+// @Summary      导出个人数据
+// @Description  导出当前用户的所有个人数据, 包括个人信息、工单、评论、上传的图片ID与登录记录
+// @Description  返回zip压缩包, 每类数据为一个JSON文件
+// @Description  上传的图片ID见 image/images.json, 其中作为订单凭证的见 order/images.json
+// @Tags         user
+// @Produce      json
+// @Produce      application/zip
+// @Success      200  {object}  string                        "zip压缩包"
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/export [get]
+func exportUserData(ctx iris.Context) {
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := exportPrivacyService(auth)
+	if response.ApiRes != nil {
+		ctx.Values().Set("response", response.ApiRes)
+		return
+	}
+	ctx.ContentType("application/zip")
+	ctx.Header("Content-Disposition", "attachment; filename=\""+response.Name+"\"")
+	ctx.StatusCode(iris.StatusOK)
+	ctx.Write(response.Data)
+}
+
+// deleteAccount godoc
+// @Summary      注销账号
+// @Description  注销当前用户的账号, 需验证密码, 开启两步验证时还需验证码
+// @Description  个人信息与工单联系方式将被匿名化, 工单及其统计数据保留, 所有登录立即失效
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.DeleteAccountRequest     true  "验证信息"
+// @Success      204   {object}  model.ApiJson{data=[]string}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user [delete]
+func deleteAccount(ctx iris.Context) {
+	aul := &DeleteAccountRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := deleteAccountService(aul, auth)
+	ctx.Values().Set("response", response)
+}
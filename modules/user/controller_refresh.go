@@ -0,0 +1,73 @@
+package user
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// refreshToken godoc
+// @Summary      刷新Token
+// @Description  使用刷新令牌换取新的访问令牌, 刷新令牌同时轮换, 旧的刷新令牌失效
+// @Description  重复使用旧的刷新令牌将吊销该登录
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.RefreshTokenRequest            true  "刷新Token请求"
+// @Success      200   {object}  model.ApiJson{data=user.TokenJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/token/refresh [post]
+func refreshToken(ctx iris.Context) {
+	aul := &RefreshTokenRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := refreshTokenService(aul, ctx.Request().RemoteAddr, auth)
+	ctx.Values().Set("response", response)
+}
+
+// logout godoc
+// @Summary      退出登录
+// @Description  吊销当前的访问令牌及其刷新令牌
+// @Tags         user
+// @Produce      json
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/logout [post]
+func logout(ctx iris.Context) {
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := logoutService(auth)
+	ctx.Values().Set("response", response)
+}
+
+// logoutAll godoc
+// @Summary      退出所有登录
+// @Description  吊销当前用户的所有访问令牌及刷新令牌
+// @Tags         user
+// @Produce      json
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/logout/all [post]
+func logoutAll(ctx iris.Context) {
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := logoutAllService(auth)
+	ctx.Values().Set("response", response)
+}
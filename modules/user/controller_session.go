@@ -0,0 +1,77 @@
+package user
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// getSessions godoc
+// @Summary      获取当前用户登录设备
+// @Description  获取当前用户所有有效的登录会话, 每次登录为一个会话
+// @Tags         user
+// @Produce      json
+// @Param        order_by  query     string                                                      false  "排序字段 (默认为最后活动时间倒序)  只接受  {field}  {asc|desc}  格式  (e.g. id desc)"
+// @Param        offset    query     uint                                                        false  "偏移量 (默认为0)"
+// @Param        limit     query     uint                                                        false  "每页数据量 (默认为50)"
+// @Success      200       {object}  model.ApiJson{data=model.Page{entries=[]user.SessionJson}}
+// @Failure      400       {object}  model.ApiJson{data=[]string}
+// @Failure      401       {object}  model.ApiJson{data=[]string}
+// @Failure      403       {object}  model.ApiJson{data=[]string}
+// @Failure      404       {object}  model.ApiJson{data=[]string}
+// @Failure      422       {object}  model.ApiJson{data=[]string}
+// @Failure      500       {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/session [get]
+func getSessions(ctx iris.Context) {
+	param := &model.PageParam{}
+	if err := ctx.ReadQuery(param); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := getSessionsService(param, auth)
+	ctx.Values().Set("response", response)
+}
+
+// revokeSession godoc
+// @Summary      注销登录设备
+// @Description  注销当前用户的指定登录会话, 其刷新令牌与访问令牌立即失效
+// @Tags         user
+// @Produce      json
+// @Param        id   path      uint                          true  "会话ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/session/{id} [delete]
+func revokeSession(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := revokeSessionService(id, auth)
+	ctx.Values().Set("response", response)
+}
+
+// revokeUserSessions godoc
+// @Summary      注销用户所有登录
+// @Description  注销指定用户的所有登录会话, 用于修改角色或封停后强制重新登录
+// @Tags         user
+// @Produce      json
+// @Param        id   path      uint                          true  "用户ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/{id}/session [delete]
+func revokeUserSessions(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := revokeUserSessionsService(id, auth)
+	ctx.Values().Set("response", response)
+}
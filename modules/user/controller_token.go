@@ -0,0 +1,107 @@
+package user
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// forgotPassword godoc
+// @Summary      忘记密码
+// @Description  向账号绑定的邮箱发送重置密码邮件
+// @Description  无论账号是否存在均返回成功
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.ForgotPasswordRequest    true  "忘记密码请求"
+// @Success      200   {object}  model.ApiJson{data=[]string}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/password/forgot [post]
+func forgotPassword(ctx iris.Context) {
+	aul := &ForgotPasswordRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := forgotPasswordService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// resetPassword godoc
+// @Summary      重置密码
+// @Description  使用邮件中的令牌重置密码, 令牌只能使用一次
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.ResetPasswordRequest     true  "重置密码请求"
+// @Success      204   {object}  model.ApiJson{data=[]string}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/password/reset [post]
+func resetPassword(ctx iris.Context) {
+	aul := &ResetPasswordRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := resetPasswordService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// sendVerifyEmail godoc
+// @Summary      发送验证邮件
+// @Description  向当前用户的邮箱发送验证邮件
+// @Tags         user
+// @Produce      json
+// @Success      200  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/email/verify [post]
+func sendVerifyEmail(ctx iris.Context) {
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := sendVerifyEmailService(auth)
+	ctx.Values().Set("response", response)
+}
+
+// verifyEmail godoc
+// @Summary      验证邮箱
+// @Description  使用邮件中的令牌验证邮箱, 令牌只能使用一次
+// @Description  验证后的邮箱才能作为登录账号使用
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.VerifyEmailRequest       true  "验证邮箱请求"
+// @Success      204   {object}  model.ApiJson{data=[]string}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/email/verify [post]
+func verifyEmail(ctx iris.Context) {
+	aul := &VerifyEmailRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := verifyEmailService(aul, auth)
+	ctx.Values().Set("response", response)
+}
@@ -0,0 +1,154 @@
+package user
+
+import (
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/kataras/iris/v12"
+)
+
+// loginTOTP godoc
+// @Summary      两步验证登录
+// @Description  登录需要两步验证时, 使用登录返回的 challenge_token 及验证码或恢复码完成登录
+// @Description  若登录时绑定两步验证, 返回值中包含恢复码
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.TOTPLoginRequest               true  "两步验证登录请求"
+// @Success      200   {object}  model.ApiJson{data=user.TokenJson}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/login/totp [post]
+func loginTOTP(ctx iris.Context) {
+	aul := &TOTPLoginRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := loginTOTPService(aul, ctx.Request().RemoteAddr, ctx.GetHeader("User-Agent"), auth)
+	ctx.Values().Set("response", response)
+}
+
+// setupTOTP godoc
+// @Summary      获取两步验证密钥
+// @Description  生成新的两步验证密钥及 otpauth:// 链接, 需使用验证码开启后生效
+// @Tags         user
+// @Produce      json
+// @Success      200  {object}  model.ApiJson{data=user.TOTPSetupJson}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/totp [post]
+func setupTOTP(ctx iris.Context) {
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := setupTOTPService(auth)
+	ctx.Values().Set("response", response)
+}
+
+// enableTOTP godoc
+// @Summary      开启两步验证
+// @Description  使用验证码开启两步验证, 返回恢复码, 恢复码仅显示一次
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.TOTPCodeRequest          true  "验证码"
+// @Success      200   {object}  model.ApiJson{data=[]string}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/totp/enable [post]
+func enableTOTP(ctx iris.Context) {
+	aul := &TOTPCodeRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := enableTOTPService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// disableTOTP godoc
+// @Summary      关闭两步验证
+// @Description  使用未使用过的验证码关闭两步验证
+// @Description  所属角色要求开启两步验证时不能关闭
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.TOTPCodeRequest          true  "验证码"
+// @Success      204   {object}  model.ApiJson{data=[]string}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/totp/disable [post]
+func disableTOTP(ctx iris.Context) {
+	aul := &TOTPCodeRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := disableTOTPService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// resetRecoveryCodes godoc
+// @Summary      重新生成恢复码
+// @Description  使用验证码重新生成恢复码, 旧的恢复码失效
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      user.TOTPCodeRequest          true  "验证码"
+// @Success      200   {object}  model.ApiJson{data=[]string}
+// @Failure      400   {object}  model.ApiJson{data=[]string}
+// @Failure      401   {object}  model.ApiJson{data=[]string}
+// @Failure      403   {object}  model.ApiJson{data=[]string}
+// @Failure      404   {object}  model.ApiJson{data=[]string}
+// @Failure      422   {object}  model.ApiJson{data=[]string}
+// @Failure      500   {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/totp/recovery [post]
+func resetRecoveryCodes(ctx iris.Context) {
+	aul := &TOTPCodeRequest{}
+	if err := ctx.ReadJSON(aul); err != nil {
+		ctx.Values().Set("response", model.ErrorInvalidData(err))
+		return
+	}
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := resetRecoveryCodesService(aul, auth)
+	ctx.Values().Set("response", response)
+}
+
+// resetTOTP godoc
+// @Summary      重置用户两步验证
+// @Description  关闭指定用户的两步验证并删除恢复码, 用于用户丢失验证设备时
+// @Tags         user
+// @Produce      json
+// @Param        id   path      uint                          true  "用户ID"
+// @Success      204  {object}  model.ApiJson{data=[]string}
+// @Failure      400  {object}  model.ApiJson{data=[]string}
+// @Failure      401  {object}  model.ApiJson{data=[]string}
+// @Failure      403  {object}  model.ApiJson{data=[]string}
+// @Failure      404  {object}  model.ApiJson{data=[]string}
+// @Failure      422  {object}  model.ApiJson{data=[]string}
+// @Failure      500  {object}  model.ApiJson{data=[]string}
+// @Router       /v1/user/{id}/totp [delete]
+func resetTOTP(ctx iris.Context) {
+	id := ctx.Params().GetUintDefault("id", 0)
+	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
+	response := resetTOTPService(id, auth)
+	ctx.Values().Set("response", response)
+}
@@ -112,7 +112,7 @@ func getAllUsers(ctx iris.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        body  body      LoginRequest                true  "登录信息"
-// @Success      200   {object}  model.ApiJson{data=string}  "JWT Token"
+// @Success      200   {object}  model.ApiJson{data=string}  "JWT Token, refresh 为 true 时为 user.TokenJson"
 // @Failure      400   {object}  model.ApiJson{data=[]string}
 // @Failure      401   {object}  model.ApiJson{data=[]string}
 // @Failure      403   {object}  model.ApiJson{data=[]string}
@@ -127,7 +127,7 @@ func userLogin(ctx iris.Context) {
 		return
 	}
 	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
-	response := userLoginService(aul, ctx.Request().RemoteAddr, auth)
+	response := userLoginService(aul, ctx.Request().RemoteAddr, ctx.GetHeader("User-Agent"), auth)
 	ctx.Values().Set("response", response)
 }
 
@@ -138,7 +138,7 @@ func userLogin(ctx iris.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        body  body      WxLoginRequest              true  "登录信息"
-// @Success      200   {object}  model.ApiJson{data=string}  "JWT Token"
+// @Success      200   {object}  model.ApiJson{data=string}  "JWT Token, refresh 为 true 时为 user.TokenJson"
 // @Failure      400   {object}  model.ApiJson{data=[]string}
 // @Failure      401   {object}  model.ApiJson{data=[]string}
 // @Failure      403   {object}  model.ApiJson{data=[]string}
@@ -153,7 +153,7 @@ func wxUserLogin(ctx iris.Context) {
 		return
 	}
 	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
-	response := wxUserLoginService(aul, ctx.Request().RemoteAddr, auth)
+	response := wxUserLoginService(aul, ctx.Request().RemoteAddr, ctx.GetHeader("User-Agent"), auth)
 	ctx.Values().Set("response", response)
 }
 
@@ -164,7 +164,7 @@ func wxUserLogin(ctx iris.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        body  body      WxRegisterRequest           true  "登录信息"
-// @Success      200   {object}  model.ApiJson{data=string}  "JWT Token"
+// @Success      200   {object}  model.ApiJson{data=string}  "JWT Token, refresh 为 true 时为 user.TokenJson"
 // @Failure      400   {object}  model.ApiJson{data=[]string}
 // @Failure      401   {object}  model.ApiJson{data=[]string}
 // @Failure      403   {object}  model.ApiJson{data=[]string}
@@ -179,7 +179,7 @@ func wxUserRegister(ctx iris.Context) {
 		return
 	}
 	auth := util.NilOrPtrCast[model.AuthInfo](ctx.Values().Get("auth"))
-	response := wxUserRegisterService(aul, ctx.Request().RemoteAddr, auth)
+	response := wxUserRegisterService(aul, ctx.Request().RemoteAddr, ctx.GetHeader("User-Agent"), auth)
 	ctx.Values().Set("response", response)
 }
 
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/xaxys/maintainman/core/dao"
+	"github.com/xaxys/maintainman/core/model"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyTouchInterval limits how often the last usage of a key is written.
+const apiKeyTouchInterval = time.Minute
+
+func dbGetAPIKeyByHash(hash string) (*APIKey, error) {
+	key := &APIKey{}
+	if err := mctx.Database.Where("hash = ?", hash).First(key).Error; err != nil {
+		mctx.Logger.Debugf("GetAPIKeyByHashErr: %v\n", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+func dbGetAPIKeysByUser(id uint, param *model.PageParam) (keys []*APIKey, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if keys, count, err = txGetAPIKeysByUser(tx, id, param); err != nil {
+			mctx.Logger.Warnf("GetAPIKeysByUserErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetAPIKeysByUser(tx *gorm.DB, id uint, param *model.PageParam) (keys []*APIKey, count uint, err error) {
+	cnt := int64(0)
+	if err = tx.Model(&APIKey{}).Where("user_id = ?", id).Count(&cnt).Error; err != nil {
+		return
+	}
+	count = uint(cnt)
+	err = dao.TxPageFilter(tx, param).Where("user_id = ?", id).Find(&keys).Error
+	return
+}
+
+func dbCreateAPIKey(key *APIKey) error {
+	if err := mctx.Database.Create(key).Error; err != nil {
+		mctx.Logger.Warnf("CreateAPIKeyErr: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// dbDeleteAPIKey deletes a key of the user.
+func dbDeleteAPIKey(userID, id uint) error {
+	result := mctx.Database.Where("id = ? AND user_id = ?", id, userID).Delete(&APIKey{})
+	if err := result.Error; err != nil {
+		mctx.Logger.Warnf("DeleteAPIKeyErr: %v\n", err)
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// dbTouchAPIKey records the usage of a key, at most once per apiKeyTouchInterval.
+func dbTouchAPIKey(key *APIKey, ip string) error {
+	now := time.Now()
+	if key.LastUsedAt.Valid && now.Sub(key.LastUsedAt.Time) < apiKeyTouchInterval {
+		return nil
+	}
+	err := mctx.Database.Model(&APIKey{}).
+		Where("id = ?", key.ID).
+		UpdateColumns(map[string]any{"last_used_at": sql.NullTime{Time: now, Valid: true}, "last_used_ip": ip}).Error
+	if err != nil {
+		mctx.Logger.Warnf("TouchAPIKeyErr: %v\n", err)
+		return fmt.Errorf("更新API Key使用记录失败")
+	}
+	return nil
+}
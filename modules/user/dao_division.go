@@ -3,6 +3,8 @@ package user
 import (
 	"database/sql"
 
+	"github.com/xaxys/maintainman/core/util"
+
 	"gorm.io/gorm"
 )
 
@@ -35,6 +37,23 @@ func txGetDivisionsByParentID(tx *gorm.DB, id uint) (divisions []*Division, err
 	return
 }
 
+func dbGetDivisionAncestorIDs(id uint) ([]uint, error) {
+	return txGetDivisionAncestorIDs(mctx.Database, id)
+}
+
+func txGetDivisionAncestorIDs(tx *gorm.DB, id uint) (ids []uint, err error) {
+	for id != 0 && !util.In(id, ids...) {
+		division := &Division{}
+		if err = tx.First(division, id).Error; err != nil {
+			mctx.Logger.Warnf("GetDivisionAncestorIDsErr: %v\n", err)
+			return
+		}
+		ids = append(ids, id)
+		id = uint(division.ParentID.Int64)
+	}
+	return
+}
+
 func dbCreateDivision(aul *CreateDivisionRequest) (*Division, error) {
 	return txCreateDivision(mctx.Database, aul)
 }
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/xaxys/maintainman/core/dao"
+	"github.com/xaxys/maintainman/core/model"
+
+	"gorm.io/gorm"
+)
+
+func dbCreateLoginRecord(record *LoginRecord) error {
+	if len(record.UserAgent) > 255 {
+		record.UserAgent = record.UserAgent[:255]
+	}
+	if err := mctx.Database.Create(record).Error; err != nil {
+		mctx.Logger.Warnf("CreateLoginRecordErr: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func dbGetLoginRecordsByUser(id uint, param *model.PageParam) (records []*LoginRecord, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if records, count, err = txGetLoginRecordsByUser(tx, id, param); err != nil {
+			mctx.Logger.Warnf("GetLoginRecordsByUserErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetLoginRecordsByUser(tx *gorm.DB, id uint, param *model.PageParam) (records []*LoginRecord, count uint, err error) {
+	cnt := int64(0)
+	if err = tx.Model(&LoginRecord{}).Where("user_id = ?", id).Count(&cnt).Error; err != nil {
+		return
+	}
+	count = uint(cnt)
+	err = dao.TxPageFilter(tx, param).Where("user_id = ?", id).Find(&records).Error
+	return
+}
+
+// dbLockUserOnFailures locks the user until `now + duration` if there are at least
+// `attempts` failures within the window, counting from the last successful login or unlocking.
+func dbLockUserOnFailures(user *User, attempts uint, window, duration time.Duration) (locked bool, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if locked, err = txLockUserOnFailures(tx, user, attempts, window, duration); err != nil {
+			mctx.Logger.Warnf("LockUserOnFailuresErr: %v\n", err)
+		}
+		return err
+	})
+	if locked {
+		cacheDeleteUser(user.ID)
+	}
+	return
+}
+
+func txLockUserOnFailures(tx *gorm.DB, user *User, attempts uint, window, duration time.Duration) (bool, error) {
+	now := time.Now()
+	since := now.Add(-window)
+	last := &LoginRecord{}
+	if err := tx.Where("user_id = ? AND status = ?", user.ID, LoginSuccess).Order("id desc").Limit(1).Find(last).Error; err != nil {
+		return false, err
+	}
+	if last.ID != 0 && last.CreatedAt.After(since) {
+		since = last.CreatedAt
+	}
+	// the failures during the last lock do not count
+	if user.LockedUntil.Valid && user.LockedUntil.Time.After(since) {
+		since = user.LockedUntil.Time
+	}
+	cnt := int64(0)
+	if err := tx.Model(&LoginRecord{}).
+		Where("user_id = ? AND status = ? AND created_at > ?", user.ID, LoginFailure, since).
+		Count(&cnt).Error; err != nil {
+		return false, err
+	}
+	if uint(cnt) < attempts {
+		return false, nil
+	}
+	if err := tx.Model(&User{}).Where("id = ?", user.ID).
+		Update("locked_until", sql.NullTime{Time: now.Add(duration), Valid: true}).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// dbUnlockUser clears the lock and records the unlocking, so that the previous failures do not count.
+func dbUnlockUser(id uint, record *LoginRecord) error {
+	err := mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err := txUnlockUser(tx, id, record); err != nil {
+			mctx.Logger.Warnf("UnlockUserErr: %v\n", err)
+			return err
+		}
+		return nil
+	})
+	cacheDeleteUser(id)
+	return err
+}
+
+func txUnlockUser(tx *gorm.DB, id uint, record *LoginRecord) error {
+	if err := tx.Model(&User{}).Where("id = ?", id).Update("locked_until", sql.NullTime{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(record).Error
+}
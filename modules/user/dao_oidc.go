@@ -0,0 +1,111 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func dbCreateOIDCState(userID uint, expire time.Duration) (state *OIDCState, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if state, err = txCreateOIDCState(tx, userID, expire); err != nil {
+			mctx.Logger.Warnf("CreateOIDCStateErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txCreateOIDCState saves a new authorization request with random state, nonce and PKCE verifier,
+// cleaning up the expired ones.
+func txCreateOIDCState(tx *gorm.DB, userID uint, expire time.Duration) (*OIDCState, error) {
+	random := make([]byte, 80)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := tx.Where("expires_at <= ?", now).Delete(&OIDCState{}).Error; err != nil {
+		return nil, err
+	}
+	state := &OIDCState{
+		State:     hex.EncodeToString(random[:32]),
+		Verifier:  base64.RawURLEncoding.EncodeToString(random[32:64]),
+		Nonce:     hex.EncodeToString(random[64:]),
+		UserID:    userID,
+		ExpiresAt: now.Add(expire),
+	}
+	if err := tx.Create(state).Error; err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func dbConsumeOIDCState(state string) (s *OIDCState, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if s, err = txConsumeOIDCState(tx, state); err != nil {
+			mctx.Logger.Warnf("ConsumeOIDCStateErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txConsumeOIDCState deletes an unexpired state, so that it can be used only once.
+func txConsumeOIDCState(tx *gorm.DB, state string) (*OIDCState, error) {
+	s := &OIDCState{}
+	if err := tx.Where("state = ? AND expires_at > ?", state, time.Now()).First(s).Error; err != nil {
+		return nil, fmt.Errorf("state无效、已使用或已过期")
+	}
+	result := tx.Delete(s)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("state无效、已使用或已过期")
+	}
+	return s, nil
+}
+
+func dbGetExternalIdentity(provider, subject string) (*ExternalIdentity, error) {
+	return txGetExternalIdentity(mctx.Database, provider, subject)
+}
+
+func txGetExternalIdentity(tx *gorm.DB, provider, subject string) (*ExternalIdentity, error) {
+	identity := &ExternalIdentity{}
+	if err := tx.Where("provider = ? AND subject = ?", provider, subject).First(identity).Error; err != nil {
+		mctx.Logger.Warnf("GetExternalIdentityErr: %v\n", err)
+		return nil, err
+	}
+	return identity, nil
+}
+
+func dbAttachExternalIdentity(identity *ExternalIdentity) error {
+	if err := txAttachExternalIdentity(mctx.Database, identity); err != nil {
+		mctx.Logger.Warnf("AttachExternalIdentityErr: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func txAttachExternalIdentity(tx *gorm.DB, identity *ExternalIdentity) error {
+	return tx.Create(identity).Error
+}
+
+// dbCreateUserWithIdentity creates a user along with its external identity.
+func dbCreateUserWithIdentity(json *CreateUserRequest, identity *ExternalIdentity) (user *User, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if user, err = txCreateUser(tx, json, 0); err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		if err = txAttachExternalIdentity(tx, identity); err != nil {
+			mctx.Logger.Warnf("AttachExternalIdentityErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
@@ -0,0 +1,95 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/jameskeane/bcrypt"
+	"gorm.io/gorm"
+)
+
+func dbGetAllLoginRecordsByUser(id uint) (records []*LoginRecord, err error) {
+	if err = mctx.Database.Where("user_id = ?", id).Order("id").Find(&records).Error; err != nil {
+		mctx.Logger.Warnf("GetAllLoginRecordsByUserErr: %v\n", err)
+	}
+	return
+}
+
+func dbGetAllRefreshTokensByUser(id uint) (tokens []*RefreshToken, err error) {
+	if err = mctx.Database.Where("user_id = ?", id).Order("id").Find(&tokens).Error; err != nil {
+		mctx.Logger.Warnf("GetAllRefreshTokensByUserErr: %v\n", err)
+	}
+	return
+}
+
+func dbGetAllAPIKeysByUser(id uint) (keys []*APIKey, err error) {
+	if err = mctx.Database.Where("user_id = ?", id).Order("id").Find(&keys).Error; err != nil {
+		mctx.Logger.Warnf("GetAllAPIKeysByUserErr: %v\n", err)
+	}
+	return
+}
+
+func dbGetExternalIdentitiesByUser(id uint) (identities []*ExternalIdentity, err error) {
+	if err = mctx.Database.Where("user_id = ?", id).Order("id").Find(&identities).Error; err != nil {
+		mctx.Logger.Warnf("GetExternalIdentitiesByUserErr: %v\n", err)
+	}
+	return
+}
+
+// dbEraseUser anonymises the personal fields of the user, who can not log in afterwards,
+// and deletes the records only meaningful to the user. The user is kept, so are the
+// references from other modules.
+func dbEraseUser(id uint) error {
+	err := mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err := txEraseUser(tx, id); err != nil {
+			mctx.Logger.Warnf("EraseUserErr: %v\n", err)
+			return err
+		}
+		return nil
+	})
+	cacheDeleteUser(id)
+	return err
+}
+
+func txEraseUser(tx *gorm.DB, id uint) error {
+	random := make([]byte, 20)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	name := deletedUserName(id)
+	cnt := int64(0)
+	if err := tx.Model(&User{}).Where("name = ? AND id <> ?", name, id).Count(&cnt).Error; err != nil {
+		return err
+	}
+	if cnt > 0 {
+		name += "_" + hex.EncodeToString(random[:4])
+	}
+	salt, _ := bcrypt.Salt(10)
+	hash, _ := bcrypt.Hash(hex.EncodeToString(random[4:]), salt)
+	if err := tx.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+		"name":           name,
+		"password":       hash,
+		"display_name":   deletedDisplayName,
+		"phone":          "",
+		"email":          "",
+		"email_verified": false,
+		"real_name":      "",
+		"open_id":        "",
+		"login_ip":       "0.0.0.0",
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"totp_step":      0,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&RefreshToken{}).Where("user_id = ?", id).
+		UpdateColumns(map[string]any{"device_name": "", "user_agent": "", "ip": "0.0.0.0"}).Error; err != nil {
+		return err
+	}
+	for _, model := range []any{&LoginRecord{}, &ExternalIdentity{}, &APIKey{}, &RecoveryCode{}, &UserToken{}} {
+		if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
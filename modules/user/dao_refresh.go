@@ -0,0 +1,191 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/xaxys/maintainman/core/dao"
+	"github.com/xaxys/maintainman/core/model"
+
+	"gorm.io/gorm"
+)
+
+func dbGetRefreshTokenByID(id uint) (*RefreshToken, error) {
+	return txGetRefreshTokenByID(mctx.Database, id)
+}
+
+func txGetRefreshTokenByID(tx *gorm.DB, id uint) (*RefreshToken, error) {
+	token := &RefreshToken{}
+	if err := tx.First(token, id).Error; err != nil {
+		mctx.Logger.Warnf("GetRefreshTokenByIDErr: %v\n", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+// dbCreateRefreshToken returns the created token and its secret, only the hash of which is stored.
+func dbCreateRefreshToken(userID uint, ip, ua string, expire time.Duration) (token *RefreshToken, secret string, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if token, secret, err = txCreateRefreshToken(tx, userID, ip, ua, expire); err != nil {
+			mctx.Logger.Warnf("CreateRefreshTokenErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txCreateRefreshToken(tx *gorm.DB, userID uint, ip, ua string, expire time.Duration) (*RefreshToken, string, error) {
+	secret, err := newRefreshSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+	now := time.Now()
+	token := &RefreshToken{
+		UserID:       userID,
+		Hash:         hashSecret(secret),
+		DeviceName:   parseDeviceName(ua),
+		UserAgent:    ua,
+		IP:           ip,
+		LastActiveAt: now,
+		ExpiresAt:    now.Add(expire),
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return nil, "", err
+	}
+	return token, secret, nil
+}
+
+// dbRotateRefreshToken replaces the secret of an active token, the old secret
+// must match so that a token can be used only once. The hash of the old secret
+// is kept to tell a reused token from a forged one.
+func dbRotateRefreshToken(id uint, secret, ip string) (string, error) {
+	newSecret, err := newRefreshSecret()
+	if err != nil {
+		return "", err
+	}
+	result := mctx.Database.Model(&RefreshToken{}).
+		Where("id = ? AND hash = ? AND revoked_at IS NULL AND expires_at > ?", id, hashSecret(secret), time.Now()).
+		Updates(map[string]any{"hash": hashSecret(newSecret), "prev_hash": hashSecret(secret), "ip": ip, "last_active_at": time.Now()})
+	if err := result.Error; err != nil {
+		mctx.Logger.Warnf("RotateRefreshTokenErr: %v\n", err)
+		return "", err
+	}
+	if result.RowsAffected == 0 {
+		return "", fmt.Errorf("刷新令牌无效、已使用或已过期")
+	}
+	return newSecret, nil
+}
+
+// dbGetSessionsByUser returns the active refresh tokens of the user.
+func dbGetSessionsByUser(id uint, param *model.PageParam) (tokens []*RefreshToken, count uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if tokens, count, err = txGetSessionsByUser(tx, id, param); err != nil {
+			mctx.Logger.Warnf("GetSessionsByUserErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txGetSessionsByUser(tx *gorm.DB, id uint, param *model.PageParam) (tokens []*RefreshToken, count uint, err error) {
+	now := time.Now()
+	cnt := int64(0)
+	if err = tx.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, now).
+		Count(&cnt).Error; err != nil {
+		return
+	}
+	count = uint(cnt)
+	err = dao.TxPageFilter(tx, param).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, now).
+		Find(&tokens).Error
+	return
+}
+
+// dbTouchSession records the activity of an active session.
+func dbTouchSession(id uint, ip string) error {
+	err := mctx.Database.Model(&RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumns(map[string]any{"ip": ip, "last_active_at": time.Now()}).Error
+	if err != nil {
+		mctx.Logger.Warnf("TouchSessionErr: %v\n", err)
+	}
+	return err
+}
+
+// dbRevokeSession revokes an active session of the user.
+func dbRevokeSession(userID, id uint) error {
+	result := mctx.Database.Model(&RefreshToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, userID, time.Now()).
+		Update("revoked_at", sql.NullTime{Time: time.Now(), Valid: true})
+	if err := result.Error; err != nil {
+		mctx.Logger.Warnf("RevokeSessionErr: %v\n", err)
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func dbRevokeRefreshToken(id uint) error {
+	err := mctx.Database.Model(&RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", sql.NullTime{Time: time.Now(), Valid: true}).Error
+	if err != nil {
+		mctx.Logger.Warnf("RevokeRefreshTokenErr: %v\n", err)
+	}
+	return err
+}
+
+// dbRevokeRefreshTokensByUser revokes all active tokens of the user and returns their IDs.
+func dbRevokeRefreshTokensByUser(userID uint) (ids []uint, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if ids, err = txRevokeRefreshTokensByUser(tx, userID); err != nil {
+			mctx.Logger.Warnf("RevokeRefreshTokensByUserErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+func txRevokeRefreshTokensByUser(tx *gorm.DB, userID uint) ([]uint, error) {
+	ids := []uint{}
+	now := time.Now()
+	if err := tx.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	if err := tx.Model(&RefreshToken{}).
+		Where("id IN ?", ids).
+		Update("revoked_at", sql.NullTime{Time: now, Valid: true}).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func newRefreshSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// hashSecret returns the hex encoded SHA-256 hash of the secret, which is random enough
+// to need no salt.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
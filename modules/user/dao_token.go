@@ -0,0 +1,139 @@
+package user
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func dbCreateUserToken(user *User, purpose string, expire time.Duration) (token *UserToken, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if token, err = txCreateUserToken(tx, user, purpose, expire); err != nil {
+			mctx.Logger.Warnf("CreateUserTokenErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txCreateUserToken issues a new token and invalidates the unused ones of the same purpose.
+func txCreateUserToken(tx *gorm.DB, user *User, purpose string, expire time.Duration) (*UserToken, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := tx.Model(&UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+		Update("used_at", sql.NullTime{Time: now, Valid: true}).Error; err != nil {
+		return nil, err
+	}
+	token := &UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Nonce:     hex.EncodeToString(nonce),
+		Email:     user.Email,
+		ExpiresAt: now.Add(expire),
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func dbConsumeUserToken(nonce, purpose string) (token *UserToken, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if token, err = txConsumeUserToken(tx, nonce, purpose); err != nil {
+			mctx.Logger.Warnf("ConsumeUserTokenErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txConsumeUserToken marks an unused and unexpired token as used, so that it can be used only once.
+func txConsumeUserToken(tx *gorm.DB, nonce, purpose string) (*UserToken, error) {
+	now := time.Now()
+	result := tx.Model(&UserToken{}).
+		Where("nonce = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", nonce, purpose, now).
+		Update("used_at", sql.NullTime{Time: now, Valid: true})
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("令牌无效、已使用或已过期")
+	}
+	token := &UserToken{}
+	if err := tx.Where("nonce = ?", nonce).First(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func dbGetActiveUserToken(nonce, purpose string) (*UserToken, error) {
+	token := &UserToken{}
+	if err := mctx.Database.
+		Where("nonce = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", nonce, purpose, time.Now()).
+		First(token).Error; err != nil {
+		mctx.Logger.Warnf("GetActiveUserTokenErr: %v\n", err)
+		return nil, fmt.Errorf("令牌无效、已使用或已过期")
+	}
+	return token, nil
+}
+
+// dbFailUserToken counts a failed attempt on the token, which is invalidated after max attempts.
+func dbFailUserToken(id uint, max uint) error {
+	return mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err := txFailUserToken(tx, id, max); err != nil {
+			mctx.Logger.Warnf("FailUserTokenErr: %v\n", err)
+			return err
+		}
+		return nil
+	})
+}
+
+func txFailUserToken(tx *gorm.DB, id uint, max uint) error {
+	if err := tx.Model(&UserToken{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Model(&UserToken{}).
+		Where("id = ? AND attempts >= ? AND used_at IS NULL", id, max).
+		Update("used_at", sql.NullTime{Time: time.Now(), Valid: true}).Error
+}
+
+func dbVerifyUserEmail(id uint, email string) error {
+	err := txVerifyUserEmail(mctx.Database, id, email)
+	if err != nil {
+		return err
+	}
+	cacheDeleteUser(id)
+	return nil
+}
+
+// dbVerifyLegacyEmails marks all the non-empty emails as verified and returns the number of them.
+func dbVerifyLegacyEmails() (int64, error) {
+	result := mctx.Database.Model(&User{}).Where("email <> ''").UpdateColumn("email_verified", true)
+	if err := result.Error; err != nil {
+		mctx.Logger.Warnf("VerifyLegacyEmailsErr: %v\n", err)
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+// txVerifyUserEmail marks the email of the user as verified if it is still the given one.
+func txVerifyUserEmail(tx *gorm.DB, id uint, email string) error {
+	result := tx.Model(&User{}).Where("id = ? AND email = ?", id, email).Update("email_verified", true)
+	if err := result.Error; err != nil {
+		mctx.Logger.Warnf("VerifyUserEmailErr: %v\n", err)
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("邮箱已变更, 请重新验证")
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package user
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const recoveryCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// dbSetTOTPSecret saves a pending secret, which takes effect after it is enabled.
+func dbSetTOTPSecret(id uint, secret string) error {
+	result := mctx.Database.Model(&User{}).
+		Where("id = ? AND totp_enabled = ?", id, false).
+		Updates(map[string]any{"totp_secret": secret, "totp_step": 0})
+	if err := result.Error; err != nil {
+		mctx.Logger.Warnf("SetTOTPSecretErr: %v\n", err)
+		return err
+	}
+	cacheDeleteUser(id)
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("已开启两步验证")
+	}
+	return nil
+}
+
+// dbUseTOTPStep records the time step of a used code, so that a code can not be used twice.
+func dbUseTOTPStep(id uint, step int64) error {
+	result := mctx.Database.Model(&User{}).
+		Where("id = ? AND totp_step < ?", id, step).
+		Update("totp_step", step)
+	if err := result.Error; err != nil {
+		mctx.Logger.Warnf("UseTOTPStepErr: %v\n", err)
+		return err
+	}
+	cacheDeleteUser(id)
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("验证码已使用, 请等待下一个验证码")
+	}
+	return nil
+}
+
+// dbEnableTOTP enables two-factor authentication and returns the new recovery codes.
+func dbEnableTOTP(id uint, count int) (codes []string, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if codes, err = txEnableTOTP(tx, id, count); err != nil {
+			mctx.Logger.Warnf("EnableTOTPErr: %v\n", err)
+		}
+		return err
+	})
+	cacheDeleteUser(id)
+	return
+}
+
+func txEnableTOTP(tx *gorm.DB, id uint, count int) ([]string, error) {
+	if err := tx.Model(&User{}).Where("id = ?", id).Update("totp_enabled", true).Error; err != nil {
+		return nil, err
+	}
+	return txResetRecoveryCodes(tx, id, count)
+}
+
+func dbDisableTOTP(id uint) error {
+	err := mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if err := txDisableTOTP(tx, id); err != nil {
+			mctx.Logger.Warnf("DisableTOTPErr: %v\n", err)
+			return err
+		}
+		return nil
+	})
+	cacheDeleteUser(id)
+	return err
+}
+
+func txDisableTOTP(tx *gorm.DB, id uint) error {
+	if err := tx.Model(&User{}).Where("id = ?", id).
+		Updates(map[string]any{"totp_secret": "", "totp_enabled": false, "totp_step": 0}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", id).Delete(&RecoveryCode{}).Error
+}
+
+func dbResetRecoveryCodes(id uint, count int) (codes []string, err error) {
+	mctx.Database.Transaction(func(tx *gorm.DB) error {
+		if codes, err = txResetRecoveryCodes(tx, id, count); err != nil {
+			mctx.Logger.Warnf("ResetRecoveryCodesErr: %v\n", err)
+		}
+		return err
+	})
+	return
+}
+
+// txResetRecoveryCodes replaces the recovery codes of the user, only the hashes of which are stored.
+func txResetRecoveryCodes(tx *gorm.DB, id uint, count int) ([]string, error) {
+	if err := tx.Where("user_id = ?", id).Delete(&RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := []string{}
+	records := []*RecoveryCode{}
+	for i := 0; i < count; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, &RecoveryCode{UserID: id, Hash: hashSecret(normalizeRecoveryCode(code))})
+	}
+	if len(records) != 0 {
+		if err := tx.Create(&records).Error; err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// dbUseRecoveryCode marks an unused recovery code of the user as used.
+func dbUseRecoveryCode(id uint, code string) error {
+	result := mctx.Database.Model(&RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", id, hashSecret(normalizeRecoveryCode(code))).
+		Update("used_at", sql.NullTime{Time: time.Now(), Valid: true})
+	if err := result.Error; err != nil {
+		mctx.Logger.Warnf("UseRecoveryCodeErr: %v\n", err)
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("恢复码无效或已使用")
+	}
+	return nil
+}
+
+// newRecoveryCode returns a code like `xxxxx-xxxxx` without ambiguous characters.
+func newRecoveryCode() (string, error) {
+	code := make([]byte, 10)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = recoveryCodeAlphabet[n.Int64()]
+	}
+	return string(code[:5]) + "-" + string(code[5:]), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
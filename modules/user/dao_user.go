@@ -78,6 +78,19 @@ func txGetUserByEmail(tx *gorm.DB, email string) (*User, error) {
 	return user, nil
 }
 
+func dbGetUserByVerifiedEmail(email string) (*User, error) {
+	return txGetUserByVerifiedEmail(mctx.Database, email)
+}
+
+func txGetUserByVerifiedEmail(tx *gorm.DB, email string) (*User, error) {
+	user := &User{}
+	if err := tx.Where("email = ? AND email_verified = ?", email, true).First(user).Error; err != nil {
+		mctx.Logger.Warnf("GetUserByVerifiedEmailErr: %v\n", err)
+		return nil, err
+	}
+	return user, nil
+}
+
 func dbGetUserByPhone(phone string) (*User, error) {
 	return txGetUserByPhone(mctx.Database, phone)
 }
@@ -206,6 +219,13 @@ func txUpdateUser(tx *gorm.DB, id uint, json *UpdateUserRequest, operator uint)
 		return nil, fmt.Errorf("role %s not found", json.RoleName)
 	}
 
+	if json.Email != "" {
+		if err := tx.Model(&User{}).Where("id = ? AND email <> ?", id, json.Email).Update("email_verified", false).Error; err != nil {
+			mctx.Logger.Warnf("UpdateUserErr: %v\n", err)
+			return nil, err
+		}
+	}
+
 	user := &User{}
 	copier.Copy(user, json)
 	user.ID = id
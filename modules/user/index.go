@@ -2,6 +2,7 @@ package user
 
 import (
 	"github.com/kataras/iris/v12"
+	"github.com/xaxys/maintainman/core/middleware"
 	"github.com/xaxys/maintainman/core/module"
 	"github.com/xaxys/maintainman/core/rbac"
 )
@@ -11,12 +12,19 @@ var Module module.Module
 func init() {
 	Module = module.Module{
 		ModuleName:    "user",
-		ModuleVersion: "1.1.0",
+		ModuleVersion: "1.9.2",
 		ModuleConfig:  userConfig,
 		ModuleEnv: map[string]any{
 			"orm.model": []any{
 				&User{},
 				&Division{},
+				&UserToken{},
+				&RefreshToken{},
+				&RecoveryCode{},
+				&LoginRecord{},
+				&ExternalIdentity{},
+				&OIDCState{},
+				&APIKey{},
 			},
 		},
 		ModuleExport: map[string]any{
@@ -24,21 +32,34 @@ func init() {
 			"appsecret": "",
 		},
 		ModulePerm: map[string]string{
-			"user.view":        "查看当前用户",
-			"user.create":      "创建用户",
-			"user.update":      "更新用户",
-			"user.updateall":   "更新所有用户",
-			"user.delete":      "删除用户",
-			"user.viewall":     "查看所有用户",
-			"user.login":       "登录",
-			"user.register":    "注册",
-			"user.wxlogin":     "微信登录",
-			"user.wxregister":  "微信注册",
-			"user.renew":       "更新Token",
-			"division.viewall": "查看所有分组",
-			"division.create":  "创建分组",
-			"division.update":  "更新分组",
-			"division.delete":  "删除分组",
+			"user.view":           "查看当前用户",
+			"user.create":         "创建用户",
+			"user.update":         "更新用户",
+			"user.updateall":      "更新所有用户",
+			"user.delete":         "删除用户",
+			"user.viewall":        "查看所有用户",
+			"user.login":          "登录",
+			"user.register":       "注册",
+			"user.wxlogin":        "微信登录",
+			"user.wxregister":     "微信注册",
+			"user.oidc":           "统一身份认证登录",
+			"user.renew":          "更新Token",
+			"user.forgot":         "找回密码",
+			"user.verify":         "验证邮箱",
+			"user.refresh":        "刷新Token",
+			"user.logout":         "退出登录",
+			"user.totp":           "两步验证",
+			"user.totp.reset":     "重置用户两步验证",
+			"user.unlock":         "解锁用户",
+			"user.apikey":         "管理API Key",
+			"user.session":        "管理登录设备",
+			"user.session.revoke": "注销用户所有登录",
+			"user.export":         "导出个人数据",
+			"user.erase":          "注销账号",
+			"division.viewall":    "查看所有分组",
+			"division.create":     "创建分组",
+			"division.update":     "更新分组",
+			"division.delete":     "删除分组",
 		},
 		EntryPoint: entry,
 	}
@@ -49,6 +70,8 @@ var mctx *module.ModuleContext
 func entry(ctx *module.ModuleContext) {
 	mctx = ctx
 	initDefaultData()
+	middleware.RegisterAPIKeyAuthenticator(authenticateAPIKey)
+	middleware.RegisterSessionTracker(touchSession)
 	Module.ModuleExport["appid"] = userConfig.GetString("wechat.appid")
 	Module.ModuleExport["appsecret"] = userConfig.GetString("wechat.secret")
 
@@ -58,10 +81,36 @@ func entry(ctx *module.ModuleContext) {
 	mctx.Route.Post("/wxregister", rbac.PermInterceptor("user.wxregister"), wxUserRegister)
 	mctx.Route.Get("/renew", rbac.PermInterceptor("user.renew"), userRenew)
 	mctx.Route.Get("/wxappid", getAppID)
+	mctx.Route.Post("/login/totp", rbac.PermInterceptor("user.login"), loginTOTP)
+	mctx.Route.Get("/oidc/login", rbac.PermInterceptor("user.oidc"), oidcLogin)
+	mctx.Route.Post("/oidc/callback", rbac.PermInterceptor("user.oidc"), oidcCallback)
+	mctx.Route.Post("/token/refresh", rbac.PermInterceptor("user.refresh"), refreshToken)
+	mctx.Route.Post("/logout", rbac.PermInterceptor("user.logout"), logout)
+	mctx.Route.Post("/logout/all", rbac.PermInterceptor("user.logout"), logoutAll)
+	mctx.Route.Post("/password/forgot", rbac.PermInterceptor("user.forgot"), forgotPassword)
+	mctx.Route.Post("/password/reset", rbac.PermInterceptor("user.forgot"), resetPassword)
+	mctx.Route.Post("/email/verify", rbac.PermInterceptor("user.verify"), verifyEmail)
 
 	mctx.Route.PartyFunc("/user", func(user iris.Party) {
 		user.Get("/", rbac.PermInterceptor("user.view"), getUser)
 		user.Put("/", rbac.PermInterceptor("user.update"), updateUser)
+		user.Delete("/", rbac.PermInterceptor("user.erase"), deleteAccount)
+		user.Get("/export", rbac.PermInterceptor("user.export"), exportUserData)
+		user.Post("/email/verify", rbac.PermInterceptor("user.update"), sendVerifyEmail)
+		user.Get("/login", rbac.PermInterceptor("user.view"), getLoginRecords)
+		user.Post("/totp", rbac.PermInterceptor("user.totp"), setupTOTP)
+		user.Post("/totp/enable", rbac.PermInterceptor("user.totp"), enableTOTP)
+		user.Post("/totp/disable", rbac.PermInterceptor("user.totp"), disableTOTP)
+		user.Post("/totp/recovery", rbac.PermInterceptor("user.totp"), resetRecoveryCodes)
+		user.Get("/apikey", rbac.PermInterceptor("user.apikey"), getAPIKeys)
+		user.Post("/apikey", rbac.PermInterceptor("user.apikey"), createAPIKey)
+		user.Delete("/apikey/{id:uint}", rbac.PermInterceptor("user.apikey"), deleteAPIKey)
+		user.Get("/session", rbac.PermInterceptor("user.session"), getSessions)
+		user.Delete("/session/{id:uint}", rbac.PermInterceptor("user.session"), revokeSession)
+		user.Delete("/{id:uint}/totp", rbac.PermInterceptor("user.totp.reset"), resetTOTP)
+		user.Get("/{id:uint}/login", rbac.PermInterceptor("user.viewall"), getLoginRecordsByUser)
+		user.Post("/{id:uint}/unlock", rbac.PermInterceptor("user.unlock"), unlockUser)
+		user.Delete("/{id:uint}/session", rbac.PermInterceptor("user.session.revoke"), revokeUserSessions)
 		user.Post("/", rbac.PermInterceptor("user.create"), createUser)
 		user.Get("/all", rbac.PermInterceptor("user.viewall"), getAllUsers)
 		user.Get("/{id:uint}", rbac.PermInterceptor("user.viewall"), getUserByID)
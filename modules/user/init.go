@@ -3,11 +3,22 @@ package user
 import (
 	"fmt"
 
+	"github.com/xaxys/maintainman/core/database"
 	"github.com/xaxys/maintainman/core/logger"
 )
 
+// legacyEmails is set if the users table predates email verification, it is
+// checked before the models are synced, which adds the column.
+var legacyEmails bool
+
+func init() {
+	migrator := database.DB.Migrator()
+	legacyEmails = migrator.HasTable(&User{}) && !migrator.HasColumn(&User{}, "EmailVerified")
+}
+
 func initDefaultData() {
 	createSystemAdmin()
+	trustLegacyEmails()
 }
 
 func createSystemAdmin() {
@@ -28,3 +39,16 @@ func createSystemAdmin() {
 		}
 	}
 }
+
+// trustLegacyEmails marks the emails set before email verification is introduced
+// as verified, so that the users can still login with them after upgrading.
+func trustLegacyEmails() {
+	if !legacyEmails || !userConfig.GetBool("email.verify.trust_existing") {
+		return
+	}
+	count, err := dbVerifyLegacyEmails()
+	if err != nil {
+		panic(fmt.Errorf("failed to mark existing emails as verified: %v", err))
+	}
+	logger.Logger.Infof("%d existing emails are marked as verified", count)
+}
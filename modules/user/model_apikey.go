@@ -0,0 +1,40 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+// APIKey authenticates its owner in place of a token, with the permissions limited to
+// the intersection of the key's and the owner's role.
+type APIKey struct {
+	ID          uint         `gorm:"primarykey"`
+	CreatedAt   time.Time    `gorm:"not null"`
+	UpdatedAt   time.Time    `gorm:"not null"`
+	UserID      uint         `gorm:"not null; index; comment:用户ID"`
+	Name        string       `gorm:"not null; size:191; comment:名称"`
+	Prefix      string       `gorm:"not null; size:20; comment:密钥前缀"`
+	Hash        string       `gorm:"not null; size:64; uniqueIndex; comment:密钥哈希"`
+	Permissions string       `gorm:"not null; size:1024; comment:权限, 逗号分隔"`
+	ExpiresAt   time.Time    `gorm:"not null; comment:过期时间"`
+	LastUsedAt  sql.NullTime `gorm:"comment:最后使用时间"`
+	LastUsedIP  string       `gorm:"not null; size:40; default:0.0.0.0; comment:最后使用IP"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name        string   `json:"name" validate:"required,lte=191"`
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,required,lte=191"` // 权限, 与所属角色的权限取交集
+	ExpiresAt   int64    `json:"expires_at"`                                                  // 过期时间戳, 为0时使用默认有效期
+}
+
+type APIKeyJson struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	Key         string   `json:"key,omitempty"` // 仅在创建时返回
+	Prefix      string   `json:"prefix"`
+	Permissions []string `json:"permissions"`
+	ExpiresAt   int64    `json:"expires_at"`
+	LastUsedAt  int64    `json:"last_used_at,omitempty"`
+	LastUsedIP  string   `json:"last_used_ip,omitempty"`
+	CreatedAt   int64    `json:"created_at"`
+}
@@ -0,0 +1,8 @@
+package user
+
+// LDAPMapping maps a group of the directory to the role and the division of the users created on first login.
+type LDAPMapping struct {
+	Group    string `mapstructure:"group"`    // 组的DN, 不区分大小写
+	Role     string `mapstructure:"role"`     // 角色 空:不修改
+	Division uint   `mapstructure:"division"` // 分组ID 0:不修改
+}
@@ -0,0 +1,44 @@
+package user
+
+import (
+	"time"
+)
+
+const (
+	LoginPassword = "password" // 密码登录
+	LoginWechat   = "wx"       // 微信登录
+	LoginTOTP     = "totp"     // 两步验证登录
+	LoginOIDC     = "oidc"     // 统一身份认证登录
+	LoginLDAP     = "ldap"     // LDAP登录
+	LoginUnlock   = "unlock"   // 管理员解锁
+)
+
+const (
+	LoginSuccess   = "success"   // 成功
+	LoginFailure   = "failure"   // 失败
+	LoginChallenge = "challenge" // 需要两步验证
+)
+
+type LoginRecord struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null; index"`
+	UserID    uint      `gorm:"not null; index; comment:用户ID, 账号不存在时为0"`
+	Account   string    `gorm:"not null; size:191; comment:登录账号"`
+	Method    string    `gorm:"not null; size:20; comment:登录方式"`
+	Status    string    `gorm:"not null; size:20; comment:登录结果"`
+	Reason    string    `gorm:"not null; size:191; comment:失败原因"`
+	IP        string    `gorm:"not null; size:40; comment:登录IP"`
+	UserAgent string    `gorm:"not null; size:255; comment:User-Agent"`
+}
+
+type LoginRecordJson struct {
+	ID        uint   `json:"id"`
+	UserID    uint   `json:"user_id"`
+	Account   string `json:"account"`
+	Method    string `json:"method"` // password, wx, totp, oidc, ldap, unlock
+	Status    string `json:"status"` // success, failure, challenge
+	Reason    string `json:"reason,omitempty"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt int64  `json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"time"
+)
+
+// ExternalIdentity links a user to an account of an external identity provider,
+// the way OpenID links a user to a WeChat account.
+type ExternalIdentity struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+	UserID    uint      `gorm:"not null; index; comment:用户ID"`
+	Provider  string    `gorm:"not null; size:50; uniqueIndex:idx_external_identity; comment:身份提供方"`
+	Subject   string    `gorm:"not null; size:191; uniqueIndex:idx_external_identity; comment:身份提供方的用户标识"`
+	Email     string    `gorm:"not null; size:191; comment:身份提供方的邮箱"`
+}
+
+// OIDCState is a pending authorization request, consumed by the callback.
+type OIDCState struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"not null"`
+	State     string    `gorm:"not null; size:64; uniqueIndex; comment:state参数"`
+	Verifier  string    `gorm:"not null; size:64; comment:PKCE code_verifier"`
+	Nonce     string    `gorm:"not null; size:64; comment:nonce参数"`
+	UserID    uint      `gorm:"not null; default:0; comment:绑定账号的用户ID, 登录时为0"`
+	ExpiresAt time.Time `gorm:"not null; comment:过期时间"`
+}
+
+// OIDCMapping maps a claim value to the role and the division of the users created on first login.
+type OIDCMapping struct {
+	Claim    string `mapstructure:"claim"`    // claim 名称, 可以是字符串或字符串数组
+	Value    string `mapstructure:"value"`    // claim 值
+	Role     string `mapstructure:"role"`     // 角色 空:不修改
+	Division uint   `mapstructure:"division"` // 分组ID 0:不修改
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" validate:"required,lte=2048"`
+	State string `json:"state" validate:"required,lte=64"`
+}
+
+type OIDCLoginJson struct {
+	URL       string `json:"url"` // 跳转至身份提供方的授权地址
+	State     string `json:"state"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type OIDCTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
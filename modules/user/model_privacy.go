@@ -0,0 +1,13 @@
+package user
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required,lte=32"`
+	Code     string `json:"code" validate:"omitempty,lte=20"` // 开启两步验证时需要验证码
+}
+
+type ExternalIdentityJson struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt int64  `json:"created_at"`
+}
@@ -0,0 +1,46 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RefreshToken is a long-lived token stored server-side, every access token
+// issued with it carries its ID as `sid` so that they can be revoked together.
+// Each one is a session of a login, listed to the user as a logged-in device.
+type RefreshToken struct {
+	ID           uint         `gorm:"primarykey"`
+	CreatedAt    time.Time    `gorm:"not null"`
+	UpdatedAt    time.Time    `gorm:"not null"`
+	UserID       uint         `gorm:"not null; index; comment:用户ID"`
+	Hash         string       `gorm:"not null; size:64; comment:令牌哈希"`
+	PrevHash     string       `gorm:"not null; size:64; comment:上一令牌哈希"`
+	DeviceName   string       `gorm:"not null; size:191; comment:设备名称"`
+	UserAgent    string       `gorm:"not null; size:255; comment:登录User-Agent"`
+	IP           string       `gorm:"not null; size:40; default:0.0.0.0; comment:最后活动IP"`
+	LastActiveAt time.Time    `gorm:"not null; comment:最后活动时间"`
+	ExpiresAt    time.Time    `gorm:"not null; comment:过期时间"`
+	RevokedAt    sql.NullTime `gorm:"comment:吊销时间"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required,lte=191"`
+}
+
+type TokenJson struct {
+	Token         string   `json:"token"`
+	RefreshToken  string   `json:"refresh_token,omitempty"`
+	ExpiresAt     int64    `json:"expires_at"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // 登录时绑定两步验证生成的恢复码
+}
+
+type SessionJson struct {
+	ID           uint   `json:"id"`
+	DeviceName   string `json:"device_name"`
+	UserAgent    string `json:"user_agent"`
+	IP           string `json:"ip"`
+	Current      bool   `json:"current"` // 是否为当前请求所在的会话
+	CreatedAt    int64  `json:"created_at"`
+	LastActiveAt int64  `json:"last_active_at"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
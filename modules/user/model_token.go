@@ -0,0 +1,37 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	TokenResetPassword = "reset"  // 重置密码
+	TokenVerifyEmail   = "verify" // 验证邮箱
+	TokenTwoFactor     = "2fa"    // 两步验证
+)
+
+type UserToken struct {
+	ID        uint         `gorm:"primarykey"`
+	CreatedAt time.Time    `gorm:"not null"`
+	UserID    uint         `gorm:"not null; index; comment:用户ID"`
+	Purpose   string       `gorm:"not null; size:20; comment:用途"`
+	Nonce     string       `gorm:"not null; size:64; uniqueIndex; comment:随机串"`
+	Email     string       `gorm:"not null; size:191; comment:发送至的邮箱"`
+	ExpiresAt time.Time    `gorm:"not null; comment:过期时间"`
+	UsedAt    sql.NullTime `gorm:"comment:使用时间"`
+	Attempts  uint         `gorm:"not null; default:0; comment:验证失败次数"`
+}
+
+type ForgotPasswordRequest struct {
+	Account string `json:"account" validate:"required,lte=191"` // 用户名、邮箱或手机号
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required,lte=512"`
+	Password string `json:"password" validate:"required,gte=8,lte=32"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required,lte=512"`
+}
@@ -0,0 +1,36 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RecoveryCode struct {
+	ID        uint         `gorm:"primarykey"`
+	CreatedAt time.Time    `gorm:"not null"`
+	UserID    uint         `gorm:"not null; index; comment:用户ID"`
+	Hash      string       `gorm:"not null; size:64; comment:恢复码哈希"`
+	UsedAt    sql.NullTime `gorm:"comment:使用时间"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,lte=20"` // 验证码
+}
+
+type TOTPLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required,lte=512"`
+	Code           string `json:"code" validate:"required,lte=20"` // 验证码或恢复码
+}
+
+type TOTPSetupJson struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// 链接, 用于生成二维码
+}
+
+type TOTPChallengeJson struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresAt      int64  `json:"expires_at"`
+	Enroll         bool   `json:"enroll"` // 所属角色要求开启两步验证, 需先绑定
+	Secret         string `json:"secret,omitempty"`
+	URI            string `json:"uri,omitempty"`
+}
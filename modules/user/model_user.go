@@ -10,27 +10,34 @@ import (
 
 type User struct {
 	model.BaseModel
-	Name        string        `gorm:"not null; size:50; unique; comment:用户名"`
-	Password    string        `gorm:"not null; size:191; comment:密码"`
-	DisplayName string        `gorm:"not null; size:191; comment:昵称"`
-	RoleName    string        `gorm:"not null; size:50; index; comment:所属角色"`
-	DivisionID  sql.NullInt64 `gorm:"comment:所属分组id"`
-	Division    *Division     `gorm:"foreignkey:DivisionID"`
-	Phone       string        `gorm:"not null; size:191; index; comment:手机号"`
-	Email       string        `gorm:"not null; size:191; index; comment:邮箱"`
-	LoginIP     string        `gorm:"not null; size:40; default:0.0.0.0; comment:最后登录IP"`
-	LoginTime   time.Time     `gorm:"not null; comment:最后登录时间"`
-	RealName    string        `gorm:"not null; size:191; comment:真实姓名"`
-	OpenID      string        `gorm:"not null; size:191; index; comment:微信openid"`
+	Name          string        `gorm:"not null; size:50; unique; comment:用户名"`
+	Password      string        `gorm:"not null; size:191; comment:密码"`
+	DisplayName   string        `gorm:"not null; size:191; comment:昵称"`
+	RoleName      string        `gorm:"not null; size:50; index; comment:所属角色"`
+	DivisionID    sql.NullInt64 `gorm:"comment:所属分组id"`
+	Division      *Division     `gorm:"foreignkey:DivisionID"`
+	Phone         string        `gorm:"not null; size:191; index; comment:手机号"`
+	Email         string        `gorm:"not null; size:191; index; comment:邮箱"`
+	EmailVerified bool          `gorm:"not null; default:false; comment:邮箱是否已验证"`
+	LoginIP       string        `gorm:"not null; size:40; default:0.0.0.0; comment:最后登录IP"`
+	LoginTime     time.Time     `gorm:"not null; comment:最后登录时间"`
+	RealName      string        `gorm:"not null; size:191; comment:真实姓名"`
+	OpenID        string        `gorm:"not null; size:191; index; comment:微信openid"`
+	TOTPSecret    string        `gorm:"not null; size:64; comment:两步验证密钥"`
+	TOTPEnabled   bool          `gorm:"not null; default:false; comment:是否开启两步验证"`
+	TOTPStep      int64         `gorm:"not null; default:0; comment:最后使用的两步验证时间步"`
+	LockedUntil   sql.NullTime  `gorm:"comment:锁定截止时间"`
 }
 
 type LoginRequest struct {
 	Account  string `json:"account" validate:"required,lte=191"`
 	Password string `json:"password" validate:"required,gte=8,lte=32"`
+	Refresh  bool   `json:"refresh"` // 为 true 时 data 返回含刷新令牌的 TokenJson, 否则为 JWT Token
 }
 
 type WxLoginRequest struct {
-	Code string `json:"code"`
+	Code    string `json:"code"`
+	Refresh bool   `json:"refresh"` // 为 true 时 data 返回含刷新令牌的 TokenJson, 否则为 JWT Token
 }
 
 type WxLoginResponse struct {
@@ -51,7 +58,8 @@ type RegisterUserRequest struct {
 }
 
 type WxRegisterRequest struct {
-	Code string `json:"code"`
+	Code    string `json:"code"`
+	Refresh bool   `json:"refresh"` // 为 true 时 data 返回含刷新令牌的 TokenJson, 否则为 JWT Token
 	RegisterUserRequest
 }
 
@@ -80,13 +88,16 @@ type AllUserRequest struct {
 }
 
 type UserJson struct {
-	ID          uint           `json:"id"`
-	Name        string         `json:"name"`
-	DisplayName string         `json:"display_name"` // 昵称
-	RoleName    string         `json:"user_role"`
-	Role        *rbac.RoleJson `json:"role,omitempty"`
-	Phone       string         `json:"phone"`
-	Email       string         `json:"email"`
-	RealName    string         `json:"real_name"`
-	LoginTime   int64          `json:"login_time"` // unix timestamp in seconds (UTC)
+	ID            uint           `json:"id"`
+	Name          string         `json:"name"`
+	DisplayName   string         `json:"display_name"` // 昵称
+	RoleName      string         `json:"user_role"`
+	Role          *rbac.RoleJson `json:"role,omitempty"`
+	Phone         string         `json:"phone"`
+	Email         string         `json:"email"`
+	EmailVerified bool           `json:"email_verified"`
+	TOTPEnabled   bool           `json:"totp_enabled"`
+	RealName      string         `json:"real_name"`
+	LoginTime     int64          `json:"login_time"`             // unix timestamp in seconds (UTC)
+	LockedUntil   int64          `json:"locked_until,omitempty"` // unix timestamp in seconds (UTC), 0 if not locked
 }
@@ -0,0 +1,161 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/rbac"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix marks the API keys, so that a leaked key can be recognized by scanners.
+const apiKeyPrefix = "mm_"
+
+func getAPIKeysService(param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(param); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if err := checkAPIKeyManagement(auth); err != nil {
+		return model.ErrorNoPermissions(err)
+	}
+	keys, count, err := dbGetAPIKeysByUser(auth.User, param)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	ks := util.TransSlice(keys, apiKeyToJson)
+	return model.SuccessPaged(ks, count, "获取成功")
+}
+
+func createAPIKeyService(aul *CreateAPIKeyRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if err := checkAPIKeyManagement(auth); err != nil {
+		return model.ErrorNoPermissions(err)
+	}
+	for _, perm := range aul.Permissions {
+		if strings.Contains(perm, ",") {
+			return model.ErrorInvalidData(fmt.Errorf("权限格式错误: %s", perm))
+		}
+		// wildcards and negations are intersected with the role on use
+		if !strings.HasSuffix(perm, "*") && !strings.HasPrefix(perm, "-") {
+			if err := rbac.CheckPermission(auth.Role, perm); err != nil {
+				return model.ErrorNoPermissions(err)
+			}
+		}
+	}
+	permissions := strings.Join(aul.Permissions, ",")
+	if len(permissions) > 1024 {
+		return model.ErrorInvalidData(fmt.Errorf("权限过多"))
+	}
+
+	now := time.Now()
+	expire := now.Add(userConfig.GetDuration("apikey.expire"))
+	if aul.ExpiresAt != 0 {
+		expire = time.Unix(aul.ExpiresAt, 0)
+	}
+	if !expire.After(now) {
+		return model.ErrorInvalidData(fmt.Errorf("过期时间必须晚于当前时间"))
+	}
+	if max := userConfig.GetDuration("apikey.max_expire"); max > 0 && expire.After(now.Add(max)) {
+		return model.ErrorInvalidData(fmt.Errorf("有效期不能超过 %s", max))
+	}
+
+	secret, err := newRefreshSecret()
+	if err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	secret = apiKeyPrefix + secret
+	key := &APIKey{
+		UserID:      auth.User,
+		Name:        aul.Name,
+		Prefix:      secret[:len(apiKeyPrefix)+8],
+		Hash:        hashSecret(secret),
+		Permissions: permissions,
+		ExpiresAt:   expire,
+	}
+	if err := dbCreateAPIKey(key); err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	json := apiKeyToJson(key)
+	json.Key = secret
+	return model.SuccessCreate(json, "创建成功, 请妥善保存API Key")
+}
+
+func deleteAPIKeyService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if err := checkAPIKeyManagement(auth); err != nil {
+		return model.ErrorNoPermissions(err)
+	}
+	if err := dbDeleteAPIKey(auth.User, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorDeleteDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "删除成功")
+}
+
+// authenticateAPIKey resolves a key to the same auth info as a token of its owner,
+// with the permissions of the key as scopes.
+func authenticateAPIKey(secret, ip string) (*model.AuthInfo, error) {
+	key, err := dbGetAPIKeyByHash(hashSecret(secret))
+	if err != nil {
+		return nil, fmt.Errorf("API Key无效")
+	}
+	if !key.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("API Key已过期")
+	}
+	user, err := dbGetUserByID(key.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("API Key无效")
+	}
+	dbTouchAPIKey(key, ip)
+	auth := &model.AuthInfo{
+		User: user.ID,
+		Name: user.Name,
+		Role: user.RoleName,
+		IP:   ip,
+		Other: map[string]any{
+			"user_id":   user.ID,
+			"user_name": user.Name,
+			"user_role": user.RoleName,
+			"api_key":   key.ID,
+			"scopes":    strings.Split(key.Permissions, ","),
+			"exp":       key.ExpiresAt.Unix(),
+		},
+	}
+	return auth, nil
+}
+
+// checkAPIKeyManagement refuses to manage the keys with a key, which would
+// otherwise be able to extend its own permissions or expiration.
+func checkAPIKeyManagement(auth *model.AuthInfo) error {
+	if auth.Other["api_key"] != nil {
+		return fmt.Errorf("权限不足：不能使用API Key管理API Key")
+	}
+	return nil
+}
+
+func apiKeyToJson(key *APIKey) *APIKeyJson {
+	if key == nil {
+		return nil
+	}
+	json := &APIKeyJson{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Permissions: strings.Split(key.Permissions, ","),
+		ExpiresAt:   key.ExpiresAt.Unix(),
+		CreatedAt:   key.CreatedAt.Unix(),
+	}
+	if key.LastUsedAt.Valid {
+		json.LastUsedAt = key.LastUsedAt.Time.Unix()
+		json.LastUsedIP = key.LastUsedIP
+	}
+	return json
+}
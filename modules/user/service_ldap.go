@@ -0,0 +1,177 @@
+package user
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xaxys/maintainman/core/ldap"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+// ldapLoginService authenticates the account by binding to the directory. It returns nil
+// to fall back to the password login if the account is not found in the directory, or is
+// a local user which has not been linked to the directory.
+func ldapLoginService(aul *LoginRequest, ip, ua string, auth *model.AuthInfo) *model.ApiJson {
+	entry, err := searchLDAPUser(aul.Account)
+	if err != nil {
+		mctx.Logger.Warnf("LDAPSearchErr: %v", err)
+		return nil
+	}
+	if entry == nil {
+		return nil
+	}
+	identity := &ExternalIdentity{
+		Provider: userConfig.GetString("ldap.provider"),
+		Subject:  entry.Get(userConfig.GetString("ldap.attributes.subject")),
+		Email:    entry.Get(userConfig.GetString("ldap.attributes.email")),
+	}
+	if identity.Subject == "" {
+		mctx.Logger.Warnf("LDAPSearchErr: no subject attribute in %s", entry.DN)
+		return nil
+	}
+
+	var user *User
+	linked, err := dbGetExternalIdentity(identity.Provider, identity.Subject)
+	if err == nil {
+		if user, err = dbGetUserByID(linked.UserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return model.ErrorNotFound(err)
+			}
+			return model.ErrorQueryDatabase(err)
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.ErrorQueryDatabase(err)
+	} else if _, err := dbGetUserByName(util.NotEmpty(entry.Get(userConfig.GetString("ldap.attributes.name")), aul.Account)); err == nil {
+		// never take over an existing local user of the same name
+		return nil
+	}
+	if user != nil {
+		if err := checkLoginLocked(user); err != nil {
+			recordLogin(user, aul.Account, LoginLDAP, LoginFailure, err.Error(), ip, ua)
+			return model.ErrorVerification(err)
+		}
+	}
+
+	if err := bindLDAPUser(entry.DN, aul.Password); err != nil {
+		if !ldap.IsErrorCode(err, ldap.ResultInvalidCredentials) {
+			mctx.Logger.Warnf("LDAPBindErr: %v", err)
+		}
+		if user != nil {
+			failLogin(user, aul.Account, LoginLDAP, "密码错误", ip, ua)
+		} else {
+			recordLogin(nil, aul.Account, LoginLDAP, LoginFailure, "密码错误", ip, ua)
+		}
+		return model.ErrorVerification(fmt.Errorf("密码错误"))
+	}
+	if user == nil {
+		if user, err = createLDAPUser(entry, identity, aul.Account); err != nil {
+			return model.ErrorInsertDatabase(err)
+		}
+	}
+	if err := dbForceLogin(user.ID, ip); err != nil {
+		return model.ErrorUpdateDatabase(fmt.Errorf("登录失败"))
+	}
+	return completeLogin(user, aul.Account, LoginLDAP, aul.Refresh, ip, ua, auth)
+}
+
+// createLDAPUser creates a user on first bind, whose role and division are mapped from the groups.
+func createLDAPUser(entry *ldap.Entry, identity *ExternalIdentity, account string) (*User, error) {
+	role, division := mapLDAPGroups(entry.GetAll(userConfig.GetString("ldap.attributes.groups")))
+	aul := &CreateUserRequest{
+		RegisterUserRequest: RegisterUserRequest{
+			Name:        util.NotEmpty(entry.Get(userConfig.GetString("ldap.attributes.name")), account),
+			DisplayName: entry.Get(userConfig.GetString("ldap.attributes.display_name")),
+			Phone:       entry.Get(userConfig.GetString("ldap.attributes.phone")),
+			Email:       identity.Email,
+		},
+		RoleName:   role,
+		DivisionID: division,
+	}
+	return createUserWithIdentity(aul, identity)
+}
+
+// mapLDAPGroups returns the role and the division of the first matched mapping respectively,
+// falling back to `ldap.role` and `ldap.division`.
+func mapLDAPGroups(groups []string) (role string, division uint) {
+	var mappings []*LDAPMapping
+	if err := userConfig.UnmarshalKey("ldap.mapping", &mappings); err != nil {
+		mctx.Logger.Warnf("invalid ldap mapping: %v", err)
+	}
+	for _, mapping := range mappings {
+		matched := false
+		for _, group := range groups {
+			matched = matched || strings.EqualFold(group, mapping.Group)
+		}
+		if !matched {
+			continue
+		}
+		role = util.NotEmpty(role, mapping.Role)
+		if division == 0 {
+			division = mapping.Division
+		}
+	}
+	role = util.NotEmpty(role, userConfig.GetString("ldap.role"))
+	if division == 0 {
+		division = userConfig.GetUint("ldap.division")
+	}
+	return
+}
+
+// searchLDAPUser returns the only entry matching the account, or nil if not found.
+func searchLDAPUser(account string) (*ldap.Entry, error) {
+	conn, err := dialLDAP()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if dn := userConfig.GetString("ldap.bind_dn"); dn != "" {
+		if err := conn.Bind(dn, userConfig.GetString("ldap.bind_password")); err != nil {
+			return nil, err
+		}
+	}
+	attributes := []string{}
+	for _, attr := range userConfig.GetStringMapString("ldap.attributes") {
+		attributes = append(attributes, attr)
+	}
+	entries, err := conn.Search(&ldap.SearchRequest{
+		BaseDN:     userConfig.GetString("ldap.base_dn"),
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     util.ProcessString(userConfig.GetString("ldap.filter"), map[string]any{"Account": ldap.EscapeFilter(account)}),
+		Attributes: attributes,
+		SizeLimit:  2,
+	})
+	if err != nil && !ldap.IsErrorCode(err, ldap.ResultSizeLimitExceeded) {
+		return nil, err
+	}
+	switch len(entries) {
+	case 0:
+		return nil, nil
+	case 1:
+		return entries[0], nil
+	default:
+		return nil, fmt.Errorf("multiple entries match account %s", account)
+	}
+}
+
+// bindLDAPUser checks the password of the entry on a new connection.
+func bindLDAPUser(dn, password string) error {
+	conn, err := dialLDAP()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Bind(dn, password)
+}
+
+func dialLDAP() (*ldap.Conn, error) {
+	var config *tls.Config
+	if userConfig.GetBool("ldap.insecure_skip_verify") {
+		config = &tls.Config{InsecureSkipVerify: true}
+	}
+	return ldap.Dial(userConfig.GetString("ldap.url"), userConfig.GetDuration("ldap.timeout"), config)
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func getLoginRecordsService(id uint, param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(param); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if param.OrderBy == "" {
+		param.OrderBy = "id desc"
+	}
+	records, count, err := dbGetLoginRecordsByUser(id, param)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	rs := util.TransSlice(records, loginRecordToJson)
+	return model.SuccessPaged(rs, count, "获取成功")
+}
+
+func unlockUserService(id uint, ip, ua string, auth *model.AuthInfo) *model.ApiJson {
+	user, err := dbGetUserByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	record := &LoginRecord{
+		UserID:    user.ID,
+		Account:   user.Name,
+		Method:    LoginUnlock,
+		Status:    LoginSuccess,
+		Reason:    fmt.Sprintf("由 %s 解锁", auth.Name),
+		IP:        ip,
+		UserAgent: ua,
+	}
+	if err := dbUnlockUser(user.ID, record); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "解锁成功")
+}
+
+// recordLogin saves a login attempt, errors are only logged so that login is never blocked by auditing.
+func recordLogin(user *User, account, method, status, reason, ip, ua string) {
+	record := &LoginRecord{
+		UserID:    util.NilOrBaseValue(user, func(v *User) uint { return v.ID }, 0),
+		Account:   account,
+		Method:    method,
+		Status:    status,
+		Reason:    reason,
+		IP:        ip,
+		UserAgent: ua,
+	}
+	dbCreateLoginRecord(record)
+}
+
+// failLogin records a failed attempt of the user and locks the user on too many failures.
+func failLogin(user *User, account, method, reason, ip, ua string) {
+	recordLogin(user, account, method, LoginFailure, reason, ip, ua)
+	attempts := userConfig.GetUint("lockout.attempts")
+	if attempts == 0 {
+		return
+	}
+	locked, err := dbLockUserOnFailures(user, attempts, userConfig.GetDuration("lockout.window"), userConfig.GetDuration("lockout.duration"))
+	if err == nil && locked {
+		mctx.Logger.Infof("user %d is locked due to too many login failures", user.ID)
+	}
+}
+
+// checkLoginLocked returns an error if the user is locked now.
+func checkLoginLocked(user *User) error {
+	if user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now()) {
+		return fmt.Errorf("登录失败次数过多, 账号已锁定至 %s", user.LockedUntil.Time.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+func loginRecordToJson(record *LoginRecord) *LoginRecordJson {
+	if record == nil {
+		return nil
+	} else {
+		return &LoginRecordJson{
+			ID:        record.ID,
+			UserID:    record.UserID,
+			Account:   record.Account,
+			Method:    record.Method,
+			Status:    record.Status,
+			Reason:    record.Reason,
+			IP:        record.IP,
+			UserAgent: record.UserAgent,
+			CreatedAt: record.CreatedAt.Unix(),
+		}
+	}
+}
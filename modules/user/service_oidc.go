@@ -0,0 +1,348 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+)
+
+var (
+	oidcClient = &http.Client{Timeout: 10 * time.Second}
+
+	// discovered endpoints of the configured issuer
+	oidcDiscoveryLock  sync.Mutex
+	oidcDiscoveryCache = map[string]*OIDCDiscovery{}
+)
+
+func oidcLoginService(auth *model.AuthInfo) *model.ApiJson {
+	if !userConfig.GetBool("oidc.enable") {
+		return model.ErrorInvalidData(fmt.Errorf("未启用统一身份认证登录"))
+	}
+	endpoints, err := getOIDCEndpoints()
+	if err != nil {
+		return model.ErrorInternalServer(err)
+	}
+	// a logged in user links the identity to itself instead of logging in
+	id := util.NilOrBaseValue(auth, func(v *model.AuthInfo) uint { return v.User }, 0)
+	state, err := dbCreateOIDCState(id, userConfig.GetDuration("oidc.state_expire"))
+	if err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", userConfig.GetString("oidc.client_id"))
+	params.Set("redirect_uri", userConfig.GetString("oidc.redirect_uri"))
+	params.Set("scope", userConfig.GetString("oidc.scope"))
+	params.Set("state", state.State)
+	params.Set("nonce", state.Nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+	sep := util.Tenary(strings.Contains(endpoints.AuthorizationEndpoint, "?"), "&", "?")
+	data := &OIDCLoginJson{
+		URL:       endpoints.AuthorizationEndpoint + sep + params.Encode(),
+		State:     state.State,
+		ExpiresAt: state.ExpiresAt.Unix(),
+	}
+	return model.Success(data, "获取成功")
+}
+
+func oidcCallbackService(aul *OIDCCallbackRequest, ip, ua string, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if !userConfig.GetBool("oidc.enable") {
+		return model.ErrorInvalidData(fmt.Errorf("未启用统一身份认证登录"))
+	}
+	state, err := dbConsumeOIDCState(aul.State)
+	if err != nil {
+		return model.ErrorVerification(err)
+	}
+	// the identity is linked to the user who started the authorization only
+	id := util.NilOrBaseValue(auth, func(v *model.AuthInfo) uint { return v.User }, 0)
+	if state.UserID != id {
+		return model.ErrorVerification(fmt.Errorf("state无效、已使用或已过期"))
+	}
+	claims, err := getOIDCClaims(aul.Code, state)
+	if err != nil {
+		mctx.Logger.Warnf("OIDCLoginErr: %+v", err)
+		recordLogin(nil, "", LoginOIDC, LoginFailure, err.Error(), ip, ua)
+		return model.ErrorVerification(err)
+	}
+	subject := cast.ToString(claims[userConfig.GetString("oidc.claims.subject")])
+	if subject == "" {
+		return model.ErrorVerification(fmt.Errorf("未获取到用户标识"))
+	}
+	identity := &ExternalIdentity{
+		Provider: userConfig.GetString("oidc.provider"),
+		Subject:  subject,
+		Email:    cast.ToString(claims[userConfig.GetString("oidc.claims.email")]),
+	}
+
+	linked, err := dbGetExternalIdentity(identity.Provider, identity.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.ErrorQueryDatabase(err)
+	}
+	if state.UserID != 0 {
+		if linked != nil {
+			if linked.UserID != state.UserID {
+				return model.ErrorInvalidData(fmt.Errorf("该账号已绑定其他用户"))
+			}
+			return model.SuccessUpdate(nil, "已绑定")
+		}
+		identity.UserID = state.UserID
+		if err := dbAttachExternalIdentity(identity); err != nil {
+			return model.ErrorInsertDatabase(err)
+		}
+		return model.SuccessUpdate(nil, "绑定成功")
+	}
+
+	var user *User
+	if linked != nil {
+		if user, err = dbGetUserByID(linked.UserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return model.ErrorNotFound(err)
+			}
+			return model.ErrorQueryDatabase(err)
+		}
+	} else if userConfig.GetBool("oidc.autocreate") {
+		if user, err = createOIDCUser(identity, claims); err != nil {
+			return model.ErrorInsertDatabase(err)
+		}
+	} else {
+		recordLogin(nil, subject, LoginOIDC, LoginFailure, "账号未绑定", ip, ua)
+		return model.ErrorVerification(fmt.Errorf("账号未绑定, 请登录后绑定"))
+	}
+	if err := checkLoginLocked(user); err != nil {
+		recordLogin(user, subject, LoginOIDC, LoginFailure, err.Error(), ip, ua)
+		return model.ErrorVerification(err)
+	}
+	if requireTOTP(user) {
+		recordLogin(user, subject, LoginOIDC, LoginChallenge, "", ip, ua)
+		return challengeTOTP(user)
+	}
+	if err := dbForceLogin(user.ID, ip); err != nil {
+		return model.ErrorUpdateDatabase(fmt.Errorf("登录失败"))
+	}
+	token, err := issueUserToken(user, ip, ua)
+	if err != nil {
+		return model.ErrorBuildJWT(err)
+	}
+	recordLogin(user, subject, LoginOIDC, LoginSuccess, "", ip, ua)
+	return model.Success(token, "登陆成功")
+}
+
+// createOIDCUser creates a user on first login, whose role and division are mapped from the claims.
+func createOIDCUser(identity *ExternalIdentity, claims map[string]any) (*User, error) {
+	role, division := mapOIDCClaims(claims)
+	aul := &CreateUserRequest{
+		RegisterUserRequest: RegisterUserRequest{
+			Name:        cast.ToString(claims[userConfig.GetString("oidc.claims.name")]),
+			DisplayName: cast.ToString(claims[userConfig.GetString("oidc.claims.display_name")]),
+			Phone:       cast.ToString(claims[userConfig.GetString("oidc.claims.phone")]),
+			Email:       identity.Email,
+		},
+		RoleName:   role,
+		DivisionID: division,
+	}
+	return createUserWithIdentity(aul, identity)
+}
+
+// mapOIDCClaims returns the role and the division of the first matched mapping respectively,
+// falling back to `oidc.role` and `oidc.division`.
+func mapOIDCClaims(claims map[string]any) (role string, division uint) {
+	var mappings []*OIDCMapping
+	if err := userConfig.UnmarshalKey("oidc.mapping", &mappings); err != nil {
+		mctx.Logger.Warnf("invalid oidc mapping: %v", err)
+	}
+	for _, mapping := range mappings {
+		values := cast.ToStringSlice(claims[mapping.Claim])
+		if v, ok := claims[mapping.Claim].(string); ok {
+			values = []string{v}
+		}
+		if !util.In(mapping.Value, values...) {
+			continue
+		}
+		role = util.NotEmpty(role, mapping.Role)
+		if division == 0 {
+			division = mapping.Division
+		}
+	}
+	role = util.NotEmpty(role, userConfig.GetString("oidc.role"))
+	if division == 0 {
+		division = userConfig.GetUint("oidc.division")
+	}
+	return
+}
+
+// getOIDCEndpoints returns the endpoints discovered from the issuer,
+// which are overridden by the configured ones if any.
+func getOIDCEndpoints() (*OIDCDiscovery, error) {
+	issuer := strings.TrimSuffix(userConfig.GetString("oidc.issuer"), "/")
+	endpoints := &OIDCDiscovery{}
+	if issuer != "" {
+		discovery, err := discoverOIDC(issuer)
+		if err != nil {
+			return nil, err
+		}
+		*endpoints = *discovery
+	}
+	endpoints.AuthorizationEndpoint = util.NotEmpty(userConfig.GetString("oidc.endpoints.authorization"), endpoints.AuthorizationEndpoint)
+	endpoints.TokenEndpoint = util.NotEmpty(userConfig.GetString("oidc.endpoints.token"), endpoints.TokenEndpoint)
+	endpoints.UserinfoEndpoint = util.NotEmpty(userConfig.GetString("oidc.endpoints.userinfo"), endpoints.UserinfoEndpoint)
+	if endpoints.AuthorizationEndpoint == "" || endpoints.TokenEndpoint == "" {
+		return nil, fmt.Errorf("未配置统一身份认证的授权地址或令牌地址")
+	}
+	return endpoints, nil
+}
+
+func discoverOIDC(issuer string) (*OIDCDiscovery, error) {
+	oidcDiscoveryLock.Lock()
+	defer oidcDiscoveryLock.Unlock()
+	if discovery, ok := oidcDiscoveryCache[issuer]; ok {
+		return discovery, nil
+	}
+	req, err := http.NewRequest("GET", issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	discovery := &OIDCDiscovery{}
+	if err := doOIDCRequest(req, discovery); err != nil {
+		return nil, fmt.Errorf("discover oidc issuer %s failed: %v", issuer, err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discover oidc issuer %s failed: mismatched issuer %s", issuer, discovery.Issuer)
+	}
+	oidcDiscoveryCache[issuer] = discovery
+	return discovery, nil
+}
+
+// getOIDCClaims exchanges the code for the tokens with the PKCE verifier, and returns
+// the claims of the ID token merged with the ones from the userinfo endpoint.
+func getOIDCClaims(code string, state *OIDCState) (map[string]any, error) {
+	endpoints, err := getOIDCEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	clientID := userConfig.GetString("oidc.client_id")
+	clientSecret := userConfig.GetString("oidc.client_secret")
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", userConfig.GetString("oidc.redirect_uri"))
+	form.Set("code_verifier", state.Verifier)
+	if userConfig.GetString("oidc.auth_method") == "post" {
+		form.Set("client_id", clientID)
+		form.Set("client_secret", clientSecret)
+	}
+	req, err := http.NewRequest("POST", endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if userConfig.GetString("oidc.auth_method") != "post" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+	token := &OIDCTokenResponse{}
+	if err := doOIDCRequest(req, token); err != nil && token.Error == "" {
+		return nil, fmt.Errorf("获取令牌失败: %v", err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("获取令牌失败: %s %s", token.Error, token.ErrorDescription)
+	}
+
+	claims := map[string]any{}
+	if token.IDToken != "" {
+		if claims, err = parseIDToken(token.IDToken, endpoints.Issuer, clientID, state.Nonce); err != nil {
+			return nil, err
+		}
+	}
+	if endpoints.UserinfoEndpoint != "" && token.AccessToken != "" {
+		req, err := http.NewRequest("GET", endpoints.UserinfoEndpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		userinfo := map[string]any{}
+		if err := doOIDCRequest(req, &userinfo); err != nil {
+			return nil, fmt.Errorf("获取用户信息失败: %v", err)
+		}
+		if sub, ok := claims["sub"]; ok && cast.ToString(sub) != cast.ToString(userinfo["sub"]) {
+			return nil, fmt.Errorf("获取用户信息失败: 用户标识不一致")
+		}
+		for k, v := range userinfo {
+			claims[k] = v
+		}
+	}
+	if len(claims) == 0 {
+		return nil, fmt.Errorf("未获取到用户信息")
+	}
+	return claims, nil
+}
+
+// parseIDToken checks the claims of an ID token received directly from the token endpoint.
+// The signature is not verified, since the TLS server validation of the token endpoint is
+// used to validate the issuer instead, as permitted by OpenID Connect Core 3.1.3.7.
+func parseIDToken(idToken, issuer, clientID, nonce string) (map[string]any, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("ID令牌无效")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("ID令牌无效")
+	}
+	claims := map[string]any{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("ID令牌无效")
+	}
+	if issuer != "" && strings.TrimSuffix(cast.ToString(claims["iss"]), "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("ID令牌签发者不匹配")
+	}
+	audience := cast.ToStringSlice(claims["aud"])
+	if aud, ok := claims["aud"].(string); ok {
+		audience = []string{aud}
+	}
+	if !util.In(clientID, audience...) {
+		return nil, fmt.Errorf("ID令牌受众不匹配")
+	}
+	if time.Unix(cast.ToInt64(claims["exp"]), 0).Before(time.Now()) {
+		return nil, fmt.Errorf("ID令牌已过期")
+	}
+	if cast.ToString(claims["nonce"]) != nonce {
+		return nil, fmt.Errorf("ID令牌nonce不匹配")
+	}
+	return claims, nil
+}
+
+// doOIDCRequest sends the request and decodes the JSON response into v,
+// which is still decoded on an error status for the error details.
+func doOIDCRequest(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	res, err := oidcClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("unexpected response %s: %v", res.Status, err)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response %s", res.Status)
+	}
+	return nil
+}
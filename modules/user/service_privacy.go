@@ -0,0 +1,193 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/module"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/jameskeane/bcrypt"
+	"gorm.io/gorm"
+)
+
+const deletedDisplayName = "已注销用户"
+
+type privacyArchive struct {
+	Data   []byte
+	Name   string
+	ApiRes *model.ApiJson
+}
+
+// exportPrivacyService builds a zip archive of the personal data of the user, containing
+// a JSON file for each kind of data, under the directory of the module holding it.
+func exportPrivacyService(auth *model.AuthInfo) *privacyArchive {
+	user, err := dbGetUserByID(auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &privacyArchive{ApiRes: model.ErrorNotFound(err)}
+		}
+		return &privacyArchive{ApiRes: model.ErrorQueryDatabase(err)}
+	}
+	data, err := collectUserData(user)
+	if err != nil {
+		return &privacyArchive{ApiRes: model.ErrorQueryDatabase(err)}
+	}
+	files := map[string]any{}
+	for name, v := range data {
+		files["user/"+name+".json"] = v
+	}
+	for _, m := range mctx.Registry.GetAll() {
+		export, ok := m.Export("privacy.export")
+		if !ok {
+			continue
+		}
+		exporter, ok := export.(module.PrivacyExporter)
+		if !ok {
+			mctx.Logger.Warnf("invalid privacy exporter of module %s", m.Name())
+			continue
+		}
+		data, err := exporter(user.ID)
+		if err != nil {
+			return &privacyArchive{ApiRes: model.ErrorQueryDatabase(err)}
+		}
+		for name, v := range data {
+			files[m.Name()+"/"+name+".json"] = v
+		}
+	}
+
+	archive, err := buildArchive(files)
+	if err != nil {
+		return &privacyArchive{ApiRes: model.ErrorInternalServer(err)}
+	}
+	return &privacyArchive{
+		Data: archive,
+		Name: fmt.Sprintf("maintainman-user-%d-%s.zip", user.ID, time.Now().Format("20060102")),
+	}
+}
+
+// deleteAccountService erases the personal data held by all modules, and then anonymises
+// the user. The orders and their statistics are kept.
+func deleteAccountService(aul *DeleteAccountRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	user, err := dbGetUserByID(auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if !bcrypt.Match(aul.Password, user.Password) {
+		return model.ErrorVerification(fmt.Errorf("密码错误"))
+	}
+	if user.TOTPEnabled {
+		if aul.Code == "" {
+			return model.ErrorVerification(fmt.Errorf("请输入两步验证码"))
+		}
+		if err := checkTOTPCode(user, aul.Code); err != nil {
+			return model.ErrorVerification(err)
+		}
+	}
+
+	for _, m := range mctx.Registry.GetAll() {
+		erase, ok := m.Export("privacy.erase")
+		if !ok {
+			continue
+		}
+		eraser, ok := erase.(module.PrivacyEraser)
+		if !ok {
+			mctx.Logger.Warnf("invalid privacy eraser of module %s", m.Name())
+			continue
+		}
+		if err := eraser(user.ID); err != nil {
+			return model.ErrorUpdateDatabase(err)
+		}
+	}
+	if err := dbEraseUser(user.ID); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	if err := revokeUserTokens(user.ID); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "账号已注销")
+}
+
+// collectUserData returns the data of the user held by this module, keyed by file name.
+func collectUserData(user *User) (map[string]any, error) {
+	records, err := dbGetAllLoginRecordsByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := dbGetAllRefreshTokensByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := dbGetAllAPIKeysByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := dbGetExternalIdentitiesByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]any{
+		"profile":             userToJson(user),
+		"login_records":       util.TransSlice(records, loginRecordToJson),
+		"sessions":            util.TransSlice(tokens, sessionToJson),
+		"api_keys":            util.TransSlice(keys, apiKeyToJson),
+		"external_identities": util.TransSlice(identities, externalIdentityToJson),
+	}
+	return data, nil
+}
+
+func buildArchive(files map[string]any) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	writer := zip.NewWriter(buf)
+	for _, name := range names {
+		content, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		w, err := writer.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func deletedUserName(id uint) string {
+	return fmt.Sprintf("deleted_%d", id)
+}
+
+func externalIdentityToJson(identity *ExternalIdentity) *ExternalIdentityJson {
+	if identity == nil {
+		return nil
+	} else {
+		return &ExternalIdentityJson{
+			Provider:  identity.Provider,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt.Unix(),
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xaxys/maintainman/core/middleware"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+)
+
+func refreshTokenService(aul *RefreshTokenRequest, ip string, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	parts := strings.SplitN(aul.RefreshToken, ".", 2)
+	if len(parts) != 2 {
+		return model.ErrorVerification(fmt.Errorf("刷新令牌无效"))
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return model.ErrorVerification(fmt.Errorf("刷新令牌无效"))
+	}
+	token, err := dbGetRefreshTokenByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorVerification(fmt.Errorf("刷新令牌无效"))
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	secret, err := dbRotateRefreshToken(token.ID, parts[1], ip)
+	if err != nil {
+		// an active token presented with its rotated secret has been used twice,
+		// it may have been stolen, so revoke it along with its access tokens.
+		// Any other secret is merely invalid and must not end the session.
+		if !token.RevokedAt.Valid && token.ExpiresAt.After(time.Now()) && token.PrevHash != "" && token.PrevHash == hashSecret(parts[1]) {
+			revokeRefreshToken(token.ID)
+		}
+		return model.ErrorVerification(err)
+	}
+	user, err := dbGetUserByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if err := dbForceLogin(user.ID, ip); err != nil {
+		return model.ErrorUpdateDatabase(fmt.Errorf("登录失败"))
+	}
+	access, expire, err := buildAccessToken(user, token.ID)
+	if err != nil {
+		return model.ErrorBuildJWT(err)
+	}
+	json := &TokenJson{
+		Token:        access,
+		RefreshToken: fmt.Sprintf("%d.%s", token.ID, secret),
+		ExpiresAt:    expire.Unix(),
+	}
+	return model.Success(json, "刷新成功")
+}
+
+func logoutService(auth *model.AuthInfo) *model.ApiJson {
+	if sid := getAuthSessionID(auth); sid != 0 {
+		if err := revokeRefreshToken(sid); err != nil {
+			return model.ErrorUpdateDatabase(err)
+		}
+	}
+	if jti := cast.ToString(auth.Other["jti"]); jti != "" {
+		middleware.RevokeToken(jti, time.Unix(cast.ToInt64(auth.Other["exp"]), 0))
+	}
+	return model.SuccessUpdate(nil, "退出登录成功")
+}
+
+func logoutAllService(auth *model.AuthInfo) *model.ApiJson {
+	if err := revokeUserTokens(auth.User); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "已退出所有登录")
+}
+
+// issueUserToken starts a new session for the user, returning an access token and a refresh token.
+func issueUserToken(user *User, ip, ua string) (*TokenJson, error) {
+	token, secret, err := dbCreateRefreshToken(user.ID, ip, ua, userConfig.GetDuration("refresh.expire"))
+	if err != nil {
+		return nil, err
+	}
+	access, expire, err := buildAccessToken(user, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	json := &TokenJson{
+		Token:        access,
+		RefreshToken: fmt.Sprintf("%d.%s", token.ID, secret),
+		ExpiresAt:    expire.Unix(),
+	}
+	return json, nil
+}
+
+// buildAccessToken issues an access token with a unique token ID (jti) within the session (sid).
+func buildAccessToken(user *User, sid uint) (string, time.Time, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", time.Time{}, err
+	}
+	expire := time.Now().Add(util.GetJwtExpire())
+	token, err := util.GetJwtStringWithClaims(user.ID, user.Name, user.RoleName, map[string]any{
+		"jti": hex.EncodeToString(jti),
+		"sid": sid,
+		"exp": expire.Unix(),
+	})
+	return token, expire, err
+}
+
+func revokeRefreshToken(id uint) error {
+	if err := dbRevokeRefreshToken(id); err != nil {
+		return err
+	}
+	middleware.RevokeSession(id)
+	mctx.Cache.Del(sessionTouchedKey(id))
+	return nil
+}
+
+// revokeUserTokens revokes all refresh tokens of the user along with the access tokens.
+func revokeUserTokens(id uint) error {
+	ids, err := dbRevokeRefreshTokensByUser(id)
+	if err != nil {
+		return err
+	}
+	for _, sid := range ids {
+		middleware.RevokeSession(sid)
+		mctx.Cache.Del(sessionTouchedKey(sid))
+	}
+	middleware.RevokeUser(id)
+	return nil
+}
+
+func getAuthSessionID(auth *model.AuthInfo) uint {
+	return util.NilOrBaseValue(auth, func(v *model.AuthInfo) uint { return cast.ToUint(v.Other["sid"]) }, 0)
+}
@@ -0,0 +1,137 @@
+package user
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xaxys/maintainman/core/middleware"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval limits how often the activity of a session is written.
+const sessionTouchInterval = time.Minute
+
+// deviceOSes and deviceBrowsers are matched against the User-Agent in order.
+var (
+	deviceOSes = [][2]string{
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+	deviceBrowsers = [][2]string{
+		{"MicroMessenger", "微信"},
+		{"Edg/", "Edge"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+)
+
+func getSessionsService(param *model.PageParam, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(param); err != nil {
+		return model.ErrorValidation(err)
+	}
+	if param.OrderBy == "" {
+		param.OrderBy = "last_active_at desc"
+	}
+	tokens, count, err := dbGetSessionsByUser(auth.User, param)
+	if err != nil {
+		return model.ErrorQueryDatabase(err)
+	}
+	current := getAuthSessionID(auth)
+	ss := util.TransSlice(tokens, func(token *RefreshToken) *SessionJson {
+		json := sessionToJson(token)
+		json.Current = token.ID == current
+		return json
+	})
+	return model.SuccessPaged(ss, count, "获取成功")
+}
+
+func revokeSessionService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if err := dbRevokeSession(auth.User, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorUpdateDatabase(err)
+	}
+	middleware.RevokeSession(id)
+	mctx.Cache.Del(sessionTouchedKey(id))
+	return model.SuccessUpdate(nil, "注销成功")
+}
+
+func revokeUserSessionsService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if _, err := dbGetUserByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if err := revokeUserTokens(id); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "已注销该用户所有登录")
+}
+
+// touchSession records the activity of a session, at most once per sessionTouchInterval.
+func touchSession(sid uint, ip string) {
+	key := sessionTouchedKey(sid)
+	if _, ok := mctx.Cache.Get(key); ok {
+		return
+	}
+	mctx.Cache.Set(key, nil, sessionTouchInterval)
+	dbTouchSession(sid, ip)
+}
+
+// sessionTouchedKey marks the session whose activity is written within sessionTouchInterval.
+func sessionTouchedKey(sid uint) string {
+	return "session:" + strconv.FormatUint(uint64(sid), 36)
+}
+
+// parseDeviceName returns a readable name of the device, such as "Windows Chrome",
+// or the product of the User-Agent if neither the OS nor the browser is known.
+func parseDeviceName(ua string) string {
+	if strings.TrimSpace(ua) == "" {
+		return "未知设备"
+	}
+	names := []string{}
+	for _, list := range [][][2]string{deviceOSes, deviceBrowsers} {
+		for _, v := range list {
+			if strings.Contains(ua, v[0]) {
+				names = append(names, v[1])
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		names = append(names, strings.Fields(ua)[0])
+	}
+	name := strings.Join(names, " ")
+	if len(name) > 191 {
+		name = name[:191]
+	}
+	return name
+}
+
+func sessionToJson(token *RefreshToken) *SessionJson {
+	if token == nil {
+		return nil
+	} else {
+		return &SessionJson{
+			ID:           token.ID,
+			DeviceName:   token.DeviceName,
+			UserAgent:    token.UserAgent,
+			IP:           token.IP,
+			CreatedAt:    token.CreatedAt.Unix(),
+			LastActiveAt: token.LastActiveAt.Unix(),
+			ExpiresAt:    token.ExpiresAt.Unix(),
+		}
+	}
+}
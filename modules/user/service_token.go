@@ -0,0 +1,162 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xaxys/maintainman/core/mail"
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func forgotPasswordService(aul *ForgotPasswordRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	var user *User
+	var err error
+	if util.EmailRegex.MatchString(aul.Account) {
+		user, err = dbGetUserByEmail(aul.Account)
+	} else if util.PhoneRegex.MatchString(aul.Account) {
+		user, err = dbGetUserByPhone(aul.Account)
+	} else {
+		user, err = dbGetUserByName(aul.Account)
+	}
+	// never tell whether the account exists
+	response := model.Success(nil, "若账号存在且设置了邮箱, 重置密码邮件已发送")
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorQueryDatabase(err)
+		}
+		return response
+	}
+	if user.Email == "" {
+		return response
+	}
+	if err := sendUserTokenMail(user, TokenResetPassword); err != nil {
+		mctx.Logger.Errorf("send reset password mail to user %d failed: %s", user.ID, err)
+	}
+	return response
+}
+
+func resetPasswordService(aul *ResetPasswordRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	token, err := parseUserToken(aul.Token, TokenResetPassword)
+	if err != nil {
+		return model.ErrorVerification(err)
+	}
+	if _, err := dbUpdateUser(token.UserID, &UpdateUserRequest{Password: aul.Password}, token.UserID); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	// the password may have been leaked, log out everywhere
+	if err := revokeUserTokens(token.UserID); err != nil {
+		mctx.Logger.Errorf("revoke tokens of user %d failed: %s", token.UserID, err)
+	}
+	// the mail has been received, so the email is proved to be owned by the user
+	if err := dbVerifyUserEmail(token.UserID, token.Email); err != nil {
+		mctx.Logger.Debugf("VerifyUserEmailErr: %v", err)
+	}
+	return model.SuccessUpdate(nil, "重置密码成功")
+}
+
+func sendVerifyEmailService(auth *model.AuthInfo) *model.ApiJson {
+	user, err := dbGetUserByID(auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if user.Email == "" {
+		return model.ErrorInvalidData(fmt.Errorf("未设置邮箱"))
+	}
+	if user.EmailVerified {
+		return model.ErrorInvalidData(fmt.Errorf("邮箱已验证"))
+	}
+	if err := sendUserTokenMail(user, TokenVerifyEmail); err != nil {
+		return model.ErrorInternalServer(err)
+	}
+	return model.Success(nil, "验证邮件已发送")
+}
+
+func verifyEmailService(aul *VerifyEmailRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	token, err := parseUserToken(aul.Token, TokenVerifyEmail)
+	if err != nil {
+		return model.ErrorVerification(err)
+	}
+	if err := dbVerifyUserEmail(token.UserID, token.Email); err != nil {
+		return model.ErrorVerification(err)
+	}
+	return model.SuccessUpdate(nil, "邮箱验证成功")
+}
+
+// sendUserTokenMail issues a token of the purpose and mails it to the user,
+// using the `email.<purpose>.*` templates of the user config.
+func sendUserTokenMail(user *User, purpose string) error {
+	if mctx.Mail == nil {
+		return fmt.Errorf("未配置邮件服务")
+	}
+	expire := userConfig.GetDuration(fmt.Sprintf("email.%s.expire", purpose))
+	token, err := dbCreateUserToken(user, purpose, expire)
+	if err != nil {
+		return err
+	}
+	vars := map[string]any{
+		"Name":        user.Name,
+		"DisplayName": user.DisplayName,
+		"Token":       signUserToken(token),
+		"Expire":      expire.String(),
+	}
+	return mctx.Mail.Send(&mail.Message{
+		To:      []string{user.Email},
+		Subject: util.ProcessString(userConfig.GetString(fmt.Sprintf("email.%s.subject", purpose)), vars),
+		Body:    util.ProcessString(userConfig.GetString(fmt.Sprintf("email.%s.body", purpose)), vars),
+	})
+}
+
+// signUserToken returns the token string delivered to the user.
+func signUserToken(token *UserToken) string {
+	return util.SignString(fmt.Sprintf("%s:%d:%s", token.Purpose, token.UserID, token.Nonce))
+}
+
+// decodeUserToken checks the signature and the purpose of a token string, returning the user ID and the nonce.
+func decodeUserToken(signed, purpose string) (uint, string, error) {
+	payload, err := util.VerifySignedString(signed)
+	if err != nil {
+		return 0, "", fmt.Errorf("令牌无效")
+	}
+	parts := strings.Split(payload, ":")
+	if len(parts) != 3 || parts[0] != purpose {
+		return 0, "", fmt.Errorf("令牌无效")
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("令牌无效")
+	}
+	return uint(id), parts[2], nil
+}
+
+// parseUserToken decodes a token string and consumes the token.
+func parseUserToken(signed, purpose string) (*UserToken, error) {
+	id, nonce, err := decodeUserToken(signed, purpose)
+	if err != nil {
+		return nil, err
+	}
+	token, err := dbConsumeUserToken(nonce, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if token.UserID != id {
+		return nil, fmt.Errorf("令牌无效")
+	}
+	return token, nil
+}
@@ -0,0 +1,237 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xaxys/maintainman/core/model"
+	"github.com/xaxys/maintainman/core/rbac"
+	"github.com/xaxys/maintainman/core/util"
+
+	"gorm.io/gorm"
+)
+
+func setupTOTPService(auth *model.AuthInfo) *model.ApiJson {
+	user, err := dbGetUserByID(auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if user.TOTPEnabled {
+		return model.ErrorInvalidData(fmt.Errorf("已开启两步验证"))
+	}
+	setup, err := newTOTPSetup(user)
+	if err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.Success(setup, "获取成功")
+}
+
+func enableTOTPService(aul *TOTPCodeRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	user, err := dbGetUserByID(auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if user.TOTPEnabled {
+		return model.ErrorInvalidData(fmt.Errorf("已开启两步验证"))
+	}
+	if user.TOTPSecret == "" {
+		return model.ErrorInvalidData(fmt.Errorf("请先获取两步验证密钥"))
+	}
+	if err := checkTOTPCode(user, aul.Code); err != nil {
+		return model.ErrorVerification(err)
+	}
+	codes, err := dbEnableTOTP(user.ID, userConfig.GetInt("totp.recovery_codes"))
+	if err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.Success(codes, "开启成功, 请妥善保存恢复码")
+}
+
+func disableTOTPService(aul *TOTPCodeRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	user, err := dbGetUserByID(auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if !user.TOTPEnabled {
+		return model.ErrorInvalidData(fmt.Errorf("未开启两步验证"))
+	}
+	if rbac.RequireTwoFactor(user.RoleName) {
+		return model.ErrorInvalidData(fmt.Errorf("所属角色要求开启两步验证"))
+	}
+	if err := checkTOTPCode(user, aul.Code); err != nil {
+		return model.ErrorVerification(err)
+	}
+	if err := dbDisableTOTP(user.ID); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "关闭成功")
+}
+
+func resetRecoveryCodesService(aul *TOTPCodeRequest, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	user, err := dbGetUserByID(auth.User)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if !user.TOTPEnabled {
+		return model.ErrorInvalidData(fmt.Errorf("未开启两步验证"))
+	}
+	if err := checkTOTPCode(user, aul.Code); err != nil {
+		return model.ErrorVerification(err)
+	}
+	codes, err := dbResetRecoveryCodes(user.ID, userConfig.GetInt("totp.recovery_codes"))
+	if err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.Success(codes, "重置成功, 请妥善保存恢复码")
+}
+
+func resetTOTPService(id uint, auth *model.AuthInfo) *model.ApiJson {
+	if _, err := dbGetUserByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if err := dbDisableTOTP(id); err != nil {
+		return model.ErrorUpdateDatabase(err)
+	}
+	return model.SuccessUpdate(nil, "重置成功")
+}
+
+func loginTOTPService(aul *TOTPLoginRequest, ip, ua string, auth *model.AuthInfo) *model.ApiJson {
+	if err := util.Validator.Struct(aul); err != nil {
+		return model.ErrorValidation(err)
+	}
+	id, nonce, err := decodeUserToken(aul.ChallengeToken, TokenTwoFactor)
+	if err != nil {
+		return model.ErrorVerification(err)
+	}
+	token, err := dbGetActiveUserToken(nonce, TokenTwoFactor)
+	if err != nil || token.UserID != id {
+		return model.ErrorVerification(fmt.Errorf("令牌无效、已使用或已过期"))
+	}
+	user, err := dbGetUserByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorNotFound(err)
+		}
+		return model.ErrorQueryDatabase(err)
+	}
+	if err := checkLoginLocked(user); err != nil {
+		recordLogin(user, user.Name, LoginTOTP, LoginFailure, err.Error(), ip, ua)
+		return model.ErrorVerification(err)
+	}
+
+	if user.TOTPEnabled && len(aul.Code) != util.TOTPDigits {
+		err = dbUseRecoveryCode(user.ID, aul.Code)
+	} else if user.TOTPEnabled {
+		err = checkTOTPCode(user, aul.Code)
+	} else if user.TOTPSecret != "" {
+		// enrolling on login, only the code of the pending secret is accepted
+		err = checkTOTPCode(user, aul.Code)
+	} else {
+		err = fmt.Errorf("未开启两步验证")
+	}
+	if err != nil {
+		failLogin(user, user.Name, LoginTOTP, err.Error(), ip, ua)
+		if err := dbFailUserToken(token.ID, userConfig.GetUint("totp.challenge.attempts")); err != nil {
+			return model.ErrorUpdateDatabase(err)
+		}
+		return model.ErrorVerification(err)
+	}
+	if _, err := dbConsumeUserToken(nonce, TokenTwoFactor); err != nil {
+		return model.ErrorVerification(err)
+	}
+
+	var codes []string
+	if !user.TOTPEnabled {
+		if codes, err = dbEnableTOTP(user.ID, userConfig.GetInt("totp.recovery_codes")); err != nil {
+			return model.ErrorUpdateDatabase(err)
+		}
+	}
+	if err := dbForceLogin(user.ID, ip); err != nil {
+		return model.ErrorUpdateDatabase(fmt.Errorf("登录失败"))
+	}
+	json, err := issueUserToken(user, ip, ua)
+	if err != nil {
+		return model.ErrorBuildJWT(err)
+	}
+	json.RecoveryCodes = codes
+	recordLogin(user, user.Name, LoginTOTP, LoginSuccess, "", ip, ua)
+	return model.Success(json, "登陆成功")
+}
+
+// requireTOTP reports whether the user must pass the two-factor verification to login.
+func requireTOTP(user *User) bool {
+	return user.TOTPEnabled || rbac.RequireTwoFactor(user.RoleName)
+}
+
+// challengeTOTP issues a short-lived challenge token instead of logging in, which is
+// exchanged for the tokens along with a code. If the role of the user forces two-factor
+// authentication but the user has not enrolled, a pending secret is also returned.
+func challengeTOTP(user *User) *model.ApiJson {
+	token, err := dbCreateUserToken(user, TokenTwoFactor, userConfig.GetDuration("totp.challenge.expire"))
+	if err != nil {
+		return model.ErrorInsertDatabase(err)
+	}
+	json := &TOTPChallengeJson{
+		ChallengeToken: signUserToken(token),
+		ExpiresAt:      token.ExpiresAt.Unix(),
+	}
+	if !user.TOTPEnabled {
+		setup, err := newTOTPSetup(user)
+		if err != nil {
+			return model.ErrorUpdateDatabase(err)
+		}
+		json.Enroll = true
+		json.Secret = setup.Secret
+		json.URI = setup.URI
+	}
+	return model.Fail(json, "需要两步验证")
+}
+
+func newTOTPSetup(user *User) (*TOTPSetupJson, error) {
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := dbSetTOTPSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+	setup := &TOTPSetupJson{
+		Secret: secret,
+		URI:    util.GetTOTPURI(userConfig.GetString("totp.issuer"), user.Name, secret),
+	}
+	return setup, nil
+}
+
+// checkTOTPCode validates a fresh code, which has never been used, of the secret of the user.
+func checkTOTPCode(user *User, code string) error {
+	step := util.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if step < 0 {
+		return fmt.Errorf("验证码错误")
+	}
+	return dbUseTOTPStep(user.ID, step)
+}
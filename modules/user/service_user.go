@@ -1,8 +1,11 @@
 package user
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/xaxys/maintainman/core/model"
 	"github.com/xaxys/maintainman/core/rbac"
@@ -145,7 +148,7 @@ func getAllUsersService(aul *AllUserRequest, auth *model.AuthInfo) *model.ApiJso
 
 const wxURL = "https://api.weixin.qq.com/sns/jscode2session"
 
-func wxUserLoginService(aul *WxLoginRequest, ip string, auth *model.AuthInfo) *model.ApiJson {
+func wxUserLoginService(aul *WxLoginRequest, ip, ua string, auth *model.AuthInfo) *model.ApiJson {
 	if err := util.Validator.Struct(aul); err != nil {
 		return model.ErrorValidation(err)
 	}
@@ -161,6 +164,7 @@ func wxUserLoginService(aul *WxLoginRequest, ip string, auth *model.AuthInfo) *m
 	if aul.Code != "" {
 		id, err := getWxUserOpenID(aul.Code)
 		if err != nil {
+			recordLogin(nil, "", LoginWechat, LoginFailure, err.Error(), ip, ua)
 			return model.ErrorValidation(err)
 		}
 		openID = id
@@ -207,17 +211,31 @@ func wxUserLoginService(aul *WxLoginRequest, ip string, auth *model.AuthInfo) *m
 		id = user.ID
 	}
 
+	if user == nil {
+		if user, err = dbGetUserByID(id); err != nil {
+			return model.ErrorQueryDatabase(err)
+		}
+	}
+	if err := checkLoginLocked(user); err != nil {
+		recordLogin(user, user.Name, LoginWechat, LoginFailure, err.Error(), ip, ua)
+		return model.ErrorVerification(err)
+	}
+	if requireTOTP(user) {
+		recordLogin(user, user.Name, LoginWechat, LoginChallenge, "", ip, ua)
+		return challengeTOTP(user)
+	}
 	if err := dbForceLogin(id, ip); err != nil {
 		return model.ErrorUpdateDatabase(fmt.Errorf("登录失败"))
 	}
-	token, err := util.GetJwtString(id, user.Name, user.RoleName)
+	token, err := issueUserToken(user, ip, ua)
 	if err != nil {
 		return model.ErrorBuildJWT(err)
 	}
-	return model.Success(token, "登陆成功")
+	recordLogin(user, user.Name, LoginWechat, LoginSuccess, "", ip, ua)
+	return loginResponse(token, aul.Refresh)
 }
 
-func wxUserRegisterService(aul *WxRegisterRequest, ip string, auth *model.AuthInfo) *model.ApiJson {
+func wxUserRegisterService(aul *WxRegisterRequest, ip, ua string, auth *model.AuthInfo) *model.ApiJson {
 	if err := util.Validator.Struct(aul); err != nil {
 		return model.ErrorValidation(err)
 	}
@@ -254,44 +272,70 @@ func wxUserRegisterService(aul *WxRegisterRequest, ip string, auth *model.AuthIn
 	if err := dbForceLogin(user.ID, ip); err != nil {
 		return model.ErrorUpdateDatabase(fmt.Errorf("登录失败"))
 	}
-	token, err := util.GetJwtString(user.ID, user.Name, user.RoleName)
+	token, err := issueUserToken(user, ip, ua)
 	if err != nil {
 		return model.ErrorBuildJWT(err)
 	}
-	return model.Success(token, "登陆成功")
+	recordLogin(user, user.Name, LoginWechat, LoginSuccess, "", ip, ua)
+	return loginResponse(token, aul.Refresh)
 }
 
-func userLoginService(aul *LoginRequest, ip string, auth *model.AuthInfo) *model.ApiJson {
+func userLoginService(aul *LoginRequest, ip, ua string, auth *model.AuthInfo) *model.ApiJson {
 	var user *User
 	var err error
 	if err := util.Validator.Struct(aul); err != nil {
 		return model.ErrorValidation(err)
 	}
+	if userConfig.GetBool("ldap.enable") {
+		if response := ldapLoginService(aul, ip, ua, auth); response != nil {
+			return response
+		}
+	}
 	if util.EmailRegex.MatchString(aul.Account) {
-		user, err = dbGetUserByEmail(aul.Account)
+		// only verified emails can be used as login account
+		user, err = dbGetUserByVerifiedEmail(aul.Account)
 		if err != nil {
-			return model.ErrorNotFound(fmt.Errorf("邮箱不存在"))
+			err = fmt.Errorf("邮箱不存在或未验证")
 		}
 	} else if util.PhoneRegex.MatchString(aul.Account) {
 		user, err = dbGetUserByPhone(aul.Account)
 		if err != nil {
-			return model.ErrorNotFound(fmt.Errorf("手机号不存在"))
+			err = fmt.Errorf("手机号不存在")
 		}
 	} else {
 		user, err = dbGetUserByName(aul.Account)
 		if err != nil {
-			return model.ErrorNotFound(fmt.Errorf("用户名不存在"))
+			err = fmt.Errorf("用户名不存在")
 		}
 	}
+	if err != nil {
+		recordLogin(nil, aul.Account, LoginPassword, LoginFailure, err.Error(), ip, ua)
+		return model.ErrorNotFound(err)
+	}
+	if err := checkLoginLocked(user); err != nil {
+		recordLogin(user, aul.Account, LoginPassword, LoginFailure, err.Error(), ip, ua)
+		return model.ErrorVerification(err)
+	}
 
 	user.LoginIP = ip
 	if err := dbCheckLogin(user, aul.Password); err != nil {
+		failLogin(user, aul.Account, LoginPassword, "密码错误", ip, ua)
 		return model.ErrorVerification(fmt.Errorf("密码错误"))
 	}
-	token, err := util.GetJwtString(user.ID, user.Name, user.RoleName)
+	return completeLogin(user, aul.Account, LoginPassword, aul.Refresh, ip, ua, auth)
+}
+
+// completeLogin issues the tokens to an authenticated user, or a challenge if two-factor is required.
+func completeLogin(user *User, account, method string, refresh bool, ip, ua string, auth *model.AuthInfo) *model.ApiJson {
+	if requireTOTP(user) {
+		recordLogin(user, account, method, LoginChallenge, "", ip, ua)
+		return challengeTOTP(user)
+	}
+	token, err := issueUserToken(user, ip, ua)
 	if err != nil {
 		return model.ErrorBuildJWT(err)
 	}
+	recordLogin(user, account, method, LoginSuccess, "", ip, ua)
 	openID := util.NilOrBaseValue(auth, func(v *model.AuthInfo) string {
 		if id := v.Other["openid"]; id != nil {
 			if openID, ok := id.(string); ok {
@@ -303,10 +347,23 @@ func userLoginService(aul *LoginRequest, ip string, auth *model.AuthInfo) *model
 	if openID != "" && user.OpenID == "" {
 		dbAttachOpenIDToUser(user.ID, openID)
 	}
-	return model.Success(token, "登陆成功")
+	return loginResponse(token, refresh)
+}
+
+// loginResponse keeps data the bare JWT Token for the clients predating refresh tokens,
+// the whole TokenJson is returned only if the client asks for it.
+func loginResponse(token *TokenJson, refresh bool) *model.ApiJson {
+	if refresh {
+		return model.Success(token, "登陆成功")
+	}
+	return model.Success(token.Token, "登陆成功")
 }
 
 func userRenewService(id uint, ip string, auth *model.AuthInfo) *model.ApiJson {
+	// a renewed token would carry the whole role, escaping the scopes and expiry of the key
+	if auth != nil && auth.Other["api_key"] != nil {
+		return model.ErrorNoPermissions(fmt.Errorf("权限不足：不能使用API Key续期登录"))
+	}
 	user, err := dbGetUserByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -317,6 +374,21 @@ func userRenewService(id uint, ip string, auth *model.AuthInfo) *model.ApiJson {
 	if err := dbForceLogin(id, ip); err != nil {
 		return model.ErrorUpdateDatabase(fmt.Errorf("登录失败"))
 	}
+	// renew within the session, so that it can not outlive its refresh token
+	if sid := getAuthSessionID(auth); sid != 0 {
+		session, err := dbGetRefreshTokenByID(sid)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.ErrorQueryDatabase(err)
+		}
+		if err != nil || session.UserID != id || session.RevokedAt.Valid || session.ExpiresAt.Before(time.Now()) {
+			return model.ErrorVerification(fmt.Errorf("登录已失效, 请重新登录"))
+		}
+		token, _, err := buildAccessToken(user, sid)
+		if err != nil {
+			return model.ErrorBuildJWT(err)
+		}
+		return model.Success(token, "登陆成功")
+	}
 	token, err := util.GetJwtString(id, user.Name, user.RoleName)
 	if err != nil {
 		return model.ErrorBuildJWT(err)
@@ -358,19 +430,41 @@ func createUserWithOpenID(aul *CreateUserRequest, openID string, operator uint)
 	return
 }
 
+// createUserWithIdentity creates a user of an external identity with a random password,
+// the name is made unique if taken by a local user.
+func createUserWithIdentity(aul *CreateUserRequest, identity *ExternalIdentity) (*User, error) {
+	name := util.NotEmpty(aul.Name, identity.Provider+"_"+identity.Subject)
+	if r := []rune(name); len(r) > 40 {
+		name = string(r[:40])
+	}
+	random := make([]byte, 20)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	if _, err := dbGetUserByName(name); err == nil {
+		name += "_" + hex.EncodeToString(random[:4])
+	}
+	aul.Name = name
+	aul.Password = hex.EncodeToString(random[4:])
+	return dbCreateUserWithIdentity(aul, identity)
+}
+
 func userToJson(user *User) *UserJson {
 	if user == nil {
 		return nil
 	} else {
 		return &UserJson{
-			ID:          user.ID,
-			Name:        user.Name,
-			DisplayName: user.DisplayName,
-			RoleName:    user.RoleName,
-			Phone:       user.Phone,
-			Email:       user.Email,
-			RealName:    user.RealName,
-			LoginTime:   user.LoginTime.Unix(),
+			ID:            user.ID,
+			Name:          user.Name,
+			DisplayName:   user.DisplayName,
+			RoleName:      user.RoleName,
+			Phone:         user.Phone,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			TOTPEnabled:   user.TOTPEnabled,
+			RealName:      user.RealName,
+			LoginTime:     user.LoginTime.Unix(),
+			LockedUntil:   util.Tenary(user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now()), user.LockedUntil.Time.Unix(), 0),
 		}
 	}
 }
@@ -0,0 +1,56 @@
+package wordcloud
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/yanyiwu/gojieba"
+)
+
+// analyzer is the segmenter shared with the other modules as the "analyzer" export.
+var analyzer = &wordAnalyzer{words: map[string][]string{}}
+
+type wordAnalyzer struct {
+	lock  sync.Mutex
+	jieba *gojieba.Jieba
+	words map[string][]string
+}
+
+// SetWords replaces the words kept unsplit on behalf of the owner. gojieba can not remove
+// a word, so the dictionaries are loaded again on the next use if the words have changed.
+func (a *wordAnalyzer) SetWords(owner string, words []string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if reflect.DeepEqual(a.words[owner], words) {
+		return
+	}
+	a.words[owner] = words
+	if a.jieba != nil {
+		a.jieba.Free()
+		a.jieba = nil
+	}
+}
+
+func (a *wordAnalyzer) CutForSearch(text string) []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.load().CutForSearch(text, true)
+}
+
+func (a *wordAnalyzer) Tag(text string) []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.load().Tag(text)
+}
+
+func (a *wordAnalyzer) load() *gojieba.Jieba {
+	if a.jieba == nil {
+		a.jieba = gojieba.NewJieba()
+		for _, words := range a.words {
+			for _, word := range words {
+				a.jieba.AddWord(word)
+			}
+		}
+	}
+	return a.jieba
+}
@@ -3,6 +3,7 @@ package wordcloud
 import (
 	"github.com/kataras/iris/v12"
 	"github.com/xaxys/maintainman/core/module"
+	"github.com/xaxys/maintainman/core/rbac"
 )
 
 var Module = module.Module{
@@ -14,7 +15,9 @@ var Module = module.Module{
 			&Word{},
 		},
 	},
-	ModuleExport: map[string]any{},
+	ModuleExport: map[string]any{
+		"analyzer": module.Analyzer(analyzer),
+	},
 	ModulePerm: map[string]string{
 		"word.upload":  "上传词库",
 		"word.getall":  "获取全部词库",
@@ -27,10 +30,9 @@ var mctx *module.ModuleContext
 
 func entry(ctx *module.ModuleContext) {
 	mctx = ctx
-    //TODO:增加权限管理
 	mctx.Route.PartyFunc("/word", func(user iris.Party) {
-		user.Get("/all", getAllWords)
-		user.Get("/{id:unit}", getWordByOrderId)
-		user.Post("/{id:uint}", uploadWords)
+		user.Get("/all", rbac.PermInterceptor("word.getall"), getAllWords)
+		user.Get("/{id:uint}", rbac.PermInterceptor("word.getword"), getWordByOrderId)
+		user.Post("/{id:uint}", rbac.PermInterceptor("word.upload"), uploadWords)
 	})
 }
@@ -4,7 +4,6 @@ import (
 	"strings"
 
 	"github.com/xaxys/maintainman/core/model"
-	"github.com/yanyiwu/gojieba"
 	"github.com/xaxys/maintainman/modules/order"
 )
 
@@ -28,9 +27,7 @@ type WordCollector struct {
 }
 
 func NewWordCollectorWithStr(str string) *WordCollector {
-	wordAnalyzer := gojieba.NewJieba()
-	defer wordAnalyzer.Free()
-	words := wordAnalyzer.Tag(str)
+	words := analyzer.Tag(str)
 	roots, wordClass := getWordClass(words)
     wordCounter := getWordCounter(roots)
 	return NewWordCollectorWithSet(generateWordSet(roots, wordClass, wordCounter)) 
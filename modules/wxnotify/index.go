@@ -135,6 +135,67 @@ func listener() {
 				"data":        data,
 			}
 
+			wxResp, err := util.HTTPRequest[wxSendMessageResponse](sendMessageURL, "POST", param, payload)
+			if err != nil {
+				mctx.Logger.Warnf("send wechat message failed: %s", err)
+				continue
+			}
+			if wxResp.ErrCode != 0 {
+				mctx.Logger.Warnf("send wechat message failed: %s", wxResp.ErrMsg)
+				continue
+			}
+		// order escalated to supervisor notification
+		case ch := <-mctx.EventBus.On("order:update:escalate"):
+			if statusTmplID == "" {
+				continue
+			}
+			orderID, _ := ch.Args[0].(uint)
+			level, _ := ch.Args[1].(uint)
+			supervisorID, _ := ch.Args[2].(uint)
+			odr, err := order.GetSimpleOrderByID(orderID)
+			if err != nil {
+				mctx.Logger.Errorf("get order failed: %s", err)
+				continue
+			}
+			supervisor, err := user.GetUserByID(supervisorID)
+			if err != nil {
+				mctx.Logger.Errorf("get supervisor failed: %s", err)
+				continue
+			}
+			if supervisor.OpenID == "" {
+				mctx.Logger.Infof("supervisor %d has no openid, skipped", supervisor.ID)
+				continue
+			}
+
+			// get template data
+			data := map[string]string{}
+			if keyStatusOrder != "" {
+				data[keyStatusOrder] = fmt.Sprintf("%d", odr.ID)
+			}
+			if keyStatusTitle != "" {
+				data[keyStatusTitle] = odr.Title
+			}
+			if keyStatusStatus != "" {
+				data[keyStatusStatus] = order.StatusName(order.StatusReported)
+			}
+			if keyStatusTime != "" {
+				data[keyStatusTime] = odr.UpdatedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			if keyStatusOther != "" {
+				data[keyStatusOther] = fmt.Sprintf("订单已上报至您处理 (第%d级主管)", level)
+			}
+
+			// send notification
+			param := map[string]string{
+				"access_token": getAccessToken(),
+			}
+
+			payload := map[string]any{
+				"touser":      supervisor.OpenID,
+				"template_id": statusTmplID,
+				"data":        data,
+			}
+
 			wxResp, err := util.HTTPRequest[wxSendMessageResponse](sendMessageURL, "POST", param, payload)
 			if err != nil {
 				mctx.Logger.Warnf("send wechat message failed: %s", err)